@@ -12,7 +12,6 @@ import (
 	"github.com/dcrodman/archon/internal/core/auth"
 	"github.com/dcrodman/archon/internal/core/data"
 	"github.com/dcrodman/archon/internal/core/debug"
-	"github.com/spf13/viper"
 )
 
 var config = flag.String("config", "./", "Path to the directory containing the server config file")
@@ -24,8 +23,14 @@ func main() {
 	flag.Usage = usage
 	flag.Parse()
 
-	archon.LoadConfig(*config)
-	cleanup, err := initDataSource()
+	cfg, err := archon.LoadConfig(*config)
+	if err != nil {
+		fmt.Println(err.Error())
+		os.Exit(1)
+	}
+	debug.Configure(cfg.Debugging)
+
+	cleanup, err := initDataSource(cfg.Database)
 	if err != nil {
 		fmt.Println(err.Error())
 		os.Exit(1)
@@ -86,15 +91,10 @@ func usage() {
 
 // initDataSource creates the connection to the database, and returns a func
 // which should be deferred for cleanup.
-func initDataSource() (func(), error) {
+func initDataSource(db archon.DatabaseConfig) (func(), error) {
 	dataSource := fmt.Sprintf(
 		"host=%s port=%d dbname=%s user=%s password=%s sslmode=%s",
-		viper.GetString("database.host"),
-		viper.GetInt("database.port"),
-		viper.GetString("database.name"),
-		viper.GetString("database.username"),
-		viper.GetString("database.password"),
-		viper.GetString("database.sslmode"),
+		db.Host, db.Port, db.Name, db.Username, db.Password, db.SSLMode,
 	)
 
 	if err := data.Initialize(dataSource, debug.Enabled()); err != nil {
@@ -119,7 +119,7 @@ func scanInput(prompt string) string {
 }
 
 func addAccount(username, password, email string) error {
-	account, err := auth.CreateAccount(username, password, email)
+	account, err := auth.CreateAccount(username, password, email, true)
 	if err != nil {
 		return fmt.Errorf("failed to create account: %v", err)
 	}
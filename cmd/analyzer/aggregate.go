@@ -20,58 +20,6 @@ var (
 		"ship",
 		"block",
 	}
-
-	patchPacketNames = map[uint64]string{
-		0x02: "PatchWelcomeType",
-		0x04: "PatchHandshakeType",
-		0x13: "PatchMessageType",
-		0x14: "PatchRedirectType",
-		0x0B: "PatchDataAckType",
-		0x0A: "PatchDirAboveType",
-		0x09: "PatchChangeDirType",
-		0x0C: "PatchCheckFileType",
-		0x0D: "PatchFileListDoneType",
-		0x0F: "PatchFileStatusType",
-		0x10: "PatchClientListDoneType",
-		0x11: "PatchUpdateFilesType",
-		0x06: "PatchFileHeaderType",
-		0x07: "PatchFileChunkType",
-		0x08: "PatchFileCompleteType",
-		0x12: "PatchUpdateCompleteType",
-	}
-
-	packetNames = map[uint64]string{
-		0x03:   "LoginWelcomeType            ",
-		0x93:   "LoginType                   ",
-		0xE6:   "LoginSecurityType           ",
-		0x1A:   "LoginClientMessageType      ",
-		0xE0:   "LoginOptionsRequestType     ",
-		0xE2:   "LoginOptionsType            ",
-		0xE3:   "LoginCharPreviewReqType     ",
-		0xE4:   "LoginCharAckType            ",
-		0xE5:   "LoginCharPreviewType        ",
-		0x01E8: "LoginChecksumType          ",
-		0x02E8: "LoginChecksumAckType       ",
-		0x03E8: "LoginGuildcardReqType      ",
-		0x01DC: "LoginGuildcardHeaderType   ",
-		0x02DC: "LoginGuildcardChunkType    ",
-		0x03DC: "LoginGuildcardChunkReqType ",
-		0x01EB: "LoginParameterHeaderType   ",
-		0x02EB: "LoginParameterChunkType    ",
-		0x03EB: "LoginParameterChunkReqType ",
-		0x04EB: "LoginParameterHeaderReqType",
-		0xEC:   "LoginSetFlagType           ",
-		0xB1:   "LoginTimestampType         ",
-		0xA0:   "LoginShipListType          ",
-		0xEE:   "LoginScrollMessageType     ",
-		0x05:   "DisconnectType",
-		0x19:   "RedirectType  ",
-		0x10:   "MenuSelectType",
-		0x83:   "LobbyListType       ",
-		0x07:   "BlockListType       ",
-		0xE7:   "FullCharacterType   ",
-		0x95:   "FullCharacterEndType",
-	}
 )
 
 func aggregateFiles() {
@@ -158,6 +106,7 @@ func writeMarkdownForFile(buf *strings.Builder, subserver, filename string) {
 		_ = writePacketBodyToFile(w, &packet)
 		w.Flush()
 		buf.WriteString("```\n")
+		writePacketFields(buf, getPacketFields(subserver, pType), packetToBytes(packet.Contents))
 
 		if *collapse {
 			buf.WriteString("</details>  \n")
@@ -0,0 +1,86 @@
+package main
+
+import (
+	"encoding/binary"
+	"fmt"
+	"math"
+	"strings"
+)
+
+// Maximum number of bytes of a field shown in the dissected packet.
+const maxFieldBytes = 16
+
+type fieldKind int
+
+const (
+	uintField fieldKind = iota
+	floatField
+	bytesField
+)
+
+// packetField is the location of one field within a packet, generated from the
+// packet schema by packetgen.
+type packetField struct {
+	Name   string
+	Offset int
+	// Number of bytes in the field, or -1 if it extends to the end of the packet.
+	Size int
+	Kind fieldKind
+}
+
+func getPacketFields(subserver string, ptype uint64) []packetField {
+	if subserver == "patch" || subserver == "data" {
+		return patchPacketFields[ptype]
+	}
+	return packetFields[ptype]
+}
+
+// writePacketFields writes a table containing the value of each field in data,
+// stopping at the first field that extends past the end of the packet.
+func writePacketFields(buf *strings.Builder, fields []packetField, data []byte) {
+	if len(fields) == 0 {
+		return
+	}
+
+	buf.WriteString("\n| Offset | Field | Value |\n")
+	buf.WriteString("| ------ | ----- | ----- |\n")
+	for _, field := range fields {
+		end := field.Offset + field.Size
+		if field.Size < 0 {
+			end = len(data)
+		}
+		if end > len(data) || field.Offset > end {
+			break
+		}
+		buf.WriteString(fmt.Sprintf(
+			"| `0x%.4X` | %s | `%s` |\n", field.Offset, field.Name, formatFieldValue(field.Kind, data[field.Offset:end]),
+		))
+	}
+	buf.WriteString("\n")
+}
+
+func formatFieldValue(kind fieldKind, value []byte) string {
+	switch {
+	case kind == uintField && len(value) == 1:
+		return fmt.Sprintf("0x%.2X", value[0])
+	case kind == uintField && len(value) == 2:
+		return fmt.Sprintf("0x%.4X", binary.LittleEndian.Uint16(value))
+	case kind == uintField && len(value) == 4:
+		return fmt.Sprintf("0x%.8X", binary.LittleEndian.Uint32(value))
+	case kind == floatField && len(value) == 4:
+		return fmt.Sprintf("%g", math.Float32frombits(binary.LittleEndian.Uint32(value)))
+	}
+
+	var b strings.Builder
+	for i, v := range value {
+		if i == maxFieldBytes {
+			b.WriteString(fmt.Sprintf(" ... (%d bytes)", len(value)))
+			break
+		}
+		if i > 0 {
+			b.WriteString(" ")
+		}
+		b.WriteString(fmt.Sprintf("%.2X", v))
+	}
+	return b.String()
+}
@@ -0,0 +1,456 @@
+// Code generated by packetgen from internal/packets/schema. DO NOT EDIT.
+
+package main
+
+// Names of the packet types exchanged with the patch and data servers.
+var patchPacketNames = map[uint64]string{
+	0x02: "PatchWelcomeType",
+	0x04: "PatchHandshakeType",
+	0x13: "PatchMessageType",
+	0x14: "PatchRedirectType",
+	0x0B: "PatchDataAckType",
+	0x0A: "PatchDirAboveType",
+	0x09: "PatchChangeDirType",
+	0x0C: "PatchCheckFileType",
+	0x0D: "PatchFileListDoneType",
+	0x0F: "PatchFileStatusType",
+	0x10: "PatchClientListDoneType",
+	0x11: "PatchUpdateFilesType",
+	0x06: "PatchFileHeaderType",
+	0x07: "PatchFileChunkType",
+	0x08: "PatchFileCompleteType",
+	0x12: "PatchUpdateCompleteType",
+}
+
+// Layout of the packets exchanged with the patch and data servers, by packet type.
+var patchPacketFields = map[uint64][]packetField{
+	0x02: { // PatchWelcome
+		{Name: "Header.Size", Offset: 0x00, Size: 2, Kind: uintField},
+		{Name: "Header.Type", Offset: 0x02, Size: 2, Kind: uintField},
+		{Name: "Copyright", Offset: 0x04, Size: 44, Kind: bytesField},
+		{Name: "Padding", Offset: 0x30, Size: 20, Kind: bytesField},
+		{Name: "ServerVector", Offset: 0x44, Size: 4, Kind: bytesField},
+		{Name: "ClientVector", Offset: 0x48, Size: 4, Kind: bytesField},
+	},
+	0x13: { // PatchWelcomeMessage
+		{Name: "Header.Size", Offset: 0x00, Size: 2, Kind: uintField},
+		{Name: "Header.Type", Offset: 0x02, Size: 2, Kind: uintField},
+		{Name: "Message", Offset: 0x04, Size: -1, Kind: bytesField},
+	},
+	0x14: { // PatchRedirect
+		{Name: "Header.Size", Offset: 0x00, Size: 2, Kind: uintField},
+		{Name: "Header.Type", Offset: 0x02, Size: 2, Kind: uintField},
+		{Name: "IPAddr", Offset: 0x04, Size: 4, Kind: bytesField},
+		{Name: "Port", Offset: 0x08, Size: 2, Kind: uintField},
+		{Name: "Padding", Offset: 0x0A, Size: 2, Kind: uintField},
+	},
+	0x09: { // ChangeDir
+		{Name: "Header.Size", Offset: 0x00, Size: 2, Kind: uintField},
+		{Name: "Header.Type", Offset: 0x02, Size: 2, Kind: uintField},
+		{Name: "Dirname", Offset: 0x04, Size: 64, Kind: bytesField},
+	},
+	0x0C: { // CheckFile
+		{Name: "Header.Size", Offset: 0x00, Size: 2, Kind: uintField},
+		{Name: "Header.Type", Offset: 0x02, Size: 2, Kind: uintField},
+		{Name: "PatchID", Offset: 0x04, Size: 4, Kind: uintField},
+		{Name: "Filename", Offset: 0x08, Size: 32, Kind: bytesField},
+	},
+	0x0F: { // FileStatus
+		{Name: "Header.Size", Offset: 0x00, Size: 2, Kind: uintField},
+		{Name: "Header.Type", Offset: 0x02, Size: 2, Kind: uintField},
+		{Name: "PatchID", Offset: 0x04, Size: 4, Kind: uintField},
+		{Name: "Checksum", Offset: 0x08, Size: 4, Kind: uintField},
+		{Name: "FileSize", Offset: 0x0C, Size: 4, Kind: uintField},
+	},
+	0x11: { // StartFileUpdate
+		{Name: "Header.Size", Offset: 0x00, Size: 2, Kind: uintField},
+		{Name: "Header.Type", Offset: 0x02, Size: 2, Kind: uintField},
+		{Name: "TotalSize", Offset: 0x04, Size: 4, Kind: uintField},
+		{Name: "NumFiles", Offset: 0x08, Size: 4, Kind: uintField},
+	},
+	0x06: { // FileHeader
+		{Name: "Header.Size", Offset: 0x00, Size: 2, Kind: uintField},
+		{Name: "Header.Type", Offset: 0x02, Size: 2, Kind: uintField},
+		{Name: "Padding", Offset: 0x04, Size: 4, Kind: uintField},
+		{Name: "FileSize", Offset: 0x08, Size: 4, Kind: uintField},
+		{Name: "Filename", Offset: 0x0C, Size: 48, Kind: bytesField},
+	},
+	0x07: { // FileChunk
+		{Name: "Header.Size", Offset: 0x00, Size: 2, Kind: uintField},
+		{Name: "Header.Type", Offset: 0x02, Size: 2, Kind: uintField},
+		{Name: "Chunk", Offset: 0x04, Size: 4, Kind: uintField},
+		{Name: "Checksum", Offset: 0x08, Size: 4, Kind: uintField},
+		{Name: "Size", Offset: 0x0C, Size: 4, Kind: uintField},
+		{Name: "Data", Offset: 0x10, Size: -1, Kind: bytesField},
+	},
+}
+
+// Names of the packet types exchanged with the login, character, ship, and block servers.
+var packetNames = map[uint64]string{
+	0xB0:   "ServerMessageType",
+	0x83:   "LobbyListType",
+	0x07:   "BlockListType",
+	0xE7:   "FullCharacterType",
+	0x95:   "FullCharacterEndType",
+	0x61:   "PlayerDataType",
+	0xC1:   "CreateGameType",
+	0x98:   "LeaveGameType",
+	0x60:   "GameCommandType",
+	0x62:   "TargetedCommandType",
+	0x6C:   "GameCommandLargeType",
+	0x6D:   "TargetedCommandLargeType",
+	0x81:   "SimpleMailType",
+	0x05:   "DisconnectType",
+	0x19:   "RedirectType",
+	0x10:   "MenuSelectType",
+	0x1D:   "PingType",
+	0x03:   "LoginWelcomeType",
+	0x93:   "LoginType",
+	0xE6:   "LoginSecurityType",
+	0x1A:   "LoginClientMessageType",
+	0xE0:   "LoginOptionsRequestType",
+	0xE2:   "LoginOptionsType",
+	0xE3:   "LoginCharPreviewReqType",
+	0xE4:   "LoginCharAckType",
+	0xE5:   "LoginCharPreviewType",
+	0x01E8: "LoginChecksumType",
+	0x02E8: "LoginChecksumAckType",
+	0x03E8: "LoginGuildcardReqType",
+	0x01DC: "LoginGuildcardHeaderType",
+	0x02DC: "LoginGuildcardChunkType",
+	0x03DC: "LoginGuildcardChunkReqType",
+	0x01EB: "LoginParameterHeaderType",
+	0x02EB: "LoginParameterChunkType",
+	0x03EB: "LoginParameterChunkReqType",
+	0x04EB: "LoginParameterHeaderReqType",
+	0xEC:   "LoginSetFlagType",
+	0xB1:   "LoginTimestampType",
+	0xA0:   "LoginShipListType",
+	0xEE:   "LoginScrollMessageType",
+}
+
+// Layout of the packets exchanged with the login, character, ship, and block servers, by packet type.
+var packetFields = map[uint64][]packetField{
+	0xB0: { // ServerMessage
+		{Name: "Header.Size", Offset: 0x00, Size: 2, Kind: uintField},
+		{Name: "Header.Type", Offset: 0x02, Size: 2, Kind: uintField},
+		{Name: "Header.Flags", Offset: 0x04, Size: 4, Kind: uintField},
+		{Name: "Padding", Offset: 0x08, Size: 8, Kind: bytesField},
+		{Name: "Language", Offset: 0x10, Size: 4, Kind: uintField},
+		{Name: "Message", Offset: 0x14, Size: -1, Kind: bytesField},
+	},
+	0x83: { // LobbyList
+		{Name: "Header.Size", Offset: 0x00, Size: 2, Kind: uintField},
+		{Name: "Header.Type", Offset: 0x02, Size: 2, Kind: uintField},
+		{Name: "Header.Flags", Offset: 0x04, Size: 4, Kind: uintField},
+		{Name: "Lobbies", Offset: 0x08, Size: -1, Kind: bytesField},
+	},
+	0xE7: { // FullCharacter
+		{Name: "Header.Size", Offset: 0x00, Size: 2, Kind: uintField},
+		{Name: "Header.Type", Offset: 0x02, Size: 2, Kind: uintField},
+		{Name: "Header.Flags", Offset: 0x04, Size: 4, Kind: uintField},
+		{Name: "NumInventoryItems", Offset: 0x08, Size: 1, Kind: uintField},
+		{Name: "HPMaterials", Offset: 0x09, Size: 1, Kind: uintField},
+		{Name: "TPMaterials", Offset: 0x0A, Size: 1, Kind: uintField},
+		{Name: "Language", Offset: 0x0B, Size: 1, Kind: uintField},
+		{Name: "Inventory", Offset: 0x0C, Size: 840, Kind: bytesField},
+		{Name: "ATP", Offset: 0x354, Size: 2, Kind: uintField},
+		{Name: "MST", Offset: 0x356, Size: 2, Kind: uintField},
+		{Name: "EVP", Offset: 0x358, Size: 2, Kind: uintField},
+		{Name: "HP", Offset: 0x35A, Size: 2, Kind: uintField},
+		{Name: "DFP", Offset: 0x35C, Size: 2, Kind: uintField},
+		{Name: "ATA", Offset: 0x35E, Size: 2, Kind: uintField},
+		{Name: "LCK", Offset: 0x360, Size: 2, Kind: uintField},
+		{Name: "Unknown", Offset: 0x362, Size: 30, Kind: bytesField},
+		{Name: "Level", Offset: 0x380, Size: 2, Kind: uintField},
+		{Name: "Unknown2", Offset: 0x382, Size: 2, Kind: uintField},
+		{Name: "Experience", Offset: 0x384, Size: 4, Kind: uintField},
+		{Name: "Meseta", Offset: 0x388, Size: 4, Kind: uintField},
+		{Name: "GuildcardStr", Offset: 0x38C, Size: 10, Kind: bytesField},
+		{Name: "Unknown3", Offset: 0x396, Size: 10, Kind: bytesField},
+		{Name: "NameColorBlue", Offset: 0x3A0, Size: 1, Kind: uintField},
+		{Name: "NameColorGreen", Offset: 0x3A1, Size: 1, Kind: uintField},
+		{Name: "NameColorRed", Offset: 0x3A2, Size: 1, Kind: uintField},
+		{Name: "NameColorTransparency", Offset: 0x3A3, Size: 1, Kind: uintField},
+		{Name: "SkinID", Offset: 0x3A4, Size: 2, Kind: uintField},
+		{Name: "Unknown4", Offset: 0x3A6, Size: 18, Kind: bytesField},
+		{Name: "SectionID", Offset: 0x3B8, Size: 1, Kind: uintField},
+		{Name: "Class", Offset: 0x3B9, Size: 1, Kind: uintField},
+		{Name: "SkinFlag", Offset: 0x3BA, Size: 1, Kind: uintField},
+		{Name: "Unknown5", Offset: 0x3BB, Size: 5, Kind: bytesField},
+		{Name: "Costume", Offset: 0x3C0, Size: 2, Kind: uintField},
+		{Name: "Skin", Offset: 0x3C2, Size: 2, Kind: uintField},
+		{Name: "Face", Offset: 0x3C4, Size: 2, Kind: uintField},
+		{Name: "Head", Offset: 0x3C6, Size: 2, Kind: uintField},
+		{Name: "Hair", Offset: 0x3C8, Size: 2, Kind: uintField},
+		{Name: "HairColorRed", Offset: 0x3CA, Size: 2, Kind: uintField},
+		{Name: "HairColorBlue", Offset: 0x3CC, Size: 2, Kind: uintField},
+		{Name: "HairColorGreen", Offset: 0x3CE, Size: 2, Kind: uintField},
+		{Name: "ProportionX", Offset: 0x3D0, Size: 4, Kind: uintField},
+		{Name: "ProportionY", Offset: 0x3D4, Size: 4, Kind: uintField},
+		{Name: "Name", Offset: 0x3D8, Size: 24, Kind: bytesField},
+		{Name: "PlayTime", Offset: 0x3F0, Size: 4, Kind: uintField},
+		{Name: "Unknown6", Offset: 0x3F4, Size: 4, Kind: bytesField},
+		{Name: "KeyConfig", Offset: 0x3F8, Size: 232, Kind: bytesField},
+		{Name: "Techniques", Offset: 0x4E0, Size: 20, Kind: bytesField},
+		{Name: "Unknown7", Offset: 0x4F4, Size: 16, Kind: bytesField},
+		{Name: "Options", Offset: 0x504, Size: 4, Kind: bytesField},
+		{Name: "Reserved4", Offset: 0x508, Size: 4, Kind: uintField},
+		{Name: "QuestData", Offset: 0x50C, Size: 512, Kind: bytesField},
+		{Name: "Reserved5", Offset: 0x70C, Size: 4, Kind: uintField},
+		{Name: "BankUse", Offset: 0x710, Size: 4, Kind: uintField},
+		{Name: "BankMeseta", Offset: 0x714, Size: 4, Kind: uintField},
+		{Name: "BankInventory", Offset: 0x718, Size: 4800, Kind: bytesField},
+		{Name: "Guildcard", Offset: 0x19D8, Size: 4, Kind: uintField},
+		{Name: "Name2", Offset: 0x19DC, Size: 24, Kind: bytesField},
+		{Name: "Unknown9", Offset: 0x19F4, Size: 56, Kind: bytesField},
+		{Name: "GuildcardText", Offset: 0x1A2C, Size: 176, Kind: bytesField},
+		{Name: "Reserved1", Offset: 0x1ADC, Size: 1, Kind: uintField},
+		{Name: "Reserved2", Offset: 0x1ADD, Size: 1, Kind: uintField},
+		{Name: "SectionID2", Offset: 0x1ADE, Size: 1, Kind: uintField},
+		{Name: "Class2", Offset: 0x1ADF, Size: 1, Kind: uintField},
+		{Name: "Unknown10", Offset: 0x1AE0, Size: 4, Kind: bytesField},
+		{Name: "SymbolChats", Offset: 0x1AE4, Size: 1248, Kind: bytesField},
+		{Name: "Shortcuts", Offset: 0x1FC4, Size: 2624, Kind: bytesField},
+		{Name: "AutoReply", Offset: 0x2A04, Size: 344, Kind: bytesField},
+		{Name: "GCBoard", Offset: 0x2B5C, Size: 172, Kind: bytesField},
+		{Name: "Unknown12", Offset: 0x2C08, Size: 200, Kind: bytesField},
+		{Name: "ChallengeData", Offset: 0x2CD0, Size: 320, Kind: bytesField},
+		{Name: "TechConfig", Offset: 0x2E10, Size: 40, Kind: bytesField},
+		{Name: "Unknown13", Offset: 0x2E38, Size: 40, Kind: bytesField},
+		{Name: "QuestData2", Offset: 0x2E60, Size: 92, Kind: bytesField},
+		{Name: "Unknown14", Offset: 0x2EBC, Size: 276, Kind: bytesField},
+		{Name: "KeyConfigGlobal", Offset: 0x2FD0, Size: 364, Kind: bytesField},
+		{Name: "JoystickConfigGlobal", Offset: 0x313C, Size: 56, Kind: bytesField},
+		{Name: "Guildcard2", Offset: 0x3174, Size: 4, Kind: uintField},
+		{Name: "TeamID", Offset: 0x3178, Size: 4, Kind: uintField},
+		{Name: "TeamInformation", Offset: 0x317C, Size: 8, Kind: bytesField},
+		{Name: "PrivilegeLevel", Offset: 0x3184, Size: 2, Kind: uintField},
+		{Name: "Reserved3", Offset: 0x3186, Size: 2, Kind: uintField},
+		{Name: "TeamName", Offset: 0x3188, Size: 28, Kind: bytesField},
+		{Name: "Unknown15", Offset: 0x31A4, Size: 4, Kind: uintField},
+		{Name: "TeamFlag", Offset: 0x31A8, Size: 2048, Kind: bytesField},
+		{Name: "TeamRewards", Offset: 0x39A8, Size: 8, Kind: bytesField},
+	},
+	0x81: { // SimpleMail
+		{Name: "Header.Size", Offset: 0x00, Size: 2, Kind: uintField},
+		{Name: "Header.Type", Offset: 0x02, Size: 2, Kind: uintField},
+		{Name: "Header.Flags", Offset: 0x04, Size: 4, Kind: uintField},
+		{Name: "PlayerTag", Offset: 0x08, Size: 4, Kind: uintField},
+		{Name: "FromGuildcard", Offset: 0x0C, Size: 4, Kind: uintField},
+		{Name: "FromName", Offset: 0x10, Size: 32, Kind: bytesField},
+		{Name: "ToGuildcard", Offset: 0x30, Size: 4, Kind: uintField},
+		{Name: "ReceivedDate", Offset: 0x34, Size: 40, Kind: bytesField},
+		{Name: "Text", Offset: 0x5C, Size: -1, Kind: bytesField},
+	},
+	0x03: { // Welcome
+		{Name: "Header.Size", Offset: 0x00, Size: 2, Kind: uintField},
+		{Name: "Header.Type", Offset: 0x02, Size: 2, Kind: uintField},
+		{Name: "Header.Flags", Offset: 0x04, Size: 4, Kind: uintField},
+		{Name: "Copyright", Offset: 0x08, Size: 96, Kind: bytesField},
+		{Name: "ServerVector", Offset: 0x68, Size: 48, Kind: bytesField},
+		{Name: "ClientVector", Offset: 0x98, Size: 48, Kind: bytesField},
+	},
+	0x93: { // Login
+		{Name: "Header.Size", Offset: 0x00, Size: 2, Kind: uintField},
+		{Name: "Header.Type", Offset: 0x02, Size: 2, Kind: uintField},
+		{Name: "Header.Flags", Offset: 0x04, Size: 4, Kind: uintField},
+		{Name: "Unknown", Offset: 0x08, Size: 8, Kind: bytesField},
+		{Name: "ClientVersion", Offset: 0x10, Size: 2, Kind: uintField},
+		{Name: "Unknown2", Offset: 0x12, Size: 4, Kind: uintField},
+		{Name: "Phase", Offset: 0x16, Size: 1, Kind: uintField},
+		{Name: "Unknown4", Offset: 0x17, Size: 1, Kind: uintField},
+		{Name: "TeamID", Offset: 0x18, Size: 4, Kind: uintField},
+		{Name: "Username", Offset: 0x1C, Size: 16, Kind: bytesField},
+		{Name: "Padding", Offset: 0x2C, Size: 32, Kind: bytesField},
+		{Name: "Password", Offset: 0x4C, Size: 16, Kind: bytesField},
+		{Name: "Unknown3", Offset: 0x5C, Size: 40, Kind: bytesField},
+		{Name: "HardwareInfo", Offset: 0x84, Size: 8, Kind: bytesField},
+		{Name: "Security", Offset: 0x8C, Size: 40, Kind: bytesField},
+	},
+	0xE6: { // Security
+		{Name: "Header.Size", Offset: 0x00, Size: 2, Kind: uintField},
+		{Name: "Header.Type", Offset: 0x02, Size: 2, Kind: uintField},
+		{Name: "Header.Flags", Offset: 0x04, Size: 4, Kind: uintField},
+		{Name: "ErrorCode", Offset: 0x08, Size: 4, Kind: uintField},
+		{Name: "PlayerTag", Offset: 0x0C, Size: 4, Kind: uintField},
+		{Name: "Guildcard", Offset: 0x10, Size: 4, Kind: uintField},
+		{Name: "TeamID", Offset: 0x14, Size: 4, Kind: uintField},
+		{Name: "Config.Magic", Offset: 0x18, Size: 4, Kind: uintField},
+		{Name: "Config.CharSelected", Offset: 0x1C, Size: 1, Kind: uintField},
+		{Name: "Config.SlotNum", Offset: 0x1D, Size: 1, Kind: uintField},
+		{Name: "Config.Flags", Offset: 0x1E, Size: 2, Kind: uintField},
+		{Name: "Config.Ports", Offset: 0x20, Size: 8, Kind: bytesField},
+		{Name: "Config.SessionToken", Offset: 0x28, Size: 16, Kind: bytesField},
+		{Name: "Config.Unused2", Offset: 0x38, Size: 8, Kind: bytesField},
+		{Name: "Capabilities", Offset: 0x40, Size: 4, Kind: uintField},
+	},
+	0x19: { // Redirect
+		{Name: "Header.Size", Offset: 0x00, Size: 2, Kind: uintField},
+		{Name: "Header.Type", Offset: 0x02, Size: 2, Kind: uintField},
+		{Name: "Header.Flags", Offset: 0x04, Size: 4, Kind: uintField},
+		{Name: "IPAddr", Offset: 0x08, Size: 4, Kind: bytesField},
+		{Name: "Port", Offset: 0x0C, Size: 2, Kind: uintField},
+		{Name: "Padding", Offset: 0x0E, Size: 2, Kind: uintField},
+	},
+	0xE2: { // Options
+		{Name: "Header.Size", Offset: 0x00, Size: 2, Kind: uintField},
+		{Name: "Header.Type", Offset: 0x02, Size: 2, Kind: uintField},
+		{Name: "Header.Flags", Offset: 0x04, Size: 4, Kind: uintField},
+		{Name: "PlayerKeyConfig.Unknown", Offset: 0x08, Size: 276, Kind: bytesField},
+		{Name: "PlayerKeyConfig.KeyConfig", Offset: 0x11C, Size: 364, Kind: bytesField},
+		{Name: "PlayerKeyConfig.JoystickConfig", Offset: 0x288, Size: 56, Kind: bytesField},
+		{Name: "PlayerKeyConfig.Guildcard", Offset: 0x2C0, Size: 4, Kind: uintField},
+		{Name: "PlayerKeyConfig.TeamID", Offset: 0x2C4, Size: 4, Kind: uintField},
+		{Name: "PlayerKeyConfig.TeamInfo", Offset: 0x2C8, Size: 8, Kind: bytesField},
+		{Name: "PlayerKeyConfig.TeamPrivilegeLevel", Offset: 0x2D0, Size: 2, Kind: uintField},
+		{Name: "PlayerKeyConfig.Reserved", Offset: 0x2D2, Size: 2, Kind: uintField},
+		{Name: "PlayerKeyConfig.Teamname", Offset: 0x2D4, Size: 32, Kind: bytesField},
+		{Name: "PlayerKeyConfig.TeamFlag", Offset: 0x2F4, Size: 2044, Kind: bytesField},
+		{Name: "PlayerKeyConfig.TeamRewards", Offset: 0xAF0, Size: 8, Kind: bytesField},
+	},
+	0xE3: { // CharacterSelection
+		{Name: "Header.Size", Offset: 0x00, Size: 2, Kind: uintField},
+		{Name: "Header.Type", Offset: 0x02, Size: 2, Kind: uintField},
+		{Name: "Header.Flags", Offset: 0x04, Size: 4, Kind: uintField},
+		{Name: "Slot", Offset: 0x08, Size: 4, Kind: uintField},
+		{Name: "Selecting", Offset: 0x0C, Size: 4, Kind: uintField},
+	},
+	0xE4: { // CharacterAck
+		{Name: "Header.Size", Offset: 0x00, Size: 2, Kind: uintField},
+		{Name: "Header.Type", Offset: 0x02, Size: 2, Kind: uintField},
+		{Name: "Header.Flags", Offset: 0x04, Size: 4, Kind: uintField},
+		{Name: "Slot", Offset: 0x08, Size: 4, Kind: uintField},
+		{Name: "Flag", Offset: 0x0C, Size: 4, Kind: uintField},
+	},
+	0x02E8: { // ChecksumAck
+		{Name: "Header.Size", Offset: 0x00, Size: 2, Kind: uintField},
+		{Name: "Header.Type", Offset: 0x02, Size: 2, Kind: uintField},
+		{Name: "Header.Flags", Offset: 0x04, Size: 4, Kind: uintField},
+		{Name: "Ack", Offset: 0x08, Size: 4, Kind: uintField},
+	},
+	0x01DC: { // GuildcardHeader
+		{Name: "Header.Size", Offset: 0x00, Size: 2, Kind: uintField},
+		{Name: "Header.Type", Offset: 0x02, Size: 2, Kind: uintField},
+		{Name: "Header.Flags", Offset: 0x04, Size: 4, Kind: uintField},
+		{Name: "Unknown", Offset: 0x08, Size: 4, Kind: uintField},
+		{Name: "Length", Offset: 0x0C, Size: 2, Kind: uintField},
+		{Name: "Padding", Offset: 0x0E, Size: 2, Kind: uintField},
+		{Name: "Checksum", Offset: 0x10, Size: 4, Kind: uintField},
+	},
+	0x03DC: { // GuildcardChunkRequest
+		{Name: "Header.Size", Offset: 0x00, Size: 2, Kind: uintField},
+		{Name: "Header.Type", Offset: 0x02, Size: 2, Kind: uintField},
+		{Name: "Header.Flags", Offset: 0x04, Size: 4, Kind: uintField},
+		{Name: "Unknown", Offset: 0x08, Size: 4, Kind: uintField},
+		{Name: "ChunkRequested", Offset: 0x0C, Size: 4, Kind: uintField},
+		{Name: "Continue", Offset: 0x10, Size: 4, Kind: uintField},
+	},
+	0x02DC: { // GuildcardChunk
+		{Name: "Header.Size", Offset: 0x00, Size: 2, Kind: uintField},
+		{Name: "Header.Type", Offset: 0x02, Size: 2, Kind: uintField},
+		{Name: "Header.Flags", Offset: 0x04, Size: 4, Kind: uintField},
+		{Name: "Unknown", Offset: 0x08, Size: 4, Kind: uintField},
+		{Name: "Chunk", Offset: 0x0C, Size: 4, Kind: uintField},
+		{Name: "Data", Offset: 0x10, Size: -1, Kind: bytesField},
+	},
+	0x01EB: { // ParameterHeader
+		{Name: "Header.Size", Offset: 0x00, Size: 2, Kind: uintField},
+		{Name: "Header.Type", Offset: 0x02, Size: 2, Kind: uintField},
+		{Name: "Header.Flags", Offset: 0x04, Size: 4, Kind: uintField},
+		{Name: "Entries", Offset: 0x08, Size: -1, Kind: bytesField},
+	},
+	0x02EB: { // ParameterChunk
+		{Name: "Header.Size", Offset: 0x00, Size: 2, Kind: uintField},
+		{Name: "Header.Type", Offset: 0x02, Size: 2, Kind: uintField},
+		{Name: "Header.Flags", Offset: 0x04, Size: 4, Kind: uintField},
+		{Name: "Chunk", Offset: 0x08, Size: 4, Kind: uintField},
+		{Name: "Data", Offset: 0x0C, Size: -1, Kind: bytesField},
+	},
+	0xEC: { // SetFlag
+		{Name: "Header.Size", Offset: 0x00, Size: 2, Kind: uintField},
+		{Name: "Header.Type", Offset: 0x02, Size: 2, Kind: uintField},
+		{Name: "Header.Flags", Offset: 0x04, Size: 4, Kind: uintField},
+		{Name: "Flag", Offset: 0x08, Size: 4, Kind: uintField},
+	},
+	0xE5: { // CharacterSummary
+		{Name: "Header.Size", Offset: 0x00, Size: 2, Kind: uintField},
+		{Name: "Header.Type", Offset: 0x02, Size: 2, Kind: uintField},
+		{Name: "Header.Flags", Offset: 0x04, Size: 4, Kind: uintField},
+		{Name: "Slot", Offset: 0x08, Size: 4, Kind: uintField},
+		{Name: "Character.Experience", Offset: 0x0C, Size: 4, Kind: uintField},
+		{Name: "Character.Level", Offset: 0x10, Size: 4, Kind: uintField},
+		{Name: "Character.GuildcardStr", Offset: 0x14, Size: 16, Kind: bytesField},
+		{Name: "Character.Unknown", Offset: 0x24, Size: 8, Kind: bytesField},
+		{Name: "Character.NameColor", Offset: 0x2C, Size: 4, Kind: uintField},
+		{Name: "Character.Model", Offset: 0x30, Size: 1, Kind: uintField},
+		{Name: "Character.Padding", Offset: 0x31, Size: 15, Kind: bytesField},
+		{Name: "Character.NameColorChksm", Offset: 0x40, Size: 4, Kind: uintField},
+		{Name: "Character.SectionID", Offset: 0x44, Size: 1, Kind: uintField},
+		{Name: "Character.Class", Offset: 0x45, Size: 1, Kind: uintField},
+		{Name: "Character.V2Flags", Offset: 0x46, Size: 1, Kind: uintField},
+		{Name: "Character.Version", Offset: 0x47, Size: 1, Kind: uintField},
+		{Name: "Character.V1Flags", Offset: 0x48, Size: 4, Kind: uintField},
+		{Name: "Character.Costume", Offset: 0x4C, Size: 2, Kind: uintField},
+		{Name: "Character.Skin", Offset: 0x4E, Size: 2, Kind: uintField},
+		{Name: "Character.Face", Offset: 0x50, Size: 2, Kind: uintField},
+		{Name: "Character.Head", Offset: 0x52, Size: 2, Kind: uintField},
+		{Name: "Character.Hair", Offset: 0x54, Size: 2, Kind: uintField},
+		{Name: "Character.HairRed", Offset: 0x56, Size: 2, Kind: uintField},
+		{Name: "Character.HairGreen", Offset: 0x58, Size: 2, Kind: uintField},
+		{Name: "Character.HairBlue", Offset: 0x5A, Size: 2, Kind: uintField},
+		{Name: "Character.PropX", Offset: 0x5C, Size: 4, Kind: floatField},
+		{Name: "Character.PropY", Offset: 0x60, Size: 4, Kind: floatField},
+		{Name: "Character.Name", Offset: 0x64, Size: 32, Kind: bytesField},
+		{Name: "Character.Playtime", Offset: 0x84, Size: 4, Kind: uintField},
+	},
+	0x1A: { // LoginClientMessage
+		{Name: "Header.Size", Offset: 0x00, Size: 2, Kind: uintField},
+		{Name: "Header.Type", Offset: 0x02, Size: 2, Kind: uintField},
+		{Name: "Header.Flags", Offset: 0x04, Size: 4, Kind: uintField},
+		{Name: "Language", Offset: 0x08, Size: 4, Kind: uintField},
+		{Name: "Message", Offset: 0x0C, Size: -1, Kind: bytesField},
+	},
+	0xB1: { // Timestamp
+		{Name: "Header.Size", Offset: 0x00, Size: 2, Kind: uintField},
+		{Name: "Header.Type", Offset: 0x02, Size: 2, Kind: uintField},
+		{Name: "Header.Flags", Offset: 0x04, Size: 4, Kind: uintField},
+		{Name: "Timestamp", Offset: 0x08, Size: 28, Kind: bytesField},
+	},
+	0xA0: { // ShipList
+		{Name: "Header.Size", Offset: 0x00, Size: 2, Kind: uintField},
+		{Name: "Header.Type", Offset: 0x02, Size: 2, Kind: uintField},
+		{Name: "Header.Flags", Offset: 0x04, Size: 4, Kind: uintField},
+		{Name: "Padding", Offset: 0x08, Size: 2, Kind: uintField},
+		{Name: "Unknown", Offset: 0x0A, Size: 2, Kind: uintField},
+		{Name: "Unknown2", Offset: 0x0C, Size: 4, Kind: uintField},
+		{Name: "Unknown3", Offset: 0x10, Size: 2, Kind: uintField},
+		{Name: "ServerName", Offset: 0x12, Size: 32, Kind: bytesField},
+		{Name: "Padding2", Offset: 0x32, Size: 4, Kind: uintField},
+		{Name: "ShipEntries", Offset: 0x36, Size: -1, Kind: bytesField},
+	},
+	0xEE: { // ScrollMessagePacket
+		{Name: "Header.Size", Offset: 0x00, Size: 2, Kind: uintField},
+		{Name: "Header.Type", Offset: 0x02, Size: 2, Kind: uintField},
+		{Name: "Header.Flags", Offset: 0x04, Size: 4, Kind: uintField},
+		{Name: "Padding", Offset: 0x08, Size: 8, Kind: bytesField},
+		{Name: "Message", Offset: 0x10, Size: -1, Kind: bytesField},
+	},
+	0x10: { // MenuSelection
+		{Name: "Header.Size", Offset: 0x00, Size: 2, Kind: uintField},
+		{Name: "Header.Type", Offset: 0x02, Size: 2, Kind: uintField},
+		{Name: "Header.Flags", Offset: 0x04, Size: 4, Kind: uintField},
+		{Name: "Unknown", Offset: 0x08, Size: 2, Kind: uintField},
+		{Name: "MenuID", Offset: 0x0A, Size: 2, Kind: uintField},
+		{Name: "ItemID", Offset: 0x0C, Size: 4, Kind: uintField},
+	},
+	0x07: { // BlockList
+		{Name: "Header.Size", Offset: 0x00, Size: 2, Kind: uintField},
+		{Name: "Header.Type", Offset: 0x02, Size: 2, Kind: uintField},
+		{Name: "Header.Flags", Offset: 0x04, Size: 4, Kind: uintField},
+		{Name: "Padding", Offset: 0x08, Size: 10, Kind: bytesField},
+		{Name: "ShipName", Offset: 0x12, Size: 32, Kind: bytesField},
+		{Name: "Unknown", Offset: 0x32, Size: 4, Kind: uintField},
+		{Name: "Blocks", Offset: 0x36, Size: -1, Kind: bytesField},
+	},
+}
@@ -1,12 +1,27 @@
-// Generates a self-signed X.509 certificate (valid for 10 years) and corresponding
-// key for TLSv1 authentication between the shipgate's API  server and a client.
+// Generates the X.509 certificates used for mutual TLS authentication between the
+// shipgate's API server and its clients (the login, character, and ship servers).
 //
 // Usage:
-//     generate_cert
 //
-// The tool will prompt for an IP address _OR_ CIDR range for the C.509 certificate.
-// If you want to make your life a little easier (albeit technically less secure),
-// use 0.0.0.0/32 as the address.
+//	certgen [-ip <address>]
+//	certgen -client <name>
+//
+// By default the tool creates a certificate authority (ca.pem and ca-key.pem) if
+// there isn't one in the current directory already, then uses it to issue the
+// shipgate's certificate (certificate.pem and key.pem) and a client certificate
+// for the servers that connect to the shipgate (client-certificate.pem and
+// client-key.pem). The tool will prompt for the IP address _OR_ CIDR range for
+// the shipgate's certificate if -ip isn't given. If you want to make your life a
+// little easier (albeit technically less secure), use 0.0.0.0/32 as the address.
+//
+// With -client, the tool instead uses the CA to issue a client certificate
+// (<name>-certificate.pem and <name>-key.pem). A client certificate named after
+// a ship authenticates that ship with the shipgate; others can be used to connect
+// to the shipgate or to authenticate with the admin API.
+//
+// The shipgate and its clients reload their certificates when the files change,
+// so certificates can be rotated by running the tool again (which keeps the
+// existing CA) and replacing the old files.
 //
 // Some code borrowed from the go standard library:
 // src/crypto/tls/generate_cert.go
@@ -16,6 +31,7 @@ import (
 	"bufio"
 	"crypto/rand"
 	"crypto/rsa"
+	"crypto/tls"
 	"crypto/x509"
 	"crypto/x509/pkix"
 	"encoding/pem"
@@ -29,14 +45,36 @@ import (
 )
 
 const (
-	certificateFilename = "certificate.pem"
-	privateKeyFilename  = "key.pem"
+	caCertificateFilename = "ca.pem"
+	caPrivateKeyFilename  = "ca-key.pem"
+	certificateFilename   = "certificate.pem"
+	privateKeyFilename    = "key.pem"
+	// Name of the client certificate issued for the servers in the main installation.
+	defaultClientName = "client"
+
+	caValidity          = 10 * 365 * 24 * time.Hour
+	certificateValidity = 365 * 24 * time.Hour
 )
 
-var ip = flag.String("ip", "", "Server's external_ip (in config.yaml) or CIDR block")
+var (
+	ip         = flag.String("ip", "", "Server's external_ip (in config.yaml) or CIDR block")
+	clientName = flag.String("client", "", "Issue a client certificate with this name (e.g. a ship name) signed by the CA")
+)
 
 func main() {
 	flag.Parse()
+
+	ca, caKey, err := loadOrCreateCA()
+	if err != nil {
+		fmt.Println(err)
+		return
+	}
+
+	if *clientName != "" {
+		generateClientCertificate(*clientName, ca, caKey)
+		return
+	}
+
 	serverIP := *ip
 	if serverIP == "" {
 		fmt.Print("server's external_ip (in config.yaml) or CIDR block: ")
@@ -45,7 +83,7 @@ func main() {
 		serverIP = scanner.Text()
 	}
 
-	template, err := createX509Template(serverIP)
+	template, err := createServerTemplate(serverIP)
 	if err != nil {
 		fmt.Println("failed to create X.509 template:", err)
 		return
@@ -57,28 +95,83 @@ func main() {
 		return
 	}
 
-	generateCertificateFile(template, privateKey)
-	generatePrivateKeyFile(privateKey)
+	generateCertificateFile(certificateFilename, template, ca, &privateKey.PublicKey, caKey)
+	generatePrivateKeyFile(privateKeyFilename, privateKey)
+	generateClientCertificate(defaultClientName, ca, caKey)
 
 	fmt.Printf(
-		"\nDone! Place %s and %s in the config folder for the shipgate and\n"+
-			"distrubute %s to any standalone ships that will be connecting to your\n"+
-			"server. If the server installation is self-contained then you just need to copy\n"+
-			"those two files to the server's config directory.\n",
+		"\nDone! Place %s, %s, %s, %s-%s, and %s-%s in the config\n"+
+			"folder for the shipgate. Keep %s somewhere safe; it's needed to issue new\n"+
+			"certificates. Standalone ships need %s and a client certificate issued with\n"+
+			"-client, but never %s or %s.\n",
+		caCertificateFilename,
 		certificateFilename,
 		privateKeyFilename,
-		certificateFilename,
+		defaultClientName, certificateFilename,
+		defaultClientName, privateKeyFilename,
+		caPrivateKeyFilename,
+		caCertificateFilename,
+		privateKeyFilename,
+		caPrivateKeyFilename,
 	)
 }
 
-func createX509Template(serverIP string) (*x509.Certificate, error) {
-	serialNumberLimit := new(big.Int).Lsh(big.NewInt(1), 128)
-	serialNumber, err := rand.Int(rand.Reader, serialNumberLimit)
+// loadOrCreateCA loads the CA from the current directory, creating it first if
+// it doesn't exist.
+func loadOrCreateCA() (*x509.Certificate, interface{}, error) {
+	if _, err := os.Stat(caCertificateFilename); os.IsNotExist(err) {
+		template, err := createCATemplate()
+		if err != nil {
+			return nil, nil, fmt.Errorf("failed to create X.509 template: %s", err)
+		}
+		privateKey, err := rsa.GenerateKey(rand.Reader, 2048)
+		if err != nil {
+			return nil, nil, fmt.Errorf("Error generating RSA key: %s", err)
+		}
+		generateCertificateFile(caCertificateFilename, template, template, &privateKey.PublicKey, privateKey)
+		generatePrivateKeyFile(caPrivateKeyFilename, privateKey)
+	}
+
+	ca, err := tls.LoadX509KeyPair(caCertificateFilename, caPrivateKeyFilename)
+	if err != nil {
+		return nil, nil, fmt.Errorf("failed to load %s and %s: %s", caCertificateFilename, caPrivateKeyFilename, err)
+	}
+	caCert, err := x509.ParseCertificate(ca.Certificate[0])
+	if err != nil {
+		return nil, nil, fmt.Errorf("failed to parse %s: %s", caCertificateFilename, err)
+	}
+	return caCert, ca.PrivateKey, nil
+}
+
+func createCATemplate() (*x509.Certificate, error) {
+	serialNumber, err := newSerialNumber()
+	if err != nil {
+		return nil, err
+	}
+
+	notBefore := time.Now()
+	return &x509.Certificate{
+		SerialNumber: serialNumber,
+		Subject: pkix.Name{
+			Organization: []string{"Archon PSO Server"},
+			CommonName:   "Archon CA",
+		},
+		NotBefore: notBefore,
+		NotAfter:  notBefore.Add(caValidity),
+
+		KeyUsage:              x509.KeyUsageDigitalSignature | x509.KeyUsageCertSign,
+		BasicConstraintsValid: true,
+		IsCA:                  true,
+	}, nil
+}
+
+func createServerTemplate(serverIP string) (*x509.Certificate, error) {
+	serialNumber, err := newSerialNumber()
 	if err != nil {
 		return nil, err
 	}
 
-	ip, ipnet, err := net.ParseCIDR(serverIP)
+	ip, _, err := net.ParseCIDR(serverIP)
 	if err != nil {
 		ip = net.ParseIP(serverIP)
 		if ip == nil {
@@ -87,65 +180,94 @@ func createX509Template(serverIP string) (*x509.Certificate, error) {
 	}
 
 	notBefore := time.Now()
-	notAfter := notBefore.Add(time.Hour * 24 * 235 * 10)
-
-	template := &x509.Certificate{
+	return &x509.Certificate{
 		SerialNumber: serialNumber,
 		Subject: pkix.Name{
 			Organization: []string{"Archon PSO Server"},
+			CommonName:   "shipgate",
 		},
 		NotBefore: notBefore,
-		NotAfter:  notAfter,
+		NotAfter:  notBefore.Add(certificateValidity),
 
-		KeyUsage:              x509.KeyUsageKeyEncipherment | x509.KeyUsageDigitalSignature | x509.KeyUsageCertSign,
-		ExtKeyUsage:           []x509.ExtKeyUsage{x509.ExtKeyUsageServerAuth},
-		BasicConstraintsValid: true,
-		IsCA:                  true,
-		IPAddresses:           []net.IP{ip},
+		KeyUsage:    x509.KeyUsageKeyEncipherment | x509.KeyUsageDigitalSignature,
+		ExtKeyUsage: []x509.ExtKeyUsage{x509.ExtKeyUsageServerAuth},
+		IPAddresses: []net.IP{ip},
+	}, nil
+}
+
+// generateClientCertificate issues a certificate for name that's signed by the CA.
+func generateClientCertificate(name string, ca *x509.Certificate, caKey interface{}) {
+	serialNumber, err := newSerialNumber()
+	if err != nil {
+		fmt.Println("failed to generate serial number:", err)
+		return
 	}
-	if ipnet != nil {
-		template.PermittedIPRanges = []*net.IPNet{ipnet}
+
+	notBefore := time.Now()
+	template := &x509.Certificate{
+		SerialNumber: serialNumber,
+		Subject: pkix.Name{
+			Organization: []string{"Archon PSO Server"},
+			CommonName:   name,
+		},
+		NotBefore:   notBefore,
+		NotAfter:    notBefore.Add(certificateValidity),
+		KeyUsage:    x509.KeyUsageDigitalSignature,
+		ExtKeyUsage: []x509.ExtKeyUsage{x509.ExtKeyUsageClientAuth},
+	}
+
+	privateKey, err := rsa.GenerateKey(rand.Reader, 2048)
+	if err != nil {
+		fmt.Printf("Error generating RSA key: %s\n", err.Error())
+		return
 	}
-	return template, nil
+
+	generateCertificateFile(name+"-"+certificateFilename, template, ca, &privateKey.PublicKey, caKey)
+	generatePrivateKeyFile(name+"-"+privateKeyFilename, privateKey)
+}
+
+func newSerialNumber() (*big.Int, error) {
+	serialNumberLimit := new(big.Int).Lsh(big.NewInt(1), 128)
+	return rand.Int(rand.Reader, serialNumberLimit)
 }
 
-func generateCertificateFile(template *x509.Certificate, privateKey *rsa.PrivateKey) {
-	certBytes, err := x509.CreateCertificate(rand.Reader, template, template, &privateKey.PublicKey, privateKey)
+func generateCertificateFile(filename string, template, parent *x509.Certificate, publicKey, signingKey interface{}) {
+	certBytes, err := x509.CreateCertificate(rand.Reader, template, parent, publicKey, signingKey)
 	if err != nil {
 		fmt.Println(err.Error())
 		return
 	}
 
-	certOut, err := os.Create("certificate.pem")
+	certOut, err := os.Create(filename)
 	if err != nil {
-		fmt.Printf("failed to create %s: %s\n", certificateFilename, err)
+		fmt.Printf("failed to create %s: %s\n", filename, err)
 		return
 	}
 
 	err = pem.Encode(certOut, &pem.Block{Type: "CERTIFICATE", Bytes: certBytes})
 	if err != nil {
-		fmt.Printf("failed to create %s: %s", certificateFilename, err)
+		fmt.Printf("failed to create %s: %s", filename, err)
 		return
 	}
 	certOut.Close()
 
-	fmt.Printf("wrote %s\n", certificateFilename)
+	fmt.Printf("wrote %s\n", filename)
 }
 
-func generatePrivateKeyFile(privateKey *rsa.PrivateKey) {
-	keyOut, err := os.OpenFile("key.pem", os.O_WRONLY|os.O_CREATE|os.O_TRUNC, 0600)
+func generatePrivateKeyFile(filename string, privateKey *rsa.PrivateKey) {
+	keyOut, err := os.OpenFile(filename, os.O_WRONLY|os.O_CREATE|os.O_TRUNC, 0600)
 	if err != nil {
-		log.Printf("failed to create %s: %s\n", privateKeyFilename, err)
+		log.Printf("failed to create %s: %s\n", filename, err)
 		return
 	}
 
 	keyBytes := x509.MarshalPKCS1PrivateKey(privateKey)
 	err = pem.Encode(keyOut, &pem.Block{Type: "RSA PRIVATE KEY", Bytes: keyBytes})
 	if err != nil {
-		fmt.Printf("failed to create %s: %s\n", privateKeyFilename, err)
+		fmt.Printf("failed to create %s: %s\n", filename, err)
 		return
 	}
 	keyOut.Close()
 
-	fmt.Printf("wrote %s\n", privateKeyFilename)
+	fmt.Printf("wrote %s\n", filename)
 }
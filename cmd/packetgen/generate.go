@@ -0,0 +1,367 @@
+package main
+
+import (
+	"fmt"
+	"go/format"
+	"strings"
+)
+
+// generatePackets returns the source of the file in the packets package generated
+// from f, containing the packet type constants, the structs, and their codecs.
+func (s *schema) generatePackets(f *schemaFile) ([]byte, error) {
+	var body strings.Builder
+
+	if len(f.Types) > 0 {
+		writeDoc(&body, "", f.TypesDoc)
+		body.WriteString("const (\n")
+		for _, t := range f.Types {
+			writeDoc(&body, "\t", t.Doc)
+			fmt.Fprintf(&body, "\t%s = %s\n", t.Name, formatPacketType(t.Value))
+		}
+		body.WriteString(")\n")
+	}
+
+	for _, def := range f.Structs {
+		body.WriteString("\n")
+		writeDoc(&body, "", def.Doc)
+		fmt.Fprintf(&body, "type %s struct {\n", def.Name)
+		writeFields(&body, "\t", def.Fields)
+		body.WriteString("}\n")
+	}
+
+	for _, def := range f.Structs {
+		body.WriteString("\n")
+		s.writeCodec(&body, def)
+	}
+
+	var imports []string
+	if strings.Contains(body.String(), "binary.") {
+		imports = append(imports, `"encoding/binary"`)
+	}
+	if strings.Contains(body.String(), "math.") {
+		imports = append(imports, `"math"`)
+	}
+
+	var src strings.Builder
+	fmt.Fprintf(&src, "// Code generated by packetgen from schema/%s.yaml. DO NOT EDIT.\n\n", f.name)
+	src.WriteString("package packets\n\n")
+	if len(imports) > 0 {
+		fmt.Fprintf(&src, "import (\n\t%s\n)\n\n", strings.Join(imports, "\n\t"))
+	}
+	src.WriteString(body.String())
+
+	return formatSource(f.name+"_gen.go", src.String())
+}
+
+func formatPacketType(value uint16) string {
+	if value > 0xFF {
+		return fmt.Sprintf("0x%04X", value)
+	}
+	return fmt.Sprintf("0x%02X", value)
+}
+
+func writeDoc(b *strings.Builder, indent, doc string) {
+	if doc == "" {
+		return
+	}
+	for _, line := range strings.Split(strings.TrimRight(doc, "\n"), "\n") {
+		if line == "" {
+			fmt.Fprintf(b, "%s//\n", indent)
+		} else {
+			fmt.Fprintf(b, "%s// %s\n", indent, line)
+		}
+	}
+}
+
+func writeFields(b *strings.Builder, indent string, fields []field) {
+	for _, fld := range fields {
+		writeDoc(b, indent, fld.Doc)
+		if len(fld.Fields) > 0 {
+			fmt.Fprintf(b, "%s%s struct {\n", indent, fld.Name)
+			writeFields(b, indent+"\t", fld.Fields)
+			fmt.Fprintf(b, "%s}\n", indent)
+			continue
+		}
+		fmt.Fprintf(b, "%s%s %s", indent, fld.Name, fld.Type)
+		if fld.Comment != "" {
+			fmt.Fprintf(b, " // %s", fld.Comment)
+		}
+		b.WriteString("\n")
+	}
+}
+
+// writeCodec writes the encoding.BinaryMarshaler, encoding.BinaryUnmarshaler, and
+// packets.Appender implementations for def.
+func (s *schema) writeCodec(b *strings.Builder, def structDef) {
+	fixedSize := s.fieldsSize(def.Fields)
+	sizeExpr := fmt.Sprintf("0x%X", fixedSize)
+	for _, fld := range def.Fields {
+		if len(fld.Fields) > 0 {
+			continue
+		}
+		if t, _ := s.parseType(fld.Type); t.slice {
+			if elemSize := s.sizeOf(t.elem); elemSize == 1 {
+				sizeExpr += fmt.Sprintf(" + len(p.%s)", fld.Name)
+			} else {
+				sizeExpr += fmt.Sprintf(" + %d*len(p.%s)", elemSize, fld.Name)
+			}
+		}
+	}
+
+	fmt.Fprintf(b, "// BinarySize returns the number of bytes in the encoded %s.\n", def.Name)
+	fmt.Fprintf(b, "func (p %s) BinarySize() int { return %s }\n\n", def.Name, sizeExpr)
+	fmt.Fprintf(b, "func (p %s) MarshalBinary() ([]byte, error) {\n", def.Name)
+	b.WriteString("\treturn p.appendTo(make([]byte, 0, p.BinarySize())), nil\n}\n\n")
+	fmt.Fprintf(b, "func (p %s) AppendBinary(b []byte) ([]byte, error) { return p.appendTo(b), nil }\n\n", def.Name)
+	fmt.Fprintf(b, "func (p *%s) UnmarshalBinary(data []byte) error {\n", def.Name)
+	fmt.Fprintf(b, "\tif len(data) < 0x%X {\n\t\treturn notEnoughData(%q, data)\n\t}\n", fixedSize, def.Name)
+	b.WriteString("\tp.decodeFrom(data)\n\treturn nil\n}\n\n")
+
+	fmt.Fprintf(b, "func (p *%s) appendTo(b []byte) []byte {\n", def.Name)
+	for _, fld := range def.Fields {
+		s.writeEncoder(b, "\t", "p."+fld.Name, fld)
+	}
+	b.WriteString("\treturn b\n}\n\n")
+
+	fmt.Fprintf(b, "func (p *%s) decodeFrom(data []byte) []byte {\n", def.Name)
+	for _, fld := range def.Fields {
+		s.writeDecoder(b, "\t", "p."+fld.Name, fld)
+	}
+	b.WriteString("\treturn data\n}\n")
+}
+
+func (s *schema) writeEncoder(b *strings.Builder, indent, expr string, fld field) {
+	if len(fld.Fields) > 0 {
+		for _, inner := range fld.Fields {
+			s.writeEncoder(b, indent, expr+"."+inner.Name, inner)
+		}
+		return
+	}
+
+	t, _ := s.parseType(fld.Type)
+	switch {
+	case (t.array || t.slice) && isByte(t.elem):
+		if t.array {
+			expr += "[:]"
+		}
+		fmt.Fprintf(b, "%sb = append(b, %s...)\n", indent, expr)
+	case t.array || t.slice:
+		fmt.Fprintf(b, "%sfor i := range %s {\n", indent, expr)
+		s.writeValueEncoder(b, indent+"\t", expr+"[i]", t.elem)
+		fmt.Fprintf(b, "%s}\n", indent)
+	default:
+		s.writeValueEncoder(b, indent, expr, t.elem)
+	}
+}
+
+func (s *schema) writeValueEncoder(b *strings.Builder, indent, expr, typeName string) {
+	primitive, conversion := typeName, ""
+	if underlying, ok := s.scalars[typeName]; ok {
+		primitive, conversion = underlying, underlying
+	}
+	if conversion != "" {
+		if isByte(primitive) {
+			conversion = "byte"
+		}
+		expr = fmt.Sprintf("%s(%s)", conversion, expr)
+	}
+
+	switch primitive {
+	case "byte", "uint8":
+		fmt.Fprintf(b, "%sb = append(b, %s)\n", indent, expr)
+	case "uint16":
+		fmt.Fprintf(b, "%sb = appendUint16(b, %s)\n", indent, expr)
+	case "uint32":
+		fmt.Fprintf(b, "%sb = appendUint32(b, %s)\n", indent, expr)
+	case "float32":
+		fmt.Fprintf(b, "%sb = appendUint32(b, math.Float32bits(%s))\n", indent, expr)
+	default:
+		fmt.Fprintf(b, "%sb = %s.appendTo(b)\n", indent, expr)
+	}
+}
+
+func (s *schema) writeDecoder(b *strings.Builder, indent, expr string, fld field) {
+	if len(fld.Fields) > 0 {
+		for _, inner := range fld.Fields {
+			s.writeDecoder(b, indent, expr+"."+inner.Name, inner)
+		}
+		return
+	}
+
+	t, _ := s.parseType(fld.Type)
+	switch {
+	case t.slice && isByte(t.elem):
+		fmt.Fprintf(b, "%s%s = append(%s[:0], data...)\n", indent, expr, expr)
+		fmt.Fprintf(b, "%sdata = data[len(data):]\n", indent)
+	case t.slice:
+		fmt.Fprintf(b, "%s%s = make([]%s, len(data)/%d)\n", indent, expr, t.elem, s.sizeOf(t.elem))
+		fmt.Fprintf(b, "%sfor i := range %s {\n", indent, expr)
+		s.writeValueDecoder(b, indent+"\t", expr+"[i]", t.elem)
+		fmt.Fprintf(b, "%s}\n", indent)
+	case t.array && isByte(t.elem):
+		fmt.Fprintf(b, "%sdata = data[copy(%s[:], data):]\n", indent, expr)
+	case t.array:
+		fmt.Fprintf(b, "%sfor i := range %s {\n", indent, expr)
+		s.writeValueDecoder(b, indent+"\t", expr+"[i]", t.elem)
+		fmt.Fprintf(b, "%s}\n", indent)
+	default:
+		s.writeValueDecoder(b, indent, expr, t.elem)
+	}
+}
+
+func (s *schema) writeValueDecoder(b *strings.Builder, indent, expr, typeName string) {
+	primitive, conversion := typeName, ""
+	if underlying, ok := s.scalars[typeName]; ok {
+		primitive, conversion = underlying, typeName
+	}
+
+	var value string
+	switch primitive {
+	case "byte", "uint8":
+		value = "data[0]"
+	case "uint16":
+		value = "binary.LittleEndian.Uint16(data)"
+	case "uint32":
+		value = "binary.LittleEndian.Uint32(data)"
+	case "float32":
+		value = "math.Float32frombits(binary.LittleEndian.Uint32(data))"
+	default:
+		fmt.Fprintf(b, "%sdata = %s.decodeFrom(data)\n", indent, expr)
+		return
+	}
+	if conversion != "" {
+		value = fmt.Sprintf("%s(%s)", conversion, value)
+	}
+	fmt.Fprintf(b, "%s%s = %s\n", indent, expr, value)
+	fmt.Fprintf(b, "%sdata = data[%d:]\n", indent, primitiveSizes[primitive])
+}
+
+// generateAnalyzerTables returns the source of the file in the analyzer containing
+// the names of the packet types and the layout of each packet's fields.
+func (s *schema) generateAnalyzerTables() ([]byte, error) {
+	var src strings.Builder
+	src.WriteString("// Code generated by packetgen from internal/packets/schema. DO NOT EDIT.\n\n")
+	src.WriteString("package main\n\n")
+
+	tables := []struct {
+		protocol string
+		prefix   string
+		desc     string
+	}{
+		{pcProtocol, "patch", "the patch and data servers"},
+		{bbProtocol, "", "the login, character, ship, and block servers"},
+	}
+	for _, table := range tables {
+		namesVar, fieldsVar := table.prefix+"PacketNames", table.prefix+"PacketFields"
+		if table.prefix == "" {
+			namesVar, fieldsVar = "packetNames", "packetFields"
+		}
+
+		fmt.Fprintf(&src, "// Names of the packet types exchanged with %s.\n", table.desc)
+		fmt.Fprintf(&src, "var %s = map[uint64]string{\n", namesVar)
+		for _, f := range s.files {
+			if f.Protocol != table.protocol {
+				continue
+			}
+			for _, t := range f.Types {
+				fmt.Fprintf(&src, "\t%s: %q,\n", formatPacketType(t.Value), t.Name)
+			}
+		}
+		src.WriteString("}\n\n")
+
+		fmt.Fprintf(&src, "// Layout of the packets exchanged with %s, by packet type.\n", table.desc)
+		fmt.Fprintf(&src, "var %s = map[uint64][]packetField{\n", fieldsVar)
+		for _, f := range s.files {
+			for _, def := range f.Structs {
+				if def.PacketType == "" || s.typeProtocols[def.PacketType] != table.protocol {
+					continue
+				}
+				fmt.Fprintf(&src, "\t%s: { // %s\n", formatPacketType(s.types[def.PacketType].Value), def.Name)
+				s.writePacketFields(&src, "", 0, def.Fields)
+				src.WriteString("\t},\n")
+			}
+		}
+		src.WriteString("}\n\n")
+	}
+
+	return formatSource("packets_gen.go", src.String())
+}
+
+// writePacketFields writes the dissector entries for fields starting at offset,
+// flattening embedded structs into their fields.
+func (s *schema) writePacketFields(b *strings.Builder, prefix string, offset int, fields []field) int {
+	for _, fld := range fields {
+		name := prefix + fld.Name
+		if len(fld.Fields) > 0 {
+			offset = s.writePacketFields(b, name+".", offset, fld.Fields)
+			continue
+		}
+
+		t, _ := s.parseType(fld.Type)
+		if def, ok := s.structs[t.elem]; ok && !t.array && !t.slice {
+			offset = s.writePacketFields(b, name+".", offset, def.Fields)
+			continue
+		}
+
+		size, kind := s.fieldSize(fld), "bytesField"
+		if !t.array && !t.slice {
+			primitive := t.elem
+			if underlying, ok := s.scalars[primitive]; ok {
+				primitive = underlying
+			}
+			kind = "uintField"
+			if primitive == "float32" {
+				kind = "floatField"
+			}
+		}
+		if t.slice {
+			size = -1
+		}
+		fmt.Fprintf(b, "\t\t{Name: %q, Offset: 0x%02X, Size: %d, Kind: %s},\n", name, offset, size, kind)
+		offset += s.fieldSize(fld)
+	}
+	return offset
+}
+
+// generateHandlerAdapters returns the source of the file in the internal package that
+// adapts packet handlers accepting each of the structs to a common signature, so that
+// the packet can be decoded and the handler called without reflection.
+func (s *schema) generateHandlerAdapters() ([]byte, error) {
+	var src strings.Builder
+	src.WriteString("// Code generated by packetgen from internal/packets/schema. DO NOT EDIT.\n\n")
+	src.WriteString("package internal\n\n")
+	src.WriteString("import (\n\t\"context\"\n\n")
+	src.WriteString("\t\"github.com/dcrodman/archon/internal/client\"\n")
+	src.WriteString("\t\"github.com/dcrodman/archon/internal/packets\"\n)\n\n")
+
+	src.WriteString("// decodingHandler returns a function that decodes the packet into the struct\n")
+	src.WriteString("// accepted by handler and passes it along, or nil if handler doesn't accept one.\n")
+	src.WriteString("func decodingHandler(handler interface{}) handlerFunc {\n")
+	src.WriteString("\tswitch fn := handler.(type) {\n")
+	for _, f := range s.files {
+		for _, def := range f.Structs {
+			for _, withContext := range []bool{true, false} {
+				params, args := "*client.Client", "c, &pkt"
+				if withContext {
+					params, args = "context.Context, "+params, "ctx, "+args
+				}
+				fmt.Fprintf(&src, "\tcase func(%s, *packets.%s) error:\n", params, def.Name)
+				src.WriteString("\t\treturn func(ctx context.Context, c *client.Client, data []byte) error {\n")
+				fmt.Fprintf(&src, "\t\t\tvar pkt packets.%s\n", def.Name)
+				src.WriteString("\t\t\tif err := pkt.UnmarshalBinary(data); err != nil {\n\t\t\t\treturn err\n\t\t\t}\n")
+				fmt.Fprintf(&src, "\t\t\treturn fn(%s)\n\t\t}\n", args)
+			}
+		}
+	}
+	src.WriteString("\t}\n\treturn nil\n}\n")
+
+	return formatSource("packet_handlers_gen.go", src.String())
+}
+
+func formatSource(filename, src string) ([]byte, error) {
+	formatted, err := format.Source([]byte(src))
+	if err != nil {
+		return nil, fmt.Errorf("failed to format generated %s: %w", filename, err)
+	}
+	return formatted, nil
+}
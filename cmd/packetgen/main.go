@@ -0,0 +1,74 @@
+// This utility generates the packet definitions in internal/packets from the YAML
+// schema files describing them, along with the tables the analyzer uses to name and
+// dissect captured packets. Each schema file produces one <name>_gen.go file in the
+// packets package containing the packet type constants, the packet structs, and the
+// codecs for encoding and decoding them. The adapters through which the servers'
+// packet handlers receive decoded packets are generated alongside them.
+//
+// It's normally run through go generate in internal/packets:
+//
+//	go generate ./internal/packets
+package main
+
+import (
+	"flag"
+	"fmt"
+	"io/ioutil"
+	"os"
+	"path/filepath"
+)
+
+var (
+	schemaDir   = flag.String("schema", "internal/packets/schema", "Directory containing the packet schema files")
+	packetsDir  = flag.String("packets", "internal/packets", "Directory to which the packet definitions are written")
+	analyzerDir = flag.String("analyzer", "cmd/analyzer", "Directory to which the analyzer's packet tables are written")
+	handlersDir = flag.String("handlers", "internal", "Directory to which the packet handler adapters are written")
+)
+
+func main() {
+	flag.Parse()
+
+	files, err := generate(*schemaDir, *packetsDir, *analyzerDir, *handlersDir)
+	if err != nil {
+		fmt.Printf("error: %v\n", err)
+		os.Exit(1)
+	}
+
+	for path, contents := range files {
+		if err := ioutil.WriteFile(path, contents, 0644); err != nil {
+			fmt.Printf("error: failed to write %s: %v\n", path, err)
+			os.Exit(1)
+		}
+	}
+}
+
+// generate returns the contents of every generated file by its path.
+func generate(schemaDir, packetsDir, analyzerDir, handlersDir string) (map[string][]byte, error) {
+	s, err := loadSchema(schemaDir)
+	if err != nil {
+		return nil, err
+	}
+
+	files := make(map[string][]byte)
+	for _, f := range s.files {
+		contents, err := s.generatePackets(f)
+		if err != nil {
+			return nil, err
+		}
+		files[filepath.Join(packetsDir, f.name+"_gen.go")] = contents
+	}
+
+	contents, err := s.generateAnalyzerTables()
+	if err != nil {
+		return nil, err
+	}
+	files[filepath.Join(analyzerDir, "packets_gen.go")] = contents
+
+	contents, err = s.generateHandlerAdapters()
+	if err != nil {
+		return nil, err
+	}
+	files[filepath.Join(handlersDir, "packet_handlers_gen.go")] = contents
+
+	return files, nil
+}
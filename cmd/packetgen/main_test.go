@@ -0,0 +1,88 @@
+package main
+
+import (
+	"bytes"
+	"io/ioutil"
+	"testing"
+)
+
+func TestGeneratedFilesUpToDate(t *testing.T) {
+	files, err := generate("../../internal/packets/schema", "../../internal/packets", "../analyzer", "../../internal")
+	if err != nil {
+		t.Fatalf("failed to generate files: %v", err)
+	}
+
+	for path, expected := range files {
+		actual, err := ioutil.ReadFile(path)
+		if err != nil {
+			t.Fatalf("failed to read %s: %v", path, err)
+		}
+		if !bytes.Equal(actual, expected) {
+			t.Errorf("%s is out of date with the schema; run go generate ./internal/packets", path)
+		}
+	}
+}
+
+func TestLoadSchemaRejectsInvalidSchemas(t *testing.T) {
+	tests := map[string]string{
+		"unknown_type": `
+protocol: bb
+structs:
+  - name: Bad
+    fields:
+      - name: Header
+        type: Header
+`,
+		"slice_not_last": `
+protocol: bb
+structs:
+  - name: Bad
+    fields:
+      - name: Data
+        type: "[]byte"
+      - name: Flags
+        type: uint32
+`,
+		"wrong_header": `
+protocol: pc
+types:
+  - name: BadType
+    value: 0x01
+structs:
+  - name: Header
+    fields:
+      - name: Size
+        type: uint16
+  - name: Bad
+    packet_type: BadType
+    fields:
+      - name: Header
+        type: Header
+`,
+		"duplicate_value": `
+protocol: bb
+types:
+  - name: FirstType
+    value: 0x01
+  - name: SecondType
+    value: 0x01
+`,
+		"unknown_key": `
+protocol: bb
+struct:
+  - name: Bad
+`,
+	}
+
+	for name, contents := range tests {
+		t.Run(name, func(t *testing.T) {
+			dir := t.TempDir()
+			if err := ioutil.WriteFile(dir+"/bad.yaml", []byte(contents), 0644); err != nil {
+				t.Fatal(err)
+			}
+			if _, err := loadSchema(dir); err == nil {
+				t.Error("expected schema to be rejected")
+			}
+		})
+	}
+}
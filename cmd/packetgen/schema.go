@@ -0,0 +1,321 @@
+package main
+
+import (
+	"fmt"
+	"io/ioutil"
+	"path/filepath"
+	"strconv"
+	"strings"
+
+	"gopkg.in/yaml.v2"
+)
+
+const (
+	pcProtocol = "pc"
+	bbProtocol = "bb"
+)
+
+// Header at the start of every packet sent using each protocol.
+var protocolHeaders = map[string]string{
+	pcProtocol: "PCHeader",
+	bbProtocol: "BBHeader",
+}
+
+// schemaFile describes the packets in one file of the packets package.
+type schemaFile struct {
+	// Base name of the schema file, which is also used to name the generated file.
+	name string
+
+	Protocol string            `yaml:"protocol"`
+	TypesDoc string            `yaml:"types_doc"`
+	Scalars  map[string]string `yaml:"scalars"`
+	Types    []packetType      `yaml:"types"`
+	Structs  []structDef       `yaml:"structs"`
+}
+
+// packetType is a constant identifying a type of packet in the packet header.
+type packetType struct {
+	Name  string `yaml:"name"`
+	Value uint16 `yaml:"value"`
+	Doc   string `yaml:"doc"`
+}
+
+// structDef is a packet (or a struct embedded in one) made up of a list of fields.
+type structDef struct {
+	Name string `yaml:"name"`
+	Doc  string `yaml:"doc"`
+	// Name of the packet type constant sent in the header of this packet, if any.
+	PacketType string  `yaml:"packet_type"`
+	Fields     []field `yaml:"fields"`
+}
+
+type field struct {
+	Name    string `yaml:"name"`
+	Type    string `yaml:"type"`
+	Doc     string `yaml:"doc"`
+	Comment string `yaml:"comment"`
+	// Fields of an anonymous struct, set instead of Type.
+	Fields []field `yaml:"fields"`
+}
+
+// schema is the combined set of schema files, which may refer to each other's types.
+type schema struct {
+	files   []*schemaFile
+	structs map[string]*structDef
+	types   map[string]packetType
+	// Underlying types of the named types declared outside of the schema.
+	scalars map[string]string
+	// Protocol of the file in which each packet type is declared.
+	typeProtocols map[string]string
+}
+
+// Sizes in bytes of the types that can be used as fields.
+var primitiveSizes = map[string]int{
+	"byte":    1,
+	"uint8":   1,
+	"uint16":  2,
+	"uint32":  4,
+	"float32": 4,
+}
+
+// loadSchema reads and validates every schema file in dir.
+func loadSchema(dir string) (*schema, error) {
+	paths, err := filepath.Glob(filepath.Join(dir, "*.yaml"))
+	if err != nil {
+		return nil, err
+	}
+	if len(paths) == 0 {
+		return nil, fmt.Errorf("no schema files found in %s", dir)
+	}
+
+	s := &schema{
+		structs:       make(map[string]*structDef),
+		types:         make(map[string]packetType),
+		scalars:       make(map[string]string),
+		typeProtocols: make(map[string]string),
+	}
+	for _, path := range paths {
+		contents, err := ioutil.ReadFile(path)
+		if err != nil {
+			return nil, err
+		}
+		f := &schemaFile{name: strings.TrimSuffix(filepath.Base(path), ".yaml")}
+		if err := yaml.UnmarshalStrict(contents, f); err != nil {
+			return nil, fmt.Errorf("failed to parse %s: %w", path, err)
+		}
+		if err := s.add(f); err != nil {
+			return nil, fmt.Errorf("%s: %w", path, err)
+		}
+	}
+
+	if err := s.validate(); err != nil {
+		return nil, err
+	}
+	return s, nil
+}
+
+// add indexes the declarations in f, checking for duplicates across files.
+func (s *schema) add(f *schemaFile) error {
+	if f.Protocol != pcProtocol && f.Protocol != bbProtocol {
+		return fmt.Errorf("unsupported protocol %q", f.Protocol)
+	}
+	for name, underlying := range f.Scalars {
+		if _, ok := primitiveSizes[underlying]; !ok {
+			return fmt.Errorf("scalar %s must be a primitive type, got %s", name, underlying)
+		}
+		s.scalars[name] = underlying
+	}
+	for _, t := range f.Types {
+		if _, ok := s.types[t.Name]; ok {
+			return fmt.Errorf("duplicate packet type %s", t.Name)
+		}
+		s.types[t.Name] = t
+		s.typeProtocols[t.Name] = f.Protocol
+	}
+	for i := range f.Structs {
+		def := &f.Structs[i]
+		if _, ok := s.structs[def.Name]; ok {
+			return fmt.Errorf("duplicate struct %s", def.Name)
+		}
+		s.structs[def.Name] = def
+	}
+	s.files = append(s.files, f)
+	return nil
+}
+
+func (s *schema) validate() error {
+	// Packet types only need to be unique amongst packets using the same protocol.
+	values := make(map[string]map[uint16]string)
+	for _, f := range s.files {
+		for _, t := range f.Types {
+			if values[f.Protocol] == nil {
+				values[f.Protocol] = make(map[uint16]string)
+			}
+			if other, ok := values[f.Protocol][t.Value]; ok {
+				return fmt.Errorf("packet types %s and %s have the same value %#04x", other, t.Name, t.Value)
+			}
+			values[f.Protocol][t.Value] = t.Name
+		}
+	}
+
+	linked := make(map[string]string)
+	for _, f := range s.files {
+		for _, def := range f.Structs {
+			if len(def.Fields) == 0 {
+				return fmt.Errorf("struct %s has no fields", def.Name)
+			}
+			if err := s.validateFields(def.Name, def.Fields, true); err != nil {
+				return err
+			}
+			if def.PacketType == "" {
+				continue
+			}
+			if _, ok := s.types[def.PacketType]; !ok {
+				return fmt.Errorf("struct %s refers to undeclared packet type %s", def.Name, def.PacketType)
+			}
+			if header := protocolHeaders[s.typeProtocols[def.PacketType]]; def.Fields[0].Type != header {
+				return fmt.Errorf("packet %s must start with a %s", def.Name, header)
+			}
+			if other, ok := linked[def.PacketType]; ok {
+				return fmt.Errorf("packet type %s is used by both %s and %s", def.PacketType, other, def.Name)
+			}
+			linked[def.PacketType] = def.Name
+		}
+	}
+	return nil
+}
+
+// validateFields checks that every field has a known type. Slices are only
+// allowed as the last field of a packet, since they're decoded from whatever
+// data remains.
+func (s *schema) validateFields(structName string, fields []field, topLevel bool) error {
+	for i, fld := range fields {
+		if len(fld.Fields) > 0 {
+			if fld.Type != "" {
+				return fmt.Errorf("%s.%s cannot have both a type and fields", structName, fld.Name)
+			}
+			if err := s.validateFields(structName+"."+fld.Name, fld.Fields, false); err != nil {
+				return err
+			}
+			continue
+		}
+
+		t, err := s.parseType(fld.Type)
+		if err != nil {
+			return fmt.Errorf("%s.%s: %w", structName, fld.Name, err)
+		}
+		if t.slice && (!topLevel || i != len(fields)-1) {
+			return fmt.Errorf("%s.%s: slices are only supported as the last field", structName, fld.Name)
+		}
+		if t.slice && s.isVariable(t.elem) {
+			return fmt.Errorf("%s.%s: slice elements must have a fixed size", structName, fld.Name)
+		}
+		if !t.slice && s.isVariable(t.elem) {
+			return fmt.Errorf("%s.%s: %s has a variable size", structName, fld.Name, t.elem)
+		}
+	}
+	return nil
+}
+
+// fieldType is a parsed field type such as "uint16", "[0x10]uint16" or "[]Block".
+type fieldType struct {
+	elem  string
+	array bool
+	// Number of elements in the array when array is set.
+	length int
+	slice  bool
+}
+
+func (s *schema) parseType(expr string) (fieldType, error) {
+	t := fieldType{elem: expr}
+	if strings.HasPrefix(expr, "[") {
+		end := strings.Index(expr, "]")
+		if end < 0 {
+			return t, fmt.Errorf("invalid type %q", expr)
+		}
+		t.elem = expr[end+1:]
+		if end == 1 {
+			t.slice = true
+		} else {
+			length, err := strconv.ParseInt(expr[1:end], 0, 32)
+			if err != nil || length <= 0 {
+				return t, fmt.Errorf("invalid array length in %q", expr)
+			}
+			t.array = true
+			t.length = int(length)
+		}
+	}
+
+	if _, ok := primitiveSizes[t.elem]; ok {
+		return t, nil
+	}
+	if _, ok := s.scalars[t.elem]; ok {
+		return t, nil
+	}
+	if _, ok := s.structs[t.elem]; ok {
+		return t, nil
+	}
+	return t, fmt.Errorf("unknown type %q", t.elem)
+}
+
+// isByte returns whether values of the type are encoded as-is.
+func isByte(typeName string) bool {
+	return typeName == "byte" || typeName == "uint8"
+}
+
+// isVariable returns whether the named type contains a slice.
+func (s *schema) isVariable(typeName string) bool {
+	def, ok := s.structs[typeName]
+	return ok && s.hasSlice(def.Fields)
+}
+
+func (s *schema) hasSlice(fields []field) bool {
+	for _, fld := range fields {
+		if len(fld.Fields) > 0 {
+			if s.hasSlice(fld.Fields) {
+				return true
+			}
+			continue
+		}
+		t, _ := s.parseType(fld.Type)
+		if t.slice || s.isVariable(t.elem) {
+			return true
+		}
+	}
+	return false
+}
+
+// sizeOf returns the number of bytes in the fixed-size portion of the named type.
+func (s *schema) sizeOf(typeName string) int {
+	if size, ok := primitiveSizes[typeName]; ok {
+		return size
+	}
+	if underlying, ok := s.scalars[typeName]; ok {
+		return primitiveSizes[underlying]
+	}
+	return s.fieldsSize(s.structs[typeName].Fields)
+}
+
+func (s *schema) fieldsSize(fields []field) int {
+	size := 0
+	for _, fld := range fields {
+		size += s.fieldSize(fld)
+	}
+	return size
+}
+
+// fieldSize returns the number of bytes in a field, which is 0 for slices.
+func (s *schema) fieldSize(fld field) int {
+	if len(fld.Fields) > 0 {
+		return s.fieldsSize(fld.Fields)
+	}
+	t, _ := s.parseType(fld.Type)
+	switch {
+	case t.slice:
+		return 0
+	case t.array:
+		return t.length * s.sizeOf(t.elem)
+	default:
+		return s.sizeOf(t.elem)
+	}
+}
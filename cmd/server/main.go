@@ -7,9 +7,12 @@ import (
 	"context"
 	"flag"
 	"fmt"
+	"net"
+	"net/http"
 	"os"
 	"os/signal"
-	"path/filepath"
+	"strconv"
+	"strings"
 	"sync"
 	"syscall"
 
@@ -18,11 +21,13 @@ import (
 	"github.com/dcrodman/archon/internal/character"
 	"github.com/dcrodman/archon/internal/core/data"
 	"github.com/dcrodman/archon/internal/core/debug"
+	"github.com/dcrodman/archon/internal/core/metrics"
 	"github.com/dcrodman/archon/internal/login"
 	patch2 "github.com/dcrodman/archon/internal/patch"
 	"github.com/dcrodman/archon/internal/ship"
 	"github.com/dcrodman/archon/internal/shipgate"
-	"github.com/spf13/viper"
+	"github.com/dcrodman/archon/internal/shipgate/api"
+	"github.com/dcrodman/archon/internal/web"
 
 	"github.com/dcrodman/archon"
 )
@@ -30,11 +35,36 @@ import (
 const databaseURITemplate = "host=%s port=%d dbname=%s user=%s password=%s sslmode=%s"
 
 var config = flag.String("config", "./", "Path to the directory containing the server config file")
+var role = flag.String("role", "all", "Comma-separated list of the servers to run (gate, patch, login, character, ship, or all)")
 
 func main() {
 	flag.Parse()
-	archon.LoadConfig(*config)
-	archon.InitLogger()
+	roles, err := archon.ParseRoles(*role)
+	if err != nil {
+		fmt.Println(err)
+		os.Exit(1)
+	}
+	runs := make(map[string]bool)
+	for _, r := range roles {
+		runs[r] = true
+	}
+
+	cfg, err := archon.LoadConfig(*config)
+	if err != nil {
+		fmt.Printf("failed to load config: %v\n", err)
+		os.Exit(1)
+	}
+	if err := cfg.ValidateRoles(roles...); err != nil {
+		fmt.Println(err)
+		os.Exit(1)
+	}
+	archon.SetActiveRoles(roles...)
+	archon.InitLogger(cfg)
+	debug.Configure(cfg.Debugging)
+	if err := archon.ResolveRedirectAddresses(context.Background()); err != nil {
+		archon.Log.Error(err)
+		os.Exit(1)
+	}
 
 	archon.Log.Info("Archon PSO Backend, Copyright (C) 2014 Andrew Rodman\n" +
 		"=====================================================\n" +
@@ -45,102 +75,114 @@ func main() {
 		"is distributed WITHOUT ANY WARRANTY; See LICENSE for details.")
 
 	archon.Log.Infof("loaded configuration from %s", *config)
+	archon.Log.Infof("running roles: %s", strings.Join(roles, ", "))
 
-	// Change to the same directory as the config file so that any relative
-	// paths in the config file will resolve.
-	if err := os.Chdir(filepath.Dir(*config)); err != nil {
-		archon.Log.Errorf("failed to change to config directory: %v", err)
-		os.Exit(1)
-	}
+	// Connect to the database if any of the servers we're running need it. Ships only
+	// access player data through the shipgate.
+	if runs[archon.RoleGate] || runs[archon.RoleCharacter] {
+		if err := data.Initialize(dataSource(cfg.Database), debug.Enabled()); err != nil {
+			archon.Log.Errorf(err.Error())
+			os.Exit(1)
+		}
+		defer data.Shutdown()
 
-	// Connect to the database.
-	if err := data.Initialize(dataSource(), debug.Enabled()); err != nil {
-		archon.Log.Errorf(err.Error())
-		os.Exit(1)
+		archon.Log.Infof("connected to database %s:%d", cfg.Database.Host, cfg.Database.Port)
 	}
-	defer data.Shutdown()
-
-	archon.Log.Infof("connected to database %s:%d",
-		viper.GetString("database.host"),
-		viper.GetInt("database.port"),
-	)
 
 	// Start any debug utilities if we're configured to do so.
 	if debug.Enabled() {
 		debug.StartUtilities()
 	}
 
-	// Set up all of the servers we want to run.
-	patchPort := viper.GetString("patch_server.patch_port")
-	dataPort := viper.GetString("patch_server.data_port")
-	loginPort := viper.GetString("login_server.port")
-	characterPort := viper.GetString("character_server.port")
-	shipPort := viper.GetString("ship_server.port")
+	// Set up all of the servers we want to run. Servers in the same process as the
+	// shipgate connect to it directly, otherwise they use the configured address.
+	shipgateAddr := cfg.ShipServer.ShipgateAddress
+	if runs[archon.RoleGate] {
+		shipgateAddr = cfg.ShipgateListenAddress()
+	}
 
-	shipgateAddr := buildAddress(viper.GetString("shipgate_server.port"))
+	var servers []*internal.Frontend
+	if runs[archon.RolePatch] {
+		servers = append(servers,
+			&internal.Frontend{
+				Address: cfg.Address(cfg.PatchServer.PatchPort),
+				Backend: patch2.NewServer("PATCH", cfg),
+				Config:  cfg,
+			},
+			&internal.Frontend{
+				Address: cfg.Address(cfg.PatchServer.DataPort),
+				Backend: patch2.NewDataServer("DATA", cfg),
+				Config:  cfg,
+			},
+		)
+	}
+	if runs[archon.RoleLogin] {
+		servers = append(servers, &internal.Frontend{
+			Address: cfg.Address(cfg.LoginServer.Port),
+			Backend: login.NewServer("LOGIN", shipgateAddr, cfg),
+			Config:  cfg,
+		})
+	}
+	if runs[archon.RoleCharacter] {
+		servers = append(servers, &internal.Frontend{
+			Address: cfg.Address(cfg.CharacterServer.Port),
+			Backend: character.NewServer("CHARACTER", shipgateAddr, cfg),
+			Config:  cfg,
+		})
+	}
 
-	// Automatically configure the block servers based on the number of
-	// ship blocks requested.
-	var blocks []ship.Block
+	var shipServer *internal.Frontend
+	var shipBackend *ship.Server
 	var blockServers []*internal.Frontend
-	for i := 1; i <= viper.GetInt("ship_server.num_blocks"); i++ {
-		name := fmt.Sprintf("BLOCK%02d", i)
-		address := buildAddress(viper.GetInt("block_server.port") + i)
+	if runs[archon.RoleShip] {
+		// Automatically configure the block servers based on the number of
+		// ship blocks requested.
+		var blocks []ship.Block
+		for i := 1; i <= cfg.ShipServer.NumBlocks; i++ {
+			name := fmt.Sprintf("BLOCK%02d", i)
+			address := cfg.BlockAddress(i)
 
-		blocks = append(blocks, ship.Block{
-			Name: name, Address: address, ID: i,
-		})
-		blockServer := &internal.Frontend{
-			Address: address,
-			Backend: block.NewServer(
-				name,
-				shipgateAddr,
-				viper.GetInt("block_server.num_lobbies"),
-			),
+			blockServer := &internal.Frontend{
+				Address: address,
+				Backend: block.NewServer(name, shipgateAddr, cfg),
+				Config:  cfg,
+			}
+			blockServers = append(blockServers, blockServer)
+			blocks = append(blocks, ship.Block{
+				Name: name, Address: address, ID: i, Frontend: blockServer,
+			})
 		}
-		blockServers = append(blockServers, blockServer)
-	}
 
-	servers := []*internal.Frontend{
-		{
-			Address: buildAddress(patchPort),
-			Backend: patch2.NewServer("PATCH", dataPort),
-		},
-		{
-			Address: buildAddress(dataPort),
-			Backend: patch2.NewDataServer("DATA"),
-		},
-		{
-			Address: buildAddress(loginPort),
-			Backend: login.NewServer("LOGIN", characterPort, shipgateAddr),
-		},
-		{
-			Address: buildAddress(characterPort),
-			Backend: character.NewServer("CHARACTER", shipgateAddr),
-		},
-		// TODO: Eventually the ship and block servers should be able to be run
-		// independently of the other four servers
-		{
-			Address: buildAddress(shipPort),
-			Backend: ship.NewServer("SHIP", blocks, shipgateAddr),
-		},
+		shipBackend = ship.NewServer("SHIP", blocks, shipgateAddr, cfg)
+		shipServer = &internal.Frontend{
+			Address: cfg.Address(cfg.ShipServer.Port),
+			Backend: shipBackend,
+			Config:  cfg,
+		}
+		shipBackend.SetFrontend(shipServer)
+		servers = append(servers, shipServer)
+		servers = append(servers, blockServers...)
 	}
-	servers = append(servers, blockServers...)
 
 	// Bind the server loops to one top-level server context so that we can shut down cleanly.
 	ctx, cancel := context.WithCancel(context.Background())
 
+	// Pick up changes to the addresses of any hostnames that clients are redirected to.
+	go archon.WatchRedirectAddresses(ctx, cfg.ExternalIPRefresh)
+
 	// Start the shipgate gRPC server and make sure it launches before the other servers start.
-	readyChan := make(chan bool)
-	errChan := make(chan error)
-	go shipgate.Start(ctx, shipgateAddr, readyChan, errChan)
-	go func() {
-		if err := <-errChan; err != nil {
-			archon.Log.Errorf("exiting due to SHIPGATE error: %v", err)
-			os.Exit(1)
-		}
-	}()
-	<-readyChan
+	if runs[archon.RoleGate] {
+		readyChan := make(chan bool)
+		errChan := make(chan error)
+		go shipgate.Start(ctx, shipgateAddr, cfg, readyChan, errChan)
+		go func() {
+			if err := <-errChan; err != nil {
+				archon.Log.Errorf("exiting due to SHIPGATE error: %v", err)
+				os.Exit(1)
+			}
+		}()
+		<-readyChan
+	}
 
 	// Start all of our servers. Failure to initialize one of the registered servers is considered terminal.
 	var serverWg sync.WaitGroup
@@ -151,29 +193,121 @@ func main() {
 		}
 	}
 
+	// Start the HTTP server for any publicly accessible API endpoints.
+	webServer := web.NewServer("WEB", cfg.Address(cfg.Web.HTTPPort))
+	if runs[archon.RoleGate] {
+		webServer.Handle("/api/accounts/", web.NewAccountHandler(
+			web.NewFileMailer(cfg.Web.MailFile),
+			cfg.Web.EmailVerification,
+			verificationURL(cfg),
+		))
+	}
+	statusHandler := &web.StatusHandler{
+		ShipName:  cfg.ShipServer.Name,
+		Ship:      shipServer,
+		Blocks:    blockServers,
+		Frontends: servers,
+	}
+	webServer.Handle("/api/status", statusHandler)
+	webServer.Handle("/api/status/", statusHandler)
+	if cfg.Web.Metrics {
+		webServer.Handle("/metrics", metrics.Handler())
+	}
+	if err := webServer.Start(ctx, &serverWg); err != nil {
+		archon.Log.Errorf("failed to start WEB server: %v", err)
+		os.Exit(1)
+	}
+
+	// Apply changes to the config files while running, either as soon as they're
+	// saved or when the process receives a SIGHUP.
+	hup := make(chan os.Signal, 1)
+	signal.Notify(hup, syscall.SIGHUP)
+	if err := archon.WatchConfig(ctx, hup); err != nil {
+		archon.Log.Warnf("config changes will not be applied until restart: %v", err)
+	}
+
 	// Register a SIGTERM handler so that Ctrl-C will shut the servers down gracefully.
 	c := make(chan os.Signal, 1)
 	signal.Notify(c, os.Interrupt, syscall.SIGTERM)
 	go exitHandler(cancel, c, &serverWg)
 
+	if cfg.Admin.Enabled {
+		// Admin actions reach the players on every ship through the shipgate's message
+		// bus, which is published to directly if the shipgate runs in this process.
+		var publish func(*api.Envelope) error
+		if runs[archon.RoleGate] {
+			publish = shipgate.Publish
+		} else if shipBackend != nil {
+			publish = shipBackend.Publish
+		}
+		adminServer, err := newAdminServer(cfg, servers, publish, runs[archon.RoleGate], c)
+		if err != nil {
+			archon.Log.Errorf("failed to configure ADMIN server: %v", err)
+			os.Exit(1)
+		}
+		if err := adminServer.Start(ctx, &serverWg); err != nil {
+			archon.Log.Errorf("failed to start ADMIN server: %v", err)
+			os.Exit(1)
+		}
+	}
+
 	serverWg.Wait()
 }
 
 // Returns the database URI of the game database.
-func dataSource() string {
+func dataSource(db archon.DatabaseConfig) string {
 	return fmt.Sprintf(
 		databaseURITemplate,
-		viper.GetString("database.host"),
-		viper.GetInt("database.port"),
-		viper.GetString("database.name"),
-		viper.GetString("database.username"),
-		viper.GetString("database.password"),
-		viper.GetString("database.sslmode"),
+		db.Host, db.Port, db.Name, db.Username, db.Password, db.SSLMode,
 	)
 }
 
-func buildAddress(port interface{}) string {
-	return fmt.Sprintf("%s:%v", viper.GetString("hostname"), port)
+// Creates the HTTP server for the admin API, which requires either a bearer token,
+// client certificates, or both depending on how it's configured.
+func newAdminServer(cfg *archon.Config, servers []*internal.Frontend, publish func(*api.Envelope) error,
+	accountsAvailable bool, exitChan chan os.Signal) (*web.Server, error) {
+	token := cfg.Admin.Token
+	clientCAFile := cfg.Admin.ClientCAFile
+	if token == "" && clientCAFile == "" {
+		return nil, fmt.Errorf("admin.token and/or admin.client_ca_file must be set")
+	}
+
+	var handler http.Handler = &web.AdminHandler{
+		Frontends: servers,
+		Shutdown: func() {
+			// Shut down the same way we would for a SIGTERM.
+			select {
+			case exitChan <- syscall.SIGTERM:
+			default:
+			}
+		},
+		Publish:           publish,
+		AccountsAvailable: accountsAvailable,
+	}
+	metricsHandler := metrics.Handler()
+	if token != "" {
+		handler = web.RequireToken(token, handler)
+		metricsHandler = web.RequireToken(token, metricsHandler)
+	}
+
+	tlsConfig, err := web.NewAdminTLSConfig(cfg.Admin.CertificateFile, cfg.Admin.KeyFile, clientCAFile)
+	if err != nil {
+		return nil, err
+	}
+
+	adminServer := web.NewServer("ADMIN", cfg.Address(cfg.Admin.Port))
+	adminServer.TLSConfig = tlsConfig
+	adminServer.Handle("/admin/", handler)
+	adminServer.Handle("/admin/metrics", metricsHandler)
+	return adminServer, nil
+}
+
+// Returns the URL players are sent to in order to verify their email address.
+func verificationURL(cfg *archon.Config) string {
+	if cfg.Web.VerificationURL != "" {
+		return cfg.Web.VerificationURL
+	}
+	return fmt.Sprintf("http://%s/api/accounts/verify", net.JoinHostPort(cfg.ExternalIP, strconv.Itoa(cfg.Web.HTTPPort)))
 }
 
 func exitHandler(cancelFn func(), c chan os.Signal, wg ...*sync.WaitGroup) {
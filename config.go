@@ -1,53 +1,724 @@
 package archon
 
 import (
+	"context"
+	"errors"
 	"fmt"
+	"net"
 	"os"
+	"path/filepath"
+	"reflect"
+	"sort"
 	"strconv"
 	"strings"
+	"sync"
+	"time"
 
+	"github.com/fsnotify/fsnotify"
+	"github.com/sirupsen/logrus"
 	"github.com/spf13/viper"
 )
 
 const defaultConfigName = "config"
 const overrideConfigName = "override"
 
+// Prefix of the environment variables that override settings in the config files. The
+// rest of the variable name is the setting's key in upper case with "." replaced by
+// "_", e.g. ARCHON_DATABASE_PASSWORD for database.password.
+const envPrefix = "ARCHON"
+
+// Time to wait for changes to the config files to settle before reloading, since
+// editors often write a file in several steps.
+const reloadDelay = 250 * time.Millisecond
+
+// Ship secret in the example config, which anyone can read and so has to be
+// replaced (setup.sh does so with a random one).
+const placeholderShipSecret = "changemeshipsecret"
+
+// Roles are the groups of servers that can be run by a process, so that (for
+// example) a ship and its blocks can be hosted separately from the shipgate.
+const (
+	// The shipgate, which every other server connects to.
+	RoleGate = "gate"
+	// The PATCH and DATA servers.
+	RolePatch = "patch"
+	// The LOGIN server.
+	RoleLogin = "login"
+	// The CHARACTER server, which presents the ship list.
+	RoleCharacter = "character"
+	// A SHIP server and its BLOCK servers.
+	RoleShip = "ship"
+)
+
+// AllRoles contains every role, which is what a self-contained server runs.
+var AllRoles = []string{RoleGate, RolePatch, RoleLogin, RoleCharacter, RoleShip}
+
+// ParseRoles parses a comma-separated list of roles, where "all" is shorthand
+// for AllRoles.
+func ParseRoles(list string) ([]string, error) {
+	var roles []string
+	seen := make(map[string]bool)
+	for _, role := range strings.Split(list, ",") {
+		role = strings.TrimSpace(role)
+		if role == "all" {
+			return AllRoles, nil
+		}
+		valid := false
+		for _, r := range AllRoles {
+			valid = valid || r == role
+		}
+		if !valid {
+			return nil, fmt.Errorf("unknown role %q (must be one of %s or all)", role, strings.Join(AllRoles, ", "))
+		}
+		if !seen[role] {
+			seen[role] = true
+			roles = append(roles, role)
+		}
+	}
+	return roles, nil
+}
+
+// Config contains every setting used by the server. See setup/config.yaml for a
+// description of each of them.
+type Config struct {
+	Hostname                string                  `mapstructure:"hostname"`
+	ExternalIP              string                  `mapstructure:"external_ip"`
+	ExternalIPRefresh       time.Duration           `mapstructure:"external_ip_refresh"`
+	RedirectAddresses       []RedirectAddressConfig `mapstructure:"redirect_addresses"`
+	MaxConnections          int                     `mapstructure:"max_connections"`
+	MaxConnectionsPerIP     int                     `mapstructure:"max_connections_per_ip"`
+	Timeouts                TimeoutsConfig          `mapstructure:"timeouts"`
+	SendQueue               SendQueueConfig         `mapstructure:"send_queue"`
+	LogFilePath             string                  `mapstructure:"log_file_path"`
+	LogLevel                string                  `mapstructure:"log_level"`
+	ShipgateCAFile          string                  `mapstructure:"shipgate_ca_file"`
+	ShipgateCertificateFile string                  `mapstructure:"shipgate_certificate_file"`
+	ShipgateClientCertFile  string                  `mapstructure:"shipgate_client_certificate_file"`
+	ShipgateClientKeyFile   string                  `mapstructure:"shipgate_client_key_file"`
+	Web                     WebConfig               `mapstructure:"web"`
+	Admin                   AdminConfig             `mapstructure:"admin"`
+	Database                DatabaseConfig          `mapstructure:"database"`
+	PatchServer             PatchServerConfig       `mapstructure:"patch_server"`
+	LoginServer             LoginServerConfig       `mapstructure:"login_server"`
+	CharacterServer         CharacterServerConfig   `mapstructure:"character_server"`
+	ShipgateServer          ShipgateServerConfig    `mapstructure:"shipgate_server"`
+	ShipServer              ShipServerConfig        `mapstructure:"ship_server"`
+	BlockServer             BlockServerConfig       `mapstructure:"block_server"`
+	Debugging               DebuggingConfig         `mapstructure:"debugging"`
+}
+
+type RedirectAddressConfig struct {
+	Subnet  string `mapstructure:"subnet"`
+	Address string `mapstructure:"address"`
+}
+
+type TimeoutsConfig struct {
+	Handshake time.Duration `mapstructure:"handshake"`
+	Idle      time.Duration `mapstructure:"idle"`
+	Packet    time.Duration `mapstructure:"packet"`
+	Write     time.Duration `mapstructure:"write"`
+	Keepalive time.Duration `mapstructure:"keepalive"`
+}
+
+type SendQueueConfig struct {
+	Size     int    `mapstructure:"size"`
+	Overflow string `mapstructure:"overflow"`
+}
+
+type WebConfig struct {
+	HTTPPort          int    `mapstructure:"http_port"`
+	EmailVerification bool   `mapstructure:"email_verification"`
+	VerificationURL   string `mapstructure:"verification_url"`
+	MailFile          string `mapstructure:"mail_file"`
+	Metrics           bool   `mapstructure:"metrics"`
+}
+
+type AdminConfig struct {
+	Enabled         bool   `mapstructure:"enabled"`
+	Port            int    `mapstructure:"port"`
+	Token           string `mapstructure:"token"`
+	CertificateFile string `mapstructure:"certificate_file"`
+	KeyFile         string `mapstructure:"key_file"`
+	ClientCAFile    string `mapstructure:"client_ca_file"`
+}
+
+type DatabaseConfig struct {
+	Host     string `mapstructure:"host"`
+	Port     int    `mapstructure:"port"`
+	Name     string `mapstructure:"name"`
+	Username string `mapstructure:"username"`
+	Password string `mapstructure:"password"`
+	SSLMode  string `mapstructure:"sslmode"`
+}
+
+type PatchServerConfig struct {
+	PatchPort      int    `mapstructure:"patch_port"`
+	DataPort       int    `mapstructure:"data_port"`
+	PatchDir       string `mapstructure:"patch_dir"`
+	WelcomeMessage string `mapstructure:"welcome_message"`
+}
+
+type LoginServerConfig struct {
+	Port int `mapstructure:"port"`
+}
+
+type CharacterServerConfig struct {
+	Port          int    `mapstructure:"port"`
+	ParametersDir string `mapstructure:"parameters_dir"`
+	ScrollMessage string `mapstructure:"scroll_message"`
+}
+
+type ShipgateServerConfig struct {
+	Port       int                 `mapstructure:"port"`
+	SSLKeyFile string              `mapstructure:"ssl_key_file"`
+	Ships      []AllowedShipConfig `mapstructure:"ships"`
+}
+
+// AllowedShipConfig is an entry in the list of ships allowed to register with the shipgate.
+type AllowedShipConfig struct {
+	Name       string `mapstructure:"name"`
+	Secret     string `mapstructure:"secret"`
+	GMOnly     bool   `mapstructure:"gm_only"`
+	Hidden     bool   `mapstructure:"hidden"`
+	MaxPlayers int    `mapstructure:"max_players"`
+}
+
+type ShipServerConfig struct {
+	Port            int    `mapstructure:"port"`
+	ShipgateAddress string `mapstructure:"shipgate_address"`
+	Name            string `mapstructure:"name"`
+	NumBlocks       int    `mapstructure:"num_blocks"`
+	Secret          string `mapstructure:"secret"`
+}
+
+type BlockServerConfig struct {
+	Port           int    `mapstructure:"port"`
+	NumLobbies     int    `mapstructure:"num_lobbies"`
+	DuplicateLogin string `mapstructure:"duplicate_login"`
+}
+
+type DebuggingConfig struct {
+	Enabled               bool   `mapstructure:"enabled"`
+	PacketAnalyzerAddress string `mapstructure:"packet_analyzer_address"`
+	PprofPort             int    `mapstructure:"pprof_port"`
+}
+
+// DefaultConfig returns the settings used for anything not set in the config files.
+func DefaultConfig() *Config {
+	return &Config{
+		Hostname:            "0.0.0.0",
+		ExternalIP:          "127.0.0.1",
+		ExternalIPRefresh:   5 * time.Minute,
+		MaxConnections:      3000,
+		MaxConnectionsPerIP: 8,
+		Timeouts: TimeoutsConfig{
+			Handshake: 30 * time.Second,
+			Idle:      10 * time.Minute,
+			Packet:    10 * time.Second,
+			Write:     10 * time.Second,
+			Keepalive: 30 * time.Second,
+		},
+		SendQueue:               SendQueueConfig{Size: 256, Overflow: "disconnect"},
+		LogLevel:                "info",
+		ShipgateCAFile:          "ca.pem",
+		ShipgateCertificateFile: "certificate.pem",
+		ShipgateClientCertFile:  "client-certificate.pem",
+		ShipgateClientKeyFile:   "client-key.pem",
+		Web:                     WebConfig{HTTPPort: 10000},
+		Admin:                   AdminConfig{Port: 10001, CertificateFile: "certificate.pem", KeyFile: "key.pem"},
+		Database: DatabaseConfig{
+			Host:    "127.0.0.1",
+			Port:    5432,
+			Name:    "archondb",
+			SSLMode: "disable",
+		},
+		PatchServer: PatchServerConfig{
+			PatchPort:      11000,
+			DataPort:       11001,
+			PatchDir:       "/usr/local/etc/archon/patches",
+			WelcomeMessage: "Unconfigured",
+		},
+		LoginServer: LoginServerConfig{Port: 12000},
+		CharacterServer: CharacterServerConfig{
+			Port:          12001,
+			ParametersDir: "/usr/local/etc/archon/parameters",
+		},
+		ShipgateServer: ShipgateServerConfig{Port: 13000, SSLKeyFile: "key.pem"},
+		ShipServer: ShipServerConfig{
+			Port:            15000,
+			ShipgateAddress: "127.0.0.1:13000",
+			Name:            "Default",
+			NumBlocks:       2,
+		},
+		BlockServer: BlockServerConfig{Port: 15001, NumLobbies: 15, DuplicateLogin: "reject"},
+		Debugging:   DebuggingConfig{PacketAnalyzerAddress: "localhost:8081", PprofPort: 4000},
+	}
+}
+
+// Address returns the address on which a server listening on port should bind.
+func (c *Config) Address(port int) string {
+	return net.JoinHostPort(c.Hostname, strconv.Itoa(port))
+}
+
+// ShipgateListenAddress returns the address on which the shipgate listens.
+func (c *Config) ShipgateListenAddress() string {
+	return c.Address(c.ShipgateServer.Port)
+}
+
+// BlockAddress returns the address of the block numbered n (starting at 1).
+func (c *Config) BlockAddress(n int) string {
+	return c.Address(c.BlockServer.Port + n)
+}
+
+// Validate checks that every setting has a usable value, returning an error
+// describing every one that doesn't.
+func (c *Config) Validate() error {
+	return c.ValidateRoles(AllRoles...)
+}
+
+// ValidateRoles is like Validate, except that settings used only by servers that
+// aren't part of roles are ignored.
+func (c *Config) ValidateRoles(roles ...string) error {
+	has := make(map[string]bool)
+	for _, role := range roles {
+		has[role] = true
+	}
+	usesDatabase := has[RoleGate] || has[RoleCharacter]
+	connectsToShipgate := has[RoleShip] || (!has[RoleGate] && (has[RoleLogin] || has[RoleCharacter]))
+
+	var problems []string
+	addProblem := func(format string, args ...interface{}) {
+		problems = append(problems, fmt.Sprintf(format, args...))
+	}
+
+	if c.Hostname == "" {
+		addProblem("hostname must be set")
+	}
+	if err := validateRedirectAddress(c.ExternalIP); err != nil {
+		addProblem("external_ip %v", err)
+	}
+	if c.ExternalIPRefresh < 0 {
+		addProblem("external_ip_refresh must not be negative")
+	}
+	for i, redirect := range c.RedirectAddresses {
+		if _, _, err := net.ParseCIDR(redirect.Subnet); err != nil {
+			addProblem("redirect_addresses[%d].subnet must be a CIDR block, got %q", i, redirect.Subnet)
+		}
+		if err := validateRedirectAddress(redirect.Address); err != nil {
+			addProblem("redirect_addresses[%d].address %v", i, err)
+		}
+	}
+	if _, err := logrus.ParseLevel(c.LogLevel); err != nil {
+		addProblem("log_level %q is not one of debug, info, warn, error", c.LogLevel)
+	}
+
+	ports := map[string]int{"web.http_port": c.Web.HTTPPort}
+	if usesDatabase {
+		ports["database.port"] = c.Database.Port
+	}
+	if has[RolePatch] {
+		ports["patch_server.patch_port"] = c.PatchServer.PatchPort
+		ports["patch_server.data_port"] = c.PatchServer.DataPort
+	}
+	if has[RoleLogin] || has[RoleCharacter] {
+		// LOGIN redirects clients to the CHARACTER port.
+		ports["character_server.port"] = c.CharacterServer.Port
+	}
+	if has[RoleLogin] {
+		ports["login_server.port"] = c.LoginServer.Port
+	}
+	if has[RoleGate] {
+		ports["shipgate_server.port"] = c.ShipgateServer.Port
+	}
+	if has[RoleShip] {
+		ports["ship_server.port"] = c.ShipServer.Port
+		ports["block_server.port"] = c.BlockServer.Port
+	}
+	if c.Admin.Enabled {
+		ports["admin.port"] = c.Admin.Port
+	}
+	if c.Debugging.Enabled {
+		ports["debugging.pprof_port"] = c.Debugging.PprofPort
+	}
+	for _, key := range sortedKeys(ports) {
+		if port := ports[key]; port <= 0 || port > 0xFFFF {
+			addProblem("%s must be a port between 1 and 65535, got %d", key, port)
+		}
+	}
+	if has[RoleShip] && c.BlockServer.Port+c.ShipServer.NumBlocks > 0xFFFF {
+		addProblem("block_server.port leaves no room for %d blocks", c.ShipServer.NumBlocks)
+	}
+	if connectsToShipgate {
+		if _, _, err := net.SplitHostPort(c.ShipServer.ShipgateAddress); err != nil {
+			addProblem("ship_server.shipgate_address must be a host:port address: %v", err)
+		}
+	}
+
+	counts := map[string]int{
+		"max_connections":          c.MaxConnections,
+		"max_connections_per_ip":   c.MaxConnectionsPerIP,
+		"send_queue.size":          c.SendQueue.Size,
+		"ship_server.num_blocks":   c.ShipServer.NumBlocks,
+		"block_server.num_lobbies": c.BlockServer.NumLobbies,
+	}
+	for _, key := range sortedKeys(counts) {
+		if counts[key] < 0 {
+			addProblem("%s must not be negative", key)
+		}
+	}
+	if c.SendQueue.Overflow != "disconnect" && c.SendQueue.Overflow != "drop" {
+		addProblem("send_queue.overflow must be \"disconnect\" or \"drop\", got %q", c.SendQueue.Overflow)
+	}
+	if c.BlockServer.DuplicateLogin != "reject" && c.BlockServer.DuplicateLogin != "kick" {
+		addProblem("block_server.duplicate_login must be \"reject\" or \"kick\", got %q", c.BlockServer.DuplicateLogin)
+	}
+
+	if has[RoleGate] {
+		names := make(map[string]bool)
+		for i, ship := range c.ShipgateServer.Ships {
+			if ship.Name == "" {
+				addProblem("shipgate_server.ships[%d].name must be set", i)
+			} else if names[ship.Name] {
+				addProblem("shipgate_server.ships[%d].name %q is listed more than once", i, ship.Name)
+			}
+			names[ship.Name] = true
+			if ship.MaxPlayers < 0 {
+				addProblem("shipgate_server.ships[%d].max_players must not be negative", i)
+			}
+			if ship.Secret == placeholderShipSecret {
+				addProblem("shipgate_server.ships[%d].secret must be changed from the example config's", i)
+			}
+		}
+	}
+	if has[RoleShip] && c.ShipServer.Secret == placeholderShipSecret {
+		addProblem("ship_server.secret must be changed from the example config's")
+	}
+	files := make(map[string]string)
+	// Every connection to the shipgate is authenticated with certificates issued by the CA.
+	dialsShipgate := has[RoleLogin] || has[RoleCharacter] || has[RoleShip]
+	if has[RoleGate] || dialsShipgate {
+		files["shipgate_ca_file"] = c.ShipgateCAFile
+	}
+	if dialsShipgate {
+		files["shipgate_client_certificate_file"] = c.ShipgateClientCertFile
+		files["shipgate_client_key_file"] = c.ShipgateClientKeyFile
+	}
+	if has[RoleGate] {
+		files["shipgate_certificate_file"] = c.ShipgateCertificateFile
+		files["shipgate_server.ssl_key_file"] = c.ShipgateServer.SSLKeyFile
+	}
+	if c.Admin.Enabled {
+		files["admin.certificate_file"] = c.Admin.CertificateFile
+		files["admin.key_file"] = c.Admin.KeyFile
+	}
+	if c.Admin.ClientCAFile != "" {
+		files["admin.client_ca_file"] = c.Admin.ClientCAFile
+	}
+	for _, key := range sortedKeys(files) {
+		if info, err := os.Stat(files[key]); err != nil || info.IsDir() {
+			addProblem("%s must be a readable file, got %q", key, files[key])
+		}
+	}
+	dirs := make(map[string]string)
+	if has[RolePatch] {
+		dirs["patch_server.patch_dir"] = c.PatchServer.PatchDir
+	}
+	if has[RoleCharacter] {
+		dirs["character_server.parameters_dir"] = c.CharacterServer.ParametersDir
+	}
+	for _, key := range sortedKeys(dirs) {
+		if info, err := os.Stat(dirs[key]); err != nil || !info.IsDir() {
+			addProblem("%s must be a directory, got %q", key, dirs[key])
+		}
+	}
+
+	if len(problems) > 0 {
+		return fmt.Errorf("invalid config: %s", strings.Join(problems, "; "))
+	}
+	return nil
+}
+
+// resolvePaths makes any relative paths in the config relative to dir.
+func (c *Config) resolvePaths(dir string) {
+	paths := []*string{
+		&c.LogFilePath,
+		&c.ShipgateCAFile,
+		&c.ShipgateCertificateFile,
+		&c.ShipgateClientCertFile,
+		&c.ShipgateClientKeyFile,
+		&c.Web.MailFile,
+		&c.Admin.CertificateFile,
+		&c.Admin.KeyFile,
+		&c.Admin.ClientCAFile,
+		&c.PatchServer.PatchDir,
+		&c.CharacterServer.ParametersDir,
+		&c.ShipgateServer.SSLKeyFile,
+	}
+	for _, path := range paths {
+		if *path != "" && !filepath.IsAbs(*path) {
+			*path = filepath.Join(dir, *path)
+		}
+	}
+}
+
 var (
-	// TODO: Remove these and put them closer to where they're actually used.
-	cachedIPBytes [4]byte
+	// Absolute path of the directory containing the config files.
+	configDir string
+	// Roles run by this process, whose settings are validated when reloading.
+	activeRoles = AllRoles
+
+	configMutex   sync.Mutex
+	currentConfig *Config
+
+	reloadCallbacks []func(cfg *Config, changed []string)
 )
 
-// LoadConfig initializes Viper with the contents of the config file under configPath.
-func LoadConfig(configPath string) {
-	viper.AddConfigPath(configPath)
-	viper.SetConfigName(defaultConfigName)
-	viper.SetConfigType("yaml")
+// Settings that take effect without restarting the server when the config is
+// reloaded. Changes to any other settings are reported but not applied. Each of
+// these needs to be copied over in ReloadConfig.
+var reloadableSettings = map[string]bool{
+	"external_ip":                     true,
+	"redirect_addresses":              true,
+	"max_connections":                 true,
+	"max_connections_per_ip":          true,
+	"log_level":                       true,
+	"patch_server.welcome_message":    true,
+	"character_server.scroll_message": true,
+	"shipgate_server.ships":           true,
+}
+
+// LoadConfig reads the config file (and the override file, if present) in configPath,
+// applying defaults and environment variable overrides. Relative paths in the config
+// are resolved relative to configPath.
+func LoadConfig(configPath string) (*Config, error) {
+	dir, err := filepath.Abs(configPath)
+	if err != nil {
+		return nil, err
+	}
+	cfg, err := readConfig(dir)
+	if err != nil {
+		return nil, err
+	}
+
+	configMutex.Lock()
+	defer configMutex.Unlock()
+	configDir = dir
+	setCurrentConfig(cfg)
+	return cfg, nil
+}
+
+func readConfig(dir string) (*Config, error) {
+	v := viper.New()
+	v.AddConfigPath(dir)
+	v.SetConfigName(defaultConfigName)
+	v.SetConfigType("yaml")
+	v.SetEnvPrefix(envPrefix)
+	v.SetEnvKeyReplacer(strings.NewReplacer(".", "_"))
+	v.AutomaticEnv()
+
+	// Registering the defaults also makes every key known to viper, which it needs
+	// in order to apply environment variables to keys not in the config files.
+	for key, value := range flattenConfig(DefaultConfig()) {
+		v.SetDefault(key, value)
+	}
+
+	if err := v.ReadInConfig(); err != nil {
+		if errors.As(err, &viper.ConfigFileNotFoundError{}) {
+			return nil, fmt.Errorf("error reading config file: no config file in path %s", dir)
+		}
+		return nil, fmt.Errorf("error reading config file: %w", err)
+	}
+	// Attempt to load any configs from the override file if present.
+	v.SetConfigName(overrideConfigName)
+	_ = v.MergeInConfig()
+
+	cfg := &Config{}
+	if err := v.Unmarshal(cfg); err != nil {
+		return nil, fmt.Errorf("error reading config file: %w", err)
+	}
+	cfg.resolvePaths(dir)
+	return cfg, nil
+}
+
+// flattenConfig returns the value of every setting in cfg by its key.
+func flattenConfig(cfg *Config) map[string]interface{} {
+	settings := make(map[string]interface{})
+	var flatten func(prefix string, v reflect.Value)
+	flatten = func(prefix string, v reflect.Value) {
+		for i := 0; i < v.NumField(); i++ {
+			key := prefix + v.Type().Field(i).Tag.Get("mapstructure")
+			if field := v.Field(i); field.Kind() == reflect.Struct {
+				flatten(key+".", field)
+			} else {
+				settings[key] = field.Interface()
+			}
+		}
+	}
+	flatten("", reflect.ValueOf(cfg).Elem())
+	return settings
+}
+
+// setCurrentConfig updates the config applied by the running server. The caller
+// must hold configMutex.
+func setCurrentConfig(cfg *Config) {
+	currentConfig = cfg
+	setRedirectRules(cfg)
+}
+
+// SetActiveRoles records the roles run by this process so that settings only used
+// by other roles (which may not be set up on this host) are ignored when the config
+// is reloaded.
+func SetActiveRoles(roles ...string) {
+	configMutex.Lock()
+	defer configMutex.Unlock()
+	activeRoles = roles
+}
 
-	if err := viper.ReadInConfig(); err != nil {
-		if _, ok := err.(viper.ConfigFileNotFoundError); ok {
-			fmt.Printf("error reading config file: no config file in path %s", configPath)
+// ConfigChanges describes the settings that differed when the config was reloaded.
+type ConfigChanges struct {
+	// Settings that were changed on the running server.
+	Applied []string `json:"applied"`
+	// Settings that were changed in the config files but won't take effect
+	// until the server is restarted.
+	RestartRequired []string `json:"restart_required"`
+}
+
+// OnConfigReload registers a callback to be invoked with the updated config and
+// the names of the settings that were changed whenever the config is reloaded.
+func OnConfigReload(callback func(cfg *Config, changed []string)) {
+	configMutex.Lock()
+	defer configMutex.Unlock()
+	reloadCallbacks = append(reloadCallbacks, callback)
+}
+
+// ReloadConfig re-reads the config files loaded by LoadConfig and applies the new
+// values of any settings that can be changed without restarting. The current
+// config is left untouched if the new one is invalid.
+func ReloadConfig() (*ConfigChanges, error) {
+	changes, cfg, callbacks, err := reloadConfig()
+	if err != nil || len(changes.Applied) == 0 {
+		return changes, err
+	}
+
+	if Log != nil {
+		level, _ := logrus.ParseLevel(cfg.LogLevel)
+		Log.SetLevel(level)
+	}
+	// Look up any hostnames that were added to the redirect addresses.
+	if err := ResolveRedirectAddresses(context.Background()); err != nil && Log != nil {
+		Log.Warn(err)
+	}
+	for _, callback := range callbacks {
+		callback(cfg, changes.Applied)
+	}
+	return changes, nil
+}
+
+// reloadConfig reads the config files and replaces the current config if any of
+// the reloadable settings changed, returning the callbacks to notify.
+func reloadConfig() (*ConfigChanges, *Config, []func(*Config, []string), error) {
+	configMutex.Lock()
+	defer configMutex.Unlock()
+
+	loaded, err := readConfig(configDir)
+	if err != nil {
+		return nil, nil, nil, err
+	}
+	if err := loaded.ValidateRoles(activeRoles...); err != nil {
+		return nil, nil, nil, err
+	}
+
+	changes := &ConfigChanges{}
+	current, updated := flattenConfig(currentConfig), flattenConfig(loaded)
+	for _, key := range sortedKeys(updated) {
+		if reflect.DeepEqual(current[key], updated[key]) {
+			continue
+		}
+		if reloadableSettings[key] {
+			changes.Applied = append(changes.Applied, key)
 		} else {
-			fmt.Printf("error reading config file: %v", err)
+			changes.RestartRequired = append(changes.RestartRequired, key)
 		}
-		os.Exit(1)
+	}
+	if len(changes.Applied) == 0 {
+		return changes, currentConfig, nil, nil
 	}
 
-	// Attempt to load any configs from the override file if present.
-	viper.SetConfigName(overrideConfigName)
-	_ = viper.MergeInConfig()
+	next := *currentConfig
+	next.ExternalIP = loaded.ExternalIP
+	next.RedirectAddresses = loaded.RedirectAddresses
+	next.MaxConnections = loaded.MaxConnections
+	next.MaxConnectionsPerIP = loaded.MaxConnectionsPerIP
+	next.LogLevel = loaded.LogLevel
+	next.PatchServer.WelcomeMessage = loaded.PatchServer.WelcomeMessage
+	next.CharacterServer.ScrollMessage = loaded.CharacterServer.ScrollMessage
+	next.ShipgateServer.Ships = loaded.ShipgateServer.Ships
+	setCurrentConfig(&next)
+
+	callbacks := make([]func(*Config, []string), len(reloadCallbacks))
+	copy(callbacks, reloadCallbacks)
+	return changes, &next, callbacks, nil
 }
 
-// BroadcastIP converts the configured broadcast IP string into 4 bytes to be used
-// with the redirect packet common to several servers.
-func BroadcastIP() [4]byte {
-	// Hacky, but chances are the IP address isn't going to start with 0 and a
-	// fixed-length array can't be null.
-	if cachedIPBytes[0] == 0x00 {
-		parts := strings.Split(viper.GetString("external_ip"), ".")
-		for i := 0; i < 4; i++ {
-			tmp, _ := strconv.ParseUint(parts[i], 10, 8)
-			cachedIPBytes[i] = uint8(tmp)
+// WatchConfig reloads the config whenever one of the config files changes or a
+// value is received on reload (e.g. from a SIGHUP handler) until ctx is done.
+func WatchConfig(ctx context.Context, reload <-chan os.Signal) error {
+	watcher, err := fsnotify.NewWatcher()
+	if err != nil {
+		return fmt.Errorf("failed to watch config files: %w", err)
+	}
+	// Watch the directory rather than the files so that files that are replaced
+	// (as many editors do) or created later on are still noticed.
+	if err := watcher.Add(configDir); err != nil {
+		watcher.Close()
+		return fmt.Errorf("failed to watch config directory %s: %w", configDir, err)
+	}
+
+	go func() {
+		defer watcher.Close()
+
+		timer := time.NewTimer(reloadDelay)
+		timer.Stop()
+		for {
+			select {
+			case <-ctx.Done():
+				return
+			case event := <-watcher.Events:
+				name := filepath.Base(event.Name)
+				if name == defaultConfigName+".yaml" || name == overrideConfigName+".yaml" {
+					timer.Reset(reloadDelay)
+				}
+			case err := <-watcher.Errors:
+				Log.Warnf("error watching config files: %v", err)
+			case <-timer.C:
+				logConfigReload()
+			case <-reload:
+				logConfigReload()
+			}
 		}
+	}()
+	return nil
+}
+
+func logConfigReload() {
+	changes, err := ReloadConfig()
+	if err != nil {
+		Log.Errorf("failed to reload config: %v", err)
+		return
+	}
+	if len(changes.Applied) > 0 {
+		Log.Infof("reloaded config; applied changes to %s", strings.Join(changes.Applied, ", "))
+	}
+	if len(changes.RestartRequired) > 0 {
+		Log.Warnf("reloaded config; changes to %s require a restart", strings.Join(changes.RestartRequired, ", "))
+	}
+}
+
+func sortedKeys(m interface{}) []string {
+	var keys []string
+	for _, key := range reflect.ValueOf(m).MapKeys() {
+		keys = append(keys, key.String())
 	}
-	return cachedIPBytes
+	sort.Strings(keys)
+	return keys
 }
@@ -0,0 +1,348 @@
+package archon
+
+import (
+	"io/ioutil"
+	"os"
+	"path/filepath"
+	"reflect"
+	"strings"
+	"testing"
+)
+
+const testConfig = `
+external_ip: 127.0.0.1
+log_level: info
+max_connections: 100
+shipgate_ca_file: cert.pem
+shipgate_certificate_file: cert.pem
+shipgate_client_certificate_file: cert.pem
+shipgate_client_key_file: key.pem
+patch_server:
+  patch_port: 11000
+  patch_dir: patches
+  welcome_message: "Welcome"
+redirect_addresses:
+  - subnet: 192.168.0.0/16
+    address: 192.168.1.10
+character_server:
+  parameters_dir: parameters
+shipgate_server:
+  ssl_key_file: key.pem
+`
+
+// loadTestConfig writes contents to a config file in a new directory, along with
+// the files and directories it refers to, and loads it.
+func loadTestConfig(t *testing.T, contents string) (string, *Config) {
+	t.Helper()
+	dir := t.TempDir()
+	for _, name := range []string{"cert.pem", "key.pem"} {
+		if err := ioutil.WriteFile(filepath.Join(dir, name), nil, 0644); err != nil {
+			t.Fatalf("failed to write %s: %v", name, err)
+		}
+	}
+	for _, name := range []string{"patches", "parameters"} {
+		if err := os.Mkdir(filepath.Join(dir, name), 0755); err != nil {
+			t.Fatalf("failed to create %s: %v", name, err)
+		}
+	}
+	writeTestConfig(t, dir, contents)
+
+	cfg, err := LoadConfig(dir)
+	if err != nil {
+		t.Fatalf("failed to load config: %v", err)
+	}
+	return dir, cfg
+}
+
+func writeTestConfig(t *testing.T, dir, contents string) {
+	t.Helper()
+	if err := ioutil.WriteFile(filepath.Join(dir, "config.yaml"), []byte(contents), 0644); err != nil {
+		t.Fatalf("failed to write config: %v", err)
+	}
+}
+
+func TestLoadConfig(t *testing.T) {
+	dir, cfg := loadTestConfig(t, testConfig)
+
+	if err := cfg.Validate(); err != nil {
+		t.Fatalf("expected config to be valid, got: %v", err)
+	}
+	if cfg.MaxConnections != 100 {
+		t.Errorf("expected max_connections = 100, got = %d", cfg.MaxConnections)
+	}
+	// Settings missing from the file use the defaults.
+	if cfg.LoginServer.Port != 12000 {
+		t.Errorf("expected default login_server.port = 12000, got = %d", cfg.LoginServer.Port)
+	}
+	if cfg.SendQueue.Overflow != "disconnect" {
+		t.Errorf("expected default send_queue.overflow = disconnect, got = %s", cfg.SendQueue.Overflow)
+	}
+	expectedRedirects := []RedirectAddressConfig{{Subnet: "192.168.0.0/16", Address: "192.168.1.10"}}
+	if !reflect.DeepEqual(cfg.RedirectAddresses, expectedRedirects) {
+		t.Errorf("expected redirect_addresses = %v, got = %v", expectedRedirects, cfg.RedirectAddresses)
+	}
+	// Relative paths are resolved against the config directory.
+	if expected := filepath.Join(dir, "patches"); cfg.PatchServer.PatchDir != expected {
+		t.Errorf("expected patch_server.patch_dir = %s, got = %s", expected, cfg.PatchServer.PatchDir)
+	}
+}
+
+func TestLoadConfig_Environment(t *testing.T) {
+	os.Setenv("ARCHON_MAX_CONNECTIONS", "50")
+	os.Setenv("ARCHON_DATABASE_PASSWORD", "secret")
+	t.Cleanup(func() {
+		os.Unsetenv("ARCHON_MAX_CONNECTIONS")
+		os.Unsetenv("ARCHON_DATABASE_PASSWORD")
+	})
+
+	_, cfg := loadTestConfig(t, testConfig)
+
+	if cfg.MaxConnections != 50 {
+		t.Errorf("expected max_connections = 50, got = %d", cfg.MaxConnections)
+	}
+	if cfg.Database.Password != "secret" {
+		t.Errorf("expected database.password = secret, got = %s", cfg.Database.Password)
+	}
+}
+
+func TestConfig_Validate(t *testing.T) {
+	tests := map[string]struct {
+		modify   func(cfg *Config)
+		expected string
+	}{
+		"external_ip": {
+			modify:   func(cfg *Config) { cfg.ExternalIP = "127.0.0" },
+			expected: "external_ip must be an IPv4 address",
+		},
+		"ipv6 external_ip": {
+			modify:   func(cfg *Config) { cfg.ExternalIP = "2001:db8::1" },
+			expected: "external_ip \"2001:db8::1\" is an IPv6 address",
+		},
+		"hostname external_ip": {
+			modify: func(cfg *Config) { cfg.ExternalIP = "pso.example.com" },
+		},
+		"redirect subnet": {
+			modify: func(cfg *Config) {
+				cfg.RedirectAddresses = []RedirectAddressConfig{{Subnet: "192.168.0.0", Address: "192.168.0.1"}}
+			},
+			expected: "redirect_addresses[0].subnet must be a CIDR block",
+		},
+		"log_level": {
+			modify:   func(cfg *Config) { cfg.LogLevel = "loud" },
+			expected: "log_level \"loud\"",
+		},
+		"port": {
+			modify:   func(cfg *Config) { cfg.LoginServer.Port = 70000 },
+			expected: "login_server.port must be a port between 1 and 65535",
+		},
+		"disabled admin port": {
+			modify: func(cfg *Config) { cfg.Admin.Port = 0 },
+		},
+		"shipgate_address": {
+			modify:   func(cfg *Config) { cfg.ShipServer.ShipgateAddress = "localhost" },
+			expected: "ship_server.shipgate_address must be a host:port address",
+		},
+		"max_connections": {
+			modify:   func(cfg *Config) { cfg.MaxConnections = -1 },
+			expected: "max_connections must not be negative",
+		},
+		"duplicate_login": {
+			modify:   func(cfg *Config) { cfg.BlockServer.DuplicateLogin = "ignore" },
+			expected: "block_server.duplicate_login must be \"reject\" or \"kick\"",
+		},
+		"duplicate ship": {
+			modify: func(cfg *Config) {
+				cfg.ShipgateServer.Ships = []AllowedShipConfig{{Name: "Default"}, {Name: "Default"}}
+			},
+			expected: "shipgate_server.ships[1].name \"Default\" is listed more than once",
+		},
+		"max_players": {
+			modify: func(cfg *Config) {
+				cfg.ShipgateServer.Ships = []AllowedShipConfig{{Name: "Default", MaxPlayers: -1}}
+			},
+			expected: "shipgate_server.ships[0].max_players must not be negative",
+		},
+		"placeholder ship secret": {
+			modify:   func(cfg *Config) { cfg.ShipServer.Secret = placeholderShipSecret },
+			expected: "ship_server.secret must be changed",
+		},
+		"placeholder allowed ship secret": {
+			modify: func(cfg *Config) {
+				cfg.ShipgateServer.Ships = []AllowedShipConfig{{Name: "Default", Secret: placeholderShipSecret}}
+			},
+			expected: "shipgate_server.ships[0].secret must be changed",
+		},
+		"missing client certificate": {
+			modify:   func(cfg *Config) { cfg.ShipgateClientCertFile = "missing.pem" },
+			expected: "shipgate_client_certificate_file must be a readable file",
+		},
+		"missing file": {
+			modify:   func(cfg *Config) { cfg.ShipgateServer.SSLKeyFile = "missing.pem" },
+			expected: "shipgate_server.ssl_key_file must be a readable file",
+		},
+		"missing admin certificate": {
+			modify: func(cfg *Config) {
+				cfg.Admin.Enabled = true
+				cfg.Admin.CertificateFile = "missing.pem"
+			},
+			expected: "admin.certificate_file must be a readable file",
+		},
+		"missing directory": {
+			modify:   func(cfg *Config) { cfg.PatchServer.PatchDir = "missing" },
+			expected: "patch_server.patch_dir must be a directory",
+		},
+	}
+
+	for name, tt := range tests {
+		t.Run(name, func(t *testing.T) {
+			_, cfg := loadTestConfig(t, testConfig)
+			tt.modify(cfg)
+
+			err := cfg.Validate()
+			if tt.expected == "" {
+				if err != nil {
+					t.Errorf("expected config to be valid, got: %v", err)
+				}
+			} else if err == nil || !strings.Contains(err.Error(), tt.expected) {
+				t.Errorf("expected error containing %q, got: %v", tt.expected, err)
+			}
+		})
+	}
+}
+
+func TestConfig_ValidateRoles(t *testing.T) {
+	_, cfg := loadTestConfig(t, testConfig)
+	// None of these are used by a ship running on its own.
+	cfg.PatchServer.PatchDir = "missing"
+	cfg.CharacterServer.ParametersDir = "missing"
+	cfg.ShipgateServer.SSLKeyFile = "missing.pem"
+	cfg.LoginServer.Port = 0
+
+	// Nor is the shipgate's certificate needed to serve the admin API.
+	cfg.ShipgateCertificateFile = "missing.pem"
+	cfg.Admin.Enabled = true
+	cfg.Admin.CertificateFile = filepath.Join(filepath.Dir(cfg.ShipgateCAFile), "cert.pem")
+
+	if err := cfg.ValidateRoles(RoleShip); err != nil {
+		t.Errorf("expected config to be valid for a ship, got: %v", err)
+	}
+	if err := cfg.ValidateRoles(RoleGate, RolePatch); err == nil {
+		t.Error("expected config to be invalid for the shipgate and patch servers")
+	}
+}
+
+func TestParseRoles(t *testing.T) {
+	tests := map[string]struct {
+		list     string
+		expected []string
+		wantErr  bool
+	}{
+		"all":        {list: "all", expected: AllRoles},
+		"single":     {list: "ship", expected: []string{RoleShip}},
+		"several":    {list: "gate, login,login,character", expected: []string{RoleGate, RoleLogin, RoleCharacter}},
+		"unknown":    {list: "gate,block", wantErr: true},
+		"empty role": {list: "gate,", wantErr: true},
+	}
+
+	for name, tt := range tests {
+		t.Run(name, func(t *testing.T) {
+			roles, err := ParseRoles(tt.list)
+			if (err != nil) != tt.wantErr {
+				t.Fatalf("expected error = %v, got: %v", tt.wantErr, err)
+			}
+			if !reflect.DeepEqual(roles, tt.expected) {
+				t.Errorf("expected roles = %v, got = %v", tt.expected, roles)
+			}
+		})
+	}
+}
+
+func TestReloadConfig(t *testing.T) {
+	dir, _ := loadTestConfig(t, testConfig)
+
+	var reloaded []string
+	var reloadedConfig *Config
+	OnConfigReload(func(cfg *Config, changed []string) {
+		reloadedConfig, reloaded = cfg, changed
+	})
+	t.Cleanup(func() { reloadCallbacks = nil })
+
+	writeTestConfig(t, dir, strings.NewReplacer(
+		"max_connections: 100", "max_connections: 200",
+		"patch_port: 11000", "patch_port: 11100",
+		`"Welcome"`, `"Hello"`,
+	).Replace(testConfig))
+	changes, err := ReloadConfig()
+	if err != nil {
+		t.Fatalf("failed to reload config: %v", err)
+	}
+
+	expected := &ConfigChanges{
+		Applied:         []string{"max_connections", "patch_server.welcome_message"},
+		RestartRequired: []string{"patch_server.patch_port"},
+	}
+	if !reflect.DeepEqual(changes, expected) {
+		t.Errorf("expected changes = %+v, got = %+v", expected, changes)
+	}
+	if !reflect.DeepEqual(reloaded, expected.Applied) {
+		t.Errorf("expected callback with %v, got = %v", expected.Applied, reloaded)
+	}
+	if reloadedConfig.MaxConnections != 200 {
+		t.Errorf("expected max_connections = 200, got = %d", reloadedConfig.MaxConnections)
+	}
+	if reloadedConfig.PatchServer.PatchPort != 11000 {
+		t.Errorf("expected patch_server.patch_port = 11000 until restart, got = %d", reloadedConfig.PatchServer.PatchPort)
+	}
+}
+
+func TestReloadConfig_ActiveRoles(t *testing.T) {
+	// A ship running on its own has none of the files used by the other roles.
+	shipConfig := strings.NewReplacer(
+		"patch_dir: patches", "patch_dir: missing",
+		"parameters_dir: parameters", "parameters_dir: missing",
+		"ssl_key_file: key.pem", "ssl_key_file: missing.pem",
+	).Replace(testConfig)
+	dir, _ := loadTestConfig(t, shipConfig)
+	SetActiveRoles(RoleShip)
+	t.Cleanup(func() { SetActiveRoles(AllRoles...) })
+
+	writeTestConfig(t, dir, strings.Replace(shipConfig, "max_connections: 100", "max_connections: 200", 1))
+	changes, err := ReloadConfig()
+	if err != nil {
+		t.Fatalf("failed to reload config: %v", err)
+	}
+	if !reflect.DeepEqual(changes.Applied, []string{"max_connections"}) {
+		t.Errorf("expected max_connections to be applied, got = %+v", changes)
+	}
+
+	// Settings used by the ship are still validated.
+	writeTestConfig(t, dir, strings.Replace(shipConfig, "shipgate_client_key_file: key.pem", "shipgate_client_key_file: missing.pem", 1))
+	if _, err := ReloadConfig(); err == nil {
+		t.Error("expected reload to fail")
+	}
+}
+
+func TestReloadConfig_Invalid(t *testing.T) {
+	tests := map[string]struct {
+		old string
+		new string
+	}{
+		"log_level":       {old: "log_level: info", new: "log_level: loud"},
+		"external_ip":     {old: "external_ip: 127.0.0.1", new: "external_ip: 127.0.0"},
+		"max_connections": {old: "max_connections: 100", new: "max_connections: -1"},
+	}
+
+	for name, tt := range tests {
+		t.Run(name, func(t *testing.T) {
+			dir, _ := loadTestConfig(t, testConfig)
+			writeTestConfig(t, dir, strings.Replace(testConfig, tt.old, tt.new, 1))
+
+			if _, err := ReloadConfig(); err == nil {
+				t.Fatal("expected reload to fail")
+			}
+			if v := currentConfig.MaxConnections; v != 100 {
+				t.Errorf("expected max_connections to be unchanged, got = %d", v)
+			}
+		})
+	}
+}
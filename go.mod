@@ -3,14 +3,16 @@ module github.com/dcrodman/archon
 go 1.16
 
 require (
+	github.com/fsnotify/fsnotify v1.4.7
 	github.com/go-test/deep v1.0.7
-	github.com/golang/protobuf v1.4.2
+	github.com/golang/protobuf v1.4.3
 	github.com/patrickmn/go-cache v2.1.0+incompatible
-	github.com/sirupsen/logrus v1.5.0
+	github.com/prometheus/client_golang v1.10.0
+	github.com/sirupsen/logrus v1.6.0
 	github.com/spf13/viper v1.6.2
 	google.golang.org/grpc v1.36.0
 	google.golang.org/protobuf v1.25.0
-	gopkg.in/yaml.v2 v2.2.8 // indirect
+	gopkg.in/yaml.v2 v2.3.0
 	gorm.io/driver/postgres v1.0.8
 	gorm.io/gorm v1.21.3
 )
@@ -25,7 +25,36 @@ type Backend interface {
 	// communicating with the client. This likely involves sending a "welcome" packet.
 	Handshake(c *client.Client) error
 
-	// Handle is the main entry point for processing client packets. It's responsible
-	// for generally handling all packets from a client as well as sending any responses.
-	Handle(ctx context.Context, c *client.Client, data []byte) error
+	// RegisterHandlers is called once the Backend has been initialized in order
+	// for it to register the functions that handle each type of packet it accepts
+	// from its clients. Packets of any other type are logged and ignored.
+	RegisterHandlers(h *PacketHandlers)
+}
+
+// Messenger is implemented by Backends that are able to display an arbitrary
+// text message to their clients.
+type Messenger interface {
+	// SendMessage displays message to the client.
+	SendMessage(c *client.Client, message string) error
+}
+
+// DisconnectHandler is implemented by Backends that need to clean up after a
+// client disconnects.
+type DisconnectHandler interface {
+	// HandleDisconnect is called once the client's connection has been closed.
+	HandleDisconnect(c *client.Client)
+}
+
+// Pinger is implemented by Backends whose clients should be periodically pinged
+// in order to keep their connections from being closed as idle.
+type Pinger interface {
+	// Ping sends the client a packet to which it is expected to respond.
+	Ping(c *client.Client) error
+}
+
+// PacketSizeLimiter is implemented by Backends whose clients are expected to send
+// packets smaller than the maximum allowed by default.
+type PacketSizeLimiter interface {
+	// MaxPacketSize returns the size of the largest packet accepted from a client.
+	MaxPacketSize() int
 }
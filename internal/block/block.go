@@ -2,32 +2,57 @@ package block
 
 import (
 	"context"
+	"fmt"
 	"strings"
+	"sync"
+	"time"
 
 	"github.com/dcrodman/archon"
+	"github.com/dcrodman/archon/internal"
 	"github.com/dcrodman/archon/internal/client"
 	"github.com/dcrodman/archon/internal/core/auth"
 	"github.com/dcrodman/archon/internal/core/bytes"
 	"github.com/dcrodman/archon/internal/core/data"
 	"github.com/dcrodman/archon/internal/packets"
 	"github.com/dcrodman/archon/internal/shipgate"
+	"github.com/dcrodman/archon/internal/shipgate/api"
 )
 
 var loginCopyright = []byte("Phantasy Star Online Blue Burst Game Server. Copyright 1999-2004 SONICTEAM.")
 
+// Lookups of the clients connected to any server in the process, overridden in tests.
+var (
+	clientsWithAccount   = internal.ClientsWithAccount
+	clientsWithGuildcard = internal.ClientsWithGuildcard
+)
+
 type Server struct {
 	name       string
 	numLobbies int
 
 	shipgateAddress string
 	shipgateClient  *shipgate.Client
+	config          *archon.Config
+
+	// Whether a login for an account that's already logged in should disconnect
+	// the existing session rather than being rejected.
+	kickDuplicateLogins bool
+	// Clients holding an account session with the shipgate, by session ID.
+	sessions      map[string]*client.Client
+	sessionsMutex sync.Mutex
+	// Sends envelopes to the players on every ship through the shipgate's message
+	// bus; set by the ship the block belongs to.
+	publish func(*api.Envelope) error
 }
 
-func NewServer(name, shipgateAddress string, lobbies int) *Server {
+func NewServer(name, shipgateAddress string, cfg *archon.Config) *Server {
 	return &Server{
-		name:            name,
-		numLobbies:      lobbies,
-		shipgateAddress: shipgateAddress,
+		name:                name,
+		numLobbies:          cfg.BlockServer.NumLobbies,
+		shipgateAddress:     shipgateAddress,
+		config:              cfg,
+		kickDuplicateLogins: cfg.BlockServer.DuplicateLogin == "kick",
+		sessions:            make(map[string]*client.Client),
 	}
 }
 
@@ -35,12 +60,33 @@ func (s *Server) Name() string {
 	return s.name
 }
 
-// Init connects to the shipgate.
+// Lobbies returns the number of lobbies in the block.
+func (s *Server) Lobbies() int {
+	return s.numLobbies
+}
+
+// Games returns the number of games currently running in the block.
+func (s *Server) Games() int {
+	// Games aren't supported yet.
+	return 0
+}
+
+// SetPublisher sets the function used to send envelopes to the players on every
+// ship, such as mail addressed to a guildcard.
+func (s *Server) SetPublisher(publish func(*api.Envelope) error) {
+	s.publish = publish
+}
+
+// Init connects to the shipgate and starts renewing the sessions of logged in players.
 func (s *Server) Init(ctx context.Context) error {
 	var err error
-	s.shipgateClient, err = shipgate.NewClient(s.shipgateAddress)
+	s.shipgateClient, err = shipgate.NewClient(s.shipgateAddress, s.config)
+	if err != nil {
+		return err
+	}
+	go s.startSessionRenewalLoop(ctx)
 
-	return err
+	return nil
 }
 
 func (s *Server) SetUpClient(c *client.Client) {
@@ -62,33 +108,43 @@ func (s *Server) Handshake(c *client.Client) error {
 	return c.SendRaw(pkt)
 }
 
-func (s *Server) Handle(ctx context.Context, c *client.Client, data []byte) error {
-	var packetHeader packets.BBHeader
-	bytes.StructFromBytes(data[:packets.BBHeaderSize], &packetHeader)
+func (s *Server) RegisterHandlers(h *internal.PacketHandlers) {
+	inLobbyOrGame := []client.State{client.InLobby, client.InGame}
 
-	var err error
-	switch packetHeader.Type {
-	case packets.LoginType:
-		var loginPkt packets.Login
-		bytes.StructFromBytes(data, &loginPkt)
-		err = s.handleLogin(ctx, c, &loginPkt)
-	default:
-		archon.Log.Infof("received unknown packet %x from %s", packetHeader.Type, c.IPAddr())
-	}
-	return err
+	h.Register(packets.LoginType, s.handleLogin, client.Handshaken)
+	// The client sends its player data once it has loaded its character, at which
+	// point it's placed in the lobby.
+	h.Register(packets.PlayerDataType, s.handleLobbyJoin, client.CharacterSelected)
+	h.Register(packets.CreateGameType, s.handleCreateGame, client.InLobby)
+	h.Register(packets.LeaveGameType, s.handleLeaveGame, client.InGame)
+	// Commands exchanged with the other players in a lobby or game. The large ones are
+	// only used within games.
+	h.Register(packets.GameCommandType, s.handleCommand, inLobbyOrGame...)
+	h.Register(packets.TargetedCommandType, s.handleCommand, inLobbyOrGame...)
+	h.Register(packets.GameCommandLargeType, s.handleCommand, client.InGame)
+	h.Register(packets.TargetedCommandLargeType, s.handleCommand, client.InGame)
+	h.Register(packets.SimpleMailType, s.handleSimpleMail, inLobbyOrGame...)
+	// Response to a keepalive ping; nothing to do.
+	h.Ignore(packets.PingType)
 }
 
 func (s *Server) handleLogin(ctx context.Context, c *client.Client, loginPkt *packets.Login) error {
 	username := string(bytes.StripPadding(loginPkt.Username[:]))
-	password := string(bytes.StripPadding(loginPkt.Password[:]))
 
-	account, err := s.shipgateClient.AuthenticateAccount(ctx, username, password)
+	// The client echoes the config it was sent by the previous server, which
+	// carries the session token it was issued when it logged in.
+	if err := c.Config.UnmarshalBinary(loginPkt.Security[:]); err != nil {
+		return err
+	}
+	account, err := s.shipgateClient.AuthenticateSessionToken(ctx, username, c.Config.SessionToken[:])
 	if err != nil {
 		switch err {
 		case auth.ErrInvalidCredentials:
 			return s.sendSecurity(c, packets.BBLoginErrorPassword)
 		case auth.ErrAccountBanned:
 			return s.sendSecurity(c, packets.BBLoginErrorBanned)
+		case shipgate.ErrUnavailable:
+			return s.sendMessage(c, shipgate.UnavailableMessage)
 		default:
 			sendErr := s.sendMessage(c, strings.Title(err.Error()))
 			if sendErr == nil {
@@ -97,7 +153,30 @@ func (s *Server) handleLogin(ctx context.Context, c *client.Client, loginPkt *pa
 			return err
 		}
 	}
-	c.Account = account
+
+	// Prevent the same account from being logged in more than once across all ships.
+	err = s.shipgateClient.AcquireSession(ctx, account, c.SessionID, s.name, s.kickDuplicateLogins)
+	switch err {
+	case nil:
+	case shipgate.ErrAccountInUse:
+		return s.sendSecurity(c, packets.BBLoginErrorUserInUse)
+	case shipgate.ErrShipFull, shipgate.ErrShipGMOnly:
+		return s.sendMessage(c, strings.Title(err.Error()))
+	case shipgate.ErrUnavailable:
+		return s.sendMessage(c, shipgate.UnavailableMessage)
+	default:
+		return err
+	}
+	c.SetTeamID(uint32(account.TeamID))
+	c.SetGuildcard(uint32(account.Guildcard))
+	c.SetAccount(account)
+	if err := c.Transition(client.Authenticated); err != nil {
+		return err
+	}
+
+	s.sessionsMutex.Lock()
+	s.sessions[c.SessionID] = c
+	s.sessionsMutex.Unlock()
 
 	if err := s.sendSecurity(c, packets.BBLoginErrorNone); err != nil {
 		return err
@@ -108,17 +187,211 @@ func (s *Server) handleLogin(ctx context.Context, c *client.Client, loginPkt *pa
 	if err := s.fetchAndSendCharacter(c); err != nil {
 		return err
 	}
+	// The character was chosen on the CHARACTER server and is identified by the
+	// slot in the client's config.
+	if err := c.Transition(client.CharacterSelected); err != nil {
+		return err
+	}
 
 	return s.sendFullCharacterEnd(c)
 }
 
+// handleLobbyJoin moves a client that has finished loading its character into the
+// block's lobby.
+func (s *Server) handleLobbyJoin(c *client.Client) error {
+	return c.Transition(client.InLobby)
+}
+
+// handleCreateGame moves the client from the lobby into the game it created.
+func (s *Server) handleCreateGame(c *client.Client) error {
+	return c.Transition(client.InGame)
+}
+
+// handleLeaveGame returns the client from its game to the lobby.
+func (s *Server) handleLeaveGame(c *client.Client) error {
+	return c.Transition(client.InLobby)
+}
+
+// handleCommand accepts a command meant for the other players in the client's lobby
+// or game. Commands aren't relayed to anyone yet; they're registered so that they're
+// only accepted from clients that have joined one.
+func (s *Server) handleCommand(c *client.Client) error {
+	return nil
+}
+
+// handleSimpleMail sends mail to the player with the guildcard it's addressed to,
+// who may be connected to any ship.
+func (s *Server) handleSimpleMail(c *client.Client, mail *packets.SimpleMail) error {
+	envelope := &api.Envelope{Payload: &api.Envelope_GuildcardMessage{GuildcardMessage: &api.GuildcardMessage{
+		ToGuildcard: mail.ToGuildcard,
+		// The sender's guildcard is the one it logged in with rather than whatever
+		// the client claims it is.
+		FromGuildcard: c.Identity().Guildcard,
+		FromName:      stripLanguageCode(bytes.ConvertFromUtf16(mail.FromName[:])),
+		Message:       stripLanguageCode(bytes.ConvertFromUtf16(mail.Text)),
+	}}}
+
+	if s.publish == nil {
+		return s.SendMessage(c, "Mail can't be sent from this block.")
+	}
+	if err := s.publish(envelope); err != nil {
+		c.Logger().Warnf("failed to send mail to guildcard %d: %v", mail.ToGuildcard, err)
+		return s.SendMessage(c, "Your mail couldn't be sent; please try again later.")
+	}
+	return nil
+}
+
+// stripLanguageCode removes the tab and language character that the client puts at
+// the beginning of the text it sends.
+func stripLanguageCode(text string) string {
+	if strings.HasPrefix(text, "\t") && len(text) >= 2 {
+		return text[2:]
+	}
+	return text
+}
+
+// HandleDisconnect releases the client's account session (if any).
+func (s *Server) HandleDisconnect(c *client.Client) {
+	s.sessionsMutex.Lock()
+	_, ok := s.sessions[c.SessionID]
+	delete(s.sessions, c.SessionID)
+	s.sessionsMutex.Unlock()
+
+	if !ok {
+		return
+	}
+
+	ctx, cancel := context.WithTimeout(archon.WithSessionID(context.Background(), c.SessionID), 5*time.Second)
+	defer cancel()
+	if err := s.shipgateClient.ReleaseSession(ctx, c.Account.ID, c.SessionID); err != nil {
+		c.Logger().Warnf("failed to release account session: %v", err)
+	}
+}
+
+// startSessionRenewalLoop periodically renews the account sessions of the clients
+// connected to the block and disconnects any whose sessions have been revoked.
+func (s *Server) startSessionRenewalLoop(ctx context.Context) {
+	for {
+		select {
+		case <-ctx.Done():
+			return
+		case <-time.After(shipgate.SessionRenewalInterval):
+			s.renewSessions(ctx)
+		}
+	}
+}
+
+func (s *Server) renewSessions(ctx context.Context) {
+	s.sessionsMutex.Lock()
+	sessions := make(map[string]*data.Account, len(s.sessions))
+	for sessionID, c := range s.sessions {
+		sessions[sessionID] = c.Account
+	}
+	s.sessionsMutex.Unlock()
+
+	if len(sessions) == 0 {
+		return
+	}
+
+	revoked, err := s.shipgateClient.RenewSessions(ctx, sessions, s.name)
+	if err != nil {
+		archon.Log.Warnf("%s failed to renew account sessions: %v", s.name, err)
+		return
+	}
+
+	for _, sessionID := range revoked {
+		s.sessionsMutex.Lock()
+		c, ok := s.sessions[sessionID]
+		s.sessionsMutex.Unlock()
+
+		if ok {
+			// Closing the connection will cause the Frontend to clean up the client.
+			c.Logger().Info("account session revoked (logged in elsewhere); disconnecting")
+			_ = c.Close()
+		}
+	}
+}
+
+// HandleEnvelope delivers an envelope from the shipgate's message bus to the
+// players in the block that it's addressed to.
+func (s *Server) HandleEnvelope(envelope *api.Envelope) {
+	switch payload := envelope.GetPayload().(type) {
+	case *api.Envelope_Announcement:
+		for _, c := range s.loggedInClients() {
+			s.deliverMessage(c, payload.Announcement.GetMessage())
+		}
+	case *api.Envelope_GuildcardMessage:
+		msg := payload.GuildcardMessage
+		for _, c := range s.blockSessions(clientsWithGuildcard(msg.GetToGuildcard())) {
+			s.deliverMessage(c, fmt.Sprintf("%s: %s", msg.GetFromName(), msg.GetMessage()))
+		}
+	case *api.Envelope_KickRequest:
+		kick := payload.KickRequest
+		for _, c := range s.blockSessions(clientsWithAccount(uint(kick.GetAccountId()))) {
+			if kick.GetSessionId() != "" && c.SessionID != kick.GetSessionId() {
+				continue
+			}
+			// Closing the connection will cause the Frontend to clean up the client.
+			c.Logger().Infof("kicked by %s: %s", originName(envelope), kick.GetReason())
+			_ = c.Close()
+		}
+	case *api.Envelope_TeamUpdate:
+		update := payload.TeamUpdate
+		for _, c := range s.blockSessions(clientsWithAccount(uint(update.GetAccountId()))) {
+			// Takes effect the next time the client is sent its security data.
+			c.SetTeamID(uint32(update.GetTeamId()))
+		}
+	}
+}
+
+// loggedInClients returns the clients holding an account session on this block.
+func (s *Server) loggedInClients() []*client.Client {
+	s.sessionsMutex.Lock()
+	defer s.sessionsMutex.Unlock()
+
+	clients := make([]*client.Client, 0, len(s.sessions))
+	for _, c := range s.sessions {
+		clients = append(clients, c)
+	}
+	return clients
+}
+
+// blockSessions returns those of clients (which may be connected to any server in
+// the process) that hold an account session on this block.
+func (s *Server) blockSessions(clients []*client.Client) []*client.Client {
+	s.sessionsMutex.Lock()
+	defer s.sessionsMutex.Unlock()
+
+	var sessions []*client.Client
+	for _, c := range clients {
+		if s.sessions[c.SessionID] == c {
+			sessions = append(sessions, c)
+		}
+	}
+	return sessions
+}
+
+func (s *Server) deliverMessage(c *client.Client, message string) {
+	if err := s.SendMessage(c, message); err != nil {
+		c.Logger().Warnf("failed to deliver message from message bus: %v", err)
+	}
+}
+
+// originName describes where an envelope came from for logging purposes.
+func originName(envelope *api.Envelope) string {
+	if envelope.GetOriginShip() == "" {
+		return "shipgate"
+	}
+	return envelope.GetOriginShip()
+}
+
 func (s *Server) sendSecurity(c *client.Client, errorCode uint32) error {
 	return c.Send(&packets.Security{
 		Header:       packets.BBHeader{Type: packets.LoginSecurityType},
 		ErrorCode:    errorCode,
 		PlayerTag:    0x00010000,
 		Guildcard:    c.Guildcard,
-		TeamID:       c.TeamID,
+		TeamID:       c.TeamID(),
 		Config:       c.Config,
 		Capabilities: 0x00000102,
 	})
@@ -132,6 +405,20 @@ func (s *Server) sendMessage(c *client.Client, message string) error {
 	})
 }
 
+// SendMessage displays message in the client's lobby or game.
+func (s *Server) SendMessage(c *client.Client, message string) error {
+	return c.Send(&packets.ServerMessage{
+		Header:   packets.BBHeader{Type: packets.ServerMessageType},
+		Language: 0x00450009,
+		Message:  bytes.ConvertToUtf16(message),
+	})
+}
+
+// Ping sends the client a keepalive ping.
+func (s *Server) Ping(c *client.Client) error {
+	return c.Send(&packets.BBHeader{Type: packets.PingType})
+}
+
 func (s *Server) sendLobbyList(c *client.Client) error {
 	lobbyEntries := make([]packets.LobbyListEntry, s.numLobbies)
 	for i := 0; i < s.numLobbies; i++ {
@@ -0,0 +1,254 @@
+package block
+
+import (
+	gobytes "bytes"
+	"io/ioutil"
+	"net"
+	"testing"
+
+	"github.com/sirupsen/logrus"
+
+	"github.com/dcrodman/archon"
+	"github.com/dcrodman/archon/internal/client"
+	"github.com/dcrodman/archon/internal/core/bytes"
+	"github.com/dcrodman/archon/internal/core/data"
+	"github.com/dcrodman/archon/internal/packets"
+	"github.com/dcrodman/archon/internal/shipgate"
+	"github.com/dcrodman/archon/internal/shipgate/api"
+)
+
+func TestMain(m *testing.M) {
+	archon.Log = logrus.New()
+	archon.Log.Out = ioutil.Discard
+	m.Run()
+}
+
+// plaintextCryptoSession is a CryptoSession that doesn't encrypt anything.
+type plaintextCryptoSession struct{}
+
+func (s plaintextCryptoSession) HeaderSize() uint16                  { return 8 }
+func (s plaintextCryptoSession) Encrypt(bytes []byte, length uint32) {}
+func (s plaintextCryptoSession) Decrypt(bytes []byte, length uint32) {}
+func (s plaintextCryptoSession) ServerVector() []byte                { return nil }
+func (s plaintextCryptoSession) ClientVector() []byte                { return nil }
+
+// connectedClient returns a client with the connection ID sessionID logged in as
+// the account with ID accountID and guildcard, along with a channel that receives
+// everything sent to it once it's closed.
+func connectedClient(sessionID string, accountID uint, guildcard uint32) (*client.Client, <-chan []byte) {
+	remote, conn := net.Pipe()
+	received := make(chan []byte, 1)
+	go func() {
+		data, _ := ioutil.ReadAll(remote)
+		received <- data
+	}()
+
+	return newLoggedInClient(conn, sessionID, accountID, guildcard), received
+}
+
+// newLoggedInClient returns a client on conn with the connection ID sessionID that's
+// logged in as the account with ID accountID and guildcard.
+func newLoggedInClient(conn net.Conn, sessionID string, accountID uint, guildcard uint32) *client.Client {
+	c := client.NewClient(conn)
+	c.SessionID = sessionID
+	c.CryptoSession = plaintextCryptoSession{}
+	c.StartWriter(8, client.DisconnectOnOverflow)
+
+	account := &data.Account{}
+	account.ID = accountID
+	c.SetAccount(account)
+	c.SetGuildcard(guildcard)
+	return c
+}
+
+// findConnectedClients replaces the lookups of the clients connected to the process
+// with ones that find them in clients until the test ends.
+func findConnectedClients(t *testing.T, clients ...*client.Client) {
+	originalClientsWithAccount, originalClientsWithGuildcard := clientsWithAccount, clientsWithGuildcard
+	t.Cleanup(func() {
+		clientsWithAccount, clientsWithGuildcard = originalClientsWithAccount, originalClientsWithGuildcard
+	})
+
+	find := func(matches func(client.Identity) bool) []*client.Client {
+		var found []*client.Client
+		for _, c := range clients {
+			if matches(c.Identity()) {
+				found = append(found, c)
+			}
+		}
+		return found
+	}
+	clientsWithAccount = func(accountID uint) []*client.Client {
+		return find(func(id client.Identity) bool { return id.AccountID == accountID })
+	}
+	clientsWithGuildcard = func(guildcard uint32) []*client.Client {
+		return find(func(id client.Identity) bool { return id.Guildcard == guildcard })
+	}
+}
+
+func TestServer_HandleEnvelope(t *testing.T) {
+	tests := map[string]struct {
+		envelope *api.Envelope
+		// Message the client should be sent, if any.
+		wantedMessage string
+		wantedTeamID  uint32
+	}{
+		"announcement": {
+			envelope:      &api.Envelope{Payload: &api.Envelope_Announcement{Announcement: &api.Announcement{Message: "Hello"}}},
+			wantedMessage: "Hello",
+		},
+		"guildcard_message": {
+			envelope: &api.Envelope{Payload: &api.Envelope_GuildcardMessage{GuildcardMessage: &api.GuildcardMessage{
+				ToGuildcard: 42, FromGuildcard: 43, FromName: "Friend", Message: "Hi",
+			}}},
+			wantedMessage: "Friend: Hi",
+		},
+		"guildcard_message_other_player": {
+			envelope: &api.Envelope{Payload: &api.Envelope_GuildcardMessage{GuildcardMessage: &api.GuildcardMessage{
+				ToGuildcard: 44, FromGuildcard: 43, FromName: "Friend", Message: "Hi",
+			}}},
+		},
+		"team_update": {
+			envelope:     &api.Envelope{Payload: &api.Envelope_TeamUpdate{TeamUpdate: &api.TeamUpdate{AccountId: 1, TeamId: 5}}},
+			wantedTeamID: 5,
+		},
+		"team_update_other_account": {
+			envelope: &api.Envelope{Payload: &api.Envelope_TeamUpdate{TeamUpdate: &api.TeamUpdate{AccountId: 2, TeamId: 5}}},
+		},
+	}
+
+	for name, tt := range tests {
+		t.Run(name, func(t *testing.T) {
+			s := &Server{sessions: make(map[string]*client.Client)}
+			c, received := connectedClient("block", 1, 42)
+			s.sessions[c.SessionID] = c
+			// The same player may also be connected to another server in the process,
+			// which doesn't deliver envelopes for the block.
+			other, otherReceived := connectedClient("character", 1, 42)
+			findConnectedClients(t, c, other)
+
+			s.HandleEnvelope(tt.envelope)
+			_ = c.Close()
+			_ = other.Close()
+			sent := <-received
+
+			if otherSent := <-otherReceived; len(otherSent) > 0 {
+				t.Errorf("expected nothing to be sent to clients outside the block, got %v", otherSent)
+			}
+			if other.TeamID() != 0 {
+				t.Errorf("expected team ID of clients outside the block not to change")
+			}
+
+			if teamID := c.TeamID(); teamID != tt.wantedTeamID {
+				t.Errorf("expected team ID = %d, got = %d", tt.wantedTeamID, teamID)
+			}
+			if tt.wantedMessage == "" {
+				if len(sent) > 0 {
+					t.Errorf("expected no packets to be sent, got %v", sent)
+				}
+				return
+			}
+			if len(sent) < 4 || uint16(sent[2])|uint16(sent[3])<<8 != packets.ServerMessageType {
+				t.Fatalf("expected a server message to be sent, got %v", sent)
+			}
+			if !gobytes.Contains(sent, bytes.ConvertToUtf16(tt.wantedMessage)) {
+				t.Errorf("expected message %q to be sent, got %v", tt.wantedMessage, sent)
+			}
+		})
+	}
+}
+
+func TestServer_LobbyAndGameStates(t *testing.T) {
+	s := &Server{sessions: make(map[string]*client.Client)}
+	selected := []client.State{client.Handshaken, client.Authenticated, client.CharacterSelected}
+	inLobby := []client.State{client.Handshaken, client.Authenticated, client.CharacterSelected, client.InLobby}
+	inGame := []client.State{client.Handshaken, client.Authenticated, client.CharacterSelected, client.InLobby, client.InGame}
+
+	tests := map[string]struct {
+		// States through which the client is moved before the handler is called.
+		from        []client.State
+		handler     func(*client.Client) error
+		wantedState client.State
+		wantErr     bool
+	}{
+		"join_lobby":         {from: selected, handler: s.handleLobbyJoin, wantedState: client.InLobby},
+		"create_game":        {from: inLobby, handler: s.handleCreateGame, wantedState: client.InGame},
+		"leave_game":         {from: inGame, handler: s.handleLeaveGame, wantedState: client.InLobby},
+		"rejoin_lobby":       {from: inLobby, handler: s.handleLobbyJoin, wantedState: client.InLobby, wantErr: true},
+		"create_in_game":     {from: inGame, handler: s.handleCreateGame, wantedState: client.InGame, wantErr: true},
+		"leave_outside_game": {from: inLobby, handler: s.handleLeaveGame, wantedState: client.InLobby, wantErr: true},
+	}
+
+	for name, tt := range tests {
+		t.Run(name, func(t *testing.T) {
+			c := &client.Client{}
+			for _, state := range tt.from {
+				if err := c.Transition(state); err != nil {
+					t.Fatalf("unexpected error moving client to %s: %v", state, err)
+				}
+			}
+
+			if err := tt.handler(c); (err != nil) != tt.wantErr {
+				t.Errorf("expected error = %v, got = %v", tt.wantErr, err)
+			}
+			if c.State() != tt.wantedState {
+				t.Errorf("expected state = %s, got = %s", tt.wantedState, c.State())
+			}
+		})
+	}
+}
+
+func TestServer_HandleSimpleMail(t *testing.T) {
+	mail := &packets.SimpleMail{
+		// Claimed by the client, but the guildcard it logged in with is used instead.
+		FromGuildcard: 99,
+		ToGuildcard:   43,
+		Text:          bytes.ConvertToUtf16("\tEHello there"),
+	}
+	copy(mail.FromName[:], bytes.ConvertToUtf16("\tEFriend"))
+
+	tests := map[string]struct {
+		publish func(*api.Envelope) error
+		// Message the sender should be sent, if any.
+		wantedMessage string
+		wantPublished bool
+	}{
+		"published":   {publish: func(*api.Envelope) error { return nil }, wantPublished: true},
+		"bus_backlog": {publish: func(*api.Envelope) error { return shipgate.ErrUnavailable }, wantedMessage: "couldn't be sent", wantPublished: true},
+		"no_bus":      {wantedMessage: "can't be sent"},
+	}
+
+	for name, tt := range tests {
+		t.Run(name, func(t *testing.T) {
+			var published *api.GuildcardMessage
+			s := &Server{sessions: make(map[string]*client.Client)}
+			if tt.publish != nil {
+				s.SetPublisher(func(envelope *api.Envelope) error {
+					published = envelope.GetGuildcardMessage()
+					return tt.publish(envelope)
+				})
+			}
+			c, received := connectedClient("block", 1, 42)
+
+			if err := s.handleSimpleMail(c, mail); err != nil {
+				t.Fatalf("unexpected error: %v", err)
+			}
+			_ = c.Close()
+			sent := <-received
+
+			if tt.wantPublished {
+				if published.GetToGuildcard() != 43 || published.GetFromGuildcard() != 42 ||
+					published.GetFromName() != "Friend" || published.GetMessage() != "Hello there" {
+					t.Errorf("expected mail from Friend (42) to 43 to be published, got %v", published)
+				}
+			}
+			if tt.wantedMessage == "" {
+				if len(sent) > 0 {
+					t.Errorf("expected no packets to be sent, got %v", sent)
+				}
+			} else if !gobytes.Contains(sent, bytes.ConvertToUtf16(tt.wantedMessage)) {
+				t.Errorf("expected message %q to be sent, got %v", tt.wantedMessage, sent)
+			}
+		})
+	}
+}
@@ -0,0 +1,259 @@
+package block
+
+import (
+	"context"
+	"crypto/ecdsa"
+	"crypto/elliptic"
+	"crypto/rand"
+	"crypto/x509"
+	"crypto/x509/pkix"
+	"encoding/pem"
+	"io"
+	"io/ioutil"
+	"math/big"
+	"net"
+	"net/http"
+	"net/http/httptest"
+	"path/filepath"
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/dcrodman/archon"
+	"github.com/dcrodman/archon/internal"
+	"github.com/dcrodman/archon/internal/client"
+	"github.com/dcrodman/archon/internal/core/bytes"
+	"github.com/dcrodman/archon/internal/packets"
+	"github.com/dcrodman/archon/internal/ship"
+	"github.com/dcrodman/archon/internal/shipgate"
+	"github.com/dcrodman/archon/internal/shipgate/api"
+	"github.com/dcrodman/archon/internal/web"
+)
+
+// writeTestCertificates writes a CA and a certificate for 127.0.0.1 issued by it
+// (usable by both the shipgate and its clients) to dir, returning the paths of the
+// CA, certificate, and key files.
+func writeTestCertificates(t *testing.T, dir string) (string, string, string) {
+	t.Helper()
+	caKey, err := ecdsa.GenerateKey(elliptic.P256(), rand.Reader)
+	if err != nil {
+		t.Fatalf("failed to generate key: %v", err)
+	}
+	caTemplate := &x509.Certificate{
+		SerialNumber:          big.NewInt(1),
+		Subject:               pkix.Name{CommonName: "Test CA"},
+		NotBefore:             time.Now().Add(-time.Hour),
+		NotAfter:              time.Now().Add(time.Hour),
+		KeyUsage:              x509.KeyUsageCertSign | x509.KeyUsageDigitalSignature,
+		BasicConstraintsValid: true,
+		IsCA:                  true,
+	}
+	caDER, err := x509.CreateCertificate(rand.Reader, caTemplate, caTemplate, &caKey.PublicKey, caKey)
+	if err != nil {
+		t.Fatalf("failed to create CA certificate: %v", err)
+	}
+	ca, _ := x509.ParseCertificate(caDER)
+
+	key, err := ecdsa.GenerateKey(elliptic.P256(), rand.Reader)
+	if err != nil {
+		t.Fatalf("failed to generate key: %v", err)
+	}
+	template := &x509.Certificate{
+		SerialNumber: big.NewInt(2),
+		Subject:      pkix.Name{CommonName: "shipgate"},
+		NotBefore:    time.Now().Add(-time.Hour),
+		NotAfter:     time.Now().Add(time.Hour),
+		KeyUsage:     x509.KeyUsageDigitalSignature,
+		ExtKeyUsage:  []x509.ExtKeyUsage{x509.ExtKeyUsageServerAuth, x509.ExtKeyUsageClientAuth},
+		IPAddresses:  []net.IP{net.ParseIP("127.0.0.1")},
+	}
+	der, err := x509.CreateCertificate(rand.Reader, template, ca, &key.PublicKey, caKey)
+	if err != nil {
+		t.Fatalf("failed to create certificate: %v", err)
+	}
+	keyDER, _ := x509.MarshalECPrivateKey(key)
+
+	files := map[string]*pem.Block{
+		"ca.pem":          {Type: "CERTIFICATE", Bytes: caDER},
+		"certificate.pem": {Type: "CERTIFICATE", Bytes: der},
+		"key.pem":         {Type: "EC PRIVATE KEY", Bytes: keyDER},
+	}
+	for name, block := range files {
+		if err := ioutil.WriteFile(filepath.Join(dir, name), pem.EncodeToMemory(block), 0600); err != nil {
+			t.Fatalf("failed to write %s: %v", name, err)
+		}
+	}
+	return filepath.Join(dir, "ca.pem"), filepath.Join(dir, "certificate.pem"), filepath.Join(dir, "key.pem")
+}
+
+// startShipgate starts a shipgate that allows the ships named ships to connect
+// (using the secret "<name>-secret") until ctx is done, and returns its address
+// along with the config with which to connect to it.
+func startShipgate(t *testing.T, ctx context.Context, ships ...string) (string, *archon.Config) {
+	cfg := archon.DefaultConfig()
+	caFile, certFile, keyFile := writeTestCertificates(t, t.TempDir())
+	cfg.ShipgateCAFile = caFile
+	cfg.ShipgateCertificateFile, cfg.ShipgateServer.SSLKeyFile = certFile, keyFile
+	cfg.ShipgateClientCertFile, cfg.ShipgateClientKeyFile = certFile, keyFile
+	for _, name := range ships {
+		cfg.ShipgateServer.Ships = append(cfg.ShipgateServer.Ships, archon.AllowedShipConfig{Name: name, Secret: name + "-secret"})
+	}
+
+	listener, err := net.Listen("tcp", "127.0.0.1:0")
+	if err != nil {
+		t.Fatalf("failed to find a free port: %v", err)
+	}
+	addr := listener.Addr().String()
+	_ = listener.Close()
+
+	readyChan, errChan := make(chan bool), make(chan error, 1)
+	go shipgate.Start(ctx, addr, cfg, readyChan, errChan)
+	select {
+	case <-readyChan:
+	case err := <-errChan:
+		t.Fatalf("failed to start shipgate: %v", err)
+	}
+	return addr, cfg
+}
+
+// startShip initializes a ship named name with a single block, connected to the
+// shipgate at shipgateAddr, and returns both.
+func startShip(t *testing.T, ctx context.Context, name, shipgateAddr string, gateCfg *archon.Config) (*ship.Server, *Server) {
+	cfg := *gateCfg
+	cfg.ExternalIP = "127.0.0.1"
+	cfg.ShipServer.Name = name
+	cfg.ShipServer.Secret = name + "-secret"
+
+	blockServer := NewServer(name+" BLOCK01", shipgateAddr, &cfg)
+	shipServer := ship.NewServer(name, []ship.Block{
+		{Name: "BLOCK01", ID: 1, Frontend: &internal.Frontend{Backend: blockServer}},
+	}, shipgateAddr, &cfg)
+	if err := shipServer.Init(ctx); err != nil {
+		t.Fatalf("failed to initialize ship %s: %v", name, err)
+	}
+	return shipServer, blockServer
+}
+
+// loggedInClient returns a client holding an account session on s, along with a
+// channel that receives the text of each server message sent to it.
+func loggedInClient(s *Server, sessionID string, accountID uint, guildcard uint32) (*client.Client, <-chan string) {
+	remote, conn := net.Pipe()
+	messages := make(chan string, 16)
+	go func() {
+		header := make([]byte, 8)
+		for {
+			if _, err := io.ReadFull(remote, header); err != nil {
+				return
+			}
+			body := make([]byte, int(header[0])|int(header[1])<<8-len(header))
+			if _, err := io.ReadFull(remote, body); err != nil {
+				return
+			}
+			// Skips the padding and language that precede the text.
+			if uint16(header[2])|uint16(header[3])<<8 == packets.ServerMessageType && len(body) > 12 {
+				messages <- bytes.ConvertFromUtf16(body[12:])
+			}
+		}
+	}()
+
+	c := newLoggedInClient(conn, sessionID, accountID, guildcard)
+	s.sessionsMutex.Lock()
+	s.sessions[c.SessionID] = c
+	s.sessionsMutex.Unlock()
+	return c, messages
+}
+
+// waitForMessage returns the next message other than "ping" sent to a client,
+// failing the test if there isn't one.
+func waitForMessage(t *testing.T, messages <-chan string) string {
+	t.Helper()
+	for {
+		select {
+		case message := <-messages:
+			if message != "ping" {
+				return message
+			}
+		case <-time.After(5 * time.Second):
+			t.Fatalf("timed out waiting for message")
+			return ""
+		}
+	}
+}
+
+func TestMessageBus_TwoShips(t *testing.T) {
+	ctx, cancel := context.WithCancel(context.Background())
+	defer cancel()
+
+	shipgateAddr, cfg := startShipgate(t, ctx, "First", "Second")
+	first, firstBlock := startShip(t, ctx, "First", shipgateAddr, cfg)
+	_, secondBlock := startShip(t, ctx, "Second", shipgateAddr, cfg)
+
+	alice, aliceMessages := loggedInClient(firstBlock, "alice", 1, 101)
+	bob, bobMessages := loggedInClient(secondBlock, "bob", 2, 102)
+	defer alice.Close()
+	defer bob.Close()
+	// Both players are found by the lookups, but each block only delivers to its own.
+	findConnectedClients(t, alice, bob)
+
+	// The ships connect to the message bus in the background, so announce until both
+	// players are reachable.
+	aliceReached, bobReached := false, false
+	for deadline := time.Now().Add(5 * time.Second); !aliceReached || !bobReached; {
+		if time.Now().After(deadline) {
+			t.Fatalf("timed out waiting for the ships to connect to the message bus")
+		}
+		_ = shipgate.Publish(&api.Envelope{Payload: &api.Envelope_Announcement{Announcement: &api.Announcement{Message: "ping"}}})
+		for wait := time.After(50 * time.Millisecond); ; {
+			select {
+			case <-aliceMessages:
+				aliceReached = true
+				continue
+			case <-bobMessages:
+				bobReached = true
+				continue
+			case <-wait:
+			}
+			break
+		}
+	}
+
+	// Mail sent by a player on the first ship reaches the addressee on the second.
+	mail := &packets.SimpleMail{ToGuildcard: 102, Text: bytes.ConvertToUtf16("\tEHello Bob")}
+	copy(mail.FromName[:], bytes.ConvertToUtf16("\tEAlice"))
+	if err := firstBlock.handleSimpleMail(alice, mail); err != nil {
+		t.Fatalf("unexpected error sending mail: %v", err)
+	}
+	if message := waitForMessage(t, bobMessages); message != "Alice: Hello Bob" {
+		t.Errorf("expected Bob to receive the mail, got %q", message)
+	}
+
+	// Broadcasts made through the admin API of the first ship reach both.
+	admin := &web.AdminHandler{Publish: first.Publish}
+	req := httptest.NewRequest(http.MethodPost, "/admin/broadcast", strings.NewReader(`{"message": "Server restart soon"}`))
+	rec := httptest.NewRecorder()
+	admin.ServeHTTP(rec, req)
+	if rec.Code != http.StatusOK || !strings.Contains(rec.Body.String(), `"published":true`) {
+		t.Fatalf("expected broadcast to be published, got %d: %s", rec.Code, rec.Body.String())
+	}
+	for name, messages := range map[string]<-chan string{"Alice": aliceMessages, "Bob": bobMessages} {
+		if message := waitForMessage(t, messages); message != "Server restart soon" {
+			t.Errorf("expected %s to receive the broadcast, got %q", name, message)
+		}
+	}
+
+	// Team changes published by the shipgate reach the player wherever they are. The
+	// announcement that follows is delivered once both ships have handled the change.
+	_ = shipgate.Publish(&api.Envelope{Payload: &api.Envelope_TeamUpdate{TeamUpdate: &api.TeamUpdate{AccountId: 2, TeamId: 7}}})
+	_ = shipgate.Publish(&api.Envelope{Payload: &api.Envelope_Announcement{Announcement: &api.Announcement{Message: "Done"}}})
+	for name, messages := range map[string]<-chan string{"Alice": aliceMessages, "Bob": bobMessages} {
+		if message := waitForMessage(t, messages); message != "Done" {
+			t.Errorf("expected %s to receive the announcement, got %q", name, message)
+		}
+	}
+	if bob.TeamID() != 7 {
+		t.Errorf("expected Bob's team ID = 7, got = %d", bob.TeamID())
+	}
+	if alice.TeamID() != 0 {
+		t.Errorf("expected Alice's team ID not to change, got = %d", alice.TeamID())
+	}
+}
@@ -10,9 +10,8 @@ import (
 	"time"
 	"unicode/utf16"
 
-	"github.com/spf13/viper"
-
 	"github.com/dcrodman/archon"
+	"github.com/dcrodman/archon/internal"
 	"github.com/dcrodman/archon/internal/client"
 	"github.com/dcrodman/archon/internal/core/auth"
 	"github.com/dcrodman/archon/internal/core/bytes"
@@ -37,10 +36,29 @@ var (
 	loginCopyright = []byte("Phantasy Star Online Blue Burst Game Server. Copyright 1999-2004 SONICTEAM.")
 
 	// Scrolling message that appears across the top of the ship selection screen.
-	shipSelectionScrollMessage     []byte
-	shipSelectionScrollMessageInit sync.Once
+	shipSelectionScrollMessage      []byte
+	shipSelectionScrollMessageMutex sync.RWMutex
 )
 
+// SetScrollMessage replaces the message displayed along the top of the ship
+// selection screen for any clients that reach it from now on.
+func SetScrollMessage(message string) {
+	encoded := bytes.ConvertToUtf16(message)
+	// The end of the message appears to be garbled unless there is an extra byte...?
+	encoded = append(encoded, 0x00)
+
+	shipSelectionScrollMessageMutex.Lock()
+	shipSelectionScrollMessage = encoded
+	shipSelectionScrollMessageMutex.Unlock()
+}
+
+// Returns the scroll message displayed along the top of the ship selection screen.
+func scrollMessage() []byte {
+	shipSelectionScrollMessageMutex.RLock()
+	defer shipSelectionScrollMessageMutex.RUnlock()
+	return shipSelectionScrollMessage
+}
+
 // Server is the CHARACTER server implementation. Clients are sent to this server
 //  after authenticating with LOGIN. Each client connects to the server in four
 // different phases (each one is a new connection):
@@ -56,13 +74,15 @@ type Server struct {
 	kvCache        *Cache
 	shipGateClient *shipgate.Client
 	shipGateAddr   string
+	config         *archon.Config
 }
 
-func NewServer(name string, shipgateAddr string) *Server {
+func NewServer(name string, shipgateAddr string, cfg *archon.Config) *Server {
 	return &Server{
 		name:         name,
 		kvCache:      NewCache(),
 		shipGateAddr: shipgateAddr,
+		config:       cfg,
 	}
 }
 
@@ -72,19 +92,28 @@ func (s *Server) Name() string {
 
 func (s *Server) Init(ctx context.Context) error {
 	var err error
-	s.shipGateClient, err = shipgate.NewClient(s.shipGateAddr)
+	s.shipGateClient, err = shipgate.NewClient(s.shipGateAddr, s.config)
 	if err != nil {
 		return err
 	}
 
-	if err := initParameterData(); err != nil {
+	if err := initParameterData(s.config.CharacterServer.ParametersDir); err != nil {
 		return err
 	}
 
-	// Start the loop that retrieves the ship list from the shipgate.
-	if err := s.shipGateClient.StartShipRefreshLoop(ctx); err != nil {
-		return err
-	}
+	SetScrollMessage(s.config.CharacterServer.ScrollMessage)
+
+	// Pick up changes to the scroll message made in the config file.
+	archon.OnConfigReload(func(cfg *archon.Config, changed []string) {
+		for _, key := range changed {
+			if key == "character_server.scroll_message" {
+				SetScrollMessage(cfg.CharacterServer.ScrollMessage)
+			}
+		}
+	})
+
+	// Keep the ship list up to date with the ships registered with the shipgate.
+	s.shipGateClient.StartShipListWatch(ctx)
 
 	return nil
 }
@@ -108,69 +137,44 @@ func (s *Server) Handshake(c *client.Client) error {
 	return c.SendRaw(pkt)
 }
 
-func (s *Server) Handle(ctx context.Context, c *client.Client, data []byte) error {
-	var packetHeader packets.BBHeader
-	bytes.StructFromBytes(data[:packets.BBHeaderSize], &packetHeader)
-
-	var err error
-	switch packetHeader.Type {
-	case packets.LoginType:
-		var loginPkt packets.Login
-		bytes.StructFromBytes(data, &loginPkt)
-		err = s.handleLogin(ctx, c, &loginPkt)
-	case packets.LoginOptionsRequestType:
-		err = s.handleOptionsRequest(c)
-	case packets.LoginCharPreviewReqType:
-		var pkt packets.CharacterSelection
-		bytes.StructFromBytes(data, &pkt)
-		err = s.handleCharacterSelect(c, &pkt)
-	case packets.LoginChecksumType:
-		// Everybody else seems to ignore this, so...
-		err = s.sendChecksumAck(c)
-	case packets.LoginGuildcardReqType:
-		err = s.handleGuildcardDataStart(c)
-	case packets.LoginGuildcardChunkReqType:
-		var chunkReq packets.GuildcardChunkRequest
-		bytes.StructFromBytes(data, &chunkReq)
-		err = s.handleGuildcardChunk(c, &chunkReq)
-	case packets.LoginParameterHeaderReqType:
-		err = s.sendParameterHeader(c, uint32(len(paramFiles)), paramHeaderData)
-	case packets.LoginParameterChunkReqType:
-		var pkt packets.BBHeader
-		bytes.StructFromBytes(data, &pkt)
-		err = s.sendParameterChunk(c, paramChunkData[int(pkt.Flags)], pkt.Flags)
-	case packets.LoginSetFlagType:
-		var pkt packets.SetFlag
-		bytes.StructFromBytes(data, &pkt)
-		s.setClientFlag(c, &pkt)
-	case packets.LoginCharPreviewType:
-		var charPkt packets.CharacterSummary
-		bytes.StructFromBytes(data, &charPkt)
-		err = s.handleCharacterUpdate(c, &charPkt)
-	case packets.MenuSelectType:
-		var menuSelectionPkt packets.MenuSelection
-		bytes.StructFromBytes(data, &menuSelectionPkt)
-		err = s.handleShipSelection(c, &menuSelectionPkt)
-	case packets.DisconnectType:
-		// Just wait for the client to disconnect.
-		break
-	default:
-		archon.Log.Infof("received unknown packet %x from %s", packetHeader.Type, c.IPAddr())
-	}
-	return err
+func (s *Server) RegisterHandlers(h *internal.PacketHandlers) {
+	// States in which the client has logged in, and may or may not have
+	// gone on to select a character.
+	loggedIn := []client.State{client.Authenticated, client.CharacterSelected}
+
+	h.Register(packets.LoginType, s.handleLogin, client.Handshaken)
+	h.Register(packets.LoginOptionsRequestType, s.handleOptionsRequest, loggedIn...)
+	h.Register(packets.LoginCharPreviewReqType, s.handleCharacterSelect, loggedIn...)
+	// Everybody else seems to ignore this, so...
+	h.Register(packets.LoginChecksumType, s.sendChecksumAck, loggedIn...)
+	h.Register(packets.LoginGuildcardReqType, s.handleGuildcardDataStart, loggedIn...)
+	h.Register(packets.LoginGuildcardChunkReqType, s.handleGuildcardChunk, loggedIn...)
+	h.Register(packets.LoginParameterHeaderReqType, s.handleParameterHeaderRequest, loggedIn...)
+	h.Register(packets.LoginParameterChunkReqType, s.handleParameterChunkRequest, loggedIn...)
+	h.Register(packets.LoginSetFlagType, s.setClientFlag, loggedIn...)
+	h.Register(packets.LoginCharPreviewType, s.handleCharacterUpdate, loggedIn...)
+	h.Register(packets.MenuSelectType, s.handleShipSelection, loggedIn...)
+	// Just wait for the client to disconnect.
+	h.Ignore(packets.DisconnectType)
 }
 
 func (s *Server) handleLogin(ctx context.Context, c *client.Client, loginPkt *packets.Login) error {
 	username := string(bytes.StripPadding(loginPkt.Username[:]))
-	password := string(bytes.StripPadding(loginPkt.Password[:]))
 
-	account, err := s.shipGateClient.AuthenticateAccount(ctx, username, password)
+	// The client echoes the config it was sent by the previous server, which
+	// carries the session token it was issued when it logged in.
+	if err := c.Config.UnmarshalBinary(loginPkt.Security[:]); err != nil {
+		return err
+	}
+	account, err := s.shipGateClient.AuthenticateSessionToken(ctx, username, c.Config.SessionToken[:])
 	if err != nil {
 		switch err {
 		case auth.ErrInvalidCredentials:
 			return s.sendSecurity(c, packets.BBLoginErrorPassword)
 		case auth.ErrAccountBanned:
 			return s.sendSecurity(c, packets.BBLoginErrorBanned)
+		case shipgate.ErrUnavailable:
+			return s.sendMessage(c, shipgate.UnavailableMessage)
 		default:
 			sendErr := s.sendMessage(c, strings.Title(err.Error()))
 			if sendErr == nil {
@@ -184,9 +188,12 @@ func (s *Server) handleLogin(ctx context.Context, c *client.Client, loginPkt *pa
 		return err
 	}
 
-	c.TeamID = uint32(account.TeamID)
-	c.Guildcard = uint32(account.Guildcard)
-	c.Account = account
+	c.SetTeamID(uint32(account.TeamID))
+	c.SetGuildcard(uint32(account.Guildcard))
+	c.SetAccount(account)
+	if err := c.Transition(client.Authenticated); err != nil {
+		return err
+	}
 
 	// At this point, the user has chosen (or created) a character and the
 	// client needs the ship list.
@@ -214,7 +221,7 @@ func (s *Server) sendSecurity(c *client.Client, errorCode uint32) error {
 		ErrorCode:    errorCode,
 		PlayerTag:    0x00010000,
 		Guildcard:    c.Guildcard,
-		TeamID:       c.TeamID,
+		TeamID:       c.TeamID(),
 		Config:       c.Config,
 		Capabilities: 0x00000102,
 	})
@@ -247,7 +254,7 @@ func (s *Server) sendTimestamp(c *client.Client) error {
 
 // Send the menu items for the ship select screen.
 func (s *Server) sendShipList(c *client.Client) error {
-	shipList := s.shipGateClient.GetConnectedShipList()
+	shipList := s.shipGateClient.GetConnectedShipList(c.Account.GM)
 
 	pkt := &packets.ShipList{
 		Header: packets.BBHeader{
@@ -266,19 +273,9 @@ func (s *Server) sendShipList(c *client.Client) error {
 
 // send whatever scrolling message was read out of the config file for the login screen.
 func (s *Server) sendScrollMessage(c *client.Client) error {
-	// Returns the scroll message displayed along the top of the ship selection screen,
-	// lazily computing it from the config file and storing it in a package var.
-	shipSelectionScrollMessageInit.Do(func() {
-		shipSelectionScrollMessage = bytes.ConvertToUtf16(
-			viper.GetString("character_server.scroll_message"),
-		)
-		// The end of the message appears to be garbled unless there is an extra byte...?
-		shipSelectionScrollMessage = append(shipSelectionScrollMessage, 0x00)
-	})
-
 	return c.Send(&packets.ScrollMessagePacket{
 		Header:  packets.BBHeader{Type: packets.LoginScrollMessageType},
-		Message: shipSelectionScrollMessage,
+		Message: scrollMessage(),
 	})
 }
 
@@ -346,6 +343,11 @@ func (s *Server) handleCharacterSelect(c *client.Client, pkt *packets.CharacterS
 		}
 		// They've selected a character from the menu.
 		c.Config.SlotNum = uint8(pkt.Slot)
+		if c.State() != client.CharacterSelected {
+			if err := c.Transition(client.CharacterSelected); err != nil {
+				return err
+			}
+		}
 		return s.sendCharacterAck(c, pkt.Slot, 1)
 	} else {
 		if char == nil {
@@ -469,19 +471,31 @@ func (s *Server) sendGuildcardChunk(c *client.Client, chunkNum uint32) error {
 		Chunk:  chunkNum,
 	}
 
-	// The client will only accept 0x6800 bytes of a chunk per packet.
-	offset := uint16(chunkNum) * maxDataChunkSize
-	remaining := uint16(len(c.GuildcardData)) - offset
-
-	if remaining > maxDataChunkSize {
-		pkt.Data = c.GuildcardData[offset : offset+maxDataChunkSize]
-	} else {
-		pkt.Data = c.GuildcardData[offset:]
+	// The client will only accept 0x6800 bytes of a chunk per packet. The chunk number
+	// comes from the client, which may also ask for one before requesting the data.
+	offset := int(chunkNum) * maxDataChunkSize
+	if offset >= len(c.GuildcardData) {
+		return fmt.Errorf("guildcard chunk %d requested of %d bytes of guildcard data", chunkNum, len(c.GuildcardData))
 	}
+	end := offset + maxDataChunkSize
+	if end > len(c.GuildcardData) {
+		end = len(c.GuildcardData)
+	}
+	pkt.Data = c.GuildcardData[offset:end]
 
 	return c.Send(pkt)
 }
 
+func (s *Server) handleParameterHeaderRequest(c *client.Client) error {
+	return s.sendParameterHeader(c, uint32(len(paramFiles)), paramHeaderData)
+}
+
+// The client requests each chunk of parameter data by its index, which is sent in
+// the header's flags.
+func (s *Server) handleParameterChunkRequest(c *client.Client, pkt *packets.BBHeader) error {
+	return s.sendParameterChunk(c, paramChunkData[int(pkt.Flags)], pkt.Flags)
+}
+
 // send the header for the parameter files we're about to start sending.
 func (s *Server) sendParameterHeader(c *client.Client, numEntries uint32, entries []byte) error {
 	return c.Send(&packets.ParameterHeader{
@@ -505,12 +519,13 @@ func (s *Server) sendParameterChunk(c *client.Client, chunkData []byte, chunk ui
 // The client may send us flags as a result of user actions in order to indicate
 // a change in state or desired behavior. For instance, setting 0x02 indicates
 // that the character dressing room has been opened.
-func (s *Server) setClientFlag(c *client.Client, pkt *packets.SetFlag) {
+func (s *Server) setClientFlag(c *client.Client, pkt *packets.SetFlag) error {
 	c.Flag = c.Flag | pkt.Flag
 	// Some flags are set right before the client disconnects, which means saving them
 	// on the Client struct alone isn't safe since the state is lost. To fix this the
 	// flags are also kept in memory to avoid bugs like accidentally recreating characters.
 	s.kvCache.Set(clientFlagKey(c), c.Flag, -1)
+	return nil
 }
 
 func clientFlagKey(c *client.Client) string {
@@ -523,7 +538,7 @@ func (s *Server) handleCharacterUpdate(c *client.Client, charPkt *packets.Charac
 	if s.hasDressingRoomFlag(c) {
 		// "Dressing room"; a request to update an existing character.
 		if err := s.updateCharacter(c, charPkt); err != nil {
-			archon.Log.Error(err.Error())
+			c.Logger().Error(err.Error())
 			return err
 		}
 	} else {
@@ -533,12 +548,12 @@ func (s *Server) handleCharacterUpdate(c *client.Client, charPkt *packets.Charac
 		existingCharacter, err := data.FindCharacter(account, int(charPkt.Slot))
 		if err != nil {
 			msg := fmt.Errorf("failed to locate character in slot %d for account %d", charPkt.Slot, account.ID)
-			archon.Log.Error(msg)
+			c.Logger().Error(msg)
 			return msg
 		}
 		if existingCharacter != nil {
 			if err := data.DeleteCharacter(existingCharacter); err != nil {
-				archon.Log.Error(err.Error())
+				c.Logger().Error(err.Error())
 				return err
 			}
 		}
@@ -656,11 +671,11 @@ func (s *Server) updateCharacter(c *client.Client, pkt *packets.CharacterSummary
 // Player selected one of the items on the ship select screen; respond with the
 // IP address and port of the ship server to  which the client will connect after
 // disconnecting from this server.
-func (s *Server) handleShipSelection(c *client.Client, menuSelectionPkt *packets.MenuSelection) error {
-	selectedShip := menuSelectionPkt.ItemID - 1
-	ip, port, err := s.shipGateClient.GetSelectedShipAddress(selectedShip)
+func (s *Server) handleShipSelection(ctx context.Context, c *client.Client, menuSelectionPkt *packets.MenuSelection) error {
+	selectedShip := menuSelectionPkt.ItemID
+	ip, port, err := s.shipGateClient.GetSelectedShipAddress(ctx, selectedShip, c.IPAddr())
 	if err != nil {
-		return fmt.Errorf("could not get selected ship: %d", selectedShip)
+		return fmt.Errorf("could not get selected ship %d: %w", selectedShip, err)
 	}
 	return c.Send(&packets.Redirect{
 		Header: packets.BBHeader{Type: packets.RedirectType},
@@ -0,0 +1,76 @@
+package character
+
+import (
+	"io/ioutil"
+	"net"
+	"testing"
+
+	"github.com/sirupsen/logrus"
+
+	"github.com/dcrodman/archon"
+	"github.com/dcrodman/archon/internal/client"
+)
+
+func TestMain(m *testing.M) {
+	archon.Log = logrus.New()
+	archon.Log.Out = ioutil.Discard
+	m.Run()
+}
+
+// plaintextCryptoSession is a CryptoSession that doesn't encrypt anything.
+type plaintextCryptoSession struct{}
+
+func (s plaintextCryptoSession) HeaderSize() uint16                  { return 8 }
+func (s plaintextCryptoSession) Encrypt(bytes []byte, length uint32) {}
+func (s plaintextCryptoSession) Decrypt(bytes []byte, length uint32) {}
+func (s plaintextCryptoSession) ServerVector() []byte                { return nil }
+func (s plaintextCryptoSession) ClientVector() []byte                { return nil }
+
+func TestServer_SendGuildcardChunk(t *testing.T) {
+	// Size of a guildcard chunk packet without its data.
+	const chunkHeaderSize = 16
+
+	tests := map[string]struct {
+		guildcardData []byte
+		chunk         uint32
+		// Number of bytes of guildcard data expected in the chunk.
+		wantedSize int
+		wantErr    bool
+	}{
+		"first_chunk":      {guildcardData: make([]byte, maxDataChunkSize+16), chunk: 0, wantedSize: maxDataChunkSize},
+		"last_chunk":       {guildcardData: make([]byte, maxDataChunkSize+16), chunk: 1, wantedSize: 16},
+		"out_of_range":     {guildcardData: make([]byte, maxDataChunkSize+16), chunk: 2, wantErr: true},
+		"overflowing":      {guildcardData: make([]byte, maxDataChunkSize+16), chunk: 0xFFFFFFFF, wantErr: true},
+		"before_data_sent": {chunk: 0, wantErr: true},
+	}
+
+	for name, tt := range tests {
+		t.Run(name, func(t *testing.T) {
+			remote, conn := net.Pipe()
+			received := make(chan []byte, 1)
+			go func() {
+				data, _ := ioutil.ReadAll(remote)
+				received <- data
+			}()
+
+			c := client.NewClient(conn)
+			c.CryptoSession = plaintextCryptoSession{}
+			c.StartWriter(8, client.DisconnectOnOverflow)
+			c.GuildcardData = tt.guildcardData
+
+			err := (&Server{}).sendGuildcardChunk(c, tt.chunk)
+			if (err != nil) != tt.wantErr {
+				t.Fatalf("expected error = %v, got = %v", tt.wantErr, err)
+			}
+			c.Close()
+
+			wantedLength := 0
+			if !tt.wantErr {
+				wantedLength = chunkHeaderSize + tt.wantedSize
+			}
+			if data := <-received; len(data) != wantedLength {
+				t.Errorf("expected %d bytes to be sent, got %d", wantedLength, len(data))
+			}
+		})
+	}
+}
@@ -11,7 +11,6 @@ import (
 	"github.com/dcrodman/archon/internal/core/bytes"
 	"github.com/dcrodman/archon/internal/core/debug"
 	"github.com/dcrodman/archon/internal/core/prs"
-	"github.com/spf13/viper"
 
 	"github.com/dcrodman/archon"
 )
@@ -68,12 +67,10 @@ type parameterEntry struct {
 	Filename [0x40]uint8
 }
 
-func initParameterData() error {
+func initParameterData(paramFileDir string) error {
 	var initErr error
 
 	paramInitLock.Do(func() {
-		paramFileDir := viper.GetString("character_server.parameters_dir")
-
 		if err := loadParameterFiles(paramFileDir); err != nil {
 			initErr = fmt.Errorf("failed to load parameter files:" + err.Error())
 			return
@@ -96,11 +93,19 @@ func initParameterData() error {
 		decompressedStatsFile, err := prs.Decompress(compressedStatsFile, decompressedSize)
 		if err != nil {
 			initErr = fmt.Errorf("failed to decompress PlyLevelTbl.prs: %v", err)
+			return
 		}
 
 		// Base character class stats are stored sequentially, each 14 bytes long.
 		for i := 0; i < NumCharacterClasses; i++ {
-			bytes.StructFromBytes(decompressedStatsFile[i*14:], &BaseStats[i])
+			if len(decompressedStatsFile) < i*14 {
+				initErr = fmt.Errorf("PlyLevelTbl.prs is missing stats for class %d", i)
+				return
+			}
+			if err := bytes.StructFromBytes(decompressedStatsFile[i*14:], &BaseStats[i]); err != nil {
+				initErr = fmt.Errorf("failed to read stats for class %d from PlyLevelTbl.prs: %v", i, err)
+				return
+			}
 		}
 	})
 
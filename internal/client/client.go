@@ -3,31 +3,63 @@ package client
 import (
 	"fmt"
 	"net"
-	"strings"
+	"sync"
+	"time"
 
+	"github.com/sirupsen/logrus"
+
+	"github.com/dcrodman/archon"
 	"github.com/dcrodman/archon/internal/core/bytes"
 	"github.com/dcrodman/archon/internal/core/data"
 	"github.com/dcrodman/archon/internal/core/debug"
+	"github.com/dcrodman/archon/internal/core/metrics"
 	"github.com/dcrodman/archon/internal/packets"
 )
 
 // Client represents a user connected through a PSOBB game client.
 type Client struct {
-	connection *net.TCPConn
+	connection net.Conn
 	ipAddr     string
 	port       string
 
+	// Name of the server (Backend) the client is connected to.
+	ServerName string
+	// Uniquely identifies this connection in logs and across shipgate requests.
+	SessionID string
+
+	// Maximum time allowed for sending a packet to the client (0 for no limit).
+	WriteTimeout time.Duration
+	// Serializes packets sent from different goroutines, since encryption is stateful.
+	sendMutex sync.Mutex
+	// Packets waiting to be written by the writer goroutine (nil until StartWriter
+	// is called). Closing is closed once the client is closed, after which the
+	// writer flushes the queue and closes writerDone.
+	queue          chan outboundPacket
+	closing        chan struct{}
+	closeOnce      sync.Once
+	writerDone     chan struct{}
+	overflowPolicy OverflowPolicy
+
+	// Progress of the client through its session with the server.
+	state      State
+	stateMutex sync.RWMutex
+
 	// Cipher implementation responsible for packet encryption.
 	CryptoSession CryptoSession
 
 	// Account associated with the player.
 	Account *data.Account
+	// Guards Account, Guildcard, and teamID, which are set by the goroutine handling
+	// the client's packets but may be read from others through Identity.
+	identityMutex sync.RWMutex
+	// Called after the account or guildcard changes (see OnIdentityChange).
+	identityChanged func()
 
 	// Client information shared amongst most Backend implementations.
 	Config packets.ClientConfig
 
 	Flag   uint32
-	TeamID uint32
+	teamID uint32
 	IsGm   bool
 	// Guildcard linked to the account.
 	Guildcard     uint32
@@ -43,13 +75,13 @@ type Client struct {
 	DebugTags map[string]interface{}
 }
 
-func NewClient(connection *net.TCPConn) *Client {
-	addr := strings.Split(connection.RemoteAddr().String(), ":")
+func NewClient(connection net.Conn) *Client {
+	ipAddr, port, _ := net.SplitHostPort(connection.RemoteAddr().String())
 
 	return &Client{
 		connection: connection,
-		ipAddr:     addr[0],
-		port:       addr[1],
+		ipAddr:     ipAddr,
+		port:       port,
 		DebugTags:  make(map[string]interface{}),
 	}
 }
@@ -57,6 +89,26 @@ func NewClient(connection *net.TCPConn) *Client {
 func (c *Client) IPAddr() string { return c.ipAddr }
 func (c *Client) Port() string   { return c.port }
 
+// Logger returns a logger annotated with the fields identifying this client's
+// session, including the account and guildcard once they're known.
+func (c *Client) Logger() *logrus.Entry {
+	fields := logrus.Fields{
+		"session_id": c.SessionID,
+		"server":     c.ServerName,
+		"ip":         c.ipAddr,
+	}
+	// Called from the client's writer goroutine and the message bus as well as the
+	// one handling its packets, so the identity has to be read under the lock.
+	identity := c.Identity()
+	if identity.Username != "" {
+		fields["account"] = identity.Username
+	}
+	if identity.Guildcard != 0 {
+		fields["guildcard"] = identity.Guildcard
+	}
+	return archon.Log.WithFields(fields)
+}
+
 // Read consumes the available bytes directly the client's TCP connection.
 func (c *Client) Read(b []byte) (int, error) {
 	return c.connection.Read(b)
@@ -67,32 +119,80 @@ func (c *Client) Write(bytes []byte) (int, error) {
 	return c.connection.Write(bytes)
 }
 
-// Close the TCP connection.
-func (c *Client) Close() error {
-	return c.connection.Close()
+// SetReadDeadline sets the time after which pending and future calls to Read
+// will fail with a timeout error. A zero value means Read will not time out.
+func (c *Client) SetReadDeadline(t time.Time) error {
+	return c.connection.SetReadDeadline(t)
+}
+
+// Buffers into which packets implementing packets.Appender are encoded, which are
+// returned to the pool once the packet has been written.
+var packetBuffers = sync.Pool{
+	New: func() interface{} {
+		b := make([]byte, 0, 1024)
+		return &b
+	},
+}
+
+// encode serializes packet, using a buffer from the pool if possible (in which
+// case the buffer is also returned).
+func encode(packet interface{}) ([]byte, *[]byte, error) {
+	appender, ok := packet.(packets.Appender)
+	if !ok {
+		data, _ := bytes.BytesFromStruct(packet)
+		return data, nil, nil
+	}
+
+	buf := packetBuffers.Get().(*[]byte)
+	data, err := appender.AppendBinary((*buf)[:0])
+	if err != nil {
+		releaseBuffer(buf, data)
+		return nil, nil, err
+	}
+	return data, buf, nil
+}
+
+// releaseBuffer returns a buffer from encode to the pool, keeping any capacity
+// gained while data was appended to it.
+func releaseBuffer(buf *[]byte, data []byte) {
+	if buf != nil {
+		*buf = data[:0]
+		packetBuffers.Put(buf)
+	}
 }
 
 // SendRaw writes all data contained in the slice to the client
 // as-is (e.g. without encrypting it first).
 func (c *Client) SendRaw(packet interface{}) error {
-	bytes, size := bytes.BytesFromStruct(packet)
+	data, buf, err := encode(packet)
+	if err != nil {
+		return fmt.Errorf("failed to encode %T: %w", packet, err)
+	}
+	size := len(data)
 
 	if debug.Enabled() {
-		debug.SendServerPacketToAnalyzer(c.DebugTags, bytes, uint16(size))
+		debug.SendServerPacketToAnalyzer(c.DebugTags, data, uint16(size))
 	}
+	c.recordPacket(data, size)
 
-	return c.transmit(bytes, uint16(size))
+	return c.enqueue(outboundPacket{data: data, size: uint16(size), buf: buf})
 }
 
 // transmit writes the contents of data to the TCP connection until the number
 // of bytes written >= length.
 func (c *Client) transmit(data []byte, length uint16) error {
+	if c.WriteTimeout > 0 {
+		if err := c.connection.SetWriteDeadline(time.Now().Add(c.WriteTimeout)); err != nil {
+			return fmt.Errorf("failed to set write deadline for client %v: %s", c.IPAddr(), err.Error())
+		}
+	}
+
 	bytesSent := 0
 
 	for bytesSent < int(length) {
-		b, err := c.Write(data[:length])
+		b, err := c.Write(data[bytesSent:length])
 		if err != nil {
-			return fmt.Errorf("failed to send to client %v: %s", c.IPAddr(), err.Error())
+			return fmt.Errorf("failed to send to client %v: %w", c.IPAddr(), err)
 		}
 		bytesSent += b
 	}
@@ -101,17 +201,31 @@ func (c *Client) transmit(data []byte, length uint16) error {
 }
 
 // Send converts a packet struct to bytes and encrypts it before  using the
-// server's session key before sending the data to the client.
+// server's session key before sending the data to the client. Once the writer
+// has been started the packet is queued rather than written immediately.
 func (c *Client) Send(packet interface{}) error {
-	data, length := bytes.BytesFromStruct(packet)
-	bytes, size := adjustPacketLength(data, uint16(length), c.CryptoSession.HeaderSize())
+	data, buf, err := encode(packet)
+	if err != nil {
+		return fmt.Errorf("failed to encode %T: %w", packet, err)
+	}
+	data, size := adjustPacketLength(data, uint16(len(data)), c.CryptoSession.HeaderSize())
 
 	if debug.Enabled() {
-		debug.SendServerPacketToAnalyzer(c.DebugTags, bytes, size)
+		debug.SendServerPacketToAnalyzer(c.DebugTags, data, size)
 	}
+	c.recordPacket(data, int(size))
 
-	c.CryptoSession.Encrypt(bytes, uint32(size))
-	return c.transmit(bytes, size)
+	return c.enqueue(outboundPacket{data: data, size: size, encrypt: true, buf: buf})
+}
+
+// recordPacket updates the outbound packet metrics with an unencrypted packet.
+func (c *Client) recordPacket(data []byte, size int) {
+	if len(data) < 4 {
+		return
+	}
+	// The packet type is at the same offset in both the PC and BB headers.
+	packetType := uint16(data[2]) | uint16(data[3])<<8
+	metrics.RecordPacket(c.ServerName, metrics.Outbound, packetType, size)
 }
 
 // adjustPacketLength pads the length of a packet to a multiple of the header length and
@@ -0,0 +1,76 @@
+package client
+
+import "github.com/dcrodman/archon/internal/core/data"
+
+// Identity is a snapshot of who a client is logged in as, which is empty until
+// the client has logged in.
+type Identity struct {
+	AccountID uint
+	Username  string
+	Guildcard uint32
+}
+
+// SetAccount records the account the client has logged in as. Only the goroutine
+// handling the client's packets may set it, which can read Account directly.
+func (c *Client) SetAccount(account *data.Account) {
+	c.identityMutex.Lock()
+	c.Account = account
+	changed := c.identityChanged
+	c.identityMutex.Unlock()
+
+	if changed != nil {
+		changed()
+	}
+}
+
+// SetGuildcard records the guildcard the client is playing as. Only the goroutine
+// handling the client's packets may set it, which can read Guildcard directly.
+func (c *Client) SetGuildcard(guildcard uint32) {
+	c.identityMutex.Lock()
+	c.Guildcard = guildcard
+	changed := c.identityChanged
+	c.identityMutex.Unlock()
+
+	if changed != nil {
+		changed()
+	}
+}
+
+// OnIdentityChange sets a function to be called after the client's account or
+// guildcard changes, which keeps the registries of connected clients indexed by
+// them up to date. It's called without the client's lock held, so it may read the
+// new identity with Identity.
+func (c *Client) OnIdentityChange(fn func()) {
+	c.identityMutex.Lock()
+	defer c.identityMutex.Unlock()
+	c.identityChanged = fn
+}
+
+// TeamID returns the ID of the team the client's account belongs to.
+func (c *Client) TeamID() uint32 {
+	c.identityMutex.RLock()
+	defer c.identityMutex.RUnlock()
+	return c.teamID
+}
+
+// SetTeamID records the team the client's account belongs to. Unlike the account
+// and guildcard it may be changed from any goroutine (e.g. by the message bus).
+func (c *Client) SetTeamID(teamID uint32) {
+	c.identityMutex.Lock()
+	defer c.identityMutex.Unlock()
+	c.teamID = teamID
+}
+
+// Identity returns who the client is logged in as, and is safe to call from any
+// goroutine (e.g. the admin API).
+func (c *Client) Identity() Identity {
+	c.identityMutex.RLock()
+	defer c.identityMutex.RUnlock()
+
+	identity := Identity{Guildcard: c.Guildcard}
+	if c.Account != nil {
+		identity.AccountID = c.Account.ID
+		identity.Username = c.Account.Username
+	}
+	return identity
+}
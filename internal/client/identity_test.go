@@ -0,0 +1,56 @@
+package client
+
+import (
+	"testing"
+
+	"github.com/dcrodman/archon/internal/core/data"
+)
+
+func TestClient_Identity(t *testing.T) {
+	account := &data.Account{Username: "player1"}
+	account.ID = 7
+
+	tests := map[string]struct {
+		account   *data.Account
+		guildcard uint32
+		wanted    Identity
+	}{
+		"not_logged_in":      {wanted: Identity{}},
+		"logged_in":          {account: account, wanted: Identity{AccountID: 7, Username: "player1"}},
+		"character_selected": {account: account, guildcard: 42, wanted: Identity{AccountID: 7, Username: "player1", Guildcard: 42}},
+	}
+
+	for name, tt := range tests {
+		t.Run(name, func(t *testing.T) {
+			c := &Client{}
+			if tt.account != nil {
+				c.SetAccount(tt.account)
+			}
+			c.SetGuildcard(tt.guildcard)
+
+			if identity := c.Identity(); identity != tt.wanted {
+				t.Errorf("expected identity = %+v, got = %+v", tt.wanted, identity)
+			}
+		})
+	}
+}
+
+func TestClient_LoggerWhileLoggingIn(t *testing.T) {
+	c := &Client{}
+	done := make(chan struct{})
+	// The writer goroutine logs while the client is still logging in.
+	go func() {
+		defer close(done)
+		for i := 0; i < 100; i++ {
+			c.Logger()
+		}
+	}()
+	c.SetAccount(&data.Account{Username: "player1"})
+	c.SetGuildcard(42)
+	<-done
+
+	fields := c.Logger().Data
+	if fields["account"] != "player1" || fields["guildcard"] != uint32(42) {
+		t.Errorf("expected account and guildcard fields, got = %v", fields)
+	}
+}
@@ -0,0 +1,191 @@
+package client
+
+import (
+	"errors"
+	"fmt"
+	"net"
+	"time"
+
+	"github.com/dcrodman/archon/internal/core/metrics"
+)
+
+// ErrSendQueueFull is returned when a packet can't be sent because the client
+// isn't keeping up with the packets already queued for it.
+var ErrSendQueueFull = errors.New("send queue full")
+
+// Maximum time Close will wait for queued packets to be written before closing
+// the connection anyway.
+const flushTimeout = 5 * time.Second
+
+// OverflowPolicy determines what happens when a packet is sent to a client
+// whose send queue is full.
+type OverflowPolicy int
+
+const (
+	// DisconnectOnOverflow closes the connection to the client.
+	DisconnectOnOverflow OverflowPolicy = iota
+	// DropOnOverflow discards the packet.
+	DropOnOverflow
+)
+
+// ParseOverflowPolicy returns the OverflowPolicy with the given name, which is
+// either "disconnect" or "drop".
+func ParseOverflowPolicy(name string) (OverflowPolicy, error) {
+	switch name {
+	case "disconnect":
+		return DisconnectOnOverflow, nil
+	case "drop":
+		return DropOnOverflow, nil
+	default:
+		return 0, fmt.Errorf("unrecognized send queue overflow policy: %s", name)
+	}
+}
+
+func (p OverflowPolicy) String() string {
+	if p == DropOnOverflow {
+		return "drop"
+	}
+	return "disconnect"
+}
+
+// outboundPacket is a packet waiting to be written to the client.
+type outboundPacket struct {
+	data []byte
+	size uint16
+	// Whether the packet should be encrypted before it's written.
+	encrypt bool
+	// Pooled buffer backing data, if any, which is released once the packet
+	// has been written (or discarded).
+	buf *[]byte
+}
+
+// StartWriter starts the goroutine responsible for writing packets to the client,
+// after which Send and SendRaw queue packets for it instead of writing them to
+// the connection themselves. Since the writer is the only goroutine that encrypts
+// packets once started, packets may be sent from any goroutine without blocking
+// on the client. At most queueSize packets are buffered; a packet sent while the
+// queue is full waits up to WriteTimeout for room before policy applies.
+//
+// StartWriter must be called before the client is shared with other goroutines.
+func (c *Client) StartWriter(queueSize int, policy OverflowPolicy) {
+	c.queue = make(chan outboundPacket, queueSize)
+	c.closing = make(chan struct{})
+	c.writerDone = make(chan struct{})
+	c.overflowPolicy = policy
+	go c.writeLoop()
+}
+
+// writeLoop writes queued packets until the client is closed, at which point
+// any packets still in the queue are flushed.
+func (c *Client) writeLoop() {
+	defer close(c.writerDone)
+
+	for {
+		select {
+		case pkt := <-c.queue:
+			if !c.writeQueued(pkt) {
+				return
+			}
+		case <-c.closing:
+			for {
+				select {
+				case pkt := <-c.queue:
+					if !c.writeQueued(pkt) {
+						return
+					}
+				default:
+					return
+				}
+			}
+		}
+	}
+}
+
+// writeQueued writes a packet taken from the queue and returns whether the
+// writer should keep going.
+func (c *Client) writeQueued(pkt outboundPacket) bool {
+	if err := c.write(pkt); err != nil {
+		if !errors.Is(err, net.ErrClosed) {
+			c.Logger().Warn(err.Error())
+		}
+		// Nothing else can be sent, so close the connection in order for the
+		// reader to notice and stop accepting any more packets.
+		_ = c.connection.Close()
+		c.closeOnce.Do(func() { close(c.closing) })
+		return false
+	}
+	return true
+}
+
+// enqueue sends the packet to the writer goroutine (if it's been started) or
+// writes it to the client directly.
+func (c *Client) enqueue(pkt outboundPacket) error {
+	if c.queue == nil {
+		c.sendMutex.Lock()
+		defer c.sendMutex.Unlock()
+		return c.write(pkt)
+	}
+
+	closedErr := fmt.Errorf("failed to send to client %v: %w", c.IPAddr(), net.ErrClosed)
+	select {
+	case <-c.closing:
+		releaseBuffer(pkt.buf, pkt.data)
+		return closedErr
+	default:
+	}
+
+	select {
+	case c.queue <- pkt:
+		return nil
+	default:
+	}
+
+	// The queue is full; give the client as long to catch up as it would have
+	// had to receive the packet if it were written directly.
+	var timeout <-chan time.Time
+	if c.WriteTimeout > 0 {
+		timer := time.NewTimer(c.WriteTimeout)
+		defer timer.Stop()
+		timeout = timer.C
+	}
+	select {
+	case c.queue <- pkt:
+		return nil
+	case <-c.closing:
+		releaseBuffer(pkt.buf, pkt.data)
+		return closedErr
+	case <-timeout:
+	}
+	releaseBuffer(pkt.buf, pkt.data)
+
+	metrics.SendQueueOverflows.WithLabelValues(c.ServerName, c.overflowPolicy.String()).Inc()
+	if c.overflowPolicy == DisconnectOnOverflow {
+		c.Logger().Warnf("disconnecting client %v: %v", c.IPAddr(), ErrSendQueueFull)
+		// Don't bother flushing the queue since the client isn't reading it.
+		_ = c.connection.Close()
+	}
+	return fmt.Errorf("failed to send to client %v: %w", c.IPAddr(), ErrSendQueueFull)
+}
+
+// write encrypts the packet (if necessary) and writes it to the connection.
+func (c *Client) write(pkt outboundPacket) error {
+	defer releaseBuffer(pkt.buf, pkt.data)
+
+	if pkt.encrypt {
+		c.CryptoSession.Encrypt(pkt.data, uint32(pkt.size))
+	}
+	return c.transmit(pkt.data, pkt.size)
+}
+
+// Close the TCP connection once any packets queued for the client have been
+// written (or flushTimeout has elapsed).
+func (c *Client) Close() error {
+	if c.writerDone != nil {
+		c.closeOnce.Do(func() { close(c.closing) })
+		select {
+		case <-c.writerDone:
+		case <-time.After(flushTimeout):
+		}
+	}
+	return c.connection.Close()
+}
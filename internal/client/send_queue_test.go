@@ -0,0 +1,110 @@
+package client
+
+import (
+	"errors"
+	"io"
+	"io/ioutil"
+	"net"
+	"testing"
+	"time"
+
+	"github.com/sirupsen/logrus"
+
+	"github.com/dcrodman/archon"
+	"github.com/dcrodman/archon/internal/packets"
+)
+
+func TestMain(m *testing.M) {
+	archon.Log = logrus.New()
+	archon.Log.Out = ioutil.Discard
+	m.Run()
+}
+
+func TestClient_CloseFlushesQueue(t *testing.T) {
+	remote, conn := net.Pipe()
+	defer remote.Close()
+
+	received := make(chan []byte)
+	go func() {
+		data, _ := ioutil.ReadAll(remote)
+		received <- data
+	}()
+
+	// More packets are sent than fit in the queue, which should only hold up the
+	// sender while the client catches up.
+	c := NewClient(conn)
+	c.WriteTimeout = time.Second
+	c.StartWriter(2, DisconnectOnOverflow)
+
+	const numPackets = 16
+	for i := 0; i < numPackets; i++ {
+		if err := c.SendRaw(&packets.PCHeader{Size: 4, Type: uint16(i)}); err != nil {
+			t.Fatalf("unexpected error sending packet: %v", err)
+		}
+	}
+	if err := c.Close(); err != nil {
+		t.Fatalf("unexpected error closing client: %v", err)
+	}
+
+	data := <-received
+	if len(data) != numPackets*4 {
+		t.Fatalf("expected %d bytes to be flushed, got %d", numPackets*4, len(data))
+	}
+	for i := 0; i < numPackets; i++ {
+		if packetType := data[i*4+2]; packetType != byte(i) {
+			t.Errorf("expected packet %d to have type %d, got %d", i, i, packetType)
+		}
+	}
+
+	if err := c.SendRaw(&packets.PCHeader{Size: 4}); !errors.Is(err, net.ErrClosed) {
+		t.Errorf("expected net.ErrClosed sending after close, got %v", err)
+	}
+}
+
+// noDeadlineConn is a connection whose writes never time out.
+type noDeadlineConn struct {
+	net.Conn
+}
+
+func (c noDeadlineConn) SetWriteDeadline(t time.Time) error { return nil }
+
+func TestClient_SendQueueOverflow(t *testing.T) {
+	tests := map[string]struct {
+		policy OverflowPolicy
+		// Whether the connection should be closed as a result of the overflow.
+		wantClosed bool
+	}{
+		"drop":       {policy: DropOnOverflow},
+		"disconnect": {policy: DisconnectOnOverflow, wantClosed: true},
+	}
+
+	for name, tt := range tests {
+		t.Run(name, func(t *testing.T) {
+			remote, conn := net.Pipe()
+			defer remote.Close()
+
+			c := NewClient(noDeadlineConn{conn})
+			c.WriteTimeout = 50 * time.Millisecond
+			c.StartWriter(1, tt.policy)
+			defer c.Close()
+
+			// Nothing is reading from the pipe, so the writer blocks on the first
+			// packet and the queue fills up with the next.
+			var err error
+			for i := 0; i < 3 && err == nil; i++ {
+				err = c.SendRaw(&packets.PCHeader{Size: 4, Type: uint16(i)})
+			}
+			if !errors.Is(err, ErrSendQueueFull) {
+				t.Fatalf("expected ErrSendQueueFull, got %v", err)
+			}
+
+			_ = remote.SetReadDeadline(time.Now().Add(time.Second))
+			_, err = remote.Read(make([]byte, 4))
+			if closed := err == io.EOF; closed != tt.wantClosed {
+				t.Errorf("expected connection closed = %v, got read error %v", tt.wantClosed, err)
+			}
+			// Let the remaining packets be flushed on close.
+			go func() { _, _ = io.Copy(ioutil.Discard, remote) }()
+		})
+	}
+}
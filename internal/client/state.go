@@ -0,0 +1,83 @@
+package client
+
+import (
+	"errors"
+	"fmt"
+)
+
+// ErrInvalidStateTransition is returned when a client is moved to a state that
+// can't follow its current one.
+var ErrInvalidStateTransition = errors.New("invalid state transition")
+
+// State identifies how far a client has progressed through its session with a
+// server, which determines the packets the server will accept from it. Since
+// the client reconnects when moving between servers, each connection starts
+// over from Connected.
+type State int
+
+const (
+	// Connected is the initial state of a client that has yet to be sent the
+	// server's welcome packet.
+	Connected State = iota
+	// Handshaken clients have been sent the welcome packet but haven't logged in.
+	Handshaken
+	// Authenticated clients have logged in with a valid account.
+	Authenticated
+	// CharacterSelected clients have chosen the character they'll be playing.
+	CharacterSelected
+	// InLobby clients have joined one of a block's lobbies.
+	InLobby
+	// InGame clients have joined a game (party) within a block.
+	InGame
+)
+
+// Transitions allowed from each state.
+var stateTransitions = map[State][]State{
+	Connected:         {Handshaken},
+	Handshaken:        {Authenticated},
+	Authenticated:     {CharacterSelected},
+	CharacterSelected: {InLobby},
+	InLobby:           {InGame},
+	InGame:            {InLobby},
+}
+
+func (s State) String() string {
+	switch s {
+	case Connected:
+		return "connected"
+	case Handshaken:
+		return "handshaken"
+	case Authenticated:
+		return "authenticated"
+	case CharacterSelected:
+		return "character-selected"
+	case InLobby:
+		return "in-lobby"
+	case InGame:
+		return "in-game"
+	default:
+		return fmt.Sprintf("State(%d)", int(s))
+	}
+}
+
+// State returns the client's current state.
+func (c *Client) State() State {
+	c.stateMutex.RLock()
+	defer c.stateMutex.RUnlock()
+	return c.state
+}
+
+// Transition moves the client to the next state in its session, returning an
+// error wrapping ErrInvalidStateTransition if it can't follow the current one.
+func (c *Client) Transition(to State) error {
+	c.stateMutex.Lock()
+	defer c.stateMutex.Unlock()
+
+	for _, allowed := range stateTransitions[c.state] {
+		if to == allowed {
+			c.state = to
+			return nil
+		}
+	}
+	return fmt.Errorf("%w: %s to %s", ErrInvalidStateTransition, c.state, to)
+}
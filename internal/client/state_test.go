@@ -0,0 +1,53 @@
+package client
+
+import (
+	"errors"
+	"testing"
+)
+
+func TestClient_Transition(t *testing.T) {
+	tests := map[string]struct {
+		// States through which the client is moved before the tested transition.
+		from    []State
+		to      State
+		wantErr bool
+	}{
+		"handshake":            {to: Handshaken},
+		"login":                {from: []State{Handshaken}, to: Authenticated},
+		"select_character":     {from: []State{Handshaken, Authenticated}, to: CharacterSelected},
+		"join_lobby":           {from: []State{Handshaken, Authenticated, CharacterSelected}, to: InLobby},
+		"join_game":            {from: []State{Handshaken, Authenticated, CharacterSelected, InLobby}, to: InGame},
+		"leave_game":           {from: []State{Handshaken, Authenticated, CharacterSelected, InLobby, InGame}, to: InLobby},
+		"after_selection":      {from: []State{Handshaken, Authenticated, CharacterSelected}, to: Authenticated, wantErr: true},
+		"game_before_lobby":    {from: []State{Handshaken, Authenticated, CharacterSelected}, to: InGame, wantErr: true},
+		"login_before_welcome": {to: Authenticated, wantErr: true},
+		"skip_login":           {from: []State{Handshaken}, to: CharacterSelected, wantErr: true},
+		"repeat_login":         {from: []State{Handshaken, Authenticated}, to: Authenticated, wantErr: true},
+		"back_to_connected":    {from: []State{Handshaken}, to: Connected, wantErr: true},
+	}
+
+	for name, tt := range tests {
+		t.Run(name, func(t *testing.T) {
+			c := &Client{}
+			for _, state := range tt.from {
+				if err := c.Transition(state); err != nil {
+					t.Fatalf("unexpected error moving client to %s: %v", state, err)
+				}
+			}
+
+			err := c.Transition(tt.to)
+			if tt.wantErr {
+				if !errors.Is(err, ErrInvalidStateTransition) {
+					t.Fatalf("expected ErrInvalidStateTransition, got %v", err)
+				}
+				if len(tt.from) > 0 && c.State() != tt.from[len(tt.from)-1] {
+					t.Errorf("expected state to remain %s, got %s", tt.from[len(tt.from)-1], c.State())
+				}
+			} else if err != nil {
+				t.Fatalf("unexpected error: %v", err)
+			} else if c.State() != tt.to {
+				t.Errorf("expected state = %s, got = %s", tt.to, c.State())
+			}
+		})
+	}
+}
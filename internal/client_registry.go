@@ -0,0 +1,223 @@
+package internal
+
+import (
+	"errors"
+	"sync"
+
+	"github.com/dcrodman/archon"
+	"github.com/dcrodman/archon/internal/client"
+)
+
+// Archon uses a shared registry of clients across all servers in order to prevent
+// clients from connecting to any part of the server and causing problems.
+var globalClients = newClientRegistry()
+
+var errTooManyConnections = errors.New("too many connections from IP address")
+
+// Limits on the number of connected clients, which are shared by every Frontend.
+var connectionLimits struct {
+	sync.RWMutex
+	max   int
+	perIP int
+}
+
+func setConnectionLimits(cfg *archon.Config) {
+	connectionLimits.Lock()
+	defer connectionLimits.Unlock()
+	connectionLimits.max = cfg.MaxConnections
+	connectionLimits.perIP = cfg.MaxConnectionsPerIP
+}
+
+func isServerFull() bool {
+	connectionLimits.RLock()
+	defer connectionLimits.RUnlock()
+	return globalClients.len() >= connectionLimits.max
+}
+
+// maxConnectionsPerIP returns the maximum number of clients that may connect from
+// the same IP address, or 0 for no limit.
+func maxConnectionsPerIP() int {
+	connectionLimits.RLock()
+	defer connectionLimits.RUnlock()
+	return connectionLimits.perIP
+}
+
+// FindClient returns the client connected to any Frontend with the connection
+// ID id, or nil if there isn't one.
+func FindClient(id string) *client.Client {
+	return globalClients.get(id)
+}
+
+// ClientsWithAccount returns the clients connected to any Frontend that are logged
+// in as the account with ID accountID.
+func ClientsWithAccount(accountID uint) []*client.Client {
+	return globalClients.withAccount(accountID)
+}
+
+// ClientsWithGuildcard returns the clients connected to any Frontend that are
+// playing as guildcard.
+func ClientsWithGuildcard(guildcard uint32) []*client.Client {
+	return globalClients.withGuildcard(guildcard)
+}
+
+// The keys under which a client was last indexed, which are tracked so that its
+// entries can be cleaned up once the values on the client change.
+type indexKeys struct {
+	ip        string
+	accountID uint
+	guildcard uint32
+}
+
+// clientRegistry is a concurrency-safe collection of connected clients keyed by
+// their unique connection ID (Client.SessionID), with secondary indexes by IP
+// address, account, and guildcard.
+type clientRegistry struct {
+	sync.RWMutex
+
+	clients     map[string]*client.Client
+	keys        map[string]indexKeys
+	byIP        clientIndex
+	byAccount   clientIndex
+	byGuildcard clientIndex
+}
+
+func newClientRegistry() *clientRegistry {
+	return &clientRegistry{
+		clients:     make(map[string]*client.Client),
+		keys:        make(map[string]indexKeys),
+		byIP:        make(clientIndex),
+		byAccount:   make(clientIndex),
+		byGuildcard: make(clientIndex),
+	}
+}
+
+// add registers c, failing if doing so would exceed maxPerIP clients from the
+// same IP address (0 allows any number).
+func (r *clientRegistry) add(c *client.Client, maxPerIP int) error {
+	r.Lock()
+	defer r.Unlock()
+
+	if maxPerIP > 0 && len(r.byIP[c.IPAddr()]) >= maxPerIP {
+		return errTooManyConnections
+	}
+	r.clients[c.SessionID] = c
+	r.index(c)
+	return nil
+}
+
+// update re-indexes c, which is called whenever its account or guildcard changes
+// (see client.Client.OnIdentityChange).
+func (r *clientRegistry) update(c *client.Client) {
+	r.Lock()
+	defer r.Unlock()
+
+	if r.clients[c.SessionID] != c {
+		return
+	}
+	r.unindex(c.SessionID)
+	r.index(c)
+}
+
+func (r *clientRegistry) remove(c *client.Client) {
+	r.Lock()
+	defer r.Unlock()
+
+	if _, ok := r.clients[c.SessionID]; !ok {
+		return
+	}
+	r.unindex(c.SessionID)
+	delete(r.clients, c.SessionID)
+}
+
+// index adds c to the secondary indexes. Must be called with the lock held.
+func (r *clientRegistry) index(c *client.Client) {
+	identity := c.Identity()
+	keys := indexKeys{ip: c.IPAddr(), accountID: identity.AccountID, guildcard: identity.Guildcard}
+
+	r.byIP.add(keys.ip, c)
+	if keys.accountID != 0 {
+		r.byAccount.add(keys.accountID, c)
+	}
+	if keys.guildcard != 0 {
+		r.byGuildcard.add(keys.guildcard, c)
+	}
+	r.keys[c.SessionID] = keys
+}
+
+// unindex removes the client with the connection ID id from the secondary
+// indexes. Must be called with the lock held.
+func (r *clientRegistry) unindex(id string) {
+	keys := r.keys[id]
+
+	r.byIP.remove(keys.ip, id)
+	r.byAccount.remove(keys.accountID, id)
+	r.byGuildcard.remove(keys.guildcard, id)
+	delete(r.keys, id)
+}
+
+func (r *clientRegistry) get(id string) *client.Client {
+	r.RLock()
+	defer r.RUnlock()
+	return r.clients[id]
+}
+
+func (r *clientRegistry) withAccount(accountID uint) []*client.Client {
+	r.RLock()
+	defer r.RUnlock()
+	return r.byAccount.get(accountID)
+}
+
+func (r *clientRegistry) withGuildcard(guildcard uint32) []*client.Client {
+	r.RLock()
+	defer r.RUnlock()
+	return r.byGuildcard.get(guildcard)
+}
+
+// all returns a snapshot of the clients in the registry.
+func (r *clientRegistry) all() []*client.Client {
+	r.RLock()
+	defer r.RUnlock()
+	return clientsIn(r.clients)
+}
+
+func (r *clientRegistry) len() int {
+	r.RLock()
+	defer r.RUnlock()
+	return len(r.clients)
+}
+
+// clientIndex maps the value of a client attribute to the clients with that
+// value, keyed by their connection ID.
+type clientIndex map[interface{}]map[string]*client.Client
+
+func (idx clientIndex) add(key interface{}, c *client.Client) {
+	clients, ok := idx[key]
+	if !ok {
+		clients = make(map[string]*client.Client)
+		idx[key] = clients
+	}
+	clients[c.SessionID] = c
+}
+
+func (idx clientIndex) remove(key interface{}, id string) {
+	clients, ok := idx[key]
+	if !ok {
+		return
+	}
+	delete(clients, id)
+	if len(clients) == 0 {
+		delete(idx, key)
+	}
+}
+
+func (idx clientIndex) get(key interface{}) []*client.Client {
+	return clientsIn(idx[key])
+}
+
+func clientsIn(clients map[string]*client.Client) []*client.Client {
+	result := make([]*client.Client, 0, len(clients))
+	for _, c := range clients {
+		result = append(result, c)
+	}
+	return result
+}
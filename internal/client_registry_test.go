@@ -0,0 +1,130 @@
+package internal
+
+import (
+	"net"
+	"testing"
+
+	"github.com/dcrodman/archon/internal/client"
+	"github.com/dcrodman/archon/internal/core/data"
+)
+
+// testConn is a net.Conn connected from an arbitrary remote address.
+type testConn struct {
+	net.Conn
+	remoteAddr net.Addr
+}
+
+func (c *testConn) RemoteAddr() net.Addr { return c.remoteAddr }
+
+func newTestClient(t *testing.T, id, addr string) *client.Client {
+	tcpAddr, err := net.ResolveTCPAddr("tcp", addr)
+	if err != nil {
+		t.Fatalf("invalid address %s: %v", addr, err)
+	}
+	c := client.NewClient(&testConn{remoteAddr: tcpAddr})
+	c.SessionID = id
+	return c
+}
+
+func TestClientRegistry_Add(t *testing.T) {
+	tests := map[string]struct {
+		maxPerIP  int
+		addrs     []string
+		wantedErr []error
+	}{
+		"unlimited": {
+			maxPerIP:  0,
+			addrs:     []string{"10.0.0.1:1000", "10.0.0.1:1001", "10.0.0.1:1002"},
+			wantedErr: []error{nil, nil, nil},
+		},
+		"limited_same_ip": {
+			maxPerIP:  2,
+			addrs:     []string{"10.0.0.1:1000", "10.0.0.1:1001", "10.0.0.1:1002"},
+			wantedErr: []error{nil, nil, errTooManyConnections},
+		},
+		"limited_different_ips": {
+			maxPerIP:  1,
+			addrs:     []string{"10.0.0.1:1000", "10.0.0.2:1000", "[::1]:1000"},
+			wantedErr: []error{nil, nil, nil},
+		},
+	}
+
+	for name, tt := range tests {
+		t.Run(name, func(t *testing.T) {
+			r := newClientRegistry()
+			for i, addr := range tt.addrs {
+				c := newTestClient(t, addr, addr)
+				if err := r.add(c, tt.maxPerIP); err != tt.wantedErr[i] {
+					t.Errorf("add(%s): expected err = %v, got = %v", addr, tt.wantedErr[i], err)
+				}
+			}
+		})
+	}
+}
+
+func TestClientRegistry_Remove(t *testing.T) {
+	r := newClientRegistry()
+	first := newTestClient(t, "first", "10.0.0.1:1000")
+	second := newTestClient(t, "second", "10.0.0.1:1001")
+	_ = r.add(first, 0)
+	_ = r.add(second, 0)
+
+	r.remove(second)
+
+	if r.get("first") != first {
+		t.Error("expected first client to remain registered")
+	}
+	if r.get("second") != nil {
+		t.Error("expected second client to be removed")
+	}
+	if clients := r.byIP.get("10.0.0.1"); len(clients) != 1 || clients[0] != first {
+		t.Errorf("expected only first client to be indexed by IP, got %v", clients)
+	}
+	if r.len() != 1 {
+		t.Errorf("expected len = 1, got = %d", r.len())
+	}
+
+	// Removing a client twice is a no-op.
+	r.remove(second)
+	if r.len() != 1 {
+		t.Errorf("expected len = 1, got = %d", r.len())
+	}
+}
+
+func TestClientRegistry_Update(t *testing.T) {
+	r := newClientRegistry()
+	c := newTestClient(t, "id", "10.0.0.1:1000")
+	_ = r.add(c, 0)
+	c.OnIdentityChange(func() { r.update(c) })
+
+	if clients := r.withAccount(1); len(clients) != 0 {
+		t.Fatalf("expected no clients indexed by account, got %d", len(clients))
+	}
+
+	account := &data.Account{}
+	account.ID = 1
+	c.SetAccount(account)
+	c.SetGuildcard(42)
+
+	if clients := r.withAccount(1); len(clients) != 1 || clients[0] != c {
+		t.Errorf("expected client to be indexed by account, got %v", clients)
+	}
+	if clients := r.withGuildcard(42); len(clients) != 1 || clients[0] != c {
+		t.Errorf("expected client to be indexed by guildcard, got %v", clients)
+	}
+
+	c.SetGuildcard(43)
+	if clients := r.withGuildcard(42); len(clients) != 0 {
+		t.Errorf("expected stale guildcard index entry to be removed, got %v", clients)
+	}
+
+	r.remove(c)
+	if clients := r.withAccount(1); len(clients) != 0 {
+		t.Errorf("expected account index entry to be removed, got %v", clients)
+	}
+	// Changes after the client is removed don't index it again.
+	c.SetGuildcard(44)
+	if clients := r.withGuildcard(44); len(clients) != 0 {
+		t.Errorf("expected removed client not to be indexed, got %v", clients)
+	}
+}
@@ -13,6 +13,7 @@ var (
 	ErrUnknown            = errors.New("an unexpected error occurred, please contact your server administrator")
 	ErrInvalidCredentials = errors.New("username/combination password not found")
 	ErrAccountBanned      = errors.New("this account has been suspended")
+	ErrAccountInactive    = errors.New("this account has not been activated")
 )
 
 // VerifyAccount checks the Accounts table for the specified credentials
@@ -25,24 +26,54 @@ func VerifyAccount(username, password string) (*data.Account, error) {
 
 	if account == nil || account.Password != HashPassword(password) {
 		return nil, ErrInvalidCredentials
-	} else if account.Banned {
-		return nil, ErrAccountBanned
+	}
+	if err := checkAccountAccess(account); err != nil {
+		return nil, err
+	}
+
+	return account, nil
+}
+
+// LookupAccount returns the account with username, provided that it's accessible.
+// Used for accounts that have already been authenticated by other means.
+func LookupAccount(username string) (*data.Account, error) {
+	account, err := findAccount(username)
+	if err != nil {
+		return nil, ErrUnknown
+	}
+
+	if account == nil {
+		return nil, ErrInvalidCredentials
+	}
+	if err := checkAccountAccess(account); err != nil {
+		return nil, err
 	}
 
 	return account, nil
 }
 
+func checkAccountAccess(account *data.Account) error {
+	if account.Banned {
+		return ErrAccountBanned
+	} else if !account.Active {
+		return ErrAccountInactive
+	}
+	return nil
+}
+
 var findAccount = func(username string) (*data.Account, error) {
 	return data.FindAccount(username)
 }
 
 // CreateAccount takes the specified credentials and creates a new record in
 // the database, returning either the expected or any errors encountered.
-func CreateAccount(username, password, email string) (*data.Account, error) {
+// Inactive accounts can't log in until they're activated.
+func CreateAccount(username, password, email string, active bool) (*data.Account, error) {
 	account := &data.Account{
 		Username: username,
 		Password: HashPassword(password),
 		Email:    email,
+		Active:   active,
 	}
 
 	if err := createAccount(account); err != nil {
@@ -56,6 +87,22 @@ var createAccount = func(account *data.Account) error {
 	return data.CreateAccount(account)
 }
 
+// ChangePassword verifies the account's current credentials and replaces
+// the password with newPassword.
+func ChangePassword(username, password, newPassword string) error {
+	account, err := VerifyAccount(username, password)
+	if err != nil {
+		return err
+	}
+
+	account.Password = HashPassword(newPassword)
+	return updateAccount(account)
+}
+
+var updateAccount = func(account *data.Account) error {
+	return data.UpdateAccount(account)
+}
+
 // DeleteAccount takes the specified credentials and soft-deletes a record in
 // the database, returning any errors encountered.
 func DeleteAccount(username string) error {
@@ -38,7 +38,7 @@ func TestCreateAccount(t *testing.T) {
 			}()
 			createAccount = tt.dbCreateFn
 
-			account, err := CreateAccount(tt.args.username, tt.args.password, tt.args.email)
+			account, err := CreateAccount(tt.args.username, tt.args.password, tt.args.email, true)
 			if err != nil && err.Error() != tt.wantedErr.Error() {
 				t.Fatalf("expected error to = %s, got = %s", tt.wantedErr, err)
 			}
@@ -53,6 +53,9 @@ func TestCreateAccount(t *testing.T) {
 				if account.Email != tt.args.email {
 					t.Errorf("expected account emmail = %s, got = %s", tt.args.email, account.Email)
 				}
+				if !account.Active {
+					t.Error("expected account to be active")
+				}
 			}
 		})
 	}
@@ -87,7 +90,7 @@ func TestVerifyAccount(t *testing.T) {
 		err     error
 	}
 
-	happyPathAccount := &data.Account{Username: "test", Password: HashPassword("test")}
+	happyPathAccount := &data.Account{Username: "test", Password: HashPassword("test"), Active: true}
 
 	tests := map[string]struct {
 		context context
@@ -114,6 +117,11 @@ func TestVerifyAccount(t *testing.T) {
 			args{username: "test", password: "test"},
 			expected{account: nil, err: ErrAccountBanned},
 		},
+		"inactive": {
+			context{account: &data.Account{Username: "test", Password: HashPassword("test")}, err: nil},
+			args{username: "test", password: "test"},
+			expected{account: nil, err: ErrAccountInactive},
+		},
 		"happy": {
 			context{account: happyPathAccount, err: nil},
 			args{username: "test", password: "test"},
@@ -140,6 +148,88 @@ func TestVerifyAccount(t *testing.T) {
 	}
 }
 
+func TestLookupAccount(t *testing.T) {
+	tests := map[string]struct {
+		account   *data.Account
+		dbErr     error
+		wantedErr error
+	}{
+		"database_error": {dbErr: fmt.Errorf("something exploded"), wantedErr: ErrUnknown},
+		"no_account":     {wantedErr: ErrInvalidCredentials},
+		"banned":         {account: &data.Account{Username: "test", Banned: true}, wantedErr: ErrAccountBanned},
+		"inactive":       {account: &data.Account{Username: "test"}, wantedErr: ErrAccountInactive},
+		"happy":          {account: &data.Account{Username: "test", Active: true}},
+	}
+
+	for name, tt := range tests {
+		t.Run(name, func(t *testing.T) {
+			originalFindAccount := findAccount
+			defer func() { findAccount = originalFindAccount }()
+			findAccount = func(username string) (*data.Account, error) {
+				return tt.account, tt.dbErr
+			}
+
+			account, err := LookupAccount("test")
+			if err != tt.wantedErr {
+				t.Errorf("expected wantedErr = %v, got = %v", tt.wantedErr, err)
+			}
+			if err == nil && account != tt.account {
+				t.Errorf("expected account = %v, got = %v", tt.account, account)
+			}
+		})
+	}
+}
+
+func TestChangePassword(t *testing.T) {
+	type args struct {
+		password    string
+		newPassword string
+	}
+	tests := map[string]struct {
+		dbUpdateFn func(account *data.Account) error
+		args       args
+		wantedErr  error
+	}{
+		"invalid_password": {
+			dbUpdateFn: func(account *data.Account) error { return nil },
+			args:       args{password: "wrong", newPassword: "new"},
+			wantedErr:  ErrInvalidCredentials,
+		},
+		"database_error": {
+			dbUpdateFn: func(account *data.Account) error { return fmt.Errorf("database error") },
+			args:       args{password: "test", newPassword: "new"},
+			wantedErr:  fmt.Errorf("database error"),
+		},
+		"happy_path": {
+			dbUpdateFn: func(account *data.Account) error { return nil },
+			args:       args{password: "test", newPassword: "new"},
+			wantedErr:  nil,
+		},
+	}
+
+	for name, tt := range tests {
+		t.Run(name, func(t *testing.T) {
+			originalFindAccount, originalUpdateAccount := findAccount, updateAccount
+			defer func() {
+				findAccount, updateAccount = originalFindAccount, originalUpdateAccount
+			}()
+
+			account := &data.Account{Username: "test", Password: HashPassword("test"), Active: true}
+			findAccount = func(username string) (*data.Account, error) { return account, nil }
+			updateAccount = tt.dbUpdateFn
+
+			err := ChangePassword("test", tt.args.password, tt.args.newPassword)
+			if (err == nil) != (tt.wantedErr == nil) || (err != nil && err.Error() != tt.wantedErr.Error()) {
+				t.Fatalf("expected error to = %v, got = %v", tt.wantedErr, err)
+			}
+
+			if err == nil && account.Password != HashPassword(tt.args.newPassword) {
+				t.Error("expected account password to equal hashed new password")
+			}
+		})
+	}
+}
+
 func TestSoftDeleteAccount(t *testing.T) {
 	type args struct {
 		username string
@@ -3,6 +3,9 @@ package bytes
 import (
 	"bytes"
 	"encoding/binary"
+	"errors"
+	"fmt"
+	"io"
 	"reflect"
 	"unicode/utf16"
 )
@@ -25,6 +28,20 @@ func ConvertToUtf16(str string) []byte {
 	return ExpandUtf16(utf16.Encode(strRunes))
 }
 
+// ConvertFromUtf16 converts UTF-16 LE text up to the first NUL character (or the
+// end of b) to a UTF-8 string. It is the inverse of ConvertToUtf16.
+func ConvertFromUtf16(b []byte) string {
+	chars := make([]uint16, 0, len(b)/2)
+	for i := 0; i+1 < len(b); i += 2 {
+		char := uint16(b[i]) | uint16(b[i+1])<<8
+		if char == 0 {
+			break
+		}
+		chars = append(chars, char)
+	}
+	return string(utf16.Decode(chars))
+}
+
 // StripPadding returns a slice of b without the trailing 0s.
 func StripPadding(b []byte) []byte {
 	for i := len(b) - 1; i >= 0; i-- {
@@ -73,9 +90,13 @@ func BytesFromStruct(data interface{}) ([]byte, int) {
 	return convertedBytes.Bytes(), convertedBytes.Len()
 }
 
+// ErrNotEnoughData is returned when decoding a struct from fewer bytes than it requires.
+var ErrNotEnoughData = errors.New("not enough data")
+
 // StructFromBytes populates the struct pointed to by targetStruct by reading in a
-// stream of bytes and filling the values in sequential order.
-func StructFromBytes(data []byte, targetStruct interface{}) {
+// stream of bytes and filling the values in sequential order. Returns an error
+// wrapping ErrNotEnoughData if data is too short to fill every field.
+func StructFromBytes(data []byte, targetStruct interface{}) error {
 	targetVal := reflect.ValueOf(targetStruct)
 
 	if valKind := targetVal.Kind(); valKind != reflect.Ptr {
@@ -96,10 +117,13 @@ func StructFromBytes(data []byte, targetStruct interface{}) {
 		default:
 			err = binary.Read(reader, binary.LittleEndian, field.Addr().Interface())
 		}
-		if err != nil {
-			panic(err.Error())
+		if err == io.EOF || err == io.ErrUnexpectedEOF {
+			return fmt.Errorf("%w to decode %T from %d bytes", ErrNotEnoughData, targetStruct, len(data))
+		} else if err != nil {
+			return fmt.Errorf("failed to decode %T: %v", targetStruct, err)
 		}
 	}
+	return nil
 }
 
 //const displayWidth = 16
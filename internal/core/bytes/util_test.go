@@ -0,0 +1,71 @@
+package bytes
+
+import (
+	"errors"
+	"testing"
+)
+
+type testStruct struct {
+	A uint16
+	B [4]byte
+	C uint32
+}
+
+func TestStructFromBytes(t *testing.T) {
+	tests := map[string]struct {
+		data    []byte
+		want    testStruct
+		wantErr error
+	}{
+		"exact_length": {
+			data: []byte{0x01, 0x00, 'a', 'b', 'c', 'd', 0x02, 0x00, 0x00, 0x00},
+			want: testStruct{A: 1, B: [4]byte{'a', 'b', 'c', 'd'}, C: 2},
+		},
+		"trailing_data": {
+			data: []byte{0x01, 0x00, 'a', 'b', 'c', 'd', 0x02, 0x00, 0x00, 0x00, 0xFF},
+			want: testStruct{A: 1, B: [4]byte{'a', 'b', 'c', 'd'}, C: 2},
+		},
+		"truncated_field": {
+			data:    []byte{0x01, 0x00, 'a', 'b', 'c', 'd', 0x02},
+			wantErr: ErrNotEnoughData,
+		},
+		"empty": {
+			data:    []byte{},
+			wantErr: ErrNotEnoughData,
+		},
+	}
+
+	for name, tt := range tests {
+		t.Run(name, func(t *testing.T) {
+			var got testStruct
+			err := StructFromBytes(tt.data, &got)
+			if !errors.Is(err, tt.wantErr) {
+				t.Fatalf("expected err = %v, got = %v", tt.wantErr, err)
+			}
+			if tt.wantErr == nil && got != tt.want {
+				t.Errorf("expected %+v, got %+v", tt.want, got)
+			}
+		})
+	}
+}
+
+func TestConvertFromUtf16(t *testing.T) {
+	tests := map[string]struct {
+		data []byte
+		want string
+	}{
+		"round_trip":     {data: ConvertToUtf16("Hello, 世界"), want: "Hello, 世界"},
+		"surrogate_pair": {data: ConvertToUtf16("😀"), want: "😀"},
+		"padded":         {data: append(ConvertToUtf16("Hi"), 0, 0, 'x', 0), want: "Hi"},
+		"odd_length":     {data: append(ConvertToUtf16("Hi"), 'x'), want: "Hi"},
+		"empty":          {data: nil, want: ""},
+	}
+
+	for name, tt := range tests {
+		t.Run(name, func(t *testing.T) {
+			if got := ConvertFromUtf16(tt.data); got != tt.want {
+				t.Errorf("expected %q, got %q", tt.want, got)
+			}
+		})
+	}
+}
@@ -18,7 +18,7 @@ type Account struct {
 	Guildcard        int  `gorm:"AUTO_INCREMENT"`
 	GM               bool `gorm:"default:false"`
 	Banned           bool `gorm:"default:false"`
-	Active           bool `gorm:"default:true"`
+	Active           bool `gorm:"default:false"`
 	TeamID           int
 	PrivilegeLevel   byte
 }
@@ -56,11 +56,33 @@ func FindUnscopedAccount(username string) (*Account, error) {
 	return &account, nil
 }
 
+// FindUnscopedAccountByEmail searches for a potentially soft-deleted account with
+// the specified email address, returning the *Account instance if found or nil if
+// there is no match.
+func FindUnscopedAccountByEmail(email string) (*Account, error) {
+	var account Account
+	err := db.Unscoped().Where("email = ?", email).First(&account).Error
+
+	if err != nil {
+		if errors.Is(err, gorm.ErrRecordNotFound) {
+			return nil, nil
+		}
+		return nil, err
+	}
+
+	return &account, nil
+}
+
 // CreateAccount persists the Account record to the database.
 func CreateAccount(account *Account) error {
 	return db.Create(account).Error
 }
 
+// UpdateAccount saves all of the fields of an existing Account record.
+func UpdateAccount(account *Account) error {
+	return db.Save(account).Error
+}
+
 // DeleteAccount soft-deletes an Account record from the database.
 func DeleteAccount(account *Account) error {
 	return db.Delete(account).Error
@@ -25,7 +25,7 @@ func Initialize(dataSource string, debug bool) error {
 		return fmt.Errorf("failed to connect to database: %s", err)
 	}
 
-	err = db.AutoMigrate(&Account{}, &PlayerOptions{}, &Character{}, &GuildcardEntry{})
+	err = db.AutoMigrate(&Account{}, &PlayerOptions{}, &Character{}, &GuildcardEntry{}, &EmailVerification{})
 	if err != nil {
 		return fmt.Errorf("unable to auto migrate db: %s", err)
 	}
@@ -0,0 +1,46 @@
+package data
+
+import (
+	"errors"
+	"time"
+
+	"gorm.io/gorm"
+)
+
+// EmailVerification is an outstanding request for the owner of an Account to
+// confirm their email address before the account can be used.
+type EmailVerification struct {
+	gorm.Model
+
+	Account   *Account
+	AccountID int
+
+	Token     string `gorm:"unique; not null"`
+	ExpiresAt time.Time
+}
+
+// FindEmailVerification returns the EmailVerification with the specified token
+// or nil if none exists.
+func FindEmailVerification(token string) (*EmailVerification, error) {
+	var verification EmailVerification
+	err := db.Preload("Account").Where("token = ?", token).First(&verification).Error
+
+	if err != nil {
+		if errors.Is(err, gorm.ErrRecordNotFound) {
+			return nil, nil
+		}
+		return nil, err
+	}
+
+	return &verification, nil
+}
+
+// CreateEmailVerification persists an EmailVerification to the database.
+func CreateEmailVerification(verification *EmailVerification) error {
+	return db.Create(verification).Error
+}
+
+// DeleteEmailVerification permanently deletes an EmailVerification record from the database.
+func DeleteEmailVerification(verification *EmailVerification) error {
+	return db.Unscoped().Delete(verification).Error
+}
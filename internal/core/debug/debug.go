@@ -8,8 +8,6 @@ import (
 	_ "net/http/pprof"
 	"time"
 
-	"github.com/spf13/viper"
-
 	"github.com/dcrodman/archon"
 )
 
@@ -21,11 +19,20 @@ type packetAnalyzerRequest struct {
 	Contents    []int
 }
 
-var packetAnalyzerChan = make(chan packetAnalyzerRequest, 10)
+var (
+	packetAnalyzerChan = make(chan packetAnalyzerRequest, 10)
+
+	config archon.DebuggingConfig
+)
+
+// Configure sets the debugging settings and should be called on startup.
+func Configure(cfg archon.DebuggingConfig) {
+	config = cfg
+}
 
 // Enabled returns whether or not the server was set to debug mode.
 func Enabled() bool {
-	return viper.GetBool("debugging.enabled")
+	return config.Enabled
 }
 
 // StartUtilities spins off the services associated with debug mode.
@@ -42,7 +49,7 @@ func packetAnalyzerEnabled() bool {
 }
 
 func PacketAnalyzerAddress() string {
-	return viper.GetString("debugging.packet_analyzer_address")
+	return config.PacketAnalyzerAddress
 }
 
 func startAnalyzerExporter() {
@@ -70,7 +77,7 @@ func startAnalyzerExporter() {
 // This function starts the default pprof HTTP server that can be accessed via localhost
 // to get runtime information about archon. See https://golang.org/pkg/net/http/pprof/
 func startPprofServer() {
-	listenerAddr := fmt.Sprintf("localhost:%s", viper.GetString("debugging.pprof_port"))
+	listenerAddr := fmt.Sprintf("localhost:%d", config.PprofPort)
 	archon.Log.Infof("starting pprof server on %s", listenerAddr)
 
 	go func() {
@@ -0,0 +1,134 @@
+// Package metrics defines the Prometheus metrics exported by the server, which are
+// exposed over HTTP by Handler.
+package metrics
+
+import (
+	"fmt"
+	"net/http"
+	"sync"
+
+	"github.com/prometheus/client_golang/prometheus"
+	"github.com/prometheus/client_golang/prometheus/promauto"
+	"github.com/prometheus/client_golang/prometheus/promhttp"
+)
+
+const namespace = "archon"
+
+// Packet directions used as label values.
+const (
+	Inbound  = "in"
+	Outbound = "out"
+)
+
+var (
+	ConnectionsAccepted = promauto.NewCounterVec(prometheus.CounterOpts{
+		Namespace: namespace,
+		Name:      "connections_accepted_total",
+		Help:      "Number of client connections accepted.",
+	}, []string{"server"})
+
+	ConnectionsRejected = promauto.NewCounterVec(prometheus.CounterOpts{
+		Namespace: namespace,
+		Name:      "connections_rejected_total",
+		Help:      "Number of client connections rejected.",
+	}, []string{"server", "reason"})
+
+	ActiveClients = promauto.NewGaugeVec(prometheus.GaugeOpts{
+		Namespace: namespace,
+		Name:      "active_clients",
+		Help:      "Number of clients currently connected.",
+	}, []string{"server"})
+
+	Packets = promauto.NewCounterVec(prometheus.CounterOpts{
+		Namespace: namespace,
+		Name:      "packets_total",
+		Help:      "Number of packets exchanged with clients.",
+	}, []string{"server", "direction", "type"})
+
+	PacketBytes = promauto.NewCounterVec(prometheus.CounterOpts{
+		Namespace: namespace,
+		Name:      "packet_bytes_total",
+		Help:      "Number of bytes exchanged with clients.",
+	}, []string{"server", "direction", "type"})
+
+	HandlerDuration = promauto.NewHistogramVec(prometheus.HistogramOpts{
+		Namespace: namespace,
+		Name:      "handler_duration_seconds",
+		Help:      "Time spent by a server handling a client packet.",
+		Buckets:   prometheus.ExponentialBuckets(0.0005, 2, 14),
+	}, []string{"server", "type"})
+
+	ClientErrors = promauto.NewCounterVec(prometheus.CounterOpts{
+		Namespace: namespace,
+		Name:      "client_errors_total",
+		Help:      "Number of errors that caused a client to be disconnected.",
+	}, []string{"server", "reason"})
+
+	SendQueueOverflows = promauto.NewCounterVec(prometheus.CounterOpts{
+		Namespace: namespace,
+		Name:      "send_queue_overflows_total",
+		Help:      "Number of packets that couldn't be queued for a client that wasn't keeping up.",
+	}, []string{"server", "policy"})
+
+	ShipgateRPCDuration = promauto.NewHistogramVec(prometheus.HistogramOpts{
+		Namespace: namespace,
+		Name:      "shipgate_rpc_duration_seconds",
+		Help:      "Time spent by the shipgate handling an RPC.",
+		Buckets:   prometheus.DefBuckets,
+	}, []string{"method", "code"})
+
+	ShipgateAuthentications = promauto.NewCounterVec(prometheus.CounterOpts{
+		Namespace: namespace,
+		Name:      "shipgate_authentications_total",
+		Help:      "Number of account authentication attempts handled by the shipgate.",
+	}, []string{"result"})
+
+	ShipgateEnvelopes = promauto.NewCounterVec(prometheus.CounterOpts{
+		Namespace: namespace,
+		Name:      "shipgate_envelopes_total",
+		Help:      "Number of envelopes published to the shipgate's message bus.",
+	}, []string{"type"})
+)
+
+// Handler returns an http.Handler that serves the metrics in the Prometheus
+// exposition format.
+func Handler() http.Handler {
+	return promhttp.Handler()
+}
+
+// Since the type of an inbound packet is chosen by the client, the number of
+// distinct types tracked for each server is capped in order to keep a misbehaving
+// client from creating an unbounded number of time series.
+const maxPacketTypes = 128
+
+var (
+	packetTypes      = make(map[string]map[uint16]bool)
+	packetTypesMutex sync.Mutex
+)
+
+// PacketType returns the label value to use for packets of type packetType
+// exchanged with clients of server.
+func PacketType(server string, packetType uint16) string {
+	packetTypesMutex.Lock()
+	defer packetTypesMutex.Unlock()
+
+	types, ok := packetTypes[server]
+	if !ok {
+		types = make(map[uint16]bool)
+		packetTypes[server] = types
+	}
+	if !types[packetType] {
+		if len(types) >= maxPacketTypes {
+			return "other"
+		}
+		types[packetType] = true
+	}
+	return fmt.Sprintf("0x%04X", packetType)
+}
+
+// RecordPacket counts a packet of size bytes exchanged with a client of server.
+func RecordPacket(server, direction string, packetType uint16, size int) {
+	label := PacketType(server, packetType)
+	Packets.WithLabelValues(server, direction, label).Inc()
+	PacketBytes.WithLabelValues(server, direction, label).Add(float64(size))
+}
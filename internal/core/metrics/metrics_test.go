@@ -0,0 +1,26 @@
+package metrics
+
+import "testing"
+
+func TestPacketType(t *testing.T) {
+	tests := map[string]struct {
+		packetType uint16
+		want       string
+	}{
+		"known type":      {packetType: 0x13, want: "0x0013"},
+		"repeated type":   {packetType: 0x00, want: "0x0000"},
+		"exceeds the cap": {packetType: 0xFFFF, want: "other"},
+	}
+
+	for i := 0; i < maxPacketTypes; i++ {
+		PacketType("TEST", uint16(i))
+	}
+
+	for name, tt := range tests {
+		t.Run(name, func(t *testing.T) {
+			if got := PacketType("TEST", tt.packetType); got != tt.want {
+				t.Errorf("expected label = %s, got = %s", tt.want, got)
+			}
+		})
+	}
+}
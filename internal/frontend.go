@@ -1,9 +1,10 @@
 package internal
 
 import (
-	"bytes"
 	"context"
+	"crypto/rand"
 	"encoding/binary"
+	"encoding/hex"
 	"errors"
 	"fmt"
 	"io"
@@ -14,7 +15,9 @@ import (
 
 	"github.com/dcrodman/archon"
 	"github.com/dcrodman/archon/internal/client"
+	"github.com/dcrodman/archon/internal/core/bytes"
 	archdebug "github.com/dcrodman/archon/internal/core/debug"
+	"github.com/dcrodman/archon/internal/core/metrics"
 )
 
 // Frontend implements the concurrent client connection logic.
@@ -24,6 +27,51 @@ import (
 type Frontend struct {
 	Address string
 	Backend Backend
+	Config  *archon.Config
+
+	// Clients currently connected to this Frontend (as opposed to globalClients,
+	// which contains the clients connected to any Frontend).
+	clients  *clientRegistry
+	handlers *PacketHandlers
+	timeouts connectionTimeouts
+	// Size of the largest packet accepted from a client.
+	maxPacketSize int
+	// Number of packets buffered for each client and what to do once that's exceeded.
+	sendQueueSize  int
+	overflowPolicy client.OverflowPolicy
+}
+
+// Size of the largest packet accepted from clients of Backends that don't implement
+// PacketSizeLimiter, which is the largest the PSO client will send.
+const defaultMaxPacketSize = 0x7C00
+
+// Number of packets buffered for each client if not configured.
+const defaultSendQueueSize = 256
+
+var errMalformedPacket = errors.New("malformed packet")
+
+// Buffers into which packets are read from clients, which are grown as needed to
+// fit the largest packet received.
+var receiveBuffers = sync.Pool{
+	New: func() interface{} {
+		b := make([]byte, 2048)
+		return &b
+	},
+}
+
+// connectionTimeouts limit how long the Frontend will wait on a client. A value
+// of 0 disables the corresponding timeout.
+type connectionTimeouts struct {
+	// Time allowed for the client to send its first packet after connecting.
+	handshake time.Duration
+	// Time the client may go without sending a packet.
+	idle time.Duration
+	// Time allowed for the rest of a packet to arrive once its header has been read.
+	packet time.Duration
+	// Time allowed for a packet to be sent to the client.
+	write time.Duration
+	// Interval at which clients of Backends implementing Pinger are pinged.
+	keepalive time.Duration
 }
 
 // Start initializes the server backend and opens a TCP socket for the specified server.
@@ -33,6 +81,33 @@ func (f *Frontend) Start(ctx context.Context, wg *sync.WaitGroup) error {
 	if err := f.Backend.Init(ctx); err != nil {
 		return fmt.Errorf("failed to initialize %s server: %v", f.Backend.Name(), err)
 	}
+	f.clients = newClientRegistry()
+	f.handlers = NewPacketHandlers()
+	f.Backend.RegisterHandlers(f.handlers)
+	f.maxPacketSize = defaultMaxPacketSize
+	if limiter, ok := f.Backend.(PacketSizeLimiter); ok {
+		f.maxPacketSize = limiter.MaxPacketSize()
+	}
+	f.timeouts = connectionTimeouts{
+		handshake: f.Config.Timeouts.Handshake,
+		idle:      f.Config.Timeouts.Idle,
+		packet:    f.Config.Timeouts.Packet,
+		write:     f.Config.Timeouts.Write,
+		keepalive: f.Config.Timeouts.Keepalive,
+	}
+	f.sendQueueSize = f.Config.SendQueue.Size
+	if f.sendQueueSize <= 0 {
+		f.sendQueueSize = defaultSendQueueSize
+	}
+	policy, err := client.ParseOverflowPolicy(f.Config.SendQueue.Overflow)
+	if err != nil {
+		return err
+	}
+	f.overflowPolicy = policy
+
+	// The connection limits apply across all Frontends and can be changed while running.
+	setConnectionLimits(f.Config)
+	archon.OnConfigReload(func(cfg *archon.Config, _ []string) { setConnectionLimits(cfg) })
 
 	socket, err := f.createSocket()
 	if err != nil {
@@ -77,7 +152,9 @@ func (f *Frontend) startBlockingLoop(ctx context.Context, socket *net.TCPListene
 			}
 
 			connection, err := socket.AcceptTCP()
-			if err != nil {
+			if errors.Is(err, net.ErrClosed) {
+				return
+			} else if err != nil {
 				archon.Log.Warnf("failed to accept connection: %s", err.Error())
 				continue
 			}
@@ -91,6 +168,7 @@ handleLoop:
 	for {
 		select {
 		case <-ctx.Done():
+			_ = socket.Close()
 			break handleLoop
 		case connection := <-connections:
 			clientWg.Add(1)
@@ -112,22 +190,38 @@ func (f *Frontend) acceptClient(ctx context.Context, connection *net.TCPConn, wg
 	defer wg.Done()
 
 	c := client.NewClient(connection)
+	c.ServerName = f.Backend.Name()
+	c.SessionID = newSessionID()
+	c.WriteTimeout = f.timeouts.write
 	f.Backend.SetUpClient(c)
+	c.StartWriter(f.sendQueueSize, f.overflowPolicy)
 
-	archon.Log.Infof("accepted %s connection from %s", f.Backend.Name(), c.IPAddr())
+	c.Logger().Infof("accepted %s connection from %s", f.Backend.Name(), c.IPAddr())
 
 	if err := f.Backend.Handshake(c); err != nil {
-		archon.Log.Errorf("Handshake() failed for client %s: %s", c.IPAddr(), err)
+		c.Logger().Errorf("Handshake() failed for client %s: %s", c.IPAddr(), err)
+		metrics.ClientErrors.WithLabelValues(f.Backend.Name(), "handshake").Inc()
+		_ = c.Close()
+		return
 	}
-
-	// Prevent multiple clients from connecting from the same IP address.
-	if globalClientList.has(c) {
-		archon.Log.Infof("%s rejected second connection from %s", f.Backend.Name(), c.IPAddr())
-		_ = connection.Close()
+	// A freshly connected client can always complete the handshake.
+	_ = c.Transition(client.Handshaken)
+
+	// Limit the number of clients that can connect from the same IP address.
+	if err := globalClients.add(c, maxConnectionsPerIP()); err != nil {
+		c.Logger().Infof("%s rejected connection from %s: %v", f.Backend.Name(), c.IPAddr(), err)
+		metrics.ConnectionsRejected.WithLabelValues(f.Backend.Name(), "too_many_connections").Inc()
+		_ = c.Close()
 		return
 	}
-
-	globalClientList.add(c)
+	_ = f.clients.add(c, 0)
+	// Keep the clients indexed by account and guildcard as the player logs in.
+	c.OnIdentityChange(func() {
+		globalClients.update(c)
+		f.clients.update(c)
+	})
+	metrics.ConnectionsAccepted.WithLabelValues(f.Backend.Name()).Inc()
+	metrics.ActiveClients.WithLabelValues(f.Backend.Name()).Inc()
 	f.processPackets(ctx, c)
 }
 
@@ -136,9 +230,33 @@ func (f *Frontend) acceptClient(ctx context.Context, connection *net.TCPConn, wg
 func (f *Frontend) processPackets(ctx context.Context, c *client.Client) {
 	defer f.closeConnectionAndRecover(f.Backend.Name(), c)
 
-	buffer := make([]byte, 2048)
+	done := make(chan struct{})
+	defer close(done)
+	// Closing the connection unblocks any pending read once the server shuts down.
+	go func() {
+		select {
+		case <-ctx.Done():
+			_ = c.Close()
+		case <-done:
+		}
+	}()
+	if pinger, ok := f.Backend.(Pinger); ok && f.timeouts.keepalive > 0 {
+		go f.keepAlive(c, pinger, done)
+	}
+
+	// Handlers decode packets into their own structs, so the buffer can be reused
+	// by another client once this one disconnects.
+	pooledBuffer := receiveBuffers.Get().(*[]byte)
+	buffer := *pooledBuffer
+	defer func() {
+		*pooledBuffer = buffer
+		receiveBuffers.Put(pooledBuffer)
+	}()
+	var packetSize int
 	var err error
 
+	// The client is expected to respond to the server's welcome packet promptly.
+	readTimeout := f.timeouts.handshake
 	for {
 		select {
 		case <-ctx.Done():
@@ -147,22 +265,62 @@ func (f *Frontend) processPackets(ctx context.Context, c *client.Client) {
 		default:
 		}
 
-		buffer, err = f.readNextPacket(c, buffer)
+		buffer, packetSize, err = f.readNextPacket(c, buffer, readTimeout)
+		readTimeout = f.timeouts.idle
 
-		if err == io.EOF {
+		var netErr net.Error
+		if err == io.EOF || ctx.Err() != nil {
+			break
+		} else if errors.As(err, &netErr) && netErr.Timeout() {
+			c.Logger().Infof("%s client %s timed out", f.Backend.Name(), c.IPAddr())
+			metrics.ClientErrors.WithLabelValues(f.Backend.Name(), "timeout").Inc()
+			break
+		} else if errors.Is(err, errMalformedPacket) {
+			c.Logger().Warnf("disconnecting %s client %s: %s", f.Backend.Name(), c.IPAddr(), err)
+			metrics.ClientErrors.WithLabelValues(f.Backend.Name(), "malformed").Inc()
 			break
 		} else if err != nil {
-			archon.Log.Warn(err.Error())
+			c.Logger().Warn(err.Error())
+			metrics.ClientErrors.WithLabelValues(f.Backend.Name(), "read").Inc()
 			break
 		}
 
+		packet := buffer[:packetSize]
 		if archdebug.Enabled() {
-			size := determinePacketSize(buffer, c.CryptoSession.HeaderSize())
-			archdebug.SendClientPacketToAnalyzer(c.DebugTags, buffer, uint16(size))
+			archdebug.SendClientPacketToAnalyzer(c.DebugTags, packet, uint16(packetSize))
+		}
+
+		// The packet type is at the same offset in both the PC and BB headers.
+		packetType := binary.LittleEndian.Uint16(packet[2:4])
+		if !f.handlers.handles(packetType) {
+			c.Logger().Infof("received unknown packet %#04x from %s client %s", packetType, f.Backend.Name(), c.IPAddr())
+			metrics.Packets.WithLabelValues(f.Backend.Name(), metrics.Inbound, "unknown").Inc()
+			metrics.PacketBytes.WithLabelValues(f.Backend.Name(), metrics.Inbound, "unknown").Add(float64(packetSize))
+			continue
 		}
+		packetLabel := metrics.PacketType(f.Backend.Name(), packetType)
+		metrics.Packets.WithLabelValues(f.Backend.Name(), metrics.Inbound, packetLabel).Inc()
+		metrics.PacketBytes.WithLabelValues(f.Backend.Name(), metrics.Inbound, packetLabel).Add(float64(packetSize))
 
-		if err = f.Backend.Handle(ctx, c, buffer); err != nil {
-			archon.Log.Warn("error in client communication: " + err.Error())
+		// The logger is rebuilt for each packet so that it includes any account
+		// information that became known while handling the previous one.
+		packetCtx := archon.WithLogger(archon.WithSessionID(ctx, c.SessionID), c.Logger())
+
+		start := time.Now()
+		err = f.handlers.dispatch(packetCtx, c, packetType, packet)
+		metrics.HandlerDuration.WithLabelValues(f.Backend.Name(), packetLabel).Observe(time.Since(start).Seconds())
+
+		if errors.Is(err, bytes.ErrNotEnoughData) {
+			c.Logger().Warnf("disconnecting %s client %s: %s: %s", f.Backend.Name(), c.IPAddr(), errMalformedPacket, err)
+			metrics.ClientErrors.WithLabelValues(f.Backend.Name(), "malformed").Inc()
+			return
+		} else if errors.Is(err, errUnexpectedPacket) {
+			c.Logger().Warnf("disconnecting %s client %s: %s", f.Backend.Name(), c.IPAddr(), err)
+			metrics.ClientErrors.WithLabelValues(f.Backend.Name(), "unexpected_packet").Inc()
+			return
+		} else if err != nil {
+			c.Logger().Warn("error in client communication: " + err.Error())
+			metrics.ClientErrors.WithLabelValues(f.Backend.Name(), "handler").Inc()
 			return
 		}
 	}
@@ -170,51 +328,117 @@ func (f *Frontend) processPackets(ctx context.Context, c *client.Client) {
 
 // closeConnectionAndRecover is the failsafe that catches any panics, disconnects the
 // client, and removes them from the list regardless of the state of the connection.
-func (*Frontend) closeConnectionAndRecover(serverName string, c *client.Client) {
+func (f *Frontend) closeConnectionAndRecover(serverName string, c *client.Client) {
 	if err := recover(); err != nil {
-		archon.Log.Errorf("error in client communication with %s: error=%s, trace: %s",
+		c.Logger().Errorf("error in client communication with %s: error=%s, trace: %s",
 			c.IPAddr(), err, debug.Stack())
+		metrics.ClientErrors.WithLabelValues(serverName, "panic").Inc()
+	}
+
+	if err := c.Close(); err != nil && !errors.Is(err, net.ErrClosed) {
+		c.Logger().Warnf("failed to close client connection: %s", err)
 	}
 
-	if err := c.Close(); err != nil {
-		archon.Log.Warnf("failed to close client connection: %s", err)
+	globalClients.remove(c)
+	f.clients.remove(c)
+	metrics.ActiveClients.WithLabelValues(serverName).Dec()
+
+	if handler, ok := f.Backend.(DisconnectHandler); ok {
+		handler.HandleDisconnect(c)
+	}
+
+	c.Logger().Infof("disconnected %s client %s", serverName, c.IPAddr())
+}
+
+// newSessionID generates a random identifier for a client connection.
+func newSessionID() string {
+	id := make([]byte, 8)
+	if _, err := rand.Read(id); err != nil {
+		panic(fmt.Errorf("failed to generate session ID: %v", err))
 	}
+	return hex.EncodeToString(id)
+}
 
-	globalClientList.remove(c)
+// ClientCount returns the number of clients currently connected to the Frontend.
+func (f *Frontend) ClientCount() int {
+	if f.clients == nil {
+		return 0
+	}
+	return f.clients.len()
+}
 
-	archon.Log.Infof("disconnected %s client %s", serverName, c.IPAddr())
+// Clients returns a snapshot of the clients currently connected to the Frontend.
+func (f *Frontend) Clients() []*client.Client {
+	if f.clients == nil {
+		return nil
+	}
+	return f.clients.all()
+}
+
+// keepAlive pings the client over the configured interval until done is closed.
+func (f *Frontend) keepAlive(c *client.Client, pinger Pinger, done <-chan struct{}) {
+	ticker := time.NewTicker(f.timeouts.keepalive)
+	defer ticker.Stop()
+
+	for {
+		select {
+		case <-done:
+			return
+		case <-ticker.C:
+			if err := pinger.Ping(c); err != nil {
+				c.Logger().Warnf("failed to ping client: %s", err)
+				// Closing the connection will cause the Frontend to clean up the client.
+				_ = c.Close()
+				return
+			}
+		}
+	}
 }
 
 // readNextPacket is a blocking call that only returns once the client has
-// sent the next packet to be processed. The buffer in c.ConnectionState is
-// updated with the decrypted packet.
-func (f *Frontend) readNextPacket(c *client.Client, buffer []byte) ([]byte, error) {
+// sent the next packet to be processed, or fails if the client doesn't start
+// sending it within timeout. Returns the (possibly reallocated) buffer holding
+// the decrypted packet along with the size of the packet.
+func (f *Frontend) readNextPacket(c *client.Client, buffer []byte, timeout time.Duration) ([]byte, int, error) {
 	headerSize := int(c.CryptoSession.HeaderSize())
 
 	// Read and decrypt the packet header.
+	if err := setReadTimeout(c, timeout); err != nil {
+		return buffer, 0, err
+	}
 	if err := f.readDataFromClient(c, headerSize, buffer); err != nil {
-		return buffer, err
+		return buffer, 0, err
+	}
+	// Once the header has arrived the rest of the packet should follow shortly after,
+	// which prevents clients from tying up the connection by sending data slowly.
+	if err := setReadTimeout(c, f.timeouts.packet); err != nil {
+		return buffer, 0, err
 	}
 
 	c.CryptoSession.Decrypt(buffer[:headerSize], uint32(headerSize))
 
-	packetSize := determinePacketSize(buffer[:2], uint16(headerSize))
+	packetSize, err := determinePacketSize(buffer[:headerSize], uint16(headerSize))
+	if err != nil {
+		return buffer, 0, err
+	} else if packetSize > f.maxPacketSize {
+		return buffer, 0, fmt.Errorf("%w: size %d exceeds the maximum of %d", errMalformedPacket, packetSize, f.maxPacketSize)
+	}
 
 	// Grow the client's receive buffer if they send us a packet bigger than its current capacity.
 	if packetSize > cap(buffer) {
-		newBuf := make([]byte, cap(buffer)+packetSize)
-		copy(newBuf, buffer)
+		newBuf := make([]byte, packetSize)
+		copy(newBuf, buffer[:headerSize])
 		buffer = newBuf
 	}
 
 	// Read and decrypt the rest of the packet.
 	if err := f.readDataFromClient(c, packetSize-headerSize, buffer[headerSize:]); err != nil {
-		return buffer, err
+		return buffer, 0, err
 	}
 
 	c.CryptoSession.Decrypt(buffer[headerSize:packetSize], uint32(packetSize-headerSize))
 
-	return buffer, nil
+	return buffer, packetSize, nil
 }
 
 func (f *Frontend) readDataFromClient(c *client.Client, n int, buffer []byte) error {
@@ -227,32 +451,42 @@ func (f *Frontend) readDataFromClient(c *client.Client, n int, buffer []byte) er
 		if bytesRead == 0 || err == io.EOF {
 			return err
 		} else if err != nil {
-			return errors.New("socket error (" + c.IPAddr() + ") " + err.Error())
+			return fmt.Errorf("socket error (%s) %w", c.IPAddr(), err)
 		}
 	}
 
 	return nil
 }
 
-// Extract the packet length from the first two bytes of data.
-func determinePacketSize(data []byte, headerSize uint16) int {
-	if len(data) < 2 {
-		// Panic since this shouldn't happen unless something's very wrong.
-		panic(errors.New("getSize(): data must be at least two bytes"))
+// setReadTimeout sets the client's read deadline to timeout from now, or clears
+// it if timeout is 0.
+func setReadTimeout(c *client.Client, timeout time.Duration) error {
+	var deadline time.Time
+	if timeout > 0 {
+		deadline = time.Now().Add(timeout)
 	}
+	if err := c.SetReadDeadline(deadline); err != nil {
+		return fmt.Errorf("failed to set read deadline for %s: %w", c.IPAddr(), err)
+	}
+	return nil
+}
 
-	var size uint16
-	reader := bytes.NewReader(data)
-	err := binary.Read(reader, binary.LittleEndian, &size)
+// Extract the packet length from the first two bytes of data, which must contain
+// the packet's header.
+func determinePacketSize(data []byte, headerSize uint16) (int, error) {
+	if len(data) < int(headerSize) {
+		return 0, fmt.Errorf("%w: header must be %d bytes, got %d", errMalformedPacket, headerSize, len(data))
+	}
 
-	if err != nil {
-		archon.Log.Warn("error decoding packet size:", err)
+	size := int(binary.LittleEndian.Uint16(data))
+	if size < int(headerSize) {
+		return 0, fmt.Errorf("%w: declared size %d is smaller than the header", errMalformedPacket, size)
 	}
 
 	// The PSO client occasionally sends packets that are longer than their declared
 	// size, but are always a multiple of the length of the packet header. Adjust the
 	// expected length just in case in order to avoid leaving stray bytes in the buffer.
-	size += size % headerSize
+	size += size % int(headerSize)
 
-	return int(size)
+	return size, nil
 }
@@ -0,0 +1,152 @@
+package internal
+
+import (
+	"context"
+	"io/ioutil"
+	"net"
+	"testing"
+	"time"
+
+	"github.com/sirupsen/logrus"
+
+	"github.com/dcrodman/archon"
+	"github.com/dcrodman/archon/internal/client"
+)
+
+func TestMain(m *testing.M) {
+	archon.Log = logrus.New()
+	archon.Log.Out = ioutil.Discard
+	m.Run()
+}
+
+// plaintextCryptoSession is a CryptoSession that doesn't encrypt anything.
+type plaintextCryptoSession struct{}
+
+func (s plaintextCryptoSession) HeaderSize() uint16                  { return 4 }
+func (s plaintextCryptoSession) Encrypt(bytes []byte, length uint32) {}
+func (s plaintextCryptoSession) Decrypt(bytes []byte, length uint32) {}
+func (s plaintextCryptoSession) ServerVector() []byte                { return nil }
+func (s plaintextCryptoSession) ClientVector() []byte                { return nil }
+
+// testBackend is a Backend that ignores every packet it receives.
+type testBackend struct{}
+
+func (b *testBackend) Name() string                       { return "TEST" }
+func (b *testBackend) Init(ctx context.Context) error     { return nil }
+func (b *testBackend) SetUpClient(c *client.Client)       { c.CryptoSession = plaintextCryptoSession{} }
+func (b *testBackend) Handshake(c *client.Client) error   { return nil }
+func (b *testBackend) RegisterHandlers(h *PacketHandlers) {}
+
+// runProcessPackets starts processPackets for a client connected over a pipe and
+// returns the remote end of the pipe along with a channel that's closed once
+// processPackets returns.
+func runProcessPackets(ctx context.Context, f *Frontend) (net.Conn, <-chan struct{}) {
+	remote, conn := net.Pipe()
+	c := client.NewClient(conn)
+	c.SessionID = "test"
+	f.Backend.SetUpClient(c)
+
+	done := make(chan struct{})
+	go func() {
+		f.processPackets(ctx, c)
+		close(done)
+	}()
+	return remote, done
+}
+
+func TestFrontend_ProcessPacketsDisconnects(t *testing.T) {
+	tests := map[string]struct {
+		timeouts connectionTimeouts
+		// Bytes sent by the client before going silent.
+		sent []byte
+	}{
+		"handshake": {
+			timeouts: connectionTimeouts{handshake: 50 * time.Millisecond},
+		},
+		"idle": {
+			timeouts: connectionTimeouts{idle: 50 * time.Millisecond},
+			sent:     []byte{0x04, 0x00, 0x00, 0x00},
+		},
+		"partial_packet": {
+			timeouts: connectionTimeouts{packet: 50 * time.Millisecond},
+			sent:     []byte{0x08, 0x00, 0x00, 0x00},
+		},
+		"oversized_packet": {
+			sent: []byte{0x00, 0x10, 0x00, 0x00},
+		},
+		"unexpected_packet": {
+			sent: []byte{0x04, 0x00, 0x93, 0x00},
+		},
+	}
+
+	for name, tt := range tests {
+		t.Run(name, func(t *testing.T) {
+			f := &Frontend{
+				Backend:       &testBackend{},
+				clients:       newClientRegistry(),
+				handlers:      NewPacketHandlers(),
+				timeouts:      tt.timeouts,
+				maxPacketSize: 0x100,
+			}
+			// Only accepted from clients that have logged in.
+			f.handlers.Register(0x93, func(c *client.Client) error { return nil }, client.Authenticated)
+			remote, done := runProcessPackets(context.Background(), f)
+			defer remote.Close()
+
+			if len(tt.sent) > 0 {
+				if _, err := remote.Write(tt.sent); err != nil {
+					t.Fatalf("failed to write packet: %v", err)
+				}
+			}
+
+			select {
+			case <-done:
+			case <-time.After(time.Second):
+				t.Fatal("expected client to be disconnected")
+			}
+		})
+	}
+}
+
+func TestFrontend_ProcessPacketsShutdown(t *testing.T) {
+	ctx, cancel := context.WithCancel(context.Background())
+	f := &Frontend{Backend: &testBackend{}, clients: newClientRegistry(), handlers: NewPacketHandlers()}
+	remote, done := runProcessPackets(ctx, f)
+	defer remote.Close()
+
+	cancel()
+
+	select {
+	case <-done:
+	case <-time.After(time.Second):
+		t.Fatal("expected client to be disconnected on shutdown")
+	}
+}
+
+func TestDeterminePacketSize(t *testing.T) {
+	tests := map[string]struct {
+		data       []byte
+		headerSize uint16
+		wantedSize int
+		wantErr    bool
+	}{
+		"pc_header":        {data: []byte{0x0C, 0x00, 0x04, 0x00}, headerSize: 4, wantedSize: 0x0C},
+		"bb_header":        {data: []byte{0x10, 0x00, 0x93, 0x00, 0, 0, 0, 0}, headerSize: 8, wantedSize: 0x10},
+		"bb_header_padded": {data: []byte{0xB4, 0x00, 0x93, 0x00, 0, 0, 0, 0}, headerSize: 8, wantedSize: 0xB8},
+		"maximum_size":     {data: []byte{0xFC, 0xFF, 0x00, 0x00}, headerSize: 4, wantedSize: 0xFFFC},
+		"short_data":       {data: []byte{0x10}, headerSize: 4, wantErr: true},
+		"smaller_than_hdr": {data: []byte{0x02, 0x00, 0x00, 0x00}, headerSize: 4, wantErr: true},
+	}
+
+	for name, tt := range tests {
+		t.Run(name, func(t *testing.T) {
+			size, err := determinePacketSize(tt.data, tt.headerSize)
+			if (err != nil) != tt.wantErr {
+				t.Fatalf("expected error = %v, got = %v", tt.wantErr, err)
+			}
+			if size != tt.wantedSize {
+				t.Errorf("expected size = %#x, got = %#x", tt.wantedSize, size)
+			}
+		})
+	}
+}
@@ -2,10 +2,10 @@ package login
 
 import (
 	"context"
-	"strconv"
 	"strings"
 
 	"github.com/dcrodman/archon"
+	"github.com/dcrodman/archon/internal"
 	"github.com/dcrodman/archon/internal/client"
 	"github.com/dcrodman/archon/internal/core/auth"
 	"github.com/dcrodman/archon/internal/core/bytes"
@@ -25,21 +25,21 @@ type Server struct {
 	characterRedirectPort uint16
 	shipGateClient        *shipgate.Client
 	shipGateAddr          string
+	config                *archon.Config
 }
 
-func NewServer(name, characterPort, shipgateAddr string) *Server {
-	charPort, _ := strconv.ParseUint(characterPort, 10, 16)
-
+func NewServer(name, shipgateAddr string, cfg *archon.Config) *Server {
 	return &Server{
 		name:                  name,
 		shipGateAddr:          shipgateAddr,
-		characterRedirectPort: uint16(charPort),
+		characterRedirectPort: uint16(cfg.CharacterServer.Port),
+		config:                cfg,
 	}
 }
 
 func (s *Server) Name() string { return s.name }
 func (s *Server) Init(_ context.Context) error {
-	shipGateClient, err := shipgate.NewClient(s.shipGateAddr)
+	shipGateClient, err := shipgate.NewClient(s.shipGateAddr, s.config)
 	if err != nil {
 		return err
 	}
@@ -66,36 +66,25 @@ func (s *Server) Handshake(c *client.Client) error {
 	return c.SendRaw(pkt)
 }
 
-func (s *Server) Handle(ctx context.Context, c *client.Client, data []byte) error {
-	var header packets.BBHeader
-	bytes.StructFromBytes(data[:packets.BBHeaderSize], &header)
-
-	var err error
-	switch header.Type {
-	case packets.LoginType:
-		var loginPkt packets.Login
-		bytes.StructFromBytes(data, &loginPkt)
-		err = s.handleLogin(ctx, c, &loginPkt)
-	case packets.DisconnectType:
-		// Just wait until we recv 0 from the client to disconnect.
-		break
-	default:
-		archon.Log.Infof("received unknown packet %x from %s", header.Type, c.IPAddr())
-	}
-
-	return err
+func (s *Server) RegisterHandlers(h *internal.PacketHandlers) {
+	h.Register(packets.LoginType, s.handleLogin, client.Handshaken)
+	// Just wait until we recv 0 from the client to disconnect.
+	h.Ignore(packets.DisconnectType)
 }
 
 func (s *Server) handleLogin(ctx context.Context, c *client.Client, loginPkt *packets.Login) error {
 	username := string(bytes.StripPadding(loginPkt.Username[:]))
 	password := string(bytes.StripPadding(loginPkt.Password[:]))
 
-	if _, err := s.shipGateClient.AuthenticateAccount(ctx, username, password); err != nil {
+	account, token, err := s.shipGateClient.AuthenticateAccount(ctx, username, password)
+	if err != nil {
 		switch err {
 		case auth.ErrInvalidCredentials:
 			return s.sendSecurity(c, packets.BBLoginErrorPassword)
 		case auth.ErrAccountBanned:
 			return s.sendSecurity(c, packets.BBLoginErrorBanned)
+		case shipgate.ErrUnavailable:
+			return s.sendMessage(c, shipgate.UnavailableMessage)
 		default:
 			sendErr := s.sendMessage(c, strings.Title(err.Error()))
 			if sendErr == nil {
@@ -104,10 +93,11 @@ func (s *Server) handleLogin(ctx context.Context, c *client.Client, loginPkt *pa
 			return err
 		}
 	}
-
-	if err := s.sendSecurity(c, packets.BBLoginErrorNone); err != nil {
+	c.SetAccount(account)
+	if err := c.Transition(client.Authenticated); err != nil {
 		return err
 	}
+
 	// The first time we receive this packet the loginClientExtension will have included the
 	// version string in the security data; check it.
 	//if ClientVersionString != string(util.StripPadding(loginPkt.Security[:])) {
@@ -116,12 +106,20 @@ func (s *Server) handleLogin(ctx context.Context, c *client.Client, loginPkt *pa
 	//}
 
 	// Copy over the config, to indicate they've passed initial authentication.
-	bytes.StructFromBytes(loginPkt.Security[:], &c.Config)
+	if err := c.Config.UnmarshalBinary(loginPkt.Security[:]); err != nil {
+		return err
+	}
 	// Newserv sets this field when the login client first connects. I think this is
 	// used to indicate that the client has made it through the LOGIN server,
 	// but for now we'll just set it and leave it alone.
 	c.Config.Magic = 0x48615467
+	// The client echoes the config back to the servers it's redirected to, which
+	// authenticate it with the session token instead of its password.
+	copy(c.Config.SessionToken[:], token)
 
+	if err := s.sendSecurity(c, packets.BBLoginErrorNone); err != nil {
+		return err
+	}
 	return s.sendCharacterRedirect(c)
 }
 
@@ -134,7 +132,7 @@ func (s *Server) sendSecurity(c *client.Client, errorCode uint32) error {
 		ErrorCode:    errorCode,
 		PlayerTag:    0x00010000,
 		Guildcard:    c.Guildcard,
-		TeamID:       c.TeamID,
+		TeamID:       c.TeamID(),
 		Config:       c.Config,
 		Capabilities: 0x00000102,
 	})
@@ -155,11 +153,13 @@ func (s *Server) sendMessage(c *client.Client, message string) error {
 func (s *Server) sendCharacterRedirect(c *client.Client) error {
 	pkt := &packets.Redirect{
 		Header: packets.BBHeader{Type: packets.RedirectType},
-		IPAddr: [4]uint8{},
 		Port:   s.characterRedirectPort,
 	}
-	ip := archon.BroadcastIP()
-	copy(pkt.IPAddr[:], ip[:])
+	ip, err := archon.RedirectIP(c.IPAddr())
+	if err != nil {
+		return err
+	}
+	pkt.IPAddr = ip
 
 	return c.Send(pkt)
 }
@@ -0,0 +1,107 @@
+package internal
+
+import (
+	"context"
+	"errors"
+	"fmt"
+
+	"github.com/dcrodman/archon/internal/client"
+)
+
+var errUnexpectedPacket = errors.New("unexpected packet")
+
+// PacketHandlers maps the types of the packets accepted by a Backend to the
+// functions that handle them. Handlers are functions of one of the forms:
+//
+//	func(ctx context.Context, c *client.Client, pkt *T) error
+//	func(c *client.Client, pkt *T) error
+//	func(ctx context.Context, c *client.Client) error
+//	func(c *client.Client) error
+//
+// where T is one of the structs generated from the packet schema, into which the
+// packet is decoded before it's passed to the handler. Packets too short to decode
+// into T are rejected as malformed.
+type PacketHandlers struct {
+	handlers map[uint16]*packetHandler
+}
+
+// handlerFunc is the form to which every handler is adapted when it's registered,
+// so that dispatching a packet doesn't depend on the handler's signature.
+type handlerFunc func(ctx context.Context, c *client.Client, data []byte) error
+
+type packetHandler struct {
+	handle handlerFunc
+	// States in which the packet will be accepted (any state if empty).
+	allowedStates []client.State
+}
+
+func NewPacketHandlers() *PacketHandlers {
+	return &PacketHandlers{handlers: make(map[uint16]*packetHandler)}
+}
+
+// Register sets the handler for packets of type packetType, which will only be
+// accepted from clients in one of allowedStates (or in any state if none are
+// given). Panics if handler isn't a function of one of the supported forms.
+func (h *PacketHandlers) Register(packetType uint16, handler interface{}, allowedStates ...client.State) {
+	handle, err := newHandlerFunc(handler)
+	if err != nil {
+		panic(fmt.Errorf("invalid handler for packet %#04x: %v", packetType, err))
+	}
+	h.handlers[packetType] = &packetHandler{handle: handle, allowedStates: allowedStates}
+}
+
+// Ignore registers packets of the given types as accepted in any state without
+// doing anything with them.
+func (h *PacketHandlers) Ignore(packetTypes ...uint16) {
+	for _, packetType := range packetTypes {
+		h.Register(packetType, func(c *client.Client) error { return nil })
+	}
+}
+
+// newHandlerFunc adapts handler to a handlerFunc, which for handlers accepting a
+// packet means decoding it first (see decodingHandler in packet_handlers_gen.go).
+func newHandlerFunc(handler interface{}) (handlerFunc, error) {
+	switch fn := handler.(type) {
+	case func(context.Context, *client.Client) error:
+		return func(ctx context.Context, c *client.Client, _ []byte) error { return fn(ctx, c) }, nil
+	case func(*client.Client) error:
+		return func(_ context.Context, c *client.Client, _ []byte) error { return fn(c) }, nil
+	}
+	if handle := decodingHandler(handler); handle != nil {
+		return handle, nil
+	}
+	return nil, fmt.Errorf("unsupported handler %T", handler)
+}
+
+// handles returns whether a handler is registered for packetType.
+func (h *PacketHandlers) handles(packetType uint16) bool {
+	_, ok := h.handlers[packetType]
+	return ok
+}
+
+// dispatch decodes data and passes it to the handler registered for packetType,
+// provided that the packet is allowed in the client's current state.
+func (h *PacketHandlers) dispatch(ctx context.Context, c *client.Client, packetType uint16, data []byte) error {
+	ph, ok := h.handlers[packetType]
+	if !ok {
+		return fmt.Errorf("no handler registered for packet %#04x", packetType)
+	}
+
+	if !ph.allows(c.State()) {
+		return fmt.Errorf("%w: %#04x not allowed in state %s", errUnexpectedPacket, packetType, c.State())
+	}
+
+	return ph.handle(ctx, c, data)
+}
+
+func (ph *packetHandler) allows(state client.State) bool {
+	if len(ph.allowedStates) == 0 {
+		return true
+	}
+	for _, allowed := range ph.allowedStates {
+		if state == allowed {
+			return true
+		}
+	}
+	return false
+}
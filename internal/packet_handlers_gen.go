@@ -0,0 +1,722 @@
+// Code generated by packetgen from internal/packets/schema. DO NOT EDIT.
+
+package internal
+
+import (
+	"context"
+
+	"github.com/dcrodman/archon/internal/client"
+	"github.com/dcrodman/archon/internal/packets"
+)
+
+// decodingHandler returns a function that decodes the packet into the struct
+// accepted by handler and passes it along, or nil if handler doesn't accept one.
+func decodingHandler(handler interface{}) handlerFunc {
+	switch fn := handler.(type) {
+	case func(context.Context, *client.Client, *packets.ServerMessage) error:
+		return func(ctx context.Context, c *client.Client, data []byte) error {
+			var pkt packets.ServerMessage
+			if err := pkt.UnmarshalBinary(data); err != nil {
+				return err
+			}
+			return fn(ctx, c, &pkt)
+		}
+	case func(*client.Client, *packets.ServerMessage) error:
+		return func(ctx context.Context, c *client.Client, data []byte) error {
+			var pkt packets.ServerMessage
+			if err := pkt.UnmarshalBinary(data); err != nil {
+				return err
+			}
+			return fn(c, &pkt)
+		}
+	case func(context.Context, *client.Client, *packets.LobbyListEntry) error:
+		return func(ctx context.Context, c *client.Client, data []byte) error {
+			var pkt packets.LobbyListEntry
+			if err := pkt.UnmarshalBinary(data); err != nil {
+				return err
+			}
+			return fn(ctx, c, &pkt)
+		}
+	case func(*client.Client, *packets.LobbyListEntry) error:
+		return func(ctx context.Context, c *client.Client, data []byte) error {
+			var pkt packets.LobbyListEntry
+			if err := pkt.UnmarshalBinary(data); err != nil {
+				return err
+			}
+			return fn(c, &pkt)
+		}
+	case func(context.Context, *client.Client, *packets.LobbyList) error:
+		return func(ctx context.Context, c *client.Client, data []byte) error {
+			var pkt packets.LobbyList
+			if err := pkt.UnmarshalBinary(data); err != nil {
+				return err
+			}
+			return fn(ctx, c, &pkt)
+		}
+	case func(*client.Client, *packets.LobbyList) error:
+		return func(ctx context.Context, c *client.Client, data []byte) error {
+			var pkt packets.LobbyList
+			if err := pkt.UnmarshalBinary(data); err != nil {
+				return err
+			}
+			return fn(c, &pkt)
+		}
+	case func(context.Context, *client.Client, *packets.InventoryItem) error:
+		return func(ctx context.Context, c *client.Client, data []byte) error {
+			var pkt packets.InventoryItem
+			if err := pkt.UnmarshalBinary(data); err != nil {
+				return err
+			}
+			return fn(ctx, c, &pkt)
+		}
+	case func(*client.Client, *packets.InventoryItem) error:
+		return func(ctx context.Context, c *client.Client, data []byte) error {
+			var pkt packets.InventoryItem
+			if err := pkt.UnmarshalBinary(data); err != nil {
+				return err
+			}
+			return fn(c, &pkt)
+		}
+	case func(context.Context, *client.Client, *packets.InventorySlot) error:
+		return func(ctx context.Context, c *client.Client, data []byte) error {
+			var pkt packets.InventorySlot
+			if err := pkt.UnmarshalBinary(data); err != nil {
+				return err
+			}
+			return fn(ctx, c, &pkt)
+		}
+	case func(*client.Client, *packets.InventorySlot) error:
+		return func(ctx context.Context, c *client.Client, data []byte) error {
+			var pkt packets.InventorySlot
+			if err := pkt.UnmarshalBinary(data); err != nil {
+				return err
+			}
+			return fn(c, &pkt)
+		}
+	case func(context.Context, *client.Client, *packets.BankItem) error:
+		return func(ctx context.Context, c *client.Client, data []byte) error {
+			var pkt packets.BankItem
+			if err := pkt.UnmarshalBinary(data); err != nil {
+				return err
+			}
+			return fn(ctx, c, &pkt)
+		}
+	case func(*client.Client, *packets.BankItem) error:
+		return func(ctx context.Context, c *client.Client, data []byte) error {
+			var pkt packets.BankItem
+			if err := pkt.UnmarshalBinary(data); err != nil {
+				return err
+			}
+			return fn(c, &pkt)
+		}
+	case func(context.Context, *client.Client, *packets.FullCharacter) error:
+		return func(ctx context.Context, c *client.Client, data []byte) error {
+			var pkt packets.FullCharacter
+			if err := pkt.UnmarshalBinary(data); err != nil {
+				return err
+			}
+			return fn(ctx, c, &pkt)
+		}
+	case func(*client.Client, *packets.FullCharacter) error:
+		return func(ctx context.Context, c *client.Client, data []byte) error {
+			var pkt packets.FullCharacter
+			if err := pkt.UnmarshalBinary(data); err != nil {
+				return err
+			}
+			return fn(c, &pkt)
+		}
+	case func(context.Context, *client.Client, *packets.SimpleMail) error:
+		return func(ctx context.Context, c *client.Client, data []byte) error {
+			var pkt packets.SimpleMail
+			if err := pkt.UnmarshalBinary(data); err != nil {
+				return err
+			}
+			return fn(ctx, c, &pkt)
+		}
+	case func(*client.Client, *packets.SimpleMail) error:
+		return func(ctx context.Context, c *client.Client, data []byte) error {
+			var pkt packets.SimpleMail
+			if err := pkt.UnmarshalBinary(data); err != nil {
+				return err
+			}
+			return fn(c, &pkt)
+		}
+	case func(context.Context, *client.Client, *packets.PCHeader) error:
+		return func(ctx context.Context, c *client.Client, data []byte) error {
+			var pkt packets.PCHeader
+			if err := pkt.UnmarshalBinary(data); err != nil {
+				return err
+			}
+			return fn(ctx, c, &pkt)
+		}
+	case func(*client.Client, *packets.PCHeader) error:
+		return func(ctx context.Context, c *client.Client, data []byte) error {
+			var pkt packets.PCHeader
+			if err := pkt.UnmarshalBinary(data); err != nil {
+				return err
+			}
+			return fn(c, &pkt)
+		}
+	case func(context.Context, *client.Client, *packets.BBHeader) error:
+		return func(ctx context.Context, c *client.Client, data []byte) error {
+			var pkt packets.BBHeader
+			if err := pkt.UnmarshalBinary(data); err != nil {
+				return err
+			}
+			return fn(ctx, c, &pkt)
+		}
+	case func(*client.Client, *packets.BBHeader) error:
+		return func(ctx context.Context, c *client.Client, data []byte) error {
+			var pkt packets.BBHeader
+			if err := pkt.UnmarshalBinary(data); err != nil {
+				return err
+			}
+			return fn(c, &pkt)
+		}
+	case func(context.Context, *client.Client, *packets.ClientConfig) error:
+		return func(ctx context.Context, c *client.Client, data []byte) error {
+			var pkt packets.ClientConfig
+			if err := pkt.UnmarshalBinary(data); err != nil {
+				return err
+			}
+			return fn(ctx, c, &pkt)
+		}
+	case func(*client.Client, *packets.ClientConfig) error:
+		return func(ctx context.Context, c *client.Client, data []byte) error {
+			var pkt packets.ClientConfig
+			if err := pkt.UnmarshalBinary(data); err != nil {
+				return err
+			}
+			return fn(c, &pkt)
+		}
+	case func(context.Context, *client.Client, *packets.Welcome) error:
+		return func(ctx context.Context, c *client.Client, data []byte) error {
+			var pkt packets.Welcome
+			if err := pkt.UnmarshalBinary(data); err != nil {
+				return err
+			}
+			return fn(ctx, c, &pkt)
+		}
+	case func(*client.Client, *packets.Welcome) error:
+		return func(ctx context.Context, c *client.Client, data []byte) error {
+			var pkt packets.Welcome
+			if err := pkt.UnmarshalBinary(data); err != nil {
+				return err
+			}
+			return fn(c, &pkt)
+		}
+	case func(context.Context, *client.Client, *packets.Login) error:
+		return func(ctx context.Context, c *client.Client, data []byte) error {
+			var pkt packets.Login
+			if err := pkt.UnmarshalBinary(data); err != nil {
+				return err
+			}
+			return fn(ctx, c, &pkt)
+		}
+	case func(*client.Client, *packets.Login) error:
+		return func(ctx context.Context, c *client.Client, data []byte) error {
+			var pkt packets.Login
+			if err := pkt.UnmarshalBinary(data); err != nil {
+				return err
+			}
+			return fn(c, &pkt)
+		}
+	case func(context.Context, *client.Client, *packets.Security) error:
+		return func(ctx context.Context, c *client.Client, data []byte) error {
+			var pkt packets.Security
+			if err := pkt.UnmarshalBinary(data); err != nil {
+				return err
+			}
+			return fn(ctx, c, &pkt)
+		}
+	case func(*client.Client, *packets.Security) error:
+		return func(ctx context.Context, c *client.Client, data []byte) error {
+			var pkt packets.Security
+			if err := pkt.UnmarshalBinary(data); err != nil {
+				return err
+			}
+			return fn(c, &pkt)
+		}
+	case func(context.Context, *client.Client, *packets.Redirect) error:
+		return func(ctx context.Context, c *client.Client, data []byte) error {
+			var pkt packets.Redirect
+			if err := pkt.UnmarshalBinary(data); err != nil {
+				return err
+			}
+			return fn(ctx, c, &pkt)
+		}
+	case func(*client.Client, *packets.Redirect) error:
+		return func(ctx context.Context, c *client.Client, data []byte) error {
+			var pkt packets.Redirect
+			if err := pkt.UnmarshalBinary(data); err != nil {
+				return err
+			}
+			return fn(c, &pkt)
+		}
+	case func(context.Context, *client.Client, *packets.Options) error:
+		return func(ctx context.Context, c *client.Client, data []byte) error {
+			var pkt packets.Options
+			if err := pkt.UnmarshalBinary(data); err != nil {
+				return err
+			}
+			return fn(ctx, c, &pkt)
+		}
+	case func(*client.Client, *packets.Options) error:
+		return func(ctx context.Context, c *client.Client, data []byte) error {
+			var pkt packets.Options
+			if err := pkt.UnmarshalBinary(data); err != nil {
+				return err
+			}
+			return fn(c, &pkt)
+		}
+	case func(context.Context, *client.Client, *packets.CharacterSelection) error:
+		return func(ctx context.Context, c *client.Client, data []byte) error {
+			var pkt packets.CharacterSelection
+			if err := pkt.UnmarshalBinary(data); err != nil {
+				return err
+			}
+			return fn(ctx, c, &pkt)
+		}
+	case func(*client.Client, *packets.CharacterSelection) error:
+		return func(ctx context.Context, c *client.Client, data []byte) error {
+			var pkt packets.CharacterSelection
+			if err := pkt.UnmarshalBinary(data); err != nil {
+				return err
+			}
+			return fn(c, &pkt)
+		}
+	case func(context.Context, *client.Client, *packets.CharacterAck) error:
+		return func(ctx context.Context, c *client.Client, data []byte) error {
+			var pkt packets.CharacterAck
+			if err := pkt.UnmarshalBinary(data); err != nil {
+				return err
+			}
+			return fn(ctx, c, &pkt)
+		}
+	case func(*client.Client, *packets.CharacterAck) error:
+		return func(ctx context.Context, c *client.Client, data []byte) error {
+			var pkt packets.CharacterAck
+			if err := pkt.UnmarshalBinary(data); err != nil {
+				return err
+			}
+			return fn(c, &pkt)
+		}
+	case func(context.Context, *client.Client, *packets.ChecksumAck) error:
+		return func(ctx context.Context, c *client.Client, data []byte) error {
+			var pkt packets.ChecksumAck
+			if err := pkt.UnmarshalBinary(data); err != nil {
+				return err
+			}
+			return fn(ctx, c, &pkt)
+		}
+	case func(*client.Client, *packets.ChecksumAck) error:
+		return func(ctx context.Context, c *client.Client, data []byte) error {
+			var pkt packets.ChecksumAck
+			if err := pkt.UnmarshalBinary(data); err != nil {
+				return err
+			}
+			return fn(c, &pkt)
+		}
+	case func(context.Context, *client.Client, *packets.GuildcardHeader) error:
+		return func(ctx context.Context, c *client.Client, data []byte) error {
+			var pkt packets.GuildcardHeader
+			if err := pkt.UnmarshalBinary(data); err != nil {
+				return err
+			}
+			return fn(ctx, c, &pkt)
+		}
+	case func(*client.Client, *packets.GuildcardHeader) error:
+		return func(ctx context.Context, c *client.Client, data []byte) error {
+			var pkt packets.GuildcardHeader
+			if err := pkt.UnmarshalBinary(data); err != nil {
+				return err
+			}
+			return fn(c, &pkt)
+		}
+	case func(context.Context, *client.Client, *packets.GuildcardChunkRequest) error:
+		return func(ctx context.Context, c *client.Client, data []byte) error {
+			var pkt packets.GuildcardChunkRequest
+			if err := pkt.UnmarshalBinary(data); err != nil {
+				return err
+			}
+			return fn(ctx, c, &pkt)
+		}
+	case func(*client.Client, *packets.GuildcardChunkRequest) error:
+		return func(ctx context.Context, c *client.Client, data []byte) error {
+			var pkt packets.GuildcardChunkRequest
+			if err := pkt.UnmarshalBinary(data); err != nil {
+				return err
+			}
+			return fn(c, &pkt)
+		}
+	case func(context.Context, *client.Client, *packets.GuildcardChunk) error:
+		return func(ctx context.Context, c *client.Client, data []byte) error {
+			var pkt packets.GuildcardChunk
+			if err := pkt.UnmarshalBinary(data); err != nil {
+				return err
+			}
+			return fn(ctx, c, &pkt)
+		}
+	case func(*client.Client, *packets.GuildcardChunk) error:
+		return func(ctx context.Context, c *client.Client, data []byte) error {
+			var pkt packets.GuildcardChunk
+			if err := pkt.UnmarshalBinary(data); err != nil {
+				return err
+			}
+			return fn(c, &pkt)
+		}
+	case func(context.Context, *client.Client, *packets.ParameterHeader) error:
+		return func(ctx context.Context, c *client.Client, data []byte) error {
+			var pkt packets.ParameterHeader
+			if err := pkt.UnmarshalBinary(data); err != nil {
+				return err
+			}
+			return fn(ctx, c, &pkt)
+		}
+	case func(*client.Client, *packets.ParameterHeader) error:
+		return func(ctx context.Context, c *client.Client, data []byte) error {
+			var pkt packets.ParameterHeader
+			if err := pkt.UnmarshalBinary(data); err != nil {
+				return err
+			}
+			return fn(c, &pkt)
+		}
+	case func(context.Context, *client.Client, *packets.ParameterChunk) error:
+		return func(ctx context.Context, c *client.Client, data []byte) error {
+			var pkt packets.ParameterChunk
+			if err := pkt.UnmarshalBinary(data); err != nil {
+				return err
+			}
+			return fn(ctx, c, &pkt)
+		}
+	case func(*client.Client, *packets.ParameterChunk) error:
+		return func(ctx context.Context, c *client.Client, data []byte) error {
+			var pkt packets.ParameterChunk
+			if err := pkt.UnmarshalBinary(data); err != nil {
+				return err
+			}
+			return fn(c, &pkt)
+		}
+	case func(context.Context, *client.Client, *packets.SetFlag) error:
+		return func(ctx context.Context, c *client.Client, data []byte) error {
+			var pkt packets.SetFlag
+			if err := pkt.UnmarshalBinary(data); err != nil {
+				return err
+			}
+			return fn(ctx, c, &pkt)
+		}
+	case func(*client.Client, *packets.SetFlag) error:
+		return func(ctx context.Context, c *client.Client, data []byte) error {
+			var pkt packets.SetFlag
+			if err := pkt.UnmarshalBinary(data); err != nil {
+				return err
+			}
+			return fn(c, &pkt)
+		}
+	case func(context.Context, *client.Client, *packets.CharacterPreview) error:
+		return func(ctx context.Context, c *client.Client, data []byte) error {
+			var pkt packets.CharacterPreview
+			if err := pkt.UnmarshalBinary(data); err != nil {
+				return err
+			}
+			return fn(ctx, c, &pkt)
+		}
+	case func(*client.Client, *packets.CharacterPreview) error:
+		return func(ctx context.Context, c *client.Client, data []byte) error {
+			var pkt packets.CharacterPreview
+			if err := pkt.UnmarshalBinary(data); err != nil {
+				return err
+			}
+			return fn(c, &pkt)
+		}
+	case func(context.Context, *client.Client, *packets.CharacterSummary) error:
+		return func(ctx context.Context, c *client.Client, data []byte) error {
+			var pkt packets.CharacterSummary
+			if err := pkt.UnmarshalBinary(data); err != nil {
+				return err
+			}
+			return fn(ctx, c, &pkt)
+		}
+	case func(*client.Client, *packets.CharacterSummary) error:
+		return func(ctx context.Context, c *client.Client, data []byte) error {
+			var pkt packets.CharacterSummary
+			if err := pkt.UnmarshalBinary(data); err != nil {
+				return err
+			}
+			return fn(c, &pkt)
+		}
+	case func(context.Context, *client.Client, *packets.LoginClientMessage) error:
+		return func(ctx context.Context, c *client.Client, data []byte) error {
+			var pkt packets.LoginClientMessage
+			if err := pkt.UnmarshalBinary(data); err != nil {
+				return err
+			}
+			return fn(ctx, c, &pkt)
+		}
+	case func(*client.Client, *packets.LoginClientMessage) error:
+		return func(ctx context.Context, c *client.Client, data []byte) error {
+			var pkt packets.LoginClientMessage
+			if err := pkt.UnmarshalBinary(data); err != nil {
+				return err
+			}
+			return fn(c, &pkt)
+		}
+	case func(context.Context, *client.Client, *packets.Timestamp) error:
+		return func(ctx context.Context, c *client.Client, data []byte) error {
+			var pkt packets.Timestamp
+			if err := pkt.UnmarshalBinary(data); err != nil {
+				return err
+			}
+			return fn(ctx, c, &pkt)
+		}
+	case func(*client.Client, *packets.Timestamp) error:
+		return func(ctx context.Context, c *client.Client, data []byte) error {
+			var pkt packets.Timestamp
+			if err := pkt.UnmarshalBinary(data); err != nil {
+				return err
+			}
+			return fn(c, &pkt)
+		}
+	case func(context.Context, *client.Client, *packets.ShipListEntry) error:
+		return func(ctx context.Context, c *client.Client, data []byte) error {
+			var pkt packets.ShipListEntry
+			if err := pkt.UnmarshalBinary(data); err != nil {
+				return err
+			}
+			return fn(ctx, c, &pkt)
+		}
+	case func(*client.Client, *packets.ShipListEntry) error:
+		return func(ctx context.Context, c *client.Client, data []byte) error {
+			var pkt packets.ShipListEntry
+			if err := pkt.UnmarshalBinary(data); err != nil {
+				return err
+			}
+			return fn(c, &pkt)
+		}
+	case func(context.Context, *client.Client, *packets.ShipList) error:
+		return func(ctx context.Context, c *client.Client, data []byte) error {
+			var pkt packets.ShipList
+			if err := pkt.UnmarshalBinary(data); err != nil {
+				return err
+			}
+			return fn(ctx, c, &pkt)
+		}
+	case func(*client.Client, *packets.ShipList) error:
+		return func(ctx context.Context, c *client.Client, data []byte) error {
+			var pkt packets.ShipList
+			if err := pkt.UnmarshalBinary(data); err != nil {
+				return err
+			}
+			return fn(c, &pkt)
+		}
+	case func(context.Context, *client.Client, *packets.ScrollMessagePacket) error:
+		return func(ctx context.Context, c *client.Client, data []byte) error {
+			var pkt packets.ScrollMessagePacket
+			if err := pkt.UnmarshalBinary(data); err != nil {
+				return err
+			}
+			return fn(ctx, c, &pkt)
+		}
+	case func(*client.Client, *packets.ScrollMessagePacket) error:
+		return func(ctx context.Context, c *client.Client, data []byte) error {
+			var pkt packets.ScrollMessagePacket
+			if err := pkt.UnmarshalBinary(data); err != nil {
+				return err
+			}
+			return fn(c, &pkt)
+		}
+	case func(context.Context, *client.Client, *packets.MenuSelection) error:
+		return func(ctx context.Context, c *client.Client, data []byte) error {
+			var pkt packets.MenuSelection
+			if err := pkt.UnmarshalBinary(data); err != nil {
+				return err
+			}
+			return fn(ctx, c, &pkt)
+		}
+	case func(*client.Client, *packets.MenuSelection) error:
+		return func(ctx context.Context, c *client.Client, data []byte) error {
+			var pkt packets.MenuSelection
+			if err := pkt.UnmarshalBinary(data); err != nil {
+				return err
+			}
+			return fn(c, &pkt)
+		}
+	case func(context.Context, *client.Client, *packets.BlockList) error:
+		return func(ctx context.Context, c *client.Client, data []byte) error {
+			var pkt packets.BlockList
+			if err := pkt.UnmarshalBinary(data); err != nil {
+				return err
+			}
+			return fn(ctx, c, &pkt)
+		}
+	case func(*client.Client, *packets.BlockList) error:
+		return func(ctx context.Context, c *client.Client, data []byte) error {
+			var pkt packets.BlockList
+			if err := pkt.UnmarshalBinary(data); err != nil {
+				return err
+			}
+			return fn(c, &pkt)
+		}
+	case func(context.Context, *client.Client, *packets.Block) error:
+		return func(ctx context.Context, c *client.Client, data []byte) error {
+			var pkt packets.Block
+			if err := pkt.UnmarshalBinary(data); err != nil {
+				return err
+			}
+			return fn(ctx, c, &pkt)
+		}
+	case func(*client.Client, *packets.Block) error:
+		return func(ctx context.Context, c *client.Client, data []byte) error {
+			var pkt packets.Block
+			if err := pkt.UnmarshalBinary(data); err != nil {
+				return err
+			}
+			return fn(c, &pkt)
+		}
+	case func(context.Context, *client.Client, *packets.PatchWelcome) error:
+		return func(ctx context.Context, c *client.Client, data []byte) error {
+			var pkt packets.PatchWelcome
+			if err := pkt.UnmarshalBinary(data); err != nil {
+				return err
+			}
+			return fn(ctx, c, &pkt)
+		}
+	case func(*client.Client, *packets.PatchWelcome) error:
+		return func(ctx context.Context, c *client.Client, data []byte) error {
+			var pkt packets.PatchWelcome
+			if err := pkt.UnmarshalBinary(data); err != nil {
+				return err
+			}
+			return fn(c, &pkt)
+		}
+	case func(context.Context, *client.Client, *packets.PatchWelcomeMessage) error:
+		return func(ctx context.Context, c *client.Client, data []byte) error {
+			var pkt packets.PatchWelcomeMessage
+			if err := pkt.UnmarshalBinary(data); err != nil {
+				return err
+			}
+			return fn(ctx, c, &pkt)
+		}
+	case func(*client.Client, *packets.PatchWelcomeMessage) error:
+		return func(ctx context.Context, c *client.Client, data []byte) error {
+			var pkt packets.PatchWelcomeMessage
+			if err := pkt.UnmarshalBinary(data); err != nil {
+				return err
+			}
+			return fn(c, &pkt)
+		}
+	case func(context.Context, *client.Client, *packets.PatchRedirect) error:
+		return func(ctx context.Context, c *client.Client, data []byte) error {
+			var pkt packets.PatchRedirect
+			if err := pkt.UnmarshalBinary(data); err != nil {
+				return err
+			}
+			return fn(ctx, c, &pkt)
+		}
+	case func(*client.Client, *packets.PatchRedirect) error:
+		return func(ctx context.Context, c *client.Client, data []byte) error {
+			var pkt packets.PatchRedirect
+			if err := pkt.UnmarshalBinary(data); err != nil {
+				return err
+			}
+			return fn(c, &pkt)
+		}
+	case func(context.Context, *client.Client, *packets.ChangeDir) error:
+		return func(ctx context.Context, c *client.Client, data []byte) error {
+			var pkt packets.ChangeDir
+			if err := pkt.UnmarshalBinary(data); err != nil {
+				return err
+			}
+			return fn(ctx, c, &pkt)
+		}
+	case func(*client.Client, *packets.ChangeDir) error:
+		return func(ctx context.Context, c *client.Client, data []byte) error {
+			var pkt packets.ChangeDir
+			if err := pkt.UnmarshalBinary(data); err != nil {
+				return err
+			}
+			return fn(c, &pkt)
+		}
+	case func(context.Context, *client.Client, *packets.CheckFile) error:
+		return func(ctx context.Context, c *client.Client, data []byte) error {
+			var pkt packets.CheckFile
+			if err := pkt.UnmarshalBinary(data); err != nil {
+				return err
+			}
+			return fn(ctx, c, &pkt)
+		}
+	case func(*client.Client, *packets.CheckFile) error:
+		return func(ctx context.Context, c *client.Client, data []byte) error {
+			var pkt packets.CheckFile
+			if err := pkt.UnmarshalBinary(data); err != nil {
+				return err
+			}
+			return fn(c, &pkt)
+		}
+	case func(context.Context, *client.Client, *packets.FileStatus) error:
+		return func(ctx context.Context, c *client.Client, data []byte) error {
+			var pkt packets.FileStatus
+			if err := pkt.UnmarshalBinary(data); err != nil {
+				return err
+			}
+			return fn(ctx, c, &pkt)
+		}
+	case func(*client.Client, *packets.FileStatus) error:
+		return func(ctx context.Context, c *client.Client, data []byte) error {
+			var pkt packets.FileStatus
+			if err := pkt.UnmarshalBinary(data); err != nil {
+				return err
+			}
+			return fn(c, &pkt)
+		}
+	case func(context.Context, *client.Client, *packets.StartFileUpdate) error:
+		return func(ctx context.Context, c *client.Client, data []byte) error {
+			var pkt packets.StartFileUpdate
+			if err := pkt.UnmarshalBinary(data); err != nil {
+				return err
+			}
+			return fn(ctx, c, &pkt)
+		}
+	case func(*client.Client, *packets.StartFileUpdate) error:
+		return func(ctx context.Context, c *client.Client, data []byte) error {
+			var pkt packets.StartFileUpdate
+			if err := pkt.UnmarshalBinary(data); err != nil {
+				return err
+			}
+			return fn(c, &pkt)
+		}
+	case func(context.Context, *client.Client, *packets.FileHeader) error:
+		return func(ctx context.Context, c *client.Client, data []byte) error {
+			var pkt packets.FileHeader
+			if err := pkt.UnmarshalBinary(data); err != nil {
+				return err
+			}
+			return fn(ctx, c, &pkt)
+		}
+	case func(*client.Client, *packets.FileHeader) error:
+		return func(ctx context.Context, c *client.Client, data []byte) error {
+			var pkt packets.FileHeader
+			if err := pkt.UnmarshalBinary(data); err != nil {
+				return err
+			}
+			return fn(c, &pkt)
+		}
+	case func(context.Context, *client.Client, *packets.FileChunk) error:
+		return func(ctx context.Context, c *client.Client, data []byte) error {
+			var pkt packets.FileChunk
+			if err := pkt.UnmarshalBinary(data); err != nil {
+				return err
+			}
+			return fn(ctx, c, &pkt)
+		}
+	case func(*client.Client, *packets.FileChunk) error:
+		return func(ctx context.Context, c *client.Client, data []byte) error {
+			var pkt packets.FileChunk
+			if err := pkt.UnmarshalBinary(data); err != nil {
+				return err
+			}
+			return fn(c, &pkt)
+		}
+	}
+	return nil
+}
@@ -0,0 +1,115 @@
+package internal
+
+import (
+	"context"
+	"errors"
+	"testing"
+
+	"github.com/dcrodman/archon/internal/client"
+	"github.com/dcrodman/archon/internal/core/bytes"
+	"github.com/dcrodman/archon/internal/packets"
+)
+
+// Packets not generated from the schema can't be decoded without reflection.
+type unsupportedPacket struct {
+	Header packets.BBHeader
+	Value  uint32
+}
+
+func TestPacketHandlers_Register(t *testing.T) {
+	tests := map[string]struct {
+		handler   interface{}
+		wantPanic bool
+	}{
+		"context_and_packet": {handler: func(ctx context.Context, c *client.Client, pkt *packets.SetFlag) error { return nil }},
+		"packet":             {handler: func(c *client.Client, pkt *packets.SetFlag) error { return nil }},
+		"context":            {handler: func(ctx context.Context, c *client.Client) error { return nil }},
+		"client_only":        {handler: func(c *client.Client) error { return nil }},
+		"not_a_function":     {handler: "handler", wantPanic: true},
+		"no_client":          {handler: func(pkt *packets.SetFlag) error { return nil }, wantPanic: true},
+		"no_error":           {handler: func(c *client.Client) {}, wantPanic: true},
+		"packet_by_value":    {handler: func(c *client.Client, pkt packets.SetFlag) error { return nil }, wantPanic: true},
+		"too_many_arguments": {handler: func(c *client.Client, pkt *packets.SetFlag, n int) error { return nil }, wantPanic: true},
+		"not_in_schema":      {handler: func(c *client.Client, pkt *unsupportedPacket) error { return nil }, wantPanic: true},
+	}
+
+	for name, tt := range tests {
+		t.Run(name, func(t *testing.T) {
+			defer func() {
+				if r := recover(); (r != nil) != tt.wantPanic {
+					t.Errorf("expected panic = %v, got = %v", tt.wantPanic, r)
+				}
+			}()
+			NewPacketHandlers().Register(0x01, tt.handler)
+		})
+	}
+}
+
+func TestPacketHandlers_Dispatch(t *testing.T) {
+	tests := map[string]struct {
+		// States through which the client is moved before dispatching.
+		states  []client.State
+		data    []byte
+		wantErr error
+		// Value expected to have been decoded from the packet (if handled).
+		wantValue uint32
+	}{
+		"decodes_packet": {
+			states:    []client.State{client.Handshaken, client.Authenticated},
+			data:      []byte{0x0C, 0x00, 0xEC, 0x00, 0x00, 0x00, 0x00, 0x00, 0x2A, 0x00, 0x00, 0x00},
+			wantValue: 0x2A,
+		},
+		"wrong_state": {
+			states:  []client.State{client.Handshaken},
+			data:    []byte{0x0C, 0x00, 0xEC, 0x00, 0x00, 0x00, 0x00, 0x00, 0x2A, 0x00, 0x00, 0x00},
+			wantErr: errUnexpectedPacket,
+		},
+		"short_packet": {
+			states:  []client.State{client.Handshaken, client.Authenticated},
+			data:    []byte{0x08, 0x00, 0xEC, 0x00, 0x00, 0x00, 0x00, 0x00},
+			wantErr: bytes.ErrNotEnoughData,
+		},
+	}
+
+	for name, tt := range tests {
+		t.Run(name, func(t *testing.T) {
+			var value uint32
+			h := NewPacketHandlers()
+			h.Register(0x01, func(ctx context.Context, c *client.Client, pkt *packets.SetFlag) error {
+				value = pkt.Flag
+				return nil
+			}, client.Authenticated)
+
+			c := &client.Client{}
+			for _, state := range tt.states {
+				if err := c.Transition(state); err != nil {
+					t.Fatalf("unexpected error moving client to %s: %v", state, err)
+				}
+			}
+			err := h.dispatch(context.Background(), c, 0x01, tt.data)
+			if !errors.Is(err, tt.wantErr) {
+				t.Fatalf("expected error = %v, got = %v", tt.wantErr, err)
+			}
+			if value != tt.wantValue {
+				t.Errorf("expected value = %#x, got = %#x", tt.wantValue, value)
+			}
+		})
+	}
+}
+
+func TestPacketHandlers_Ignore(t *testing.T) {
+	h := NewPacketHandlers()
+	h.Ignore(0x05, 0x1D)
+
+	for _, packetType := range []uint16{0x05, 0x1D} {
+		if !h.handles(packetType) {
+			t.Errorf("expected packet %#04x to be handled", packetType)
+		}
+		if err := h.dispatch(context.Background(), &client.Client{}, packetType, nil); err != nil {
+			t.Errorf("expected no error dispatching %#04x, got %v", packetType, err)
+		}
+	}
+	if h.handles(0x01) {
+		t.Error("expected packet 0x01 not to be handled")
+	}
+}
@@ -0,0 +1,29 @@
+package packets
+
+import (
+	"fmt"
+
+	"github.com/dcrodman/archon/internal/core/bytes"
+)
+
+// Appender is implemented by packets that can be encoded directly into an existing
+// buffer, which allows the buffer to be reused across packets.
+type Appender interface {
+	// AppendBinary appends the encoded packet to b and returns the extended buffer.
+	AppendBinary(b []byte) ([]byte, error)
+}
+
+func appendUint16(b []byte, v uint16) []byte {
+	return append(b, byte(v), byte(v>>8))
+}
+
+func appendUint32(b []byte, v uint32) []byte {
+	return append(b, byte(v), byte(v>>8), byte(v>>16), byte(v>>24))
+}
+
+// notEnoughData returns the error for data too short to decode the named packet type.
+// It takes the name rather than the packet itself in order to avoid the packet
+// escaping to the heap when decoded successfully.
+func notEnoughData(typeName string, data []byte) error {
+	return fmt.Errorf("%w to decode packets.%s from %d bytes", bytes.ErrNotEnoughData, typeName, len(data))
+}
@@ -0,0 +1,163 @@
+package packets
+
+import (
+	"encoding"
+	"errors"
+	"math/rand"
+	"reflect"
+	"testing"
+	"testing/quick"
+
+	"github.com/dcrodman/archon/internal/core/bytes"
+)
+
+type packet interface {
+	encoding.BinaryMarshaler
+	encoding.BinaryUnmarshaler
+	Appender
+	BinarySize() int
+}
+
+// Every type defined in the package, which should all have codecs.
+func allPackets() []packet {
+	return []packet{
+		&PCHeader{},
+		&BBHeader{},
+		&ClientConfig{},
+		&PatchWelcome{},
+		&PatchWelcomeMessage{},
+		&PatchRedirect{},
+		&ChangeDir{},
+		&CheckFile{},
+		&FileStatus{},
+		&StartFileUpdate{},
+		&FileHeader{},
+		&FileChunk{},
+		&Welcome{},
+		&Login{},
+		&Security{},
+		&Redirect{},
+		&Options{},
+		&CharacterSelection{},
+		&CharacterAck{},
+		&ChecksumAck{},
+		&GuildcardHeader{},
+		&GuildcardChunkRequest{},
+		&GuildcardChunk{},
+		&ParameterHeader{},
+		&ParameterChunk{},
+		&SetFlag{},
+		&CharacterPreview{},
+		&CharacterSummary{},
+		&LoginClientMessage{},
+		&Timestamp{},
+		&ShipListEntry{},
+		&ShipList{},
+		&ScrollMessagePacket{},
+		&MenuSelection{},
+		&BlockList{},
+		&Block{},
+		&ServerMessage{},
+		&LobbyListEntry{},
+		&LobbyList{},
+		&InventoryItem{},
+		&InventorySlot{},
+		&BankItem{},
+		&FullCharacter{},
+	}
+}
+
+func TestCodecsMatchReflection(t *testing.T) {
+	r := rand.New(rand.NewSource(1))
+
+	for _, p := range allPackets() {
+		typ := reflect.TypeOf(p).Elem()
+		t.Run(typ.Name(), func(t *testing.T) {
+			for i := 0; i < 10; i++ {
+				value, ok := quick.Value(typ, r)
+				if !ok {
+					t.Fatalf("failed to generate %s", typ)
+				}
+				pkt := value.Addr().Interface().(packet)
+
+				expected, size := bytes.BytesFromStruct(pkt)
+				encoded, err := pkt.MarshalBinary()
+				if err != nil {
+					t.Fatalf("unexpected error encoding: %v", err)
+				}
+				if !reflect.DeepEqual(encoded, expected) {
+					t.Fatalf("expected encoding to match BytesFromStruct:\n%x\ngot:\n%x", expected, encoded)
+				}
+				if pkt.BinarySize() != size {
+					t.Errorf("expected BinarySize() = %d, got = %d", size, pkt.BinarySize())
+				}
+
+				decoded := reflect.New(typ).Interface().(packet)
+				if err := decoded.UnmarshalBinary(encoded); err != nil {
+					t.Fatalf("unexpected error decoding: %v", err)
+				}
+				reencoded, _ := decoded.AppendBinary(nil)
+				if !reflect.DeepEqual(reencoded, encoded) {
+					t.Fatalf("expected decoded packet to encode to:\n%x\ngot:\n%x", encoded, reencoded)
+				}
+			}
+		})
+	}
+}
+
+func TestCodecsRejectShortData(t *testing.T) {
+	for _, p := range allPackets() {
+		typ := reflect.TypeOf(p).Elem()
+		t.Run(typ.Name(), func(t *testing.T) {
+			size := reflect.Zero(typ).Interface().(interface{ BinarySize() int }).BinarySize()
+			pkt := reflect.New(typ).Interface().(packet)
+			if err := pkt.UnmarshalBinary(make([]byte, size-1)); !errors.Is(err, bytes.ErrNotEnoughData) {
+				t.Errorf("expected ErrNotEnoughData, got %v", err)
+			}
+		})
+	}
+}
+
+func newFullCharacter() *FullCharacter {
+	value, _ := quick.Value(reflect.TypeOf(FullCharacter{}), rand.New(rand.NewSource(1)))
+	return value.Addr().Interface().(*FullCharacter)
+}
+
+func BenchmarkFullCharacter_BytesFromStruct(b *testing.B) {
+	pkt := newFullCharacter()
+	b.ReportAllocs()
+	b.ResetTimer()
+	for i := 0; i < b.N; i++ {
+		bytes.BytesFromStruct(pkt)
+	}
+}
+
+func BenchmarkFullCharacter_AppendBinary(b *testing.B) {
+	pkt := newFullCharacter()
+	buf := make([]byte, 0, pkt.BinarySize())
+	b.ReportAllocs()
+	b.ResetTimer()
+	for i := 0; i < b.N; i++ {
+		buf, _ = pkt.AppendBinary(buf[:0])
+	}
+}
+
+func BenchmarkLogin_StructFromBytes(b *testing.B) {
+	data, _ := (&Login{}).MarshalBinary()
+	b.ReportAllocs()
+	b.ResetTimer()
+	for i := 0; i < b.N; i++ {
+		var pkt Login
+		_ = bytes.StructFromBytes(data, &pkt)
+	}
+}
+
+func BenchmarkLogin_UnmarshalBinary(b *testing.B) {
+	data, _ := (&Login{}).MarshalBinary()
+	b.ReportAllocs()
+	b.ResetTimer()
+	for i := 0; i < b.N; i++ {
+		var pkt Login
+		_ = pkt.UnmarshalBinary(data)
+	}
+}
@@ -1,26 +1,20 @@
 // Packets used by multiple server types.
+//
+// Every packet type implements encoding.BinaryMarshaler and encoding.BinaryUnmarshaler
+// with a codec generated from its schema, which is considerably faster than the
+// reflection-based bytes.BytesFromStruct and bytes.StructFromBytes while producing
+// the same output. Trailing slices (such as a message) are decoded from whatever data
+// remains after the fixed-size fields.
 package packets
 
+// The packet structs and packet type constants are generated from the files in schema/.
+//go:generate go run ../../cmd/packetgen -schema schema -packets . -analyzer ../../cmd/analyzer -handlers ..
+
 const (
 	PCHeaderSize = 0x04
 	BBHeaderSize = 0x08
 )
 
-// Blueburst, PC, and Gamecube clients all use a 4 byte header to
-// communicate with the patch server instead of the 8 byte one used
-// by Blueburst for the other servers.
-type PCHeader struct {
-	Size uint16
-	Type uint16
-}
-
-// Packet header for every packet sent between the server and BlueBurst clients.
-type BBHeader struct {
-	Size  uint16
-	Type  uint16
-	Flags uint32
-}
-
 // Error codes used by the 0xE6 security/auth response packet.
 type BBLoginError uint32
 
@@ -39,22 +33,3 @@ const (
 	BBLoginErrorPatch
 	BBLoginErrorDisconnect
 )
-
-// Packet types common to multiple servers.
-const (
-	DisconnectType = 0x05
-	RedirectType   = 0x19
-	MenuSelectType = 0x10
-)
-
-type ClientConfig struct {
-	// The rest of this holds various portions of client state to represent
-	// the client's progression through the login process.
-	Magic        uint32 // Must be set to 0x48615467
-	CharSelected uint8  // Has a character been selected?
-	SlotNum      uint8  // Slot number of selected Character
-	Flags        uint16
-	Ports        [4]uint16
-	Unused       [4]uint32
-	Unused2      [2]uint32
-}
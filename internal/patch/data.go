@@ -9,8 +9,8 @@ import (
 	"strconv"
 
 	"github.com/dcrodman/archon"
+	"github.com/dcrodman/archon/internal"
 	"github.com/dcrodman/archon/internal/client"
-	"github.com/dcrodman/archon/internal/core/bytes"
 	"github.com/dcrodman/archon/internal/packets"
 )
 
@@ -20,17 +20,19 @@ import (
 // corresponding client files (or do not exist), this server allows the client to
 // download the correct file contents and forces a restart.
 type DataServer struct {
-	name string
+	name     string
+	patchDir string
 }
 
-func NewDataServer(name string) *DataServer {
-	return &DataServer{name: name}
+func NewDataServer(name string, cfg *archon.Config) *DataServer {
+	return &DataServer{name: name, patchDir: cfg.PatchServer.PatchDir}
 }
 
-func (s DataServer) Name() string { return s.name }
+func (s DataServer) Name() string       { return s.name }
+func (s DataServer) MaxPacketSize() int { return maxClientPacketSize }
 
 func (s *DataServer) Init(ctx context.Context) error {
-	return initializePatchData()
+	return initializePatchData(s.patchDir)
 }
 
 func (s *DataServer) SetUpClient(c *client.Client) {
@@ -51,27 +53,11 @@ func (s *DataServer) Handshake(c *client.Client) error {
 	return c.SendRaw(pkt)
 }
 
-func (s *DataServer) Handle(ctx context.Context, c *client.Client, data []byte) error {
-	var hdr packets.PCHeader
-
-	bytes.StructFromBytes(data[:packets.PCHeaderSize], &hdr)
-
-	var err error
-	switch hdr.Type {
-	case packets.PatchWelcomeType:
-		err = s.sendWelcomeAck(c)
-	case packets.PatchHandshakeType:
-		err = s.handlePatchLogin(c)
-	case packets.PatchFileStatusType:
-		var fileStatus packets.FileStatus
-		bytes.StructFromBytes(data, &fileStatus)
-		s.handleFileStatus(c, &fileStatus)
-	case packets.PatchClientListDoneType:
-		err = s.updateClientFiles(c)
-	default:
-		archon.Log.Infof("received unknown packet %02x from %s", hdr.Type, c.IPAddr())
-	}
-	return err
+func (s *DataServer) RegisterHandlers(h *internal.PacketHandlers) {
+	h.Register(packets.PatchWelcomeType, s.sendWelcomeAck, client.Handshaken)
+	h.Register(packets.PatchHandshakeType, s.handlePatchLogin, client.Handshaken)
+	h.Register(packets.PatchFileStatusType, s.handleFileStatus, client.Handshaken)
+	h.Register(packets.PatchClientListDoneType, s.updateClientFiles, client.Handshaken)
 }
 
 // Simple acknowledgement to the welcome response.
@@ -157,12 +143,13 @@ func (s *DataServer) sendFileListDone(c *client.Client) error {
 
 // The client sent us a checksum for one of the patch files. Compare it to what we
 // have and add it to the list of files to update if there is any discrepancy.
-func (s *DataServer) handleFileStatus(c *client.Client, fileStatus *packets.FileStatus) {
+func (s *DataServer) handleFileStatus(c *client.Client, fileStatus *packets.FileStatus) error {
 	patchFile := patchIndex[fileStatus.PatchID]
 
 	if fileStatus.Checksum != patchFile.checksum || fileStatus.FileSize != patchFile.fileSize {
 		c.FilesToUpdate[int(fileStatus.PatchID)] = patchFile
 	}
+	return nil
 }
 
 // The client finished sending all of the file check packets. If they have
@@ -231,7 +218,7 @@ func (s *DataServer) updateClientFile(c *client.Client, patch *fileEntry) error
 
 	file, err := os.Open(patch.path)
 	if err != nil {
-		archon.Log.Error(err.Error())
+		c.Logger().Error(err.Error())
 		return err
 	}
 
@@ -2,40 +2,49 @@ package patch
 
 import (
 	"context"
-	"strconv"
 	"sync"
 
-	"github.com/spf13/viper"
-
 	"github.com/dcrodman/archon"
+	"github.com/dcrodman/archon/internal"
 	"github.com/dcrodman/archon/internal/client"
 	"github.com/dcrodman/archon/internal/core/bytes"
 	"github.com/dcrodman/archon/internal/packets"
 )
 
-// Convert the welcome message to UTF-16LE and cache it. PSOBB expects this prefix to the message,
-//not completely sure why. Language perhaps?
+// Packets sent by clients to the PATCH and DATA servers are all small.
+const maxClientPacketSize = 0x400
 
 var (
 	messageBytes []byte
-	messageInit  sync.Once
+	messageMutex sync.RWMutex
 
 	// Copyright message expected by the client for the patch welcome.
 	copyright = []byte("Patch Server. Copyright SonicTeam, LTD. 2001")
 )
 
-func getWelcomeMessage() ([]byte, uint16) {
-	messageInit.Do(func() {
-		messageBytes = bytes.ConvertToUtf16(viper.GetString("patch_server.welcome_message"))
+// SetWelcomeMessage replaces the message displayed on the patch screen for any
+// clients that connect from now on.
+func SetWelcomeMessage(message string) {
+	// Convert the welcome message to UTF-16LE and cache it. PSOBB expects this prefix to the message,
+	// not completely sure why. Language perhaps?
+	encoded := bytes.ConvertToUtf16(message)
 
-		if len(messageBytes) > (1 << 16) {
-			archon.Log.Warn("patch server welcome message exceeds 65,000 characters")
-			messageBytes = messageBytes[:1<<16-2]
-		}
-		// Set the unicode byte order mark appropriately since we use LE encoding.
-		messageBytes = append([]byte{0xFF, 0xFE}, messageBytes...)
-	})
+	if len(encoded) > (1 << 16) {
+		archon.Log.Warn("patch server welcome message exceeds 65,000 characters")
+		encoded = encoded[:1<<16-2]
+	}
+	// Set the unicode byte order mark appropriately since we use LE encoding.
+	encoded = append([]byte{0xFF, 0xFE}, encoded...)
 
+	messageMutex.Lock()
+	messageBytes = encoded
+	messageMutex.Unlock()
+}
+
+// getWelcomeMessage returns the encoded welcome message and its size.
+func getWelcomeMessage() ([]byte, uint16) {
+	messageMutex.RLock()
+	defer messageMutex.RUnlock()
 	return messageBytes, uint16(len(messageBytes))
 }
 
@@ -46,18 +55,37 @@ type Server struct {
 	name string
 	// Parsed representation of the login port.
 	dataRedirectPort uint16
+	welcomeMessage   string
 }
 
-func NewServer(name, dataPort string) *Server {
+func NewServer(name string, cfg *archon.Config) *Server {
 	// Convert the data port to a BE uint for the redirect packet.
-	parsedDataPort, _ := strconv.ParseUint(dataPort, 10, 16)
-	dataRedirectPort := uint16((parsedDataPort >> 8) | (parsedDataPort << 8))
+	dataPort := uint16(cfg.PatchServer.DataPort)
+	dataRedirectPort := (dataPort >> 8) | (dataPort << 8)
 
-	return &Server{name: name, dataRedirectPort: dataRedirectPort}
+	return &Server{
+		name:             name,
+		dataRedirectPort: dataRedirectPort,
+		welcomeMessage:   cfg.PatchServer.WelcomeMessage,
+	}
 }
 
-func (s *Server) Name() string                   { return s.name }
-func (s *Server) Init(ctx context.Context) error { return nil }
+func (s *Server) Name() string       { return s.name }
+func (s *Server) MaxPacketSize() int { return maxClientPacketSize }
+
+func (s *Server) Init(ctx context.Context) error {
+	SetWelcomeMessage(s.welcomeMessage)
+
+	// Pick up changes to the welcome message made in the config file.
+	archon.OnConfigReload(func(cfg *archon.Config, changed []string) {
+		for _, key := range changed {
+			if key == "patch_server.welcome_message" {
+				SetWelcomeMessage(cfg.PatchServer.WelcomeMessage)
+			}
+		}
+	})
+	return nil
+}
 
 func (s *Server) SetUpClient(c *client.Client) {
 	c.CryptoSession = client.NewPCCryptoSession()
@@ -76,22 +104,9 @@ func (s *Server) Handshake(c *client.Client) error {
 	return c.SendRaw(pkt)
 }
 
-func (s *Server) Handle(ctx context.Context, c *client.Client, data []byte) error {
-	var header packets.PCHeader
-	bytes.StructFromBytes(data[:packets.PCHeaderSize], &header)
-
-	var err error
-	switch header.Type {
-	case packets.PatchWelcomeType:
-		err = s.sendWelcomeAck(c)
-	case packets.PatchHandshakeType:
-		if err = s.sendWelcomeMessage(c); err == nil {
-			err = s.sendPatchRedirect(c)
-		}
-	default:
-		archon.Log.Infof("received unknown packet %2x from %s", header.Type, c.IPAddr())
-	}
-	return err
+func (s *Server) RegisterHandlers(h *internal.PacketHandlers) {
+	h.Register(packets.PatchWelcomeType, s.sendWelcomeAck, client.Handshaken)
+	h.Register(packets.PatchHandshakeType, s.handlePatchLogin, client.Handshaken)
 }
 
 func (s *Server) sendWelcomeAck(c *client.Client) error {
@@ -102,6 +117,15 @@ func (s *Server) sendWelcomeAck(c *client.Client) error {
 	})
 }
 
+// Once the client has authenticated, display the welcome message and send them
+// to the DATA server.
+func (s *Server) handlePatchLogin(c *client.Client) error {
+	if err := s.sendWelcomeMessage(c); err != nil {
+		return err
+	}
+	return s.sendPatchRedirect(c)
+}
+
 // Message displayed on the patch download screen.
 func (s *Server) sendWelcomeMessage(c *client.Client) error {
 	message, size := getWelcomeMessage()
@@ -120,13 +144,15 @@ func (s *Server) sendWelcomeMessage(c *client.Client) error {
 func (s *Server) sendPatchRedirect(c *client.Client) error {
 	pkt := packets.PatchRedirect{
 		Header:  packets.PCHeader{Type: packets.PatchRedirectType},
-		IPAddr:  [4]uint8{},
 		Port:    s.dataRedirectPort,
 		Padding: 0,
 	}
 
-	hostnameBytes := archon.BroadcastIP()
-	copy(pkt.IPAddr[:], hostnameBytes[:])
+	ip, err := archon.RedirectIP(c.IPAddr())
+	if err != nil {
+		return err
+	}
+	pkt.IPAddr = ip
 
 	return c.Send(pkt)
 }
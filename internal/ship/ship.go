@@ -2,16 +2,19 @@ package ship
 
 import (
 	"context"
+	"errors"
 	"fmt"
 	"net"
 	"strconv"
 	"strings"
+	"sync"
+	"time"
 
-	"github.com/spf13/viper"
-	"google.golang.org/grpc"
-	"google.golang.org/grpc/credentials"
+	"google.golang.org/grpc/codes"
+	"google.golang.org/grpc/status"
 
 	"github.com/dcrodman/archon"
+	"github.com/dcrodman/archon/internal"
 	"github.com/dcrodman/archon/internal/client"
 	"github.com/dcrodman/archon/internal/core/auth"
 	"github.com/dcrodman/archon/internal/core/bytes"
@@ -36,6 +39,18 @@ type Block struct {
 	Name    string
 	Address string
 	ID      int
+	// Frontend serving the block, whose population is reported to the shipgate.
+	Frontend *internal.Frontend
+}
+
+// blockBackend is implemented by the BLOCK server.
+type blockBackend interface {
+	Lobbies() int
+	Games() int
+	// HandleEnvelope delivers an envelope from the message bus to the block's players.
+	HandleEnvelope(envelope *api.Envelope)
+	// SetPublisher sets the function the block uses to publish envelopes.
+	SetPublisher(publish func(*api.Envelope) error)
 }
 
 // Server is the SHIP server implementation. This is similar to PATCH and LOGIN
@@ -47,60 +62,202 @@ type Server struct {
 	blocks             []Block
 	grpcShipgateClient api.ShipgateServiceClient
 	shipGateClient     *shipgate.Client
+	messageBus         *shipgate.MessageBus
 	shipGateAddr       string
+	config             *archon.Config
+	// Frontend serving this ship, if set.
+	frontend *internal.Frontend
+
+	// Addresses at which players reach the ship (see register), which are kept up
+	// to date with the config.
+	externalIP        string
+	redirectAddresses []archon.RedirectAddressConfig
+	addressesMutex    sync.Mutex
 }
 
-func NewServer(name string, blocks []Block, shipgateAddr string) *Server {
-	return &Server{
-		name:         name,
-		blocks:       blocks,
-		shipGateAddr: shipgateAddr,
+func NewServer(name string, blocks []Block, shipgateAddr string, cfg *archon.Config) *Server {
+	s := &Server{
+		name:              name,
+		blocks:            blocks,
+		shipGateAddr:      shipgateAddr,
+		config:            cfg,
+		externalIP:        cfg.ExternalIP,
+		redirectAddresses: cfg.RedirectAddresses,
+	}
+	// The blocks reach players on other ships through the ship's message bus.
+	for _, block := range blocks {
+		if block.Frontend == nil {
+			continue
+		}
+		if backend, ok := block.Frontend.Backend.(blockBackend); ok {
+			backend.SetPublisher(s.Publish)
+		}
 	}
+	return s
 }
 
 func (s *Server) Name() string {
 	return s.name
 }
 
+// SetFrontend sets the Frontend serving the ship, whose population is included in
+// the heartbeats sent to the shipgate.
+func (s *Server) SetFrontend(f *internal.Frontend) {
+	s.frontend = f
+}
+
 // Init connects the ship to the shipgate and registers so that it
 // can begin receiving players.
 func (s *Server) Init(ctx context.Context) error {
 	var err error
-	s.shipGateClient, err = shipgate.NewClient(s.shipGateAddr)
+	s.shipGateClient, err = shipgate.NewClient(s.shipGateAddr, s.config)
 	if err != nil {
 		return err
 	}
 
 	// Connect to the shipgate.
-	creds, err := credentials.NewClientTLSFromFile(viper.GetString("shipgate_certificate_file"), "")
-	if err != nil {
-		return fmt.Errorf("failed to load certificate file for shipgate: %s", err)
-	}
-
-	conn, err := grpc.Dial(viper.GetString("ship_server.shipgate_address"), grpc.WithTransportCredentials(creds))
+	conn, err := shipgate.Dial(s.shipGateAddr, s.config)
 	if err != nil {
 		return fmt.Errorf("failed to connect to shipgate: %s", err)
 	}
 
 	s.grpcShipgateClient = api.NewShipgateServiceClient(conn)
 
-	// Register this ship with the shipgate so that it can start accepting players.
-	_, err = s.grpcShipgateClient.RegisterShip(ctx, &api.RegistrationRequest{
-		Name:    viper.GetString("ship_server.name"),
-		Port:    viper.GetString("ship_server.port"),
-		Address: viper.GetString("hostname"),
+	// Register this ship with the shipgate so that it can start accepting players. If
+	// the shipgate isn't up yet, the heartbeat loop registers once it's reachable.
+	if err := s.register(ctx); errors.Is(err, shipgate.ErrUnavailable) {
+		archon.Log.Warn(err)
+	} else if err != nil {
+		return err
+	}
+	go s.startHeartbeatLoop(ctx)
+
+	// Register again when the addresses change so that players are sent to the new ones.
+	archon.OnConfigReload(func(cfg *archon.Config, changed []string) {
+		for _, key := range changed {
+			if key == "external_ip" || key == "redirect_addresses" {
+				s.addressesMutex.Lock()
+				s.externalIP, s.redirectAddresses = cfg.ExternalIP, cfg.RedirectAddresses
+				s.addressesMutex.Unlock()
+
+				go func() {
+					if err := s.register(ctx); err != nil {
+						archon.Log.Error(err)
+					}
+				}()
+				return
+			}
+		}
 	})
-	if err != nil {
-		return fmt.Errorf("error registering with shipgate: %v", err)
+
+	// Connect to the message bus so that players can be reached from other ships.
+	s.messageBus = shipgate.NewMessageBus(s.grpcShipgateClient, s.config.ShipServer.Name, s.config.ShipServer.Secret, s.deliverEnvelope)
+	s.messageBus.Start(ctx)
+
+	// Keep the ship list up to date with the ships registered with the shipgate.
+	s.shipGateClient.StartShipListWatch(ctx)
+
+	return nil
+}
+
+func (s *Server) register(ctx context.Context) error {
+	req := &api.RegistrationRequest{
+		Name: s.config.ShipServer.Name,
+		Port: strconv.Itoa(s.config.ShipServer.Port),
 	}
-	// Start the loop that retrieves the ship list from the shipgate.
-	if err := s.shipGateClient.StartShipRefreshLoop(ctx); err != nil {
-		return err
+	// Players are sent here from the ship selection screen, so these need to be the
+	// addresses they can reach this server at rather than the one it binds to. As
+	// with the block redirects, players on the subnets in redirect_addresses are
+	// sent to the addresses given for them.
+	s.addressesMutex.Lock()
+	req.Address = s.externalIP
+	for _, redirect := range s.redirectAddresses {
+		req.RedirectAddresses = append(req.RedirectAddresses, &api.RedirectAddress{
+			Subnet:  redirect.Subnet,
+			Address: redirect.Address,
+		})
 	}
+	s.addressesMutex.Unlock()
 
+	ctx = shipgate.WithShipSecret(ctx, s.config.ShipServer.Secret)
+	_, err := s.grpcShipgateClient.RegisterShip(ctx, req)
+	if err != nil {
+		return fmt.Errorf("error registering with shipgate: %w", err)
+	}
 	return nil
 }
 
+// startHeartbeatLoop periodically reports the ship's population to the shipgate,
+// which stops sending players to ships that miss their heartbeats.
+func (s *Server) startHeartbeatLoop(ctx context.Context) {
+	for {
+		select {
+		case <-ctx.Done():
+			return
+		case <-time.After(shipgate.ShipHeartbeatInterval):
+			s.sendHeartbeat(ctx)
+		}
+	}
+}
+
+func (s *Server) sendHeartbeat(ctx context.Context) {
+	_, err := s.grpcShipgateClient.Heartbeat(shipgate.WithShipSecret(ctx, s.config.ShipServer.Secret), s.heartbeat())
+	switch {
+	case status.Code(err) == codes.NotFound:
+		// The shipgate has forgotten about us (probably due to a restart or a network
+		// partition), so register again in order to start receiving players again.
+		archon.Log.Warnf("%s is no longer registered with the shipgate; registering again", s.name)
+		if err := s.register(ctx); err != nil {
+			archon.Log.Error(err)
+		}
+	case err != nil && ctx.Err() == nil:
+		archon.Log.Errorf("failed to send heartbeat to shipgate: %v", err)
+	}
+}
+
+// Publish sends the envelope to the players it's addressed to on every ship
+// (including this one) through the shipgate's message bus. It returns
+// ErrUnavailable until the ship has been initialized.
+func (s *Server) Publish(envelope *api.Envelope) error {
+	if s.messageBus == nil {
+		return shipgate.ErrUnavailable
+	}
+	return s.messageBus.Publish(envelope)
+}
+
+// deliverEnvelope passes an envelope received from the message bus on to the blocks.
+func (s *Server) deliverEnvelope(envelope *api.Envelope) {
+	for _, block := range s.blocks {
+		if block.Frontend == nil {
+			continue
+		}
+		if backend, ok := block.Frontend.Backend.(blockBackend); ok {
+			backend.HandleEnvelope(envelope)
+		}
+	}
+}
+
+// heartbeat returns the current population of the ship and its blocks.
+func (s *Server) heartbeat() *api.ShipHeartbeat {
+	heartbeat := &api.ShipHeartbeat{Name: s.config.ShipServer.Name}
+	if s.frontend != nil {
+		heartbeat.PlayerCount = int32(s.frontend.ClientCount())
+	}
+	for _, block := range s.blocks {
+		blockStatus := &api.ShipHeartbeat_Block{Name: block.Name}
+		if block.Frontend != nil {
+			blockStatus.PlayerCount = int32(block.Frontend.ClientCount())
+			if backend, ok := block.Frontend.Backend.(blockBackend); ok {
+				blockStatus.Lobbies = int32(backend.Lobbies())
+				blockStatus.Games = int32(backend.Games())
+			}
+		}
+		heartbeat.PlayerCount += blockStatus.PlayerCount
+		heartbeat.Blocks = append(heartbeat.Blocks, blockStatus)
+	}
+	return heartbeat
+}
+
 func (s *Server) SetUpClient(c *client.Client) {
 	c.CryptoSession = client.NewBlueBurstCryptoSession()
 	c.DebugTags["server_type"] = "ship"
@@ -120,44 +277,42 @@ func (s *Server) Handshake(c *client.Client) error {
 	return c.SendRaw(pkt)
 }
 
-func (s *Server) Handle(ctx context.Context, c *client.Client, data []byte) error {
-	var header packets.BBHeader
-	bytes.StructFromBytes(data[:packets.BBHeaderSize], &header)
-
-	var err error
-	switch header.Type {
-	case packets.LoginType:
-		var loginPkt packets.Login
-		bytes.StructFromBytes(data, &loginPkt)
-		err = s.handleShipLogin(ctx, c, &loginPkt)
-	case packets.MenuSelectType:
-		var menuSelectPkt packets.MenuSelection
-		bytes.StructFromBytes(data, &menuSelectPkt)
-		err = s.handleMenuSelection(c, &menuSelectPkt)
-	default:
-		archon.Log.Infof("received unknown packet %02x from %s", header.Type, c.IPAddr())
-	}
-	return err
+func (s *Server) RegisterHandlers(h *internal.PacketHandlers) {
+	h.Register(packets.LoginType, s.handleShipLogin, client.Handshaken)
+	h.Register(packets.MenuSelectType, s.handleMenuSelection, client.Authenticated)
+	// Response to a keepalive ping; nothing to do.
+	h.Ignore(packets.PingType)
 }
 
 func (s *Server) handleShipLogin(ctx context.Context, c *client.Client, loginPkt *packets.Login) error {
 	username := string(bytes.StripPadding(loginPkt.Username[:]))
-	password := string(bytes.StripPadding(loginPkt.Password[:]))
 
-	if _, err := s.shipGateClient.AuthenticateAccount(ctx, username, password); err != nil {
+	// The client echoes the config it was sent by the previous server, which
+	// carries the session token it was issued when it logged in.
+	if err := c.Config.UnmarshalBinary(loginPkt.Security[:]); err != nil {
+		return err
+	}
+	account, err := s.shipGateClient.AuthenticateSessionToken(ctx, username, c.Config.SessionToken[:])
+	if err != nil {
 		switch err {
 		case auth.ErrInvalidCredentials:
 			return s.sendSecurity(c, packets.BBLoginErrorPassword)
 		case auth.ErrAccountBanned:
 			return s.sendSecurity(c, packets.BBLoginErrorBanned)
+		case shipgate.ErrUnavailable:
+			return s.SendMessage(c, shipgate.UnavailableMessage)
 		default:
-			sendErr := s.sendMessage(c, strings.Title(err.Error()))
+			sendErr := s.SendMessage(c, strings.Title(err.Error()))
 			if sendErr == nil {
 				return sendErr
 			}
 			return err
 		}
 	}
+	c.SetAccount(account)
+	if err := c.Transition(client.Authenticated); err != nil {
+		return err
+	}
 
 	if err := s.sendSecurity(c, packets.BBLoginErrorNone); err != nil {
 		return err
@@ -172,13 +327,14 @@ func (s *Server) sendSecurity(c *client.Client, errorCode uint32) error {
 		ErrorCode:    errorCode,
 		PlayerTag:    0x00010000,
 		Guildcard:    c.Guildcard,
-		TeamID:       c.TeamID,
+		TeamID:       c.TeamID(),
 		Config:       c.Config,
 		Capabilities: 0x00000102,
 	})
 }
 
-func (s *Server) sendMessage(c *client.Client, message string) error {
+// SendMessage displays message in a dialog box on the client's block selection screen.
+func (s *Server) SendMessage(c *client.Client, message string) error {
 	return c.Send(&packets.LoginClientMessage{
 		Header:   packets.BBHeader{Type: packets.LoginClientMessageType},
 		Language: 0x00450009,
@@ -186,6 +342,11 @@ func (s *Server) sendMessage(c *client.Client, message string) error {
 	})
 }
 
+// Ping sends the client a keepalive ping.
+func (s *Server) Ping(c *client.Client) error {
+	return c.Send(&packets.BBHeader{Type: packets.PingType})
+}
+
 // send the client the block list on the selection screen.
 func (s *Server) sendBlockList(c *client.Client) error {
 	var blocks []packets.Block
@@ -214,18 +375,18 @@ func (s *Server) sendBlockList(c *client.Client) error {
 		Unknown: 0x08,
 		Blocks:  blocks,
 	}
-	copy(blockListPkt.ShipName[:], []byte(viper.GetString("ship_server.name")))
+	copy(blockListPkt.ShipName[:], []byte(s.config.ShipServer.Name))
 
 	return c.Send(blockListPkt)
 }
 
-func (s *Server) handleMenuSelection(c *client.Client, pkt *packets.MenuSelection) error {
+func (s *Server) handleMenuSelection(ctx context.Context, c *client.Client, pkt *packets.MenuSelection) error {
 	// They can be at either the ship or block selection menu, so make sure we have the right one.
 	// Note: Should probably figure out what menuSelectPkt.MenuID is for (oandif that's the right name).
 	var err error
 	// Case if user gets back from block selection to ship selection
 	if pkt.MenuID == 1 && pkt.ItemID == 1 {
-		err = s.handleShipSelection(c, pkt.ItemID-1)
+		err = s.handleShipSelection(ctx, c, pkt.ItemID)
 		if err != nil {
 			return err
 		}
@@ -234,7 +395,7 @@ func (s *Server) handleMenuSelection(c *client.Client, pkt *packets.MenuSelectio
 	case blockListMenuType:
 		err = s.handleBlockSelection(c, pkt.ItemID^blockListMenuType)
 	case shipListMenuType:
-		err = s.handleShipSelection(c, pkt.ItemID^shipListMenuType)
+		err = s.handleShipSelection(ctx, c, pkt.ItemID^shipListMenuType)
 	default:
 		err = fmt.Errorf("unrecognized menu ID: %v", pkt.MenuID)
 	}
@@ -262,7 +423,7 @@ func (s *Server) handleBlockSelection(c *client.Client, selection uint32) error
 }
 
 func (s *Server) sendShipList(c *client.Client) error {
-	shipList := s.shipGateClient.GetConnectedShipList()
+	shipList := s.shipGateClient.GetConnectedShipList(c.Account.GM)
 
 	pkt := &packets.ShipList{
 		Header: packets.BBHeader{
@@ -280,10 +441,10 @@ func (s *Server) sendShipList(c *client.Client) error {
 }
 
 // Player selected one of the items on the ship select screen.
-func (s *Server) handleShipSelection(c *client.Client, selection uint32) error {
-	ip, port, err := s.shipGateClient.GetSelectedShipAddress(selection)
+func (s *Server) handleShipSelection(ctx context.Context, c *client.Client, selection uint32) error {
+	ip, port, err := s.shipGateClient.GetSelectedShipAddress(ctx, selection, c.IPAddr())
 	if err != nil {
-		return fmt.Errorf("could not get selected ship: %d", selection)
+		return fmt.Errorf("could not get selected ship %d: %w", selection, err)
 	}
 	return c.Send(&packets.Redirect{
 		Header: packets.BBHeader{Type: packets.RedirectType},
@@ -295,17 +456,23 @@ func (s *Server) handleShipSelection(c *client.Client, selection uint32) error {
 // Send the IP address and port of the character server to  which the client will
 // connect after disconnecting from this server.
 func (s *Server) sendBlockRedirect(c *client.Client, block Block) error {
-	addressParts := strings.Split(block.Address, ":")
-	blockIP := net.ParseIP(addressParts[0]).To4()
-	port, err := strconv.Atoi(addressParts[1])
+	_, portStr, err := net.SplitHostPort(block.Address)
+	if err != nil {
+		return fmt.Errorf("error parsing block address %v: %v", block.Address, err)
+	}
+	port, err := strconv.Atoi(portStr)
 	if err != nil {
 		return fmt.Errorf("error parsing port from block address: %v", block.Address)
 	}
+	// The blocks run alongside the ship, so they're reachable at the same address.
+	blockIP, err := archon.RedirectIP(c.IPAddr())
+	if err != nil {
+		return err
+	}
 
-	pkt := &packets.Redirect{
+	return c.Send(&packets.Redirect{
 		Header: packets.BBHeader{Type: packets.RedirectType},
+		IPAddr: blockIP,
 		Port:   uint16(port),
-	}
-	copy(pkt.IPAddr[:], blockIP)
-	return c.Send(pkt)
+	})
 }
@@ -22,11 +22,54 @@ type ShipgateServiceClient interface {
 	// GetActiveShips returns the list of Ships that currently connected to the
 	// shipgate and ready to receive players.
 	GetActiveShips(ctx context.Context, in *emptypb.Empty, opts ...grpc.CallOption) (*ShipList, error)
-	// RegisterShip informs the shipgate that it is able to serve players.
+	// WatchShips sends the list of active ships followed by each change to it
+	// until the client disconnects. The stream ends with UNAVAILABLE if the
+	// shipgate is shutting down or the client falls too far behind, in which case
+	// the client should watch again.
+	WatchShips(ctx context.Context, in *emptypb.Empty, opts ...grpc.CallOption) (ShipgateService_WatchShipsClient, error)
+	// RegisterShip informs the shipgate that it is able to serve players. Ships
+	// must be on the shipgate's allow-list and authenticate with either a client
+	// certificate issued for the ship's name or the ship's secret (sent in the
+	// x-ship-secret metadata). Fails with UNAUTHENTICATED or PERMISSION_DENIED
+	// otherwise, as does Heartbeat.
 	RegisterShip(ctx context.Context, in *RegistrationRequest, opts ...grpc.CallOption) (*emptypb.Empty, error)
-	// AuthenticateAccount verifies an account. A password should be provided
-	// via the rpc call metadata.
+	// Heartbeat reports that a registered ship is still able to serve players.
+	// Ships that stop sending heartbeats are no longer returned by GetActiveShips.
+	// Fails with NOT_FOUND if the ship isn't registered (e.g. because the shipgate
+	// restarted), in which case the ship should register again.
+	Heartbeat(ctx context.Context, in *ShipHeartbeat, opts ...grpc.CallOption) (*emptypb.Empty, error)
+	// AuthenticateAccount verifies an account and issues it a new session token,
+	// revoking any previous one. A password should be provided via the rpc call
+	// metadata. Fails with UNAUTHENTICATED if the credentials are invalid,
+	// PERMISSION_DENIED if the account is banned, or FAILED_PRECONDITION if it
+	// hasn't been activated.
 	AuthenticateAccount(ctx context.Context, in *AccountAuthRequest, opts ...grpc.CallOption) (*AccountAuthResponse, error)
+	// AuthenticateSessionToken verifies an account using the session token issued
+	// by AuthenticateAccount rather than its password. Tokens expire if they go
+	// unused and the account has no session being renewed.
+	AuthenticateSessionToken(ctx context.Context, in *SessionTokenAuthRequest, opts ...grpc.CallOption) (*AccountAuthResponse, error)
+	// AcquireSession marks an account as logged in. Fails with ALREADY_EXISTS if
+	// another session holds the account, unless a takeover is requested. Fails
+	// with RESOURCE_EXHAUSTED if the ship is full or PERMISSION_DENIED if the
+	// ship is GM-only and the account isn't a GM. Like RenewSessions and
+	// ReleaseSession, the caller must authenticate as the request's ship in the
+	// same way as for RegisterShip.
+	AcquireSession(ctx context.Context, in *SessionRequest, opts ...grpc.CallOption) (*emptypb.Empty, error)
+	// RenewSessions extends the leases on a set of sessions, which expire if they
+	// aren't renewed periodically. Sessions the shipgate doesn't know about (e.g.
+	// after it restarts) are acquired again unless the ship is now GM-only or full;
+	// the response lists those sessions along with any that are held elsewhere or
+	// whose leases expired.
+	RenewSessions(ctx context.Context, in *SessionRenewalRequest, opts ...grpc.CallOption) (*SessionRenewalResponse, error)
+	// ReleaseSession marks an account as logged out.
+	ReleaseSession(ctx context.Context, in *SessionRequest, opts ...grpc.CallOption) (*emptypb.Empty, error)
+	// ConnectMessageBus connects a ship to the message bus, over which it publishes
+	// envelopes and receives those published by every ship. The ship's name is sent
+	// in the x-ship-name metadata and it authenticates the same way as it does for
+	// RegisterShip. Delivery is best effort: the stream ends with UNAVAILABLE if the
+	// shipgate is shutting down or the ship falls too far behind, and envelopes sent
+	// while a ship is disconnected aren't delivered to it.
+	ConnectMessageBus(ctx context.Context, opts ...grpc.CallOption) (ShipgateService_ConnectMessageBusClient, error)
 }
 
 type shipgateServiceClient struct {
@@ -46,6 +89,38 @@ func (c *shipgateServiceClient) GetActiveShips(ctx context.Context, in *emptypb.
 	return out, nil
 }
 
+func (c *shipgateServiceClient) WatchShips(ctx context.Context, in *emptypb.Empty, opts ...grpc.CallOption) (ShipgateService_WatchShipsClient, error) {
+	stream, err := c.cc.NewStream(ctx, &ShipgateService_ServiceDesc.Streams[0], "/api.ShipgateService/WatchShips", opts...)
+	if err != nil {
+		return nil, err
+	}
+	x := &shipgateServiceWatchShipsClient{stream}
+	if err := x.ClientStream.SendMsg(in); err != nil {
+		return nil, err
+	}
+	if err := x.ClientStream.CloseSend(); err != nil {
+		return nil, err
+	}
+	return x, nil
+}
+
+type ShipgateService_WatchShipsClient interface {
+	Recv() (*ShipEvent, error)
+	grpc.ClientStream
+}
+
+type shipgateServiceWatchShipsClient struct {
+	grpc.ClientStream
+}
+
+func (x *shipgateServiceWatchShipsClient) Recv() (*ShipEvent, error) {
+	m := new(ShipEvent)
+	if err := x.ClientStream.RecvMsg(m); err != nil {
+		return nil, err
+	}
+	return m, nil
+}
+
 func (c *shipgateServiceClient) RegisterShip(ctx context.Context, in *RegistrationRequest, opts ...grpc.CallOption) (*emptypb.Empty, error) {
 	out := new(emptypb.Empty)
 	err := c.cc.Invoke(ctx, "/api.ShipgateService/RegisterShip", in, out, opts...)
@@ -55,6 +130,15 @@ func (c *shipgateServiceClient) RegisterShip(ctx context.Context, in *Registrati
 	return out, nil
 }
 
+func (c *shipgateServiceClient) Heartbeat(ctx context.Context, in *ShipHeartbeat, opts ...grpc.CallOption) (*emptypb.Empty, error) {
+	out := new(emptypb.Empty)
+	err := c.cc.Invoke(ctx, "/api.ShipgateService/Heartbeat", in, out, opts...)
+	if err != nil {
+		return nil, err
+	}
+	return out, nil
+}
+
 func (c *shipgateServiceClient) AuthenticateAccount(ctx context.Context, in *AccountAuthRequest, opts ...grpc.CallOption) (*AccountAuthResponse, error) {
 	out := new(AccountAuthResponse)
 	err := c.cc.Invoke(ctx, "/api.ShipgateService/AuthenticateAccount", in, out, opts...)
@@ -64,6 +148,73 @@ func (c *shipgateServiceClient) AuthenticateAccount(ctx context.Context, in *Acc
 	return out, nil
 }
 
+func (c *shipgateServiceClient) AuthenticateSessionToken(ctx context.Context, in *SessionTokenAuthRequest, opts ...grpc.CallOption) (*AccountAuthResponse, error) {
+	out := new(AccountAuthResponse)
+	err := c.cc.Invoke(ctx, "/api.ShipgateService/AuthenticateSessionToken", in, out, opts...)
+	if err != nil {
+		return nil, err
+	}
+	return out, nil
+}
+
+func (c *shipgateServiceClient) AcquireSession(ctx context.Context, in *SessionRequest, opts ...grpc.CallOption) (*emptypb.Empty, error) {
+	out := new(emptypb.Empty)
+	err := c.cc.Invoke(ctx, "/api.ShipgateService/AcquireSession", in, out, opts...)
+	if err != nil {
+		return nil, err
+	}
+	return out, nil
+}
+
+func (c *shipgateServiceClient) RenewSessions(ctx context.Context, in *SessionRenewalRequest, opts ...grpc.CallOption) (*SessionRenewalResponse, error) {
+	out := new(SessionRenewalResponse)
+	err := c.cc.Invoke(ctx, "/api.ShipgateService/RenewSessions", in, out, opts...)
+	if err != nil {
+		return nil, err
+	}
+	return out, nil
+}
+
+func (c *shipgateServiceClient) ReleaseSession(ctx context.Context, in *SessionRequest, opts ...grpc.CallOption) (*emptypb.Empty, error) {
+	out := new(emptypb.Empty)
+	err := c.cc.Invoke(ctx, "/api.ShipgateService/ReleaseSession", in, out, opts...)
+	if err != nil {
+		return nil, err
+	}
+	return out, nil
+}
+
+func (c *shipgateServiceClient) ConnectMessageBus(ctx context.Context, opts ...grpc.CallOption) (ShipgateService_ConnectMessageBusClient, error) {
+	stream, err := c.cc.NewStream(ctx, &ShipgateService_ServiceDesc.Streams[1], "/api.ShipgateService/ConnectMessageBus", opts...)
+	if err != nil {
+		return nil, err
+	}
+	x := &shipgateServiceConnectMessageBusClient{stream}
+	return x, nil
+}
+
+type ShipgateService_ConnectMessageBusClient interface {
+	Send(*Envelope) error
+	Recv() (*Envelope, error)
+	grpc.ClientStream
+}
+
+type shipgateServiceConnectMessageBusClient struct {
+	grpc.ClientStream
+}
+
+func (x *shipgateServiceConnectMessageBusClient) Send(m *Envelope) error {
+	return x.ClientStream.SendMsg(m)
+}
+
+func (x *shipgateServiceConnectMessageBusClient) Recv() (*Envelope, error) {
+	m := new(Envelope)
+	if err := x.ClientStream.RecvMsg(m); err != nil {
+		return nil, err
+	}
+	return m, nil
+}
+
 // ShipgateServiceServer is the server API for ShipgateService service.
 // All implementations must embed UnimplementedShipgateServiceServer
 // for forward compatibility
@@ -71,11 +222,54 @@ type ShipgateServiceServer interface {
 	// GetActiveShips returns the list of Ships that currently connected to the
 	// shipgate and ready to receive players.
 	GetActiveShips(context.Context, *emptypb.Empty) (*ShipList, error)
-	// RegisterShip informs the shipgate that it is able to serve players.
+	// WatchShips sends the list of active ships followed by each change to it
+	// until the client disconnects. The stream ends with UNAVAILABLE if the
+	// shipgate is shutting down or the client falls too far behind, in which case
+	// the client should watch again.
+	WatchShips(*emptypb.Empty, ShipgateService_WatchShipsServer) error
+	// RegisterShip informs the shipgate that it is able to serve players. Ships
+	// must be on the shipgate's allow-list and authenticate with either a client
+	// certificate issued for the ship's name or the ship's secret (sent in the
+	// x-ship-secret metadata). Fails with UNAUTHENTICATED or PERMISSION_DENIED
+	// otherwise, as does Heartbeat.
 	RegisterShip(context.Context, *RegistrationRequest) (*emptypb.Empty, error)
-	// AuthenticateAccount verifies an account. A password should be provided
-	// via the rpc call metadata.
+	// Heartbeat reports that a registered ship is still able to serve players.
+	// Ships that stop sending heartbeats are no longer returned by GetActiveShips.
+	// Fails with NOT_FOUND if the ship isn't registered (e.g. because the shipgate
+	// restarted), in which case the ship should register again.
+	Heartbeat(context.Context, *ShipHeartbeat) (*emptypb.Empty, error)
+	// AuthenticateAccount verifies an account and issues it a new session token,
+	// revoking any previous one. A password should be provided via the rpc call
+	// metadata. Fails with UNAUTHENTICATED if the credentials are invalid,
+	// PERMISSION_DENIED if the account is banned, or FAILED_PRECONDITION if it
+	// hasn't been activated.
 	AuthenticateAccount(context.Context, *AccountAuthRequest) (*AccountAuthResponse, error)
+	// AuthenticateSessionToken verifies an account using the session token issued
+	// by AuthenticateAccount rather than its password. Tokens expire if they go
+	// unused and the account has no session being renewed.
+	AuthenticateSessionToken(context.Context, *SessionTokenAuthRequest) (*AccountAuthResponse, error)
+	// AcquireSession marks an account as logged in. Fails with ALREADY_EXISTS if
+	// another session holds the account, unless a takeover is requested. Fails
+	// with RESOURCE_EXHAUSTED if the ship is full or PERMISSION_DENIED if the
+	// ship is GM-only and the account isn't a GM. Like RenewSessions and
+	// ReleaseSession, the caller must authenticate as the request's ship in the
+	// same way as for RegisterShip.
+	AcquireSession(context.Context, *SessionRequest) (*emptypb.Empty, error)
+	// RenewSessions extends the leases on a set of sessions, which expire if they
+	// aren't renewed periodically. Sessions the shipgate doesn't know about (e.g.
+	// after it restarts) are acquired again unless the ship is now GM-only or full;
+	// the response lists those sessions along with any that are held elsewhere or
+	// whose leases expired.
+	RenewSessions(context.Context, *SessionRenewalRequest) (*SessionRenewalResponse, error)
+	// ReleaseSession marks an account as logged out.
+	ReleaseSession(context.Context, *SessionRequest) (*emptypb.Empty, error)
+	// ConnectMessageBus connects a ship to the message bus, over which it publishes
+	// envelopes and receives those published by every ship. The ship's name is sent
+	// in the x-ship-name metadata and it authenticates the same way as it does for
+	// RegisterShip. Delivery is best effort: the stream ends with UNAVAILABLE if the
+	// shipgate is shutting down or the ship falls too far behind, and envelopes sent
+	// while a ship is disconnected aren't delivered to it.
+	ConnectMessageBus(ShipgateService_ConnectMessageBusServer) error
 	mustEmbedUnimplementedShipgateServiceServer()
 }
 
@@ -86,12 +280,33 @@ type UnimplementedShipgateServiceServer struct {
 func (UnimplementedShipgateServiceServer) GetActiveShips(context.Context, *emptypb.Empty) (*ShipList, error) {
 	return nil, status.Errorf(codes.Unimplemented, "method GetActiveShips not implemented")
 }
+func (UnimplementedShipgateServiceServer) WatchShips(*emptypb.Empty, ShipgateService_WatchShipsServer) error {
+	return status.Errorf(codes.Unimplemented, "method WatchShips not implemented")
+}
 func (UnimplementedShipgateServiceServer) RegisterShip(context.Context, *RegistrationRequest) (*emptypb.Empty, error) {
 	return nil, status.Errorf(codes.Unimplemented, "method RegisterShip not implemented")
 }
+func (UnimplementedShipgateServiceServer) Heartbeat(context.Context, *ShipHeartbeat) (*emptypb.Empty, error) {
+	return nil, status.Errorf(codes.Unimplemented, "method Heartbeat not implemented")
+}
 func (UnimplementedShipgateServiceServer) AuthenticateAccount(context.Context, *AccountAuthRequest) (*AccountAuthResponse, error) {
 	return nil, status.Errorf(codes.Unimplemented, "method AuthenticateAccount not implemented")
 }
+func (UnimplementedShipgateServiceServer) AuthenticateSessionToken(context.Context, *SessionTokenAuthRequest) (*AccountAuthResponse, error) {
+	return nil, status.Errorf(codes.Unimplemented, "method AuthenticateSessionToken not implemented")
+}
+func (UnimplementedShipgateServiceServer) AcquireSession(context.Context, *SessionRequest) (*emptypb.Empty, error) {
+	return nil, status.Errorf(codes.Unimplemented, "method AcquireSession not implemented")
+}
+func (UnimplementedShipgateServiceServer) RenewSessions(context.Context, *SessionRenewalRequest) (*SessionRenewalResponse, error) {
+	return nil, status.Errorf(codes.Unimplemented, "method RenewSessions not implemented")
+}
+func (UnimplementedShipgateServiceServer) ReleaseSession(context.Context, *SessionRequest) (*emptypb.Empty, error) {
+	return nil, status.Errorf(codes.Unimplemented, "method ReleaseSession not implemented")
+}
+func (UnimplementedShipgateServiceServer) ConnectMessageBus(ShipgateService_ConnectMessageBusServer) error {
+	return status.Errorf(codes.Unimplemented, "method ConnectMessageBus not implemented")
+}
 func (UnimplementedShipgateServiceServer) mustEmbedUnimplementedShipgateServiceServer() {}
 
 // UnsafeShipgateServiceServer may be embedded to opt out of forward compatibility for this service.
@@ -123,6 +338,27 @@ func _ShipgateService_GetActiveShips_Handler(srv interface{}, ctx context.Contex
 	return interceptor(ctx, in, info, handler)
 }
 
+func _ShipgateService_WatchShips_Handler(srv interface{}, stream grpc.ServerStream) error {
+	m := new(emptypb.Empty)
+	if err := stream.RecvMsg(m); err != nil {
+		return err
+	}
+	return srv.(ShipgateServiceServer).WatchShips(m, &shipgateServiceWatchShipsServer{stream})
+}
+
+type ShipgateService_WatchShipsServer interface {
+	Send(*ShipEvent) error
+	grpc.ServerStream
+}
+
+type shipgateServiceWatchShipsServer struct {
+	grpc.ServerStream
+}
+
+func (x *shipgateServiceWatchShipsServer) Send(m *ShipEvent) error {
+	return x.ServerStream.SendMsg(m)
+}
+
 func _ShipgateService_RegisterShip_Handler(srv interface{}, ctx context.Context, dec func(interface{}) error, interceptor grpc.UnaryServerInterceptor) (interface{}, error) {
 	in := new(RegistrationRequest)
 	if err := dec(in); err != nil {
@@ -141,6 +377,24 @@ func _ShipgateService_RegisterShip_Handler(srv interface{}, ctx context.Context,
 	return interceptor(ctx, in, info, handler)
 }
 
+func _ShipgateService_Heartbeat_Handler(srv interface{}, ctx context.Context, dec func(interface{}) error, interceptor grpc.UnaryServerInterceptor) (interface{}, error) {
+	in := new(ShipHeartbeat)
+	if err := dec(in); err != nil {
+		return nil, err
+	}
+	if interceptor == nil {
+		return srv.(ShipgateServiceServer).Heartbeat(ctx, in)
+	}
+	info := &grpc.UnaryServerInfo{
+		Server:     srv,
+		FullMethod: "/api.ShipgateService/Heartbeat",
+	}
+	handler := func(ctx context.Context, req interface{}) (interface{}, error) {
+		return srv.(ShipgateServiceServer).Heartbeat(ctx, req.(*ShipHeartbeat))
+	}
+	return interceptor(ctx, in, info, handler)
+}
+
 func _ShipgateService_AuthenticateAccount_Handler(srv interface{}, ctx context.Context, dec func(interface{}) error, interceptor grpc.UnaryServerInterceptor) (interface{}, error) {
 	in := new(AccountAuthRequest)
 	if err := dec(in); err != nil {
@@ -159,6 +413,104 @@ func _ShipgateService_AuthenticateAccount_Handler(srv interface{}, ctx context.C
 	return interceptor(ctx, in, info, handler)
 }
 
+func _ShipgateService_AuthenticateSessionToken_Handler(srv interface{}, ctx context.Context, dec func(interface{}) error, interceptor grpc.UnaryServerInterceptor) (interface{}, error) {
+	in := new(SessionTokenAuthRequest)
+	if err := dec(in); err != nil {
+		return nil, err
+	}
+	if interceptor == nil {
+		return srv.(ShipgateServiceServer).AuthenticateSessionToken(ctx, in)
+	}
+	info := &grpc.UnaryServerInfo{
+		Server:     srv,
+		FullMethod: "/api.ShipgateService/AuthenticateSessionToken",
+	}
+	handler := func(ctx context.Context, req interface{}) (interface{}, error) {
+		return srv.(ShipgateServiceServer).AuthenticateSessionToken(ctx, req.(*SessionTokenAuthRequest))
+	}
+	return interceptor(ctx, in, info, handler)
+}
+
+func _ShipgateService_AcquireSession_Handler(srv interface{}, ctx context.Context, dec func(interface{}) error, interceptor grpc.UnaryServerInterceptor) (interface{}, error) {
+	in := new(SessionRequest)
+	if err := dec(in); err != nil {
+		return nil, err
+	}
+	if interceptor == nil {
+		return srv.(ShipgateServiceServer).AcquireSession(ctx, in)
+	}
+	info := &grpc.UnaryServerInfo{
+		Server:     srv,
+		FullMethod: "/api.ShipgateService/AcquireSession",
+	}
+	handler := func(ctx context.Context, req interface{}) (interface{}, error) {
+		return srv.(ShipgateServiceServer).AcquireSession(ctx, req.(*SessionRequest))
+	}
+	return interceptor(ctx, in, info, handler)
+}
+
+func _ShipgateService_RenewSessions_Handler(srv interface{}, ctx context.Context, dec func(interface{}) error, interceptor grpc.UnaryServerInterceptor) (interface{}, error) {
+	in := new(SessionRenewalRequest)
+	if err := dec(in); err != nil {
+		return nil, err
+	}
+	if interceptor == nil {
+		return srv.(ShipgateServiceServer).RenewSessions(ctx, in)
+	}
+	info := &grpc.UnaryServerInfo{
+		Server:     srv,
+		FullMethod: "/api.ShipgateService/RenewSessions",
+	}
+	handler := func(ctx context.Context, req interface{}) (interface{}, error) {
+		return srv.(ShipgateServiceServer).RenewSessions(ctx, req.(*SessionRenewalRequest))
+	}
+	return interceptor(ctx, in, info, handler)
+}
+
+func _ShipgateService_ReleaseSession_Handler(srv interface{}, ctx context.Context, dec func(interface{}) error, interceptor grpc.UnaryServerInterceptor) (interface{}, error) {
+	in := new(SessionRequest)
+	if err := dec(in); err != nil {
+		return nil, err
+	}
+	if interceptor == nil {
+		return srv.(ShipgateServiceServer).ReleaseSession(ctx, in)
+	}
+	info := &grpc.UnaryServerInfo{
+		Server:     srv,
+		FullMethod: "/api.ShipgateService/ReleaseSession",
+	}
+	handler := func(ctx context.Context, req interface{}) (interface{}, error) {
+		return srv.(ShipgateServiceServer).ReleaseSession(ctx, req.(*SessionRequest))
+	}
+	return interceptor(ctx, in, info, handler)
+}
+
+func _ShipgateService_ConnectMessageBus_Handler(srv interface{}, stream grpc.ServerStream) error {
+	return srv.(ShipgateServiceServer).ConnectMessageBus(&shipgateServiceConnectMessageBusServer{stream})
+}
+
+type ShipgateService_ConnectMessageBusServer interface {
+	Send(*Envelope) error
+	Recv() (*Envelope, error)
+	grpc.ServerStream
+}
+
+type shipgateServiceConnectMessageBusServer struct {
+	grpc.ServerStream
+}
+
+func (x *shipgateServiceConnectMessageBusServer) Send(m *Envelope) error {
+	return x.ServerStream.SendMsg(m)
+}
+
+func (x *shipgateServiceConnectMessageBusServer) Recv() (*Envelope, error) {
+	m := new(Envelope)
+	if err := x.ServerStream.RecvMsg(m); err != nil {
+		return nil, err
+	}
+	return m, nil
+}
+
 // ShipgateService_ServiceDesc is the grpc.ServiceDesc for ShipgateService service.
 // It's only intended for direct use with grpc.RegisterService,
 // and not to be introspected or modified (even as a copy)
@@ -174,11 +526,43 @@ var ShipgateService_ServiceDesc = grpc.ServiceDesc{
 			MethodName: "RegisterShip",
 			Handler:    _ShipgateService_RegisterShip_Handler,
 		},
+		{
+			MethodName: "Heartbeat",
+			Handler:    _ShipgateService_Heartbeat_Handler,
+		},
 		{
 			MethodName: "AuthenticateAccount",
 			Handler:    _ShipgateService_AuthenticateAccount_Handler,
 		},
+		{
+			MethodName: "AuthenticateSessionToken",
+			Handler:    _ShipgateService_AuthenticateSessionToken_Handler,
+		},
+		{
+			MethodName: "AcquireSession",
+			Handler:    _ShipgateService_AcquireSession_Handler,
+		},
+		{
+			MethodName: "RenewSessions",
+			Handler:    _ShipgateService_RenewSessions_Handler,
+		},
+		{
+			MethodName: "ReleaseSession",
+			Handler:    _ShipgateService_ReleaseSession_Handler,
+		},
+	},
+	Streams: []grpc.StreamDesc{
+		{
+			StreamName:    "WatchShips",
+			Handler:       _ShipgateService_WatchShips_Handler,
+			ServerStreams: true,
+		},
+		{
+			StreamName:    "ConnectMessageBus",
+			Handler:       _ShipgateService_ConnectMessageBus_Handler,
+			ServerStreams: true,
+			ClientStreams: true,
+		},
 	},
-	Streams:  []grpc.StreamDesc{},
 	Metadata: "api.proto",
 }
@@ -0,0 +1,176 @@
+package shipgate
+
+import (
+	"crypto/tls"
+	"crypto/x509"
+	"fmt"
+	"io/ioutil"
+	"os"
+	"sync"
+	"time"
+
+	"google.golang.org/grpc/credentials"
+
+	"github.com/dcrodman/archon"
+)
+
+// How often the certificate files are checked for changes. Overridden in tests.
+var certificateCheckInterval = 10 * time.Second
+
+// certificateStore holds a certificate and the CA used to verify the other end of
+// a connection, reloading them when their files change so that they can be rotated
+// without restarting the server. Connections that are already established keep
+// using the certificates they were created with.
+type certificateStore struct {
+	caFile, certFile, keyFile string
+
+	mutex    sync.Mutex
+	cert     *tls.Certificate
+	pool     *x509.CertPool
+	modTimes map[string]time.Time
+	checked  time.Time
+}
+
+func newCertificateStore(caFile, certFile, keyFile string) (*certificateStore, error) {
+	s := &certificateStore{caFile: caFile, certFile: certFile, keyFile: keyFile}
+	if err := s.load(); err != nil {
+		return nil, err
+	}
+	return s, nil
+}
+
+// current returns the certificate and CA pool, reloading them first if their
+// files have changed. A certificate that fails to reload is logged and the
+// previous one is kept.
+func (s *certificateStore) current() (*tls.Certificate, *x509.CertPool) {
+	s.mutex.Lock()
+	defer s.mutex.Unlock()
+
+	if now().Sub(s.checked) >= certificateCheckInterval {
+		s.checked = now()
+		if s.changed() {
+			if err := s.load(); err != nil {
+				archon.Log.Warnf("failed to reload shipgate certificates; keeping the previous ones: %v", err)
+			} else {
+				archon.Log.Infof("reloaded shipgate certificate %s", s.certFile)
+			}
+		}
+	}
+	return s.cert, s.pool
+}
+
+// changed reports whether any of the files have been modified since they were loaded.
+func (s *certificateStore) changed() bool {
+	for _, file := range []string{s.caFile, s.certFile, s.keyFile} {
+		info, err := os.Stat(file)
+		if err != nil || !info.ModTime().Equal(s.modTimes[file]) {
+			return true
+		}
+	}
+	return false
+}
+
+func (s *certificateStore) load() error {
+	modTimes := make(map[string]time.Time)
+	for _, file := range []string{s.caFile, s.certFile, s.keyFile} {
+		info, err := os.Stat(file)
+		if err != nil {
+			return fmt.Errorf("unable to load %s: %s", file, err)
+		}
+		modTimes[file] = info.ModTime()
+	}
+
+	caFile, err := ioutil.ReadFile(s.caFile)
+	if err != nil {
+		return fmt.Errorf("unable to load CA file: %s", err)
+	}
+	pool := x509.NewCertPool()
+	if !pool.AppendCertsFromPEM(caFile) {
+		return fmt.Errorf("unable to load CA file: no certificates found in %s", s.caFile)
+	}
+
+	cert, err := loadX509Certificate(s.certFile, s.keyFile)
+	if err != nil {
+		return err
+	}
+
+	s.cert, s.pool, s.modTimes = cert, pool, modTimes
+	return nil
+}
+
+// serverTLSConfig requires clients to present a certificate issued by the CA.
+func serverTLSConfig(store *certificateStore) *tls.Config {
+	return &tls.Config{
+		MinVersion: tls.VersionTLS12,
+		GetConfigForClient: func(*tls.ClientHelloInfo) (*tls.Config, error) {
+			cert, pool := store.current()
+			return &tls.Config{
+				MinVersion:   tls.VersionTLS12,
+				Certificates: []tls.Certificate{*cert},
+				ClientAuth:   tls.RequireAndVerifyClientCert,
+				ClientCAs:    pool,
+				NextProtos:   []string{"h2"},
+			}, nil
+		},
+	}
+}
+
+// clientTLSConfig presents the client certificate and verifies that the shipgate's
+// certificate was issued by the CA.
+func clientTLSConfig(store *certificateStore) *tls.Config {
+	return &tls.Config{
+		MinVersion: tls.VersionTLS12,
+		GetClientCertificate: func(*tls.CertificateRequestInfo) (*tls.Certificate, error) {
+			cert, _ := store.current()
+			return cert, nil
+		},
+		// The default verification can't pick up a new CA, so the shipgate's certificate
+		// is verified by VerifyConnection instead.
+		InsecureSkipVerify: true,
+		VerifyConnection: func(state tls.ConnectionState) error {
+			if len(state.PeerCertificates) == 0 {
+				return fmt.Errorf("shipgate did not present a certificate")
+			}
+			_, pool := store.current()
+			intermediates := x509.NewCertPool()
+			for _, cert := range state.PeerCertificates[1:] {
+				intermediates.AddCert(cert)
+			}
+			_, err := state.PeerCertificates[0].Verify(x509.VerifyOptions{
+				DNSName:       state.ServerName,
+				Roots:         pool,
+				Intermediates: intermediates,
+			})
+			return err
+		},
+	}
+}
+
+// ClientCredentials returns the transport credentials with which to connect to the
+// shipgate, which present the client certificate from cfg.
+func ClientCredentials(cfg *archon.Config) (credentials.TransportCredentials, error) {
+	store, err := newCertificateStore(cfg.ShipgateCAFile, cfg.ShipgateClientCertFile, cfg.ShipgateClientKeyFile)
+	if err != nil {
+		return nil, fmt.Errorf("failed to load certificates for shipgate: %s", err)
+	}
+	return credentials.NewTLS(clientTLSConfig(store)), nil
+}
+
+func loadX509Certificate(certPath, keyPath string) (*tls.Certificate, error) {
+	certFile, err := ioutil.ReadFile(certPath)
+	if err != nil {
+		return nil, fmt.Errorf("unable to load certificate file: %s", err)
+	}
+
+	keyFile, err := ioutil.ReadFile(keyPath)
+	if err != nil {
+		return nil, fmt.Errorf("unable to load key file: %s", err)
+	}
+
+	cert, err := tls.X509KeyPair(certFile, keyFile)
+	if err != nil {
+		return nil, fmt.Errorf("unable to load X.509 certificate: %s", err)
+	}
+
+	return &cert, nil
+}
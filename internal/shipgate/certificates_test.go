@@ -0,0 +1,184 @@
+package shipgate
+
+import (
+	"crypto/ecdsa"
+	"crypto/elliptic"
+	"crypto/rand"
+	"crypto/tls"
+	"crypto/x509"
+	"crypto/x509/pkix"
+	"encoding/pem"
+	"io/ioutil"
+	"math/big"
+	"net"
+	"os"
+	"path/filepath"
+	"testing"
+	"time"
+)
+
+type testCA struct {
+	cert *x509.Certificate
+	key  *ecdsa.PrivateKey
+}
+
+func newTestCA(t *testing.T) *testCA {
+	t.Helper()
+	key, err := ecdsa.GenerateKey(elliptic.P256(), rand.Reader)
+	if err != nil {
+		t.Fatalf("failed to generate key: %v", err)
+	}
+	template := &x509.Certificate{
+		SerialNumber:          big.NewInt(1),
+		Subject:               pkix.Name{CommonName: "Test CA"},
+		NotBefore:             time.Now().Add(-time.Hour),
+		NotAfter:              time.Now().Add(time.Hour),
+		KeyUsage:              x509.KeyUsageCertSign | x509.KeyUsageDigitalSignature,
+		BasicConstraintsValid: true,
+		IsCA:                  true,
+	}
+	der, err := x509.CreateCertificate(rand.Reader, template, template, &key.PublicKey, key)
+	if err != nil {
+		t.Fatalf("failed to create CA certificate: %v", err)
+	}
+	cert, _ := x509.ParseCertificate(der)
+	return &testCA{cert: cert, key: key}
+}
+
+// issue writes a certificate for name signed by the CA to dir, along with the CA,
+// and returns the paths of the CA, certificate, and key files.
+func (ca *testCA) issue(t *testing.T, dir, name string, serial int64) (string, string, string) {
+	t.Helper()
+	key, err := ecdsa.GenerateKey(elliptic.P256(), rand.Reader)
+	if err != nil {
+		t.Fatalf("failed to generate key: %v", err)
+	}
+	template := &x509.Certificate{
+		SerialNumber: big.NewInt(serial),
+		Subject:      pkix.Name{CommonName: name},
+		NotBefore:    time.Now().Add(-time.Hour),
+		NotAfter:     time.Now().Add(time.Hour),
+		KeyUsage:     x509.KeyUsageDigitalSignature,
+		ExtKeyUsage:  []x509.ExtKeyUsage{x509.ExtKeyUsageServerAuth, x509.ExtKeyUsageClientAuth},
+		IPAddresses:  []net.IP{net.ParseIP("127.0.0.1")},
+	}
+	der, err := x509.CreateCertificate(rand.Reader, template, ca.cert, &key.PublicKey, ca.key)
+	if err != nil {
+		t.Fatalf("failed to create certificate: %v", err)
+	}
+	keyDER, _ := x509.MarshalECPrivateKey(key)
+
+	caFile := filepath.Join(dir, "ca.pem")
+	certFile := filepath.Join(dir, name+"-certificate.pem")
+	keyFile := filepath.Join(dir, name+"-key.pem")
+	writePEM(t, caFile, "CERTIFICATE", ca.cert.Raw)
+	writePEM(t, certFile, "CERTIFICATE", der)
+	writePEM(t, keyFile, "EC PRIVATE KEY", keyDER)
+	return caFile, certFile, keyFile
+}
+
+func writePEM(t *testing.T, path, blockType string, der []byte) {
+	t.Helper()
+	data := pem.EncodeToMemory(&pem.Block{Type: blockType, Bytes: der})
+	if err := ioutil.WriteFile(path, data, 0600); err != nil {
+		t.Fatalf("failed to write %s: %v", path, err)
+	}
+	// Make sure the change is noticed even if the file system's timestamps are coarse.
+	modTime := time.Now().Add(time.Duration(len(data)) * time.Second)
+	if err := os.Chtimes(path, modTime, modTime); err != nil {
+		t.Fatalf("failed to touch %s: %v", path, err)
+	}
+}
+
+func serialNumber(cert *tls.Certificate) int64 {
+	parsed, _ := x509.ParseCertificate(cert.Certificate[0])
+	return parsed.SerialNumber.Int64()
+}
+
+func TestCertificateStore_Reload(t *testing.T) {
+	originalInterval := certificateCheckInterval
+	defer func() { certificateCheckInterval = originalInterval }()
+	certificateCheckInterval = 0
+
+	dir := t.TempDir()
+	ca := newTestCA(t)
+	caFile, certFile, keyFile := ca.issue(t, dir, "shipgate", 2)
+
+	store, err := newCertificateStore(caFile, certFile, keyFile)
+	if err != nil {
+		t.Fatalf("failed to load certificates: %v", err)
+	}
+	if cert, _ := store.current(); serialNumber(cert) != 2 {
+		t.Fatalf("expected serial number = 2, got = %d", serialNumber(cert))
+	}
+
+	ca.issue(t, dir, "shipgate", 3)
+	if cert, _ := store.current(); serialNumber(cert) != 3 {
+		t.Errorf("expected rotated certificate to be loaded, got serial number = %d", serialNumber(cert))
+	}
+
+	// A certificate that can't be loaded is ignored in favor of the previous one.
+	writePEM(t, certFile, "CERTIFICATE", []byte("garbage"))
+	if cert, _ := store.current(); serialNumber(cert) != 3 {
+		t.Errorf("expected previous certificate to be kept, got serial number = %d", serialNumber(cert))
+	}
+}
+
+func TestMutualTLS(t *testing.T) {
+	dir := t.TempDir()
+	ca := newTestCA(t)
+	caFile, certFile, keyFile := ca.issue(t, dir, "shipgate", 2)
+	serverStore, err := newCertificateStore(caFile, certFile, keyFile)
+	if err != nil {
+		t.Fatalf("failed to load certificates: %v", err)
+	}
+	_, certFile, keyFile = ca.issue(t, dir, "ship", 3)
+	clientStore, err := newCertificateStore(caFile, certFile, keyFile)
+	if err != nil {
+		t.Fatalf("failed to load certificates: %v", err)
+	}
+	otherDir := t.TempDir()
+	otherStore, err := newCertificateStore(newTestCA(t).issue(t, otherDir, "ship", 4))
+	if err != nil {
+		t.Fatalf("failed to load certificates: %v", err)
+	}
+
+	tests := map[string]struct {
+		clientConfig *tls.Config
+		wantErr      bool
+	}{
+		"client_certificate": {clientConfig: clientTLSConfig(clientStore)},
+		"no_client_certificate": {
+			clientConfig: &tls.Config{RootCAs: clientStore.pool},
+			wantErr:      true,
+		},
+		"other_ca": {clientConfig: clientTLSConfig(otherStore), wantErr: true},
+	}
+
+	for name, tt := range tests {
+		t.Run(name, func(t *testing.T) {
+			serverConn, clientConn := net.Pipe()
+			server := tls.Server(serverConn, serverTLSConfig(serverStore))
+			tt.clientConfig.ServerName = "127.0.0.1"
+			client := tls.Client(clientConn, tt.clientConfig)
+
+			serverErr := make(chan error, 1)
+			go func() {
+				serverErr <- server.Handshake()
+				server.Close()
+			}()
+			clientErr := client.Handshake()
+			if clientErr == nil {
+				// The server rejects missing client certificates after the client's
+				// side of the handshake completes.
+				_, clientErr = client.Read(make([]byte, 1))
+			}
+			client.Close()
+
+			err := <-serverErr
+			if (err != nil) != tt.wantErr {
+				t.Errorf("expected server handshake error = %v, got: %v (client error: %v)", tt.wantErr, err, clientErr)
+			}
+		})
+	}
+}
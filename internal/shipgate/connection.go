@@ -0,0 +1,243 @@
+package shipgate
+
+import (
+	"context"
+	"errors"
+	"math/rand"
+	"sync"
+	"time"
+
+	"google.golang.org/grpc"
+	"google.golang.org/grpc/backoff"
+	"google.golang.org/grpc/codes"
+	// Registers the client side of the health checking configured in serviceConfig.
+	_ "google.golang.org/grpc/health"
+	"google.golang.org/grpc/keepalive"
+	"google.golang.org/grpc/status"
+
+	"github.com/dcrodman/archon"
+	"github.com/dcrodman/archon/internal/shipgate/api"
+)
+
+const (
+	// Time allowed for each attempt at an RPC, unless the caller's context has an
+	// earlier deadline.
+	rpcTimeout = 5 * time.Second
+	// Number of times idempotent RPCs are attempted before giving up.
+	maxRPCAttempts = 3
+	// Delay before retrying an RPC, which doubles after each attempt up to maxRetryBackoff.
+	initialRetryBackoff = 100 * time.Millisecond
+	maxRetryBackoff     = time.Second
+	// Number of consecutive calls that have to fail because the shipgate couldn't be
+	// reached before calls start failing immediately, and for how long they do so.
+	breakerThreshold = 5
+	breakerCooldown  = 10 * time.Second
+	// How often the connection is checked while idle, so that streams (which have no
+	// deadline) notice when the shipgate's host disappears.
+	keepaliveTime = time.Minute
+	// Delay before reopening a stream that ended, which doubles after each failed
+	// attempt up to maxReconnectBackoff.
+	initialReconnectBackoff = time.Second
+	maxReconnectBackoff     = 30 * time.Second
+)
+
+// The connection is only used while the shipgate's health service reports that it's
+// serving, which it stops doing when it's shutting down.
+var serviceConfig = `{
+	"loadBalancingConfig": [{"round_robin": {}}],
+	"healthCheckConfig": {"serviceName": "` + api.ShipgateService_ServiceDesc.ServiceName + `"}
+}`
+
+// ErrUnavailable is returned by calls to the shipgate when it can't be reached.
+var ErrUnavailable = errors.New("shipgate is unavailable")
+
+// UnavailableMessage is displayed to players who can't be served because the
+// shipgate is unavailable.
+const UnavailableMessage = "The server is currently unavailable.\nPlease try again in a few minutes."
+
+// RPCs that are safe to retry, since making them more than once has the same
+// effect as making them once.
+var idempotentMethods = map[string]bool{
+	fullMethod("GetActiveShips"):           true,
+	fullMethod("RegisterShip"):             true,
+	fullMethod("Heartbeat"):                true,
+	fullMethod("AuthenticateSessionToken"): true,
+	fullMethod("RenewSessions"):            true,
+	fullMethod("ReleaseSession"):           true,
+}
+
+func fullMethod(name string) string {
+	return "/" + api.ShipgateService_ServiceDesc.ServiceName + "/" + name
+}
+
+// Dial connects to the shipgate at addr. The connection is re-established in the
+// background if the shipgate restarts. Each call made over it is given a deadline,
+// idempotent calls are retried, and calls fail with ErrUnavailable while the
+// shipgate can't be reached rather than waiting on it.
+func Dial(addr string, cfg *archon.Config) (*grpc.ClientConn, error) {
+	creds, err := ClientCredentials(cfg)
+	if err != nil {
+		return nil, err
+	}
+
+	breaker := &circuitBreaker{threshold: breakerThreshold, cooldown: breakerCooldown}
+	connectBackoff := backoff.DefaultConfig
+	connectBackoff.MaxDelay = 30 * time.Second
+
+	return grpc.Dial(
+		addr,
+		grpc.WithTransportCredentials(creds),
+		grpc.WithConnectParams(grpc.ConnectParams{Backoff: connectBackoff, MinConnectTimeout: rpcTimeout}),
+		grpc.WithDefaultServiceConfig(serviceConfig),
+		grpc.WithKeepaliveParams(keepalive.ClientParameters{Time: keepaliveTime, Timeout: rpcTimeout}),
+		grpc.WithChainUnaryInterceptor(SessionIDInterceptor, breaker.intercept, retryInterceptor),
+	)
+}
+
+// retryInterceptor gives each attempt at an RPC a deadline and retries idempotent
+// RPCs that failed because the shipgate couldn't be reached.
+func retryInterceptor(ctx context.Context, method string, req, reply interface{},
+	cc *grpc.ClientConn, invoker grpc.UnaryInvoker, opts ...grpc.CallOption) error {
+	attempts := 1
+	if idempotentMethods[method] {
+		attempts = maxRPCAttempts
+	}
+
+	delay := initialRetryBackoff
+	for attempt := 1; ; attempt++ {
+		attemptCtx, cancel := context.WithTimeout(ctx, rpcTimeout)
+		err := invoker(attemptCtx, method, req, reply, cc, opts...)
+		cancel()
+		if attempt >= attempts || !isUnreachable(err) || ctx.Err() != nil {
+			return err
+		}
+
+		select {
+		case <-ctx.Done():
+			return err
+		case <-time.After(jitter(delay)):
+		}
+		if delay *= 2; delay > maxRetryBackoff {
+			delay = maxRetryBackoff
+		}
+	}
+}
+
+// reconnectLoop opens a stream with connect until ctx is done, opening it again
+// (with a backoff) whenever it ends. connect reports whether the stream was opened
+// successfully, which resets the backoff.
+func reconnectLoop(ctx context.Context, name string, connect func(context.Context) (bool, error)) {
+	delay := initialReconnectBackoff
+	for {
+		connected, err := connect(ctx)
+		if ctx.Err() != nil {
+			return
+		}
+		if connected {
+			archon.Log.Warnf("lost %s; reconnecting: %v", name, err)
+			delay = initialReconnectBackoff
+		}
+
+		select {
+		case <-ctx.Done():
+			return
+		case <-time.After(jitter(delay)):
+		}
+		if delay *= 2; delay > maxReconnectBackoff {
+			delay = maxReconnectBackoff
+		}
+	}
+}
+
+// jitter returns a random duration between half and one and a half times delay so
+// that servers don't all retry at the same moment.
+func jitter(delay time.Duration) time.Duration {
+	return delay/2 + time.Duration(rand.Int63n(int64(delay)))
+}
+
+// isUnreachable reports whether err means the shipgate couldn't be reached (or
+// didn't respond in time).
+func isUnreachable(err error) bool {
+	code := status.Code(err)
+	return code == codes.Unavailable || code == codes.DeadlineExceeded
+}
+
+// circuitBreaker fails calls immediately while the shipgate is unreachable so that
+// clients aren't left waiting on calls that are going to fail anyway. Once the
+// cooldown has passed a single call is let through to check whether the shipgate
+// has come back.
+type circuitBreaker struct {
+	threshold int
+	cooldown  time.Duration
+
+	mutex     sync.Mutex
+	failures  int
+	openUntil time.Time
+	probing   bool
+}
+
+func (b *circuitBreaker) intercept(ctx context.Context, method string, req, reply interface{},
+	cc *grpc.ClientConn, invoker grpc.UnaryInvoker, opts ...grpc.CallOption) error {
+	if !b.allow() {
+		return ErrUnavailable
+	}
+
+	err := invoker(ctx, method, req, reply, cc, opts...)
+	switch {
+	case isUnreachable(err) && ctx.Err() == nil:
+		b.recordFailure()
+		return ErrUnavailable
+	case ctx.Err() != nil:
+		// The caller gave up, which says nothing about the shipgate.
+		b.release()
+	default:
+		b.recordSuccess()
+	}
+	return err
+}
+
+// allow reports whether a call should be made.
+func (b *circuitBreaker) allow() bool {
+	b.mutex.Lock()
+	defer b.mutex.Unlock()
+
+	if b.failures < b.threshold {
+		return true
+	}
+	if now().Before(b.openUntil) || b.probing {
+		return false
+	}
+	b.probing = true
+	return true
+}
+
+func (b *circuitBreaker) recordFailure() {
+	b.mutex.Lock()
+	defer b.mutex.Unlock()
+
+	b.failures++
+	b.probing = false
+	if b.failures >= b.threshold {
+		if b.failures == b.threshold {
+			archon.Log.Warnf("shipgate is unreachable; failing calls to it for %v", b.cooldown)
+		}
+		b.openUntil = now().Add(b.cooldown)
+	}
+}
+
+func (b *circuitBreaker) recordSuccess() {
+	b.mutex.Lock()
+	defer b.mutex.Unlock()
+
+	if b.failures >= b.threshold {
+		archon.Log.Infof("shipgate is reachable again")
+	}
+	b.failures = 0
+	b.probing = false
+}
+
+func (b *circuitBreaker) release() {
+	b.mutex.Lock()
+	b.probing = false
+	b.mutex.Unlock()
+}
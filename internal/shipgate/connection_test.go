@@ -0,0 +1,113 @@
+package shipgate
+
+import (
+	"context"
+	"testing"
+	"time"
+
+	"google.golang.org/grpc"
+	"google.golang.org/grpc/codes"
+	"google.golang.org/grpc/status"
+)
+
+// fakeInvoker fails with each of errs in turn, then succeeds.
+type fakeInvoker struct {
+	errs  []error
+	calls int
+}
+
+func (f *fakeInvoker) invoke(ctx context.Context, method string, req, reply interface{}, cc *grpc.ClientConn, opts ...grpc.CallOption) error {
+	f.calls++
+	if f.calls <= len(f.errs) {
+		return f.errs[f.calls-1]
+	}
+	return nil
+}
+
+func TestRetryInterceptor(t *testing.T) {
+	unavailable := status.Error(codes.Unavailable, "connection refused")
+	notFound := status.Error(codes.NotFound, "not found")
+
+	tests := map[string]struct {
+		method      string
+		errs        []error
+		wantedCalls int
+		wantedCode  codes.Code
+	}{
+		"success": {
+			method: fullMethod("Heartbeat"), wantedCalls: 1, wantedCode: codes.OK,
+		},
+		"retried": {
+			method: fullMethod("Heartbeat"), errs: []error{unavailable}, wantedCalls: 2, wantedCode: codes.OK,
+		},
+		"retries_exhausted": {
+			method: fullMethod("Heartbeat"), errs: []error{unavailable, unavailable, unavailable},
+			wantedCalls: maxRPCAttempts, wantedCode: codes.Unavailable,
+		},
+		"not_idempotent": {
+			method: fullMethod("AcquireSession"), errs: []error{unavailable}, wantedCalls: 1, wantedCode: codes.Unavailable,
+		},
+		"other_error": {
+			method: fullMethod("Heartbeat"), errs: []error{notFound}, wantedCalls: 1, wantedCode: codes.NotFound,
+		},
+	}
+
+	for name, tt := range tests {
+		t.Run(name, func(t *testing.T) {
+			invoker := &fakeInvoker{errs: tt.errs}
+			err := retryInterceptor(context.Background(), tt.method, nil, nil, nil, invoker.invoke)
+			if code := status.Code(err); code != tt.wantedCode {
+				t.Errorf("expected code = %v, got = %v", tt.wantedCode, code)
+			}
+			if invoker.calls != tt.wantedCalls {
+				t.Errorf("expected %d calls, got = %d", tt.wantedCalls, invoker.calls)
+			}
+		})
+	}
+}
+
+func TestCircuitBreaker(t *testing.T) {
+	originalNow := now
+	defer func() { now = originalNow }()
+
+	current := time.Now()
+	now = func() time.Time { return current }
+
+	unavailable := &fakeInvoker{errs: []error{status.Error(codes.Unavailable, "connection refused")}}
+	call := func(b *circuitBreaker, invoker *fakeInvoker) error {
+		return b.intercept(context.Background(), fullMethod("Heartbeat"), nil, nil, nil, invoker.invoke)
+	}
+
+	b := &circuitBreaker{threshold: 2, cooldown: time.Minute}
+	for i := 0; i < b.threshold; i++ {
+		unavailable.calls = 0
+		if err := call(b, unavailable); err != ErrUnavailable {
+			t.Fatalf("expected ErrUnavailable, got = %v", err)
+		}
+	}
+
+	// Calls fail without being made until the cooldown has passed.
+	invoker := &fakeInvoker{}
+	if err := call(b, invoker); err != ErrUnavailable || invoker.calls != 0 {
+		t.Errorf("expected call to fail immediately, got err = %v after %d calls", err, invoker.calls)
+	}
+
+	// After which a failed probe keeps the breaker open...
+	current = current.Add(b.cooldown)
+	unavailable.calls = 0
+	if err := call(b, unavailable); err != ErrUnavailable || unavailable.calls != 1 {
+		t.Errorf("expected probe to be made, got err = %v after %d calls", err, unavailable.calls)
+	}
+	if err := call(b, invoker); err != ErrUnavailable || invoker.calls != 0 {
+		t.Errorf("expected call to fail immediately, got err = %v after %d calls", err, invoker.calls)
+	}
+
+	// ...and a successful one closes it.
+	current = current.Add(b.cooldown)
+	if err := call(b, invoker); err != nil {
+		t.Errorf("expected probe to succeed, got = %v", err)
+	}
+	if err := call(b, invoker); err != nil || invoker.calls != 2 {
+		t.Errorf("expected call to be made, got err = %v after %d calls", err, invoker.calls)
+	}
+}
@@ -0,0 +1,76 @@
+package shipgate
+
+import (
+	"context"
+	"time"
+
+	"google.golang.org/grpc/codes"
+	"google.golang.org/grpc/status"
+	"google.golang.org/protobuf/types/known/emptypb"
+
+	"github.com/dcrodman/archon"
+	"github.com/dcrodman/archon/internal/shipgate/api"
+)
+
+// How long a ship remains active without sending a heartbeat. Ships should send
+// heartbeats well within this window (see ShipHeartbeatInterval).
+const shipHeartbeatTimeout = 30 * time.Second
+
+// ShipHeartbeatInterval is how often registered ships should send heartbeats.
+const ShipHeartbeatInterval = shipHeartbeatTimeout / 3
+
+func (s *shipgateServiceServer) Heartbeat(ctx context.Context, req *api.ShipHeartbeat) (*emptypb.Empty, error) {
+	s.connectedShipsMutex.Lock()
+	defer s.connectedShipsMutex.Unlock()
+
+	s.deactivateStaleShips()
+	connectedShip, ok := s.connectedShips[req.GetName()]
+	if !ok || !connectedShip.active {
+		return nil, status.Error(codes.NotFound, "ship is not registered")
+	}
+
+	connectedShip.lastHeartbeat = now()
+	if players := int(req.GetPlayerCount()); players != connectedShip.players {
+		connectedShip.players = players
+		s.publishShipEvent(api.ShipEvent_UPDATED, connectedShip)
+	}
+	connectedShip.blocks = make([]BlockStatus, 0, len(req.GetBlocks()))
+	for _, block := range req.GetBlocks() {
+		connectedShip.blocks = append(connectedShip.blocks, BlockStatus{
+			Name:    block.GetName(),
+			Players: int(block.GetPlayerCount()),
+			Lobbies: int(block.GetLobbies()),
+			Games:   int(block.GetGames()),
+		})
+	}
+	return &emptypb.Empty{}, nil
+}
+
+// deactivateStaleShips marks the ships that have missed their heartbeats as inactive
+// so that players are no longer sent to them. Must be called with the ships lock held.
+func (s *shipgateServiceServer) deactivateStaleShips() {
+	for _, connectedShip := range s.connectedShips {
+		if connectedShip.active && now().Sub(connectedShip.lastHeartbeat) > shipHeartbeatTimeout {
+			connectedShip.active = false
+			connectedShip.players = 0
+			connectedShip.blocks = nil
+			archon.Log.Warnf("SHIPGATE deactivated ship %s after missed heartbeats", connectedShip.name)
+			s.publishShipEvent(api.ShipEvent_DEREGISTERED, connectedShip)
+		}
+	}
+}
+
+// startStaleShipLoop periodically deactivates ships that have missed their heartbeats
+// so that watchers find out about them without waiting for another request.
+func (s *shipgateServiceServer) startStaleShipLoop(ctx context.Context) {
+	for {
+		select {
+		case <-ctx.Done():
+			return
+		case <-time.After(ShipHeartbeatInterval):
+			s.connectedShipsMutex.Lock()
+			s.deactivateStaleShips()
+			s.connectedShipsMutex.Unlock()
+		}
+	}
+}
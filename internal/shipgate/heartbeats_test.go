@@ -0,0 +1,61 @@
+package shipgate
+
+import (
+	"context"
+	"testing"
+	"time"
+
+	"google.golang.org/grpc/codes"
+	"google.golang.org/grpc/status"
+	"google.golang.org/protobuf/types/known/emptypb"
+
+	"github.com/dcrodman/archon/internal/shipgate/api"
+)
+
+func TestHeartbeat(t *testing.T) {
+	originalNow := now
+	defer func() { now = originalNow }()
+
+	current := time.Now()
+	now = func() time.Time { return current }
+
+	s := &shipgateServiceServer{connectedShips: make(map[string]*ship)}
+	for _, name := range []string{"First", "Second"} {
+		_, _ = s.RegisterShip(context.Background(), &api.RegistrationRequest{Name: name, Address: "127.0.0.1", Port: "15000"})
+	}
+
+	// Only the first ship keeps sending heartbeats.
+	current = current.Add(shipHeartbeatTimeout - time.Second)
+	_, err := s.Heartbeat(context.Background(), &api.ShipHeartbeat{
+		Name:        "First",
+		PlayerCount: 3,
+		Blocks:      []*api.ShipHeartbeat_Block{{Name: "BLOCK01", PlayerCount: 3, Lobbies: 15}},
+	})
+	if err != nil {
+		t.Fatalf("unexpected error: %v", err)
+	}
+	current = current.Add(2 * time.Second)
+
+	ships, _ := s.GetActiveShips(context.Background(), &emptypb.Empty{})
+	if len(ships.GetShips()) != 1 || ships.GetShips()[0].GetName() != "First" {
+		t.Fatalf("expected only the first ship to be active, got %v", ships.GetShips())
+	}
+	if players := ships.GetShips()[0].GetPlayerCount(); players != 3 {
+		t.Errorf("expected player count = 3, got = %d", players)
+	}
+
+	// The inactive ship has to register again before its heartbeats are accepted.
+	_, err = s.Heartbeat(context.Background(), &api.ShipHeartbeat{Name: "Second"})
+	if code := status.Code(err); code != codes.NotFound {
+		t.Errorf("expected code = %v, got = %v", codes.NotFound, code)
+	}
+	_, _ = s.RegisterShip(context.Background(), &api.RegistrationRequest{Name: "Second", Address: "127.0.0.1", Port: "15001"})
+
+	statuses := s.registeredShips()
+	if len(statuses) != 2 || !statuses[1].Active || statuses[1].Port != "15001" {
+		t.Errorf("expected the second ship to be reactivated, got %+v", statuses)
+	}
+	if len(statuses[0].Blocks) != 1 || statuses[0].Blocks[0].Lobbies != 15 {
+		t.Errorf("expected the first ship's block status to be recorded, got %+v", statuses[0].Blocks)
+	}
+}
@@ -0,0 +1,187 @@
+package shipgate
+
+import (
+	"context"
+	"io"
+
+	"google.golang.org/grpc/codes"
+	"google.golang.org/grpc/metadata"
+	"google.golang.org/grpc/status"
+
+	"github.com/dcrodman/archon"
+	"github.com/dcrodman/archon/internal/core/metrics"
+	"github.com/dcrodman/archon/internal/shipgate/api"
+)
+
+const (
+	// Metadata key under which ships send their name when connecting to the message bus.
+	shipNameMetadataKey = "x-ship-name"
+	// Header sent by the shipgate once a ship is connected to the message bus.
+	messageBusConnectedKey = "x-message-bus-connected"
+)
+
+// Number of envelopes that can be queued for a ship before it's disconnected from
+// the message bus for falling behind.
+const messageBusBufferSize = 256
+
+var errMessageBusClosed = status.Error(codes.Unavailable, "disconnected from message bus; connect again to keep receiving envelopes")
+
+func (s *shipgateServiceServer) ConnectMessageBus(stream api.ShipgateService_ConnectMessageBusServer) error {
+	ctx := stream.Context()
+	name := shipName(ctx)
+	if err := s.authenticateShip(ctx, name); err != nil {
+		archon.LoggerFromContext(ctx).WithField("ship", name).
+			Warnf("SHIPGATE rejected ship: %v", status.Convert(err).Message())
+		return err
+	}
+
+	envelopes, err := s.subscribe()
+	if err != nil {
+		return err
+	}
+	defer s.unsubscribe(envelopes)
+	// Let the ship know that it's connected, since it may be a while before any
+	// envelopes are sent to it.
+	if err := stream.SendHeader(metadata.Pairs(messageBusConnectedKey, "true")); err != nil {
+		return err
+	}
+	archon.Log.Infof("SHIPGATE ship %s connected to the message bus", name)
+
+	// Envelopes are received from the ship in their own goroutine, which exits once
+	// the stream ends.
+	recvErr := make(chan error, 1)
+	go func() {
+		for {
+			envelope, err := stream.Recv()
+			if err != nil {
+				recvErr <- err
+				return
+			}
+			envelope.OriginShip = name
+			s.publishEnvelope(envelope)
+		}
+	}()
+
+	for {
+		select {
+		case err := <-recvErr:
+			if err == io.EOF {
+				return nil
+			}
+			return err
+		case envelope, ok := <-envelopes:
+			if !ok {
+				return errMessageBusClosed
+			}
+			if err := stream.Send(envelope); err != nil {
+				return err
+			}
+		}
+	}
+}
+
+// shipName returns the name sent by the ship in the request metadata.
+func shipName(ctx context.Context) string {
+	if md, ok := metadata.FromIncomingContext(ctx); ok {
+		if names := md.Get(shipNameMetadataKey); len(names) > 0 {
+			return names[0]
+		}
+	}
+	return ""
+}
+
+// subscribe returns a channel on which every envelope published to the message bus
+// is sent. The channel is closed if the subscriber falls behind or the shipgate is
+// shutting down.
+func (s *shipgateServiceServer) subscribe() (chan *api.Envelope, error) {
+	s.subscribersMutex.Lock()
+	defer s.subscribersMutex.Unlock()
+
+	if s.subscribersClosed {
+		return nil, errMessageBusClosed
+	}
+	if s.subscribers == nil {
+		s.subscribers = make(map[chan *api.Envelope]bool)
+	}
+	envelopes := make(chan *api.Envelope, messageBusBufferSize)
+	s.subscribers[envelopes] = true
+	return envelopes, nil
+}
+
+func (s *shipgateServiceServer) unsubscribe(envelopes chan *api.Envelope) {
+	s.subscribersMutex.Lock()
+	defer s.subscribersMutex.Unlock()
+
+	if s.subscribers[envelopes] {
+		delete(s.subscribers, envelopes)
+		close(envelopes)
+	}
+}
+
+// closeMessageBus disconnects every ship from the message bus (and refuses new
+// connections) so that the server can shut down without waiting on them.
+func (s *shipgateServiceServer) closeMessageBus() {
+	s.subscribersMutex.Lock()
+	defer s.subscribersMutex.Unlock()
+
+	s.subscribersClosed = true
+	for envelopes := range s.subscribers {
+		delete(s.subscribers, envelopes)
+		close(envelopes)
+	}
+}
+
+// Publish sends the envelope to every ship connected to the message bus of the
+// shipgate running in this process. It returns ErrUnavailable if the shipgate
+// isn't running.
+func Publish(envelope *api.Envelope) error {
+	activeServiceMutex.RLock()
+	s := activeService
+	activeServiceMutex.RUnlock()
+
+	if s == nil {
+		return ErrUnavailable
+	}
+	s.publishEnvelope(envelope)
+	return nil
+}
+
+// publishEnvelope sends the envelope to every ship connected to the message bus.
+func (s *shipgateServiceServer) publishEnvelope(envelope *api.Envelope) {
+	payloadType := envelopeType(envelope)
+	if payloadType == "" {
+		archon.Log.Warnf("SHIPGATE dropped envelope without a payload from %s", envelope.GetOriginShip())
+		return
+	}
+	metrics.ShipgateEnvelopes.WithLabelValues(payloadType).Inc()
+
+	s.subscribersMutex.Lock()
+	defer s.subscribersMutex.Unlock()
+
+	for envelopes := range s.subscribers {
+		select {
+		case envelopes <- envelope:
+		default:
+			// Rather than block every ship on a slow one, disconnect it.
+			delete(s.subscribers, envelopes)
+			close(envelopes)
+		}
+	}
+}
+
+// envelopeType returns the name of the envelope's payload, which is recorded in
+// the envelope metrics.
+func envelopeType(envelope *api.Envelope) string {
+	switch envelope.GetPayload().(type) {
+	case *api.Envelope_Announcement:
+		return "announcement"
+	case *api.Envelope_GuildcardMessage:
+		return "guildcard_message"
+	case *api.Envelope_KickRequest:
+		return "kick_request"
+	case *api.Envelope_TeamUpdate:
+		return "team_update"
+	default:
+		return ""
+	}
+}
@@ -0,0 +1,98 @@
+package shipgate
+
+import (
+	"context"
+
+	"google.golang.org/grpc/metadata"
+
+	"github.com/dcrodman/archon/internal/shipgate/api"
+)
+
+// Number of envelopes that can be waiting to be sent to the shipgate before
+// Publish starts failing.
+const outgoingEnvelopeBufferSize = 64
+
+// MessageBus connects a ship to the shipgate's message bus, over which it can
+// deliver envelopes to players on other ships.
+type MessageBus struct {
+	client   api.ShipgateServiceClient
+	shipName string
+	secret   string
+	// Called with each envelope received from the shipgate.
+	handler  func(*api.Envelope)
+	outgoing chan *api.Envelope
+}
+
+// NewMessageBus returns a MessageBus that connects as the ship named shipName
+// (authenticating with secret, if set) and calls handler with every envelope
+// published by any ship, including this one.
+func NewMessageBus(client api.ShipgateServiceClient, shipName, secret string, handler func(*api.Envelope)) *MessageBus {
+	return &MessageBus{
+		client:   client,
+		shipName: shipName,
+		secret:   secret,
+		handler:  handler,
+		outgoing: make(chan *api.Envelope, outgoingEnvelopeBufferSize),
+	}
+}
+
+// Start connects to the message bus in the background, reconnecting whenever the
+// connection is lost, until ctx is done.
+func (b *MessageBus) Start(ctx context.Context) {
+	go reconnectLoop(ctx, "message bus connection", b.connect)
+}
+
+// Publish queues the envelope to be sent to every ship. Envelopes published while
+// the bus is reconnecting are sent once it's connected again, unless too many of
+// them pile up, in which case ErrUnavailable is returned.
+func (b *MessageBus) Publish(envelope *api.Envelope) error {
+	select {
+	case b.outgoing <- envelope:
+		return nil
+	default:
+		return ErrUnavailable
+	}
+}
+
+// connect sends and receives envelopes until the stream ends, reporting whether it
+// was connected.
+func (b *MessageBus) connect(ctx context.Context) (bool, error) {
+	ctx, cancel := context.WithCancel(WithShipSecret(ctx, b.secret))
+	defer cancel()
+
+	ctx = metadata.AppendToOutgoingContext(ctx, shipNameMetadataKey, b.shipName)
+	stream, err := b.client.ConnectMessageBus(ctx)
+	if err != nil {
+		return false, err
+	}
+	// The shipgate sends a header once the ship has been connected, and no headers
+	// at all if it was rejected.
+	if header, err := stream.Header(); err != nil {
+		return false, err
+	} else if len(header.Get(messageBusConnectedKey)) == 0 {
+		_, err := stream.Recv()
+		return false, err
+	}
+
+	// Envelopes are sent in their own goroutine, which exits once the stream ends.
+	go func() {
+		for {
+			select {
+			case <-ctx.Done():
+				return
+			case envelope := <-b.outgoing:
+				if err := stream.Send(envelope); err != nil {
+					return
+				}
+			}
+		}
+	}()
+
+	for {
+		envelope, err := stream.Recv()
+		if err != nil {
+			return true, err
+		}
+		b.handler(envelope)
+	}
+}
@@ -0,0 +1,174 @@
+package shipgate
+
+import (
+	"context"
+	"net"
+	"testing"
+	"time"
+
+	"google.golang.org/grpc"
+
+	"github.com/dcrodman/archon"
+	"github.com/dcrodman/archon/internal/shipgate/api"
+)
+
+func receiveEnvelope(envelopes chan *api.Envelope) *api.Envelope {
+	select {
+	case envelope := <-envelopes:
+		return envelope
+	default:
+		return nil
+	}
+}
+
+func TestPublishEnvelope(t *testing.T) {
+	announcement := &api.Envelope{Payload: &api.Envelope_Announcement{Announcement: &api.Announcement{Message: "Hello"}}}
+	kick := &api.Envelope{Payload: &api.Envelope_KickRequest{KickRequest: &api.KickRequest{AccountId: 1}}}
+
+	tests := map[string]struct {
+		envelope  *api.Envelope
+		delivered bool
+	}{
+		"announcement": {envelope: announcement, delivered: true},
+		"kick_request": {envelope: kick, delivered: true},
+		"no_payload":   {envelope: &api.Envelope{OriginShip: "First"}, delivered: false},
+	}
+
+	for name, tt := range tests {
+		t.Run(name, func(t *testing.T) {
+			s := &shipgateServiceServer{}
+			first, _ := s.subscribe()
+			second, _ := s.subscribe()
+
+			s.publishEnvelope(tt.envelope)
+			for _, envelopes := range []chan *api.Envelope{first, second} {
+				if envelope := receiveEnvelope(envelopes); (envelope != nil) != tt.delivered {
+					t.Errorf("expected delivered = %v, got = %v", tt.delivered, envelope)
+				}
+			}
+		})
+	}
+}
+
+func TestPublishEnvelope_SlowSubscriber(t *testing.T) {
+	s := &shipgateServiceServer{}
+	slow, _ := s.subscribe()
+	fast, _ := s.subscribe()
+
+	announcement := &api.Envelope{Payload: &api.Envelope_Announcement{Announcement: &api.Announcement{Message: "Hello"}}}
+	for i := 0; i <= messageBusBufferSize; i++ {
+		s.publishEnvelope(announcement)
+		// Only one of the subscribers keeps up.
+		receiveEnvelope(fast)
+	}
+
+	received := 0
+	for range slow {
+		received++
+	}
+	if received != messageBusBufferSize {
+		t.Errorf("expected %d envelopes before the slow subscriber was disconnected, got = %d", messageBusBufferSize, received)
+	}
+	if !s.subscribers[fast] || len(s.subscribers) != 1 {
+		t.Errorf("expected only the fast subscriber to remain connected")
+	}
+
+	s.closeMessageBus()
+	if _, ok := <-fast; ok {
+		t.Errorf("expected subscriber to be disconnected when the message bus is closed")
+	}
+	if _, err := s.subscribe(); err == nil {
+		t.Errorf("expected subscriptions to be refused after the message bus is closed")
+	}
+}
+
+// serveShipgate serves s on a local port for the duration of the test and returns
+// a client connected to it.
+func serveShipgate(t *testing.T, s *shipgateServiceServer) api.ShipgateServiceClient {
+	listener, err := net.Listen("tcp", "127.0.0.1:0")
+	if err != nil {
+		t.Fatalf("failed to listen: %v", err)
+	}
+	server := grpc.NewServer()
+	api.RegisterShipgateServiceServer(server, s)
+	go func() { _ = server.Serve(listener) }()
+
+	conn, err := grpc.Dial(listener.Addr().String(), grpc.WithInsecure())
+	if err != nil {
+		t.Fatalf("failed to connect to shipgate: %v", err)
+	}
+	t.Cleanup(func() {
+		_ = conn.Close()
+		s.closeMessageBus()
+		server.Stop()
+	})
+	return api.NewShipgateServiceClient(conn)
+}
+
+// waitForEnvelope returns the next envelope received by a ship, failing the test
+// if there isn't one.
+func waitForEnvelope(t *testing.T, envelopes chan *api.Envelope) *api.Envelope {
+	select {
+	case envelope := <-envelopes:
+		return envelope
+	case <-time.After(5 * time.Second):
+		t.Fatalf("timed out waiting for envelope")
+		return nil
+	}
+}
+
+func TestMessageBus(t *testing.T) {
+	s := &shipgateServiceServer{sessions: make(map[uint64]*accountSession)}
+	s.setAllowedShips([]archon.AllowedShipConfig{
+		{Name: "First", Secret: "first-secret"},
+		{Name: "Second", Secret: "second-secret"},
+	})
+	client := serveShipgate(t, s)
+
+	ctx, cancel := context.WithCancel(context.Background())
+	defer cancel()
+
+	first, second := make(chan *api.Envelope, 8), make(chan *api.Envelope, 8)
+	firstBus := NewMessageBus(client, "First", "first-secret", func(e *api.Envelope) { first <- e })
+	firstBus.Start(ctx)
+	NewMessageBus(client, "Second", "second-secret", func(e *api.Envelope) { second <- e }).Start(ctx)
+	// A ship presenting another's secret isn't connected.
+	NewMessageBus(client, "Second", "first-secret", func(*api.Envelope) {}).Start(ctx)
+
+	for deadline := time.Now().Add(5 * time.Second); ; time.Sleep(10 * time.Millisecond) {
+		s.subscribersMutex.Lock()
+		connected := len(s.subscribers)
+		s.subscribersMutex.Unlock()
+		if connected == 2 {
+			break
+		} else if time.Now().After(deadline) {
+			t.Fatalf("expected 2 ships to connect to the message bus, got %d", connected)
+		}
+	}
+
+	// Envelopes published by a ship are delivered to every ship.
+	announcement := &api.Envelope{Payload: &api.Envelope_Announcement{Announcement: &api.Announcement{Message: "Hello"}}}
+	if err := firstBus.Publish(announcement); err != nil {
+		t.Fatalf("unexpected error publishing envelope: %v", err)
+	}
+	for _, envelopes := range []chan *api.Envelope{first, second} {
+		envelope := waitForEnvelope(t, envelopes)
+		if envelope.GetOriginShip() != "First" || envelope.GetAnnouncement().GetMessage() != "Hello" {
+			t.Errorf("expected announcement from First, got %v", envelope)
+		}
+	}
+
+	// As are requests to kick the players whose sessions are taken over.
+	for _, sessionID := range []string{"old", "new"} {
+		req := &api.SessionRequest{AccountId: 1, SessionId: sessionID, Ship: "First", Takeover: true}
+		if _, err := client.AcquireSession(ctx, req); err != nil {
+			t.Fatalf("unexpected error acquiring session: %v", err)
+		}
+	}
+	for _, envelopes := range []chan *api.Envelope{first, second} {
+		kick := waitForEnvelope(t, envelopes).GetKickRequest()
+		if kick.GetAccountId() != 1 || kick.GetSessionId() != "old" {
+			t.Errorf("expected request to kick session old, got %v", kick)
+		}
+	}
+}
@@ -0,0 +1,44 @@
+package shipgate
+
+import (
+	"context"
+
+	"github.com/sirupsen/logrus"
+	"google.golang.org/grpc"
+	"google.golang.org/grpc/metadata"
+
+	"github.com/dcrodman/archon"
+)
+
+// Metadata key under which the ID of the client session on whose behalf an RPC
+// is being made is sent to the shipgate.
+const sessionIDMetadataKey = "x-session-id"
+
+// SessionIDInterceptor is a client interceptor that attaches the session ID
+// carried by the context (if any) to outgoing RPCs so that the shipgate's log
+// messages can be correlated with the client's.
+func SessionIDInterceptor(ctx context.Context, method string, req, reply interface{},
+	cc *grpc.ClientConn, invoker grpc.UnaryInvoker, opts ...grpc.CallOption) error {
+	if sessionID := archon.SessionIDFromContext(ctx); sessionID != "" {
+		ctx = metadata.AppendToOutgoingContext(ctx, sessionIDMetadataKey, sessionID)
+	}
+	return invoker(ctx, method, req, reply, cc, opts...)
+}
+
+// sessionLoggingInterceptor adds a logger annotated with the caller's session ID
+// (if one was sent) to the context of each RPC handled by the shipgate.
+func sessionLoggingInterceptor(ctx context.Context, req interface{}, info *grpc.UnaryServerInfo, handler grpc.UnaryHandler) (interface{}, error) {
+	fields := logrus.Fields{"rpc": info.FullMethod}
+
+	if md, ok := metadata.FromIncomingContext(ctx); ok {
+		if ids := md.Get(sessionIDMetadataKey); len(ids) > 0 {
+			fields["session_id"] = ids[0]
+			ctx = archon.WithSessionID(ctx, ids[0])
+		}
+	}
+
+	logger := archon.Log.WithFields(fields)
+	logger.Debug("SHIPGATE handling request")
+
+	return handler(archon.WithLogger(ctx, logger), req)
+}
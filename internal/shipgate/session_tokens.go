@@ -0,0 +1,86 @@
+package shipgate
+
+import (
+	"context"
+	"crypto/rand"
+	"crypto/subtle"
+	"errors"
+	"time"
+
+	"github.com/dcrodman/archon"
+	"github.com/dcrodman/archon/internal/core/auth"
+	"github.com/dcrodman/archon/internal/core/metrics"
+	"github.com/dcrodman/archon/internal/shipgate/api"
+)
+
+// How long a session token remains valid after it was last used. Tokens for
+// accounts whose sessions are being renewed don't expire.
+const sessionTokenLifetime = time.Hour
+
+// Number of bytes in a session token, which has to fit in the client's config.
+const sessionTokenLength = 16
+
+var errInvalidSessionToken = errors.New("invalid or expired session token")
+
+// sessionToken is issued to an account when it logs in through the LOGIN server
+// so that the other servers don't need its password to authenticate it.
+type sessionToken struct {
+	token   []byte
+	expires time.Time
+}
+
+func (s *shipgateServiceServer) AuthenticateSessionToken(ctx context.Context, req *api.SessionTokenAuthRequest) (*api.AccountAuthResponse, error) {
+	account, err := auth.LookupAccount(req.GetUsername())
+	if err == nil {
+		err = s.verifySessionToken(uint64(account.ID), req.GetSessionToken())
+	}
+	metrics.ShipgateAuthentications.WithLabelValues(authenticationResult(err)).Inc()
+	if err != nil {
+		archon.LoggerFromContext(ctx).WithField("account", req.GetUsername()).
+			Infof("SHIPGATE failed to authenticate session token: %v", err)
+		return nil, authenticationStatus(err)
+	}
+	return accountResponse(account), nil
+}
+
+// issueSessionToken creates a new session token for the account, replacing any
+// that was previously issued.
+func (s *shipgateServiceServer) issueSessionToken(accountID uint64) ([]byte, error) {
+	token := make([]byte, sessionTokenLength)
+	if _, err := rand.Read(token); err != nil {
+		return nil, err
+	}
+
+	s.sessionsMutex.Lock()
+	defer s.sessionsMutex.Unlock()
+
+	for id, existing := range s.sessionTokens {
+		if now().After(existing.expires) {
+			delete(s.sessionTokens, id)
+		}
+	}
+	s.sessionTokens[accountID] = &sessionToken{token: token, expires: now().Add(sessionTokenLifetime)}
+	return token, nil
+}
+
+// verifySessionToken checks that token is the account's current session token
+// and extends its lifetime.
+func (s *shipgateServiceServer) verifySessionToken(accountID uint64, token []byte) error {
+	s.sessionsMutex.Lock()
+	defer s.sessionsMutex.Unlock()
+
+	existing, ok := s.sessionTokens[accountID]
+	if !ok || now().After(existing.expires) || subtle.ConstantTimeCompare(existing.token, token) != 1 {
+		return errInvalidSessionToken
+	}
+	existing.expires = now().Add(sessionTokenLifetime)
+	return nil
+}
+
+// extendSessionToken keeps the account's session token (if any) from expiring.
+// Must be called with the sessions lock held.
+func (s *shipgateServiceServer) extendSessionToken(accountID uint64) {
+	if existing, ok := s.sessionTokens[accountID]; ok && !now().After(existing.expires) {
+		existing.expires = now().Add(sessionTokenLifetime)
+	}
+}
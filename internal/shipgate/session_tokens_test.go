@@ -0,0 +1,63 @@
+package shipgate
+
+import (
+	"bytes"
+	"context"
+	"testing"
+	"time"
+
+	"github.com/dcrodman/archon/internal/shipgate/api"
+)
+
+func TestVerifySessionToken(t *testing.T) {
+	originalNow := now
+	defer func() { now = originalNow }()
+
+	current := time.Now()
+	now = func() time.Time { return current }
+
+	s := &shipgateServiceServer{
+		sessions:      make(map[uint64]*accountSession),
+		sessionTokens: make(map[uint64]*sessionToken),
+	}
+	first, err := s.issueSessionToken(1)
+	if err != nil {
+		t.Fatalf("failed to issue session token: %v", err)
+	}
+	if len(first) != sessionTokenLength {
+		t.Errorf("expected a %d byte token, got %d bytes", sessionTokenLength, len(first))
+	}
+
+	if err := s.verifySessionToken(1, first); err != nil {
+		t.Errorf("expected token to be valid, got: %v", err)
+	}
+	if err := s.verifySessionToken(2, first); err != errInvalidSessionToken {
+		t.Errorf("expected token to be invalid for another account, got: %v", err)
+	}
+
+	// Logging in again revokes the previous token.
+	second, _ := s.issueSessionToken(1)
+	if err := s.verifySessionToken(1, first); err != errInvalidSessionToken {
+		t.Errorf("expected previous token to be revoked, got: %v", err)
+	}
+	if bytes.Equal(first, second) {
+		t.Error("expected a new token to be issued")
+	}
+
+	// Renewing the account's session keeps the token from expiring.
+	_, _ = s.AcquireSession(context.Background(), &api.SessionRequest{AccountId: 1, SessionId: "block"})
+	for elapsed := time.Duration(0); elapsed <= sessionTokenLifetime; elapsed += SessionRenewalInterval {
+		current = current.Add(SessionRenewalInterval)
+		_, _ = s.RenewSessions(context.Background(), &api.SessionRenewalRequest{
+			Sessions: []*api.SessionRequest{{AccountId: 1, SessionId: "block"}},
+		})
+	}
+	if err := s.verifySessionToken(1, second); err != nil {
+		t.Errorf("expected token to be valid while its session is renewed, got: %v", err)
+	}
+
+	current = current.Add(sessionTokenLifetime + time.Second)
+	if err := s.verifySessionToken(1, second); err != errInvalidSessionToken {
+		t.Errorf("expected token to expire, got: %v", err)
+	}
+}
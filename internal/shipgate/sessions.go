@@ -0,0 +1,161 @@
+package shipgate
+
+import (
+	"context"
+	"time"
+
+	"google.golang.org/grpc/codes"
+	"google.golang.org/grpc/status"
+	"google.golang.org/protobuf/types/known/emptypb"
+
+	"github.com/dcrodman/archon"
+	"github.com/dcrodman/archon/internal/shipgate/api"
+)
+
+// How long an account session is held without being renewed. Holders should
+// renew their sessions well within this window (see SessionRenewalInterval).
+const sessionLeaseDuration = 30 * time.Second
+
+// SessionRenewalInterval is how often holders of account sessions should renew them.
+const SessionRenewalInterval = sessionLeaseDuration / 3
+
+// accountSession records which client connection an account is logged in through.
+type accountSession struct {
+	sessionID string
+	ship      string
+	block     string
+	expires   time.Time
+}
+
+// Overridden in tests.
+var now = time.Now
+
+func (s *shipgateServiceServer) AcquireSession(ctx context.Context, req *api.SessionRequest) (*emptypb.Empty, error) {
+	s.sessionsMutex.Lock()
+	defer s.sessionsMutex.Unlock()
+
+	s.pruneExpiredSessions()
+	logger := archon.LoggerFromContext(ctx).WithField("account_id", req.GetAccountId())
+
+	if err := s.checkShipRestrictions(req); err != nil {
+		logger.Infof("SHIPGATE rejected login to ship %s: %s", req.GetShip(), status.Convert(err).Message())
+		return nil, err
+	}
+
+	existing, ok := s.sessions[req.GetAccountId()]
+	if ok && existing.sessionID != req.GetSessionId() && !now().After(existing.expires) {
+		if !req.GetTakeover() {
+			logger.Infof("SHIPGATE rejected duplicate login (already logged in on %s %s)",
+				existing.ship, existing.block)
+			return nil, status.Error(codes.AlreadyExists, "account is already logged in")
+		}
+		logger.Infof("SHIPGATE revoked session %s on %s %s due to a new login",
+			existing.sessionID, existing.ship, existing.block)
+		// The block would find out the next time it renews its sessions, but this
+		// way the player doesn't linger in the meantime.
+		s.publishEnvelope(&api.Envelope{Payload: &api.Envelope_KickRequest{KickRequest: &api.KickRequest{
+			AccountId: req.GetAccountId(),
+			SessionId: existing.sessionID,
+			Reason:    "logged in elsewhere",
+		}}})
+	}
+
+	s.sessions[req.GetAccountId()] = &accountSession{
+		sessionID: req.GetSessionId(),
+		ship:      req.GetShip(),
+		block:     req.GetBlock(),
+		expires:   now().Add(sessionLeaseDuration),
+	}
+	return &emptypb.Empty{}, nil
+}
+
+func (s *shipgateServiceServer) RenewSessions(ctx context.Context, req *api.SessionRenewalRequest) (*api.SessionRenewalResponse, error) {
+	s.sessionsMutex.Lock()
+	defer s.sessionsMutex.Unlock()
+
+	logger := archon.LoggerFromContext(ctx)
+	resp := &api.SessionRenewalResponse{}
+	for _, session := range req.GetSessions() {
+		existing, ok := s.sessions[session.GetAccountId()]
+		switch {
+		case !ok:
+			// The shipgate doesn't know about the session (most likely because it
+			// restarted), so the ship holds on to it as long as it could acquire it now.
+			if err := s.checkShipRestrictions(session); err != nil {
+				logger.WithField("account_id", session.GetAccountId()).
+					Infof("SHIPGATE revoked unknown session %s on %s: %s", session.GetSessionId(), session.GetShip(), status.Convert(err).Message())
+				resp.RevokedSessionIds = append(resp.RevokedSessionIds, session.GetSessionId())
+				continue
+			}
+			s.sessions[session.GetAccountId()] = &accountSession{
+				sessionID: session.GetSessionId(),
+				ship:      session.GetShip(),
+				block:     session.GetBlock(),
+				expires:   now().Add(sessionLeaseDuration),
+			}
+		case existing.sessionID != session.GetSessionId() || existing.ship != session.GetShip():
+			// A ship can only renew its own sessions.
+			resp.RevokedSessionIds = append(resp.RevokedSessionIds, session.GetSessionId())
+			continue
+		case now().After(existing.expires):
+			resp.RevokedSessionIds = append(resp.RevokedSessionIds, session.GetSessionId())
+			continue
+		default:
+			existing.expires = now().Add(sessionLeaseDuration)
+		}
+		s.extendSessionToken(session.GetAccountId())
+	}
+	return resp, nil
+}
+
+func (s *shipgateServiceServer) ReleaseSession(ctx context.Context, req *api.SessionRequest) (*emptypb.Empty, error) {
+	s.sessionsMutex.Lock()
+	defer s.sessionsMutex.Unlock()
+
+	// The session may have already been taken over by another login.
+	if existing, ok := s.sessions[req.GetAccountId()]; ok && existing.sessionID == req.GetSessionId() && existing.ship == req.GetShip() {
+		delete(s.sessions, req.GetAccountId())
+	}
+	return &emptypb.Empty{}, nil
+}
+
+// checkShipRestrictions returns an error if the account can't hold a session on the
+// ship that req was made by, either because the ship is only open to GMs or because
+// it's full. Must be called with the sessions lock held.
+func (s *shipgateServiceServer) checkShipRestrictions(req *api.SessionRequest) error {
+	allowed, ok := s.allowedShip(req.GetShip())
+	if !ok {
+		return nil
+	}
+	if allowed.GMOnly && !req.GetGm() {
+		return status.Error(codes.PermissionDenied, "ship is only open to GMs")
+	}
+	if allowed.MaxPlayers > 0 && s.shipSessions(req.GetShip(), req.GetAccountId()) >= allowed.MaxPlayers {
+		return status.Error(codes.ResourceExhausted, "ship is full")
+	}
+	return nil
+}
+
+// shipSessions returns the number of accounts other than accountID that are logged
+// into ship. Must be called with the sessions lock held.
+func (s *shipgateServiceServer) shipSessions(ship string, accountID uint64) int {
+	count := 0
+	for id, session := range s.sessions {
+		if id != accountID && session.ship == ship && !now().After(session.expires) {
+			count++
+		}
+	}
+	return count
+}
+
+// pruneExpiredSessions removes the sessions whose leases expired long enough ago
+// that their holders have stopped renewing them. Sessions that only just expired are
+// kept so that a late renewal is revoked rather than treated as one the shipgate
+// has never seen. Must be called with the sessions lock held.
+func (s *shipgateServiceServer) pruneExpiredSessions() {
+	for accountID, session := range s.sessions {
+		if now().After(session.expires.Add(sessionLeaseDuration)) {
+			delete(s.sessions, accountID)
+		}
+	}
+}
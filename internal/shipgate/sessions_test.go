@@ -0,0 +1,243 @@
+package shipgate
+
+import (
+	"context"
+	"io/ioutil"
+	"reflect"
+	"testing"
+	"time"
+
+	"github.com/sirupsen/logrus"
+	"google.golang.org/grpc/codes"
+	"google.golang.org/grpc/status"
+
+	"github.com/dcrodman/archon"
+	"github.com/dcrodman/archon/internal/shipgate/api"
+)
+
+func TestMain(m *testing.M) {
+	archon.Log = logrus.New()
+	archon.Log.Out = ioutil.Discard
+	m.Run()
+}
+
+func TestAcquireSession(t *testing.T) {
+	tests := map[string]struct {
+		existing   *accountSession
+		takeover   bool
+		wantedCode codes.Code
+		wantedID   string
+	}{
+		"no_existing_session": {
+			wantedCode: codes.OK,
+			wantedID:   "new",
+		},
+		"same_session": {
+			existing:   &accountSession{sessionID: "new", expires: time.Now().Add(time.Minute)},
+			wantedCode: codes.OK,
+			wantedID:   "new",
+		},
+		"duplicate_login": {
+			existing:   &accountSession{sessionID: "old", expires: time.Now().Add(time.Minute)},
+			wantedCode: codes.AlreadyExists,
+			wantedID:   "old",
+		},
+		"duplicate_login_takeover": {
+			existing:   &accountSession{sessionID: "old", expires: time.Now().Add(time.Minute)},
+			takeover:   true,
+			wantedCode: codes.OK,
+			wantedID:   "new",
+		},
+		"expired_session": {
+			existing:   &accountSession{sessionID: "old", expires: time.Now().Add(-time.Second)},
+			wantedCode: codes.OK,
+			wantedID:   "new",
+		},
+	}
+
+	for name, tt := range tests {
+		t.Run(name, func(t *testing.T) {
+			s := &shipgateServiceServer{sessions: make(map[uint64]*accountSession)}
+			if tt.existing != nil {
+				s.sessions[1] = tt.existing
+			}
+
+			_, err := s.AcquireSession(context.Background(), &api.SessionRequest{
+				AccountId: 1, SessionId: "new", Takeover: tt.takeover,
+			})
+			if code := status.Code(err); code != tt.wantedCode {
+				t.Errorf("expected code = %v, got = %v", tt.wantedCode, code)
+			}
+			if id := s.sessions[1].sessionID; id != tt.wantedID {
+				t.Errorf("expected session holder = %s, got = %s", tt.wantedID, id)
+			}
+		})
+	}
+}
+
+func TestRenewSessions(t *testing.T) {
+	originalNow := now
+	defer func() { now = originalNow }()
+
+	current := time.Now()
+	now = func() time.Time { return current }
+
+	s := &shipgateServiceServer{sessions: make(map[uint64]*accountSession)}
+	for accountID, sessionID := range map[uint64]string{1: "first", 2: "second"} {
+		_, _ = s.AcquireSession(context.Background(), &api.SessionRequest{AccountId: accountID, SessionId: sessionID})
+	}
+	// The second account logs in again elsewhere.
+	_, _ = s.AcquireSession(context.Background(), &api.SessionRequest{AccountId: 2, SessionId: "third", Takeover: true})
+
+	current = current.Add(sessionLeaseDuration - time.Second)
+	resp, err := s.RenewSessions(context.Background(), &api.SessionRenewalRequest{
+		Sessions: []*api.SessionRequest{
+			{AccountId: 1, SessionId: "first"},
+			{AccountId: 2, SessionId: "second"},
+		},
+	})
+	if err != nil {
+		t.Fatalf("unexpected error: %v", err)
+	}
+	if revoked := resp.GetRevokedSessionIds(); len(revoked) != 1 || revoked[0] != "second" {
+		t.Errorf("expected only the second session to be revoked, got %v", revoked)
+	}
+
+	// The renewed session should outlive its original lease while the other expires.
+	current = current.Add(2 * time.Second)
+	resp, _ = s.RenewSessions(context.Background(), &api.SessionRenewalRequest{
+		Sessions: []*api.SessionRequest{
+			{AccountId: 1, SessionId: "first"},
+			{AccountId: 2, SessionId: "third"},
+		},
+	})
+	if revoked := resp.GetRevokedSessionIds(); len(revoked) != 1 || revoked[0] != "third" {
+		t.Errorf("expected only the expired session to be revoked, got %v", revoked)
+	}
+
+	// Releasing a session that was taken over has no effect.
+	_, _ = s.ReleaseSession(context.Background(), &api.SessionRequest{AccountId: 2, SessionId: "second"})
+	if _, ok := s.sessions[2]; !ok {
+		t.Error("expected session held by another connection to remain")
+	}
+
+	// Nor can a ship renew or release another ship's sessions.
+	resp, _ = s.RenewSessions(context.Background(), &api.SessionRenewalRequest{
+		Sessions: []*api.SessionRequest{{AccountId: 1, SessionId: "first", Ship: "Other"}},
+	})
+	if revoked := resp.GetRevokedSessionIds(); len(revoked) != 1 || revoked[0] != "first" {
+		t.Errorf("expected session held on another ship to be revoked, got %v", revoked)
+	}
+	_, _ = s.ReleaseSession(context.Background(), &api.SessionRequest{AccountId: 1, SessionId: "first", Ship: "Other"})
+	if _, ok := s.sessions[1]; !ok {
+		t.Error("expected session held on another ship to remain")
+	}
+}
+
+func TestRenewSessions_AfterRestart(t *testing.T) {
+	tests := map[string]struct {
+		ship archon.AllowedShipConfig
+		// Sessions renewed by the ship after the shipgate restarts.
+		renewals      []*api.SessionRequest
+		wantedRevoked []string
+	}{
+		"readopted": {
+			ship: archon.AllowedShipConfig{Name: "Ship"},
+			renewals: []*api.SessionRequest{
+				{AccountId: 1, SessionId: "first", Ship: "Ship"},
+				{AccountId: 2, SessionId: "second", Ship: "Ship"},
+			},
+		},
+		"ship_full": {
+			ship: archon.AllowedShipConfig{Name: "Ship", MaxPlayers: 1},
+			renewals: []*api.SessionRequest{
+				{AccountId: 1, SessionId: "first", Ship: "Ship"},
+				{AccountId: 2, SessionId: "second", Ship: "Ship"},
+			},
+			wantedRevoked: []string{"second"},
+		},
+		"gm_only": {
+			ship: archon.AllowedShipConfig{Name: "Ship", GMOnly: true},
+			renewals: []*api.SessionRequest{
+				{AccountId: 1, SessionId: "first", Ship: "Ship", Gm: true},
+				{AccountId: 2, SessionId: "second", Ship: "Ship"},
+			},
+			wantedRevoked: []string{"second"},
+		},
+	}
+
+	for name, tt := range tests {
+		t.Run(name, func(t *testing.T) {
+			s := &shipgateServiceServer{sessions: make(map[uint64]*accountSession)}
+			s.setAllowedShips([]archon.AllowedShipConfig{{Name: "Ship"}})
+			for _, session := range tt.renewals {
+				if _, err := s.AcquireSession(context.Background(), session); err != nil {
+					t.Fatalf("unexpected error acquiring session: %v", err)
+				}
+			}
+
+			// The shipgate restarts, losing every session, and has been reconfigured.
+			s = &shipgateServiceServer{sessions: make(map[uint64]*accountSession)}
+			s.setAllowedShips([]archon.AllowedShipConfig{tt.ship})
+
+			resp, err := s.RenewSessions(context.Background(), &api.SessionRenewalRequest{Sessions: tt.renewals})
+			if err != nil {
+				t.Fatalf("unexpected error: %v", err)
+			}
+			if revoked := resp.GetRevokedSessionIds(); !reflect.DeepEqual(revoked, tt.wantedRevoked) {
+				t.Errorf("expected revoked sessions = %v, got = %v", tt.wantedRevoked, revoked)
+			}
+
+			// Sessions that weren't revoked are held again, so the accounts can't log in
+			// anywhere else.
+			for _, session := range tt.renewals {
+				_, err := s.AcquireSession(context.Background(), &api.SessionRequest{
+					AccountId: session.GetAccountId(), SessionId: "elsewhere", Ship: "Ship", Gm: true,
+				})
+				wantedCode := codes.AlreadyExists
+				for _, revoked := range tt.wantedRevoked {
+					if revoked == session.GetSessionId() {
+						wantedCode = codes.OK
+					}
+				}
+				if tt.ship.MaxPlayers > 0 && wantedCode == codes.OK {
+					// The revoked session's account can't log in while the ship is full.
+					wantedCode = codes.ResourceExhausted
+				}
+				if code := status.Code(err); code != wantedCode {
+					t.Errorf("expected code = %v logging in account %d elsewhere, got = %v", wantedCode, session.GetAccountId(), code)
+				}
+			}
+		})
+	}
+}
+
+func TestAcquireSession_ShipRestrictions(t *testing.T) {
+	tests := map[string]struct {
+		ship       archon.AllowedShipConfig
+		gm         bool
+		wantedCode codes.Code
+	}{
+		"unrestricted":  {ship: archon.AllowedShipConfig{Name: "Ship"}, wantedCode: codes.OK},
+		"full":          {ship: archon.AllowedShipConfig{Name: "Ship", MaxPlayers: 1}, wantedCode: codes.ResourceExhausted},
+		"not_full":      {ship: archon.AllowedShipConfig{Name: "Ship", MaxPlayers: 2}, wantedCode: codes.OK},
+		"gm_only":       {ship: archon.AllowedShipConfig{Name: "Ship", GMOnly: true}, wantedCode: codes.PermissionDenied},
+		"gm_only_as_gm": {ship: archon.AllowedShipConfig{Name: "Ship", GMOnly: true}, gm: true, wantedCode: codes.OK},
+	}
+
+	for name, tt := range tests {
+		t.Run(name, func(t *testing.T) {
+			s := &shipgateServiceServer{sessions: make(map[uint64]*accountSession)}
+			s.setAllowedShips([]archon.AllowedShipConfig{tt.ship})
+			// Another account is already logged into the ship.
+			s.sessions[1] = &accountSession{sessionID: "other", ship: "Ship", expires: time.Now().Add(time.Minute)}
+
+			_, err := s.AcquireSession(context.Background(), &api.SessionRequest{
+				AccountId: 2, SessionId: "new", Ship: "Ship", Gm: tt.gm,
+			})
+			if code := status.Code(err); code != tt.wantedCode {
+				t.Errorf("expected code = %v, got = %v", tt.wantedCode, code)
+			}
+		})
+	}
+}
@@ -0,0 +1,127 @@
+package shipgate
+
+import (
+	"context"
+	"crypto/subtle"
+
+	"google.golang.org/grpc"
+	"google.golang.org/grpc/codes"
+	"google.golang.org/grpc/credentials"
+	"google.golang.org/grpc/metadata"
+	"google.golang.org/grpc/peer"
+	"google.golang.org/grpc/status"
+
+	"github.com/dcrodman/archon"
+	"github.com/dcrodman/archon/internal/shipgate/api"
+)
+
+// Metadata key under which ships send their shared secret to the shipgate.
+const shipSecretMetadataKey = "x-ship-secret"
+
+// WithShipSecret returns a copy of ctx that sends secret to the shipgate in order
+// to authenticate RPCs made on behalf of a ship.
+func WithShipSecret(ctx context.Context, secret string) context.Context {
+	if secret == "" {
+		return ctx
+	}
+	return metadata.AppendToOutgoingContext(ctx, shipSecretMetadataKey, secret)
+}
+
+// setAllowedShips replaces the list of ships that are allowed to register.
+func (s *shipgateServiceServer) setAllowedShips(ships []archon.AllowedShipConfig) {
+	allowed := make(map[string]archon.AllowedShipConfig)
+	for _, ship := range ships {
+		allowed[ship.Name] = ship
+	}
+
+	s.allowedShipsMutex.Lock()
+	s.allowedShips = allowed
+	s.allowedShipsMutex.Unlock()
+}
+
+// allowedShip returns the allow-list entry for the ship named name, if there is one.
+func (s *shipgateServiceServer) allowedShip(name string) (archon.AllowedShipConfig, bool) {
+	s.allowedShipsMutex.RLock()
+	defer s.allowedShipsMutex.RUnlock()
+	ship, ok := s.allowedShips[name]
+	return ship, ok
+}
+
+// A request made on behalf of a ship, which has to be authenticated as that ship.
+type shipRequest interface {
+	GetName() string
+}
+
+// shipAuthInterceptor rejects requests made on behalf of a ship unless the caller
+// is able to authenticate as that ship.
+func (s *shipgateServiceServer) shipAuthInterceptor(ctx context.Context, req interface{}, info *grpc.UnaryServerInfo, handler grpc.UnaryHandler) (interface{}, error) {
+	for _, name := range requestShips(req) {
+		if err := s.authenticateShip(ctx, name); err != nil {
+			archon.LoggerFromContext(ctx).WithField("ship", name).
+				Warnf("SHIPGATE rejected ship: %v", status.Convert(err).Message())
+			return nil, err
+		}
+	}
+	return handler(ctx, req)
+}
+
+// requestShips returns the names of the ships on whose behalf req is made. Other
+// requests (e.g. AuthenticateAccount and AuthenticateSessionToken, which are also
+// made by the login and character servers) only require the client certificate
+// presented on every connection, since they're authorized by the credentials or
+// session token in the request rather than by the server making it.
+func requestShips(req interface{}) []string {
+	switch req := req.(type) {
+	case shipRequest:
+		return []string{req.GetName()}
+	case *api.SessionRequest:
+		return []string{req.GetShip()}
+	case *api.SessionRenewalRequest:
+		var names []string
+		seen := make(map[string]bool)
+		for _, session := range req.GetSessions() {
+			if !seen[session.GetShip()] {
+				seen[session.GetShip()] = true
+				names = append(names, session.GetShip())
+			}
+		}
+		return names
+	default:
+		return nil
+	}
+}
+
+// authenticateShip checks that the caller is allowed to act as the ship named name,
+// either by presenting a client certificate issued for that name or the ship's secret.
+func (s *shipgateServiceServer) authenticateShip(ctx context.Context, name string) error {
+	allowed, ok := s.allowedShip(name)
+	if !ok {
+		return status.Error(codes.PermissionDenied, "ship is not on the shipgate's allow-list")
+	}
+
+	if peerCommonName(ctx) == name {
+		return nil
+	}
+	if md, ok := metadata.FromIncomingContext(ctx); ok && allowed.Secret != "" {
+		for _, secret := range md.Get(shipSecretMetadataKey) {
+			if subtle.ConstantTimeCompare([]byte(secret), []byte(allowed.Secret)) == 1 {
+				return nil
+			}
+		}
+	}
+	return status.Error(codes.Unauthenticated, "invalid ship credentials")
+}
+
+// peerCommonName returns the common name of the verified client certificate the
+// caller presented, if any.
+func peerCommonName(ctx context.Context) string {
+	p, ok := peer.FromContext(ctx)
+	if !ok {
+		return ""
+	}
+	tlsInfo, ok := p.AuthInfo.(credentials.TLSInfo)
+	if !ok || len(tlsInfo.State.VerifiedChains) == 0 || len(tlsInfo.State.VerifiedChains[0]) == 0 {
+		return ""
+	}
+	return tlsInfo.State.VerifiedChains[0][0].Subject.CommonName
+}
@@ -0,0 +1,101 @@
+package shipgate
+
+import (
+	"context"
+	"crypto/tls"
+	"crypto/x509"
+	"crypto/x509/pkix"
+	"testing"
+
+	"google.golang.org/grpc"
+	"google.golang.org/grpc/codes"
+	"google.golang.org/grpc/credentials"
+	"google.golang.org/grpc/metadata"
+	"google.golang.org/grpc/peer"
+	"google.golang.org/grpc/status"
+
+	"github.com/dcrodman/archon"
+	"github.com/dcrodman/archon/internal/shipgate/api"
+)
+
+func TestAuthenticateShip(t *testing.T) {
+	withSecret := func(secret string) context.Context {
+		return metadata.NewIncomingContext(context.Background(), metadata.Pairs(shipSecretMetadataKey, secret))
+	}
+	withCertificate := func(commonName string) context.Context {
+		cert := &x509.Certificate{Subject: pkix.Name{CommonName: commonName}}
+		return peer.NewContext(context.Background(), &peer.Peer{
+			AuthInfo: credentials.TLSInfo{State: tls.ConnectionState{VerifiedChains: [][]*x509.Certificate{{cert}}}},
+		})
+	}
+
+	tests := map[string]struct {
+		ctx        context.Context
+		ship       string
+		wantedCode codes.Code
+	}{
+		"secret":                 {ctx: withSecret("hunter2"), ship: "Secret", wantedCode: codes.OK},
+		"wrong_secret":           {ctx: withSecret("hunter3"), ship: "Secret", wantedCode: codes.Unauthenticated},
+		"no_credentials":         {ctx: context.Background(), ship: "Secret", wantedCode: codes.Unauthenticated},
+		"unknown_ship":           {ctx: withSecret("hunter2"), ship: "Unknown", wantedCode: codes.PermissionDenied},
+		"certificate":            {ctx: withCertificate("Certificate"), ship: "Certificate", wantedCode: codes.OK},
+		"certificate_other_ship": {ctx: withCertificate("Secret"), ship: "Certificate", wantedCode: codes.Unauthenticated},
+		"empty_secret":           {ctx: withSecret(""), ship: "Certificate", wantedCode: codes.Unauthenticated},
+	}
+
+	s := &shipgateServiceServer{}
+	s.setAllowedShips([]archon.AllowedShipConfig{
+		{Name: "Secret", Secret: "hunter2"},
+		// Can only authenticate with a client certificate.
+		{Name: "Certificate"},
+	})
+
+	for name, tt := range tests {
+		t.Run(name, func(t *testing.T) {
+			err := s.authenticateShip(tt.ctx, tt.ship)
+			if code := status.Code(err); code != tt.wantedCode {
+				t.Errorf("expected code = %v, got = %v", tt.wantedCode, code)
+			}
+		})
+	}
+}
+
+func TestShipAuthInterceptor(t *testing.T) {
+	tests := map[string]struct {
+		req        interface{}
+		wantedCode codes.Code
+	}{
+		"register":        {req: &api.RegistrationRequest{Name: "Secret"}, wantedCode: codes.OK},
+		"register_other":  {req: &api.RegistrationRequest{Name: "Other"}, wantedCode: codes.Unauthenticated},
+		"acquire_session": {req: &api.SessionRequest{Ship: "Secret"}, wantedCode: codes.OK},
+		"acquire_other":   {req: &api.SessionRequest{Ship: "Other"}, wantedCode: codes.Unauthenticated},
+		"acquire_no_ship": {req: &api.SessionRequest{}, wantedCode: codes.PermissionDenied},
+		"renew_sessions": {
+			req:        &api.SessionRenewalRequest{Sessions: []*api.SessionRequest{{Ship: "Secret"}, {Ship: "Secret"}}},
+			wantedCode: codes.OK,
+		},
+		"renew_other": {
+			req:        &api.SessionRenewalRequest{Sessions: []*api.SessionRequest{{Ship: "Secret"}, {Ship: "Other"}}},
+			wantedCode: codes.Unauthenticated,
+		},
+		// Made by the login and character servers too, so not tied to a ship.
+		"session_token": {req: &api.SessionTokenAuthRequest{Username: "player"}, wantedCode: codes.OK},
+	}
+
+	s := &shipgateServiceServer{}
+	s.setAllowedShips([]archon.AllowedShipConfig{
+		{Name: "Secret", Secret: "hunter2"},
+		{Name: "Other", Secret: "hunter3"},
+	})
+	ctx := metadata.NewIncomingContext(context.Background(), metadata.Pairs(shipSecretMetadataKey, "hunter2"))
+	handler := func(ctx context.Context, req interface{}) (interface{}, error) { return nil, nil }
+
+	for name, tt := range tests {
+		t.Run(name, func(t *testing.T) {
+			_, err := s.shipAuthInterceptor(ctx, tt.req, &grpc.UnaryServerInfo{}, handler)
+			if code := status.Code(err); code != tt.wantedCode {
+				t.Errorf("expected code = %v, got = %v", tt.wantedCode, code)
+			}
+		})
+	}
+}
@@ -0,0 +1,109 @@
+package shipgate
+
+import (
+	"google.golang.org/grpc/codes"
+	"google.golang.org/grpc/status"
+	"google.golang.org/protobuf/types/known/emptypb"
+
+	"github.com/dcrodman/archon/internal/shipgate/api"
+)
+
+// Number of events that can be queued for a watcher before it's disconnected for
+// falling behind.
+const shipWatcherBufferSize = 64
+
+var errShipWatchEnded = status.Error(codes.Unavailable, "ship watch ended; watch again to resynchronize")
+
+func (s *shipgateServiceServer) WatchShips(_ *emptypb.Empty, stream api.ShipgateService_WatchShipsServer) error {
+	events, err := s.addShipWatcher()
+	if err != nil {
+		return err
+	}
+	defer s.removeShipWatcher(events)
+
+	for {
+		select {
+		case <-stream.Context().Done():
+			return nil
+		case event, ok := <-events:
+			if !ok {
+				return errShipWatchEnded
+			}
+			if err := stream.Send(event); err != nil {
+				return err
+			}
+		}
+	}
+}
+
+// addShipWatcher returns a channel on which the current ships are sent, followed
+// by every change to them. The channel is closed if the watcher falls behind or the
+// shipgate is shutting down.
+func (s *shipgateServiceServer) addShipWatcher() (chan *api.ShipEvent, error) {
+	s.connectedShipsMutex.Lock()
+	defer s.connectedShipsMutex.Unlock()
+
+	if s.shipWatchersClosed {
+		return nil, errShipWatchEnded
+	}
+	if s.shipWatchers == nil {
+		s.shipWatchers = make(map[chan *api.ShipEvent]bool)
+	}
+
+	s.deactivateStaleShips()
+	events := make(chan *api.ShipEvent, shipWatcherBufferSize)
+	events <- &api.ShipEvent{Type: api.ShipEvent_SNAPSHOT, Ships: s.activeShips()}
+	s.shipWatchers[events] = true
+	return events, nil
+}
+
+func (s *shipgateServiceServer) removeShipWatcher(events chan *api.ShipEvent) {
+	s.connectedShipsMutex.Lock()
+	defer s.connectedShipsMutex.Unlock()
+
+	if s.shipWatchers[events] {
+		delete(s.shipWatchers, events)
+		close(events)
+	}
+}
+
+// closeShipWatchers ends every watch (and refuses new ones) so that the server
+// can shut down without waiting on them.
+func (s *shipgateServiceServer) closeShipWatchers() {
+	s.connectedShipsMutex.Lock()
+	defer s.connectedShipsMutex.Unlock()
+
+	s.shipWatchersClosed = true
+	for events := range s.shipWatchers {
+		delete(s.shipWatchers, events)
+		close(events)
+	}
+}
+
+// publishShipEvent sends a change to a ship to the watchers. Must be called with
+// the ships lock held.
+func (s *shipgateServiceServer) publishShipEvent(eventType api.ShipEvent_Type, connectedShip *ship) {
+	s.publish(&api.ShipEvent{Type: eventType, Ships: []*api.ShipList_Ship{s.shipListEntry(connectedShip)}})
+}
+
+// publishShipSnapshot sends the current ships to the watchers, which is needed when
+// a change (e.g. to the allow-list) affects all of them.
+func (s *shipgateServiceServer) publishShipSnapshot() {
+	s.connectedShipsMutex.Lock()
+	defer s.connectedShipsMutex.Unlock()
+
+	s.publish(&api.ShipEvent{Type: api.ShipEvent_SNAPSHOT, Ships: s.activeShips()})
+}
+
+func (s *shipgateServiceServer) publish(event *api.ShipEvent) {
+	for events := range s.shipWatchers {
+		select {
+		case events <- event:
+		default:
+			// Rather than block the shipgate on a slow watcher, end its watch. It
+			// gets a fresh snapshot when it watches again.
+			delete(s.shipWatchers, events)
+			close(events)
+		}
+	}
+}
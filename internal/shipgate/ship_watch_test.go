@@ -0,0 +1,157 @@
+package shipgate
+
+import (
+	"context"
+	"fmt"
+	"reflect"
+	"sort"
+	"testing"
+	"time"
+
+	"github.com/dcrodman/archon/internal/shipgate/api"
+)
+
+// receiveShipEvent returns the next event sent to the watcher, or nil if there
+// isn't one (or the watch ended).
+func receiveShipEvent(events chan *api.ShipEvent) *api.ShipEvent {
+	select {
+	case event := <-events:
+		return event
+	default:
+		return nil
+	}
+}
+
+func TestWatchShips(t *testing.T) {
+	originalNow := now
+	defer func() { now = originalNow }()
+
+	current := time.Now()
+	now = func() time.Time { return current }
+
+	s := &shipgateServiceServer{connectedShips: make(map[string]*ship)}
+	_, _ = s.RegisterShip(context.Background(), &api.RegistrationRequest{Name: "First", Address: "127.0.0.1", Port: "15000"})
+
+	events, err := s.addShipWatcher()
+	if err != nil {
+		t.Fatalf("unexpected error: %v", err)
+	}
+	if event := receiveShipEvent(events); event.GetType() != api.ShipEvent_SNAPSHOT || len(event.GetShips()) != 1 {
+		t.Fatalf("expected a snapshot of the first ship, got %v", event)
+	}
+
+	_, _ = s.RegisterShip(context.Background(), &api.RegistrationRequest{Name: "Second", Address: "127.0.0.1", Port: "15001"})
+	_, _ = s.Heartbeat(context.Background(), &api.ShipHeartbeat{Name: "First", PlayerCount: 2})
+	// Heartbeats that don't change the population aren't published.
+	_, _ = s.Heartbeat(context.Background(), &api.ShipHeartbeat{Name: "First", PlayerCount: 2})
+	current = current.Add(shipHeartbeatTimeout + time.Second)
+	_, _ = s.Heartbeat(context.Background(), &api.ShipHeartbeat{Name: "First"})
+
+	var received []string
+	for event := receiveShipEvent(events); event != nil; event = receiveShipEvent(events) {
+		for _, ship := range event.GetShips() {
+			received = append(received, fmt.Sprintf("%v %s (%d)", event.GetType(), ship.GetName(), ship.GetPlayerCount()))
+		}
+	}
+	// Stale ships are deactivated in no particular order.
+	if len(received) > 2 {
+		sort.Strings(received[2:])
+	}
+	expected := []string{
+		"REGISTERED Second (0)",
+		"UPDATED First (2)",
+		"DEREGISTERED First (0)",
+		"DEREGISTERED Second (0)",
+	}
+	if !reflect.DeepEqual(received, expected) {
+		t.Errorf("expected events = %v, got = %v", expected, received)
+	}
+
+	s.closeShipWatchers()
+	if _, ok := <-events; ok {
+		t.Errorf("expected watch to end when watchers are closed")
+	}
+	if _, err := s.addShipWatcher(); err == nil {
+		t.Errorf("expected watches to be refused after watchers are closed")
+	}
+}
+
+func TestWatchShips_SlowWatcher(t *testing.T) {
+	s := &shipgateServiceServer{connectedShips: make(map[string]*ship)}
+	_, _ = s.RegisterShip(context.Background(), &api.RegistrationRequest{Name: "First", Address: "127.0.0.1", Port: "15000"})
+
+	events, err := s.addShipWatcher()
+	if err != nil {
+		t.Fatalf("unexpected error: %v", err)
+	}
+	for i := 1; i <= shipWatcherBufferSize; i++ {
+		_, _ = s.Heartbeat(context.Background(), &api.ShipHeartbeat{Name: "First", PlayerCount: int32(i)})
+	}
+
+	received := 0
+	for range events {
+		received++
+	}
+	if received != shipWatcherBufferSize {
+		t.Errorf("expected %d events before the watch ended, got = %d", shipWatcherBufferSize, received)
+	}
+	if len(s.shipWatchers) != 0 {
+		t.Errorf("expected slow watcher to be removed")
+	}
+}
+
+func TestApplyShipEvent(t *testing.T) {
+	ship := func(id int32, players int32) *api.ShipList_Ship {
+		return &api.ShipList_Ship{Id: id, Name: "Ship", Ip: "127.0.0.1", Port: "15000", PlayerCount: players}
+	}
+	info := func(id, players int) shipInfo {
+		return shipInfo{id: id, name: "Ship", ip: "127.0.0.1", port: "15000", players: players}
+	}
+
+	tests := map[string]struct {
+		existing    []shipInfo
+		event       *api.ShipEvent
+		wantedShips []shipInfo
+	}{
+		"snapshot": {
+			existing:    []shipInfo{info(1, 0), info(2, 0)},
+			event:       &api.ShipEvent{Type: api.ShipEvent_SNAPSHOT, Ships: []*api.ShipList_Ship{ship(2, 5), ship(3, 0)}},
+			wantedShips: []shipInfo{info(2, 5), info(3, 0)},
+		},
+		"empty_snapshot": {
+			existing:    []shipInfo{info(1, 0)},
+			event:       &api.ShipEvent{Type: api.ShipEvent_SNAPSHOT},
+			wantedShips: []shipInfo{},
+		},
+		"registered": {
+			existing:    []shipInfo{info(1, 0), info(3, 0)},
+			event:       &api.ShipEvent{Type: api.ShipEvent_REGISTERED, Ships: []*api.ShipList_Ship{ship(2, 0)}},
+			wantedShips: []shipInfo{info(1, 0), info(2, 0), info(3, 0)},
+		},
+		"updated": {
+			existing:    []shipInfo{info(1, 0), info(2, 0)},
+			event:       &api.ShipEvent{Type: api.ShipEvent_UPDATED, Ships: []*api.ShipList_Ship{ship(2, 7)}},
+			wantedShips: []shipInfo{info(1, 0), info(2, 7)},
+		},
+		"deregistered": {
+			existing:    []shipInfo{info(1, 0), info(2, 0)},
+			event:       &api.ShipEvent{Type: api.ShipEvent_DEREGISTERED, Ships: []*api.ShipList_Ship{ship(1, 0)}},
+			wantedShips: []shipInfo{info(2, 0)},
+		},
+		"unknown_deregistered": {
+			existing:    []shipInfo{info(1, 0)},
+			event:       &api.ShipEvent{Type: api.ShipEvent_DEREGISTERED, Ships: []*api.ShipList_Ship{ship(2, 0)}},
+			wantedShips: []shipInfo{info(1, 0)},
+		},
+	}
+
+	for name, tt := range tests {
+		t.Run(name, func(t *testing.T) {
+			c := &Client{connectedShips: tt.existing}
+			c.applyShipEvent(tt.event)
+			if !reflect.DeepEqual(c.connectedShips, tt.wantedShips) {
+				t.Errorf("expected ships = %+v, got = %+v", tt.wantedShips, c.connectedShips)
+			}
+		})
+	}
+}
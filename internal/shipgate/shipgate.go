@@ -2,34 +2,60 @@ package shipgate
 
 import (
 	"context"
-	"crypto/tls"
 	"fmt"
-	"io/ioutil"
 	"net"
+	"time"
 
+	"github.com/dcrodman/archon/internal/core/metrics"
 	"github.com/dcrodman/archon/internal/shipgate/api"
-	"github.com/spf13/viper"
 	"google.golang.org/grpc"
 	"google.golang.org/grpc/credentials"
+	"google.golang.org/grpc/health"
+	healthpb "google.golang.org/grpc/health/grpc_health_v1"
+	"google.golang.org/grpc/keepalive"
+	"google.golang.org/grpc/status"
 
 	"github.com/dcrodman/archon"
 )
 
 // Start starts the gRPC API servers listening on addr.
-func Start(ctx context.Context, addr string, readyChan chan bool, errChan chan error) {
-	cert, err := loadX509Certificate()
+func Start(ctx context.Context, addr string, cfg *archon.Config, readyChan chan bool, errChan chan error) {
+	// Clients must present a certificate issued by the CA (see cmd/certgen). Ships
+	// may also authenticate using theirs instead of a secret.
+	certificates, err := newCertificateStore(cfg.ShipgateCAFile, cfg.ShipgateCertificateFile, cfg.ShipgateServer.SSLKeyFile)
 	if err != nil {
 		errChan <- err
 		return
 	}
+	creds := credentials.NewTLS(serverTLSConfig(certificates))
 
-	creds := credentials.NewServerTLSFromCert(cert)
-	opts := []grpc.ServerOption{grpc.Creds(creds)}
-	grpcServer := grpc.NewServer(opts...)
-
-	api.RegisterShipgateServiceServer(grpcServer, &shipgateServiceServer{
+	service := &shipgateServiceServer{
 		connectedShips: make(map[string]*ship),
+		sessions:       make(map[uint64]*accountSession),
+		sessionTokens:  make(map[uint64]*sessionToken),
+	}
+	service.setAllowedShips(cfg.ShipgateServer.Ships)
+	archon.OnConfigReload(func(cfg *archon.Config, _ []string) {
+		service.setAllowedShips(cfg.ShipgateServer.Ships)
+		service.publishShipSnapshot()
 	})
+	go service.startStaleShipLoop(ctx)
+
+	opts := []grpc.ServerOption{
+		grpc.Creds(creds),
+		grpc.ChainUnaryInterceptor(metricsInterceptor, sessionLoggingInterceptor, service.shipAuthInterceptor),
+		// Allow the keepalive pings that clients use to detect broken ship watches.
+		grpc.KeepaliveEnforcementPolicy(keepalive.EnforcementPolicy{MinTime: keepaliveTime / 2}),
+	}
+	grpcServer := grpc.NewServer(opts...)
+	api.RegisterShipgateServiceServer(grpcServer, service)
+	setActiveService(service)
+
+	// Clients watch the health service in order to stop sending requests to the
+	// shipgate as soon as it starts shutting down.
+	healthServer := health.NewServer()
+	healthServer.SetServingStatus(api.ShipgateService_ServiceDesc.ServiceName, healthpb.HealthCheckResponse_SERVING)
+	healthpb.RegisterHealthServer(grpcServer, healthServer)
 
 	listener, err := net.Listen("tcp", addr)
 	if err != nil {
@@ -52,25 +78,17 @@ func Start(ctx context.Context, addr string, readyChan chan bool, errChan chan e
 	readyChan <- true
 	<-ctx.Done()
 
+	healthServer.Shutdown()
+	service.closeShipWatchers()
+	service.closeMessageBus()
 	grpcServer.GracefulStop()
 	archon.Log.Printf("SHIPGATE server exited")
 }
 
-func loadX509Certificate() (*tls.Certificate, error) {
-	certFile, err := ioutil.ReadFile(viper.GetString("shipgate_certificate_file"))
-	if err != nil {
-		return nil, fmt.Errorf("unable to load certificate file: %s", err)
-	}
-
-	keyFile, err := ioutil.ReadFile(viper.GetString("shipgate_server.ssl_key_file"))
-	if err != nil {
-		return nil, fmt.Errorf("unable to load key file: %s", err)
-	}
-
-	cert, err := tls.X509KeyPair(certFile, keyFile)
-	if err != nil {
-		return nil, fmt.Errorf("unable to load X.509 certificate: %s", err)
-	}
-
-	return &cert, nil
+// metricsInterceptor records the latency and result code of each RPC handled by the shipgate.
+func metricsInterceptor(ctx context.Context, req interface{}, info *grpc.UnaryServerInfo, handler grpc.UnaryHandler) (interface{}, error) {
+	start := time.Now()
+	resp, err := handler(ctx, req)
+	metrics.ShipgateRPCDuration.WithLabelValues(info.FullMethod, status.Code(err).String()).Observe(time.Since(start).Seconds())
+	return resp, err
 }
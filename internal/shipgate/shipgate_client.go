@@ -2,29 +2,35 @@ package shipgate
 
 import (
 	"context"
+	"errors"
 	"fmt"
 	"net"
+	"sort"
 	"strconv"
 	"sync"
 	"time"
+	"unicode/utf16"
 
-	"github.com/spf13/viper"
-	"google.golang.org/grpc"
+	"google.golang.org/grpc/codes"
 	"google.golang.org/grpc/metadata"
+	"google.golang.org/grpc/status"
 	"gorm.io/gorm"
 
 	"github.com/dcrodman/archon"
+	"github.com/dcrodman/archon/internal/core/auth"
 	"github.com/dcrodman/archon/internal/core/bytes"
 	"github.com/dcrodman/archon/internal/core/data"
 	"github.com/dcrodman/archon/internal/packets"
 	"github.com/dcrodman/archon/internal/shipgate/api"
 	"github.com/golang/protobuf/ptypes/empty"
-	"google.golang.org/grpc/credentials"
 )
 
 type Client struct {
 	shipgateAddress string
 	shipgateClient  api.ShipgateServiceClient
+	// Name and secret of the ship on behalf of which sessions are acquired.
+	shipName   string
+	shipSecret string
 
 	connectedShipsMutex sync.RWMutex
 	connectedShips      []shipInfo
@@ -33,53 +39,69 @@ type Client struct {
 // Character servers internal representation of Ship connection information
 // for the ship selection screen.
 type shipInfo struct {
-	id   int
-	name []byte
-	ip   string
-	port string
+	id      int
+	name    string
+	ip      string
+	port    string
+	players int
+	gmOnly  bool
+	// Addresses that clients from particular subnets are sent to instead of ip.
+	redirectAddresses []*api.RedirectAddress
 }
 
-func NewClient(shipgateAddress string) (*Client, error) {
-	creds, err := credentials.NewClientTLSFromFile(viper.GetString("shipgate_certificate_file"), "")
-	if err != nil {
-		return nil, fmt.Errorf("failed to load certificate file for shipgate: %s", err)
+// address returns the address to which the client at clientIP should be sent: that
+// of the first of the ship's redirect addresses whose subnet contains clientIP,
+// otherwise the one the ship registered with.
+func (ship *shipInfo) address(clientIP string) string {
+	if ip := net.ParseIP(clientIP); ip != nil {
+		for _, redirect := range ship.redirectAddresses {
+			if _, subnet, err := net.ParseCIDR(redirect.GetSubnet()); err == nil && subnet.Contains(ip) {
+				return redirect.GetAddress()
+			}
+		}
 	}
+	return ship.ip
+}
 
-	conn, err := grpc.Dial(shipgateAddress, grpc.WithTransportCredentials(creds))
+func NewClient(shipgateAddress string, cfg *archon.Config) (*Client, error) {
+	conn, err := Dial(shipgateAddress, cfg)
 	if err != nil {
 		return nil, fmt.Errorf("failed to connect to shipgate: %s", err)
 	}
 
 	return &Client{
 		shipgateAddress: shipgateAddress,
-		// Lazy, but just leave the connection open until the server shuts down.
+		// The connection is left open (and re-established as needed) until the server shuts down.
 		shipgateClient: api.NewShipgateServiceClient(conn),
+		shipName:       cfg.ShipServer.Name,
+		shipSecret:     cfg.ShipServer.Secret,
 	}, nil
 }
 
-func (s *Client) StartShipRefreshLoop(ctx context.Context) error {
-	// The first set is fetched synchronously so that the ship list will start populated.
-	// Also gives us a chance to validate that the shipgate address is valid.
-	if err := s.refreshShipList(); err != nil {
-		return err
-	}
-	go s.startShipListRefreshLoop(ctx)
-
-	return nil
+// StartShipListWatch keeps the ship list up to date with the ships registered with
+// the shipgate, which pushes changes to them as they happen.
+func (s *Client) StartShipListWatch(ctx context.Context) {
+	// The last known ships are kept while the watch is reconnecting.
+	go reconnectLoop(ctx, "ship list watch", s.receiveShipEvents)
 }
 
-func (s *Client) GetConnectedShipList() []packets.ShipListEntry {
+// GetConnectedShipList returns the entries for the ship select menu. GM-only ships
+// are only included if gm is set.
+func (s *Client) GetConnectedShipList(gm bool) []packets.ShipListEntry {
 	s.connectedShipsMutex.RLock()
 	defer s.connectedShipsMutex.RUnlock()
 
 	shipList := make([]packets.ShipListEntry, 0)
-	for i, ship := range s.connectedShips {
+	for _, ship := range s.connectedShips {
+		if ship.gmOnly && !gm {
+			continue
+		}
 		entry := packets.ShipListEntry{
-			MenuID:   uint16(i + 1),
+			MenuID:   uint16(len(shipList) + 1),
 			ShipID:   uint32(ship.id),
 			ShipName: [36]byte{},
 		}
-		copy(entry.ShipName[:], ship.name)
+		copy(entry.ShipName[:], bytes.ExpandUtf16(shipListName(ship.name, ship.players)))
 		shipList = append(shipList, entry)
 	}
 	if len(shipList) == 0 {
@@ -93,20 +115,59 @@ func (s *Client) GetConnectedShipList() []packets.ShipListEntry {
 	return shipList
 }
 
-func (s *Client) GetSelectedShipAddress(selectedShip uint32) (net.IP, int, error) {
-	s.connectedShipsMutex.Lock()
-	defer s.connectedShipsMutex.Unlock()
+// Number of UTF-16 characters that fit in the name of a ship select menu entry.
+const shipListNameLength = len(packets.ShipListEntry{}.ShipName) / 2
+
+// shipListName returns the UTF-16 name of a ship in the ship select menu, which is
+// followed by its population so that players can pick a busy (or quiet) ship. Long
+// names are shortened rather than cutting off the population.
+func shipListName(name string, players int) []uint16 {
+	population := utf16.Encode([]rune(fmt.Sprintf(" (%d)", players)))
+	shipName := utf16.Encode([]rune(name))
+	if maxLength := shipListNameLength - len(population); len(shipName) > maxLength {
+		shipName = shipName[:maxLength]
+		// Don't leave half of a surrogate pair behind.
+		if last := rune(shipName[len(shipName)-1]); utf16.IsSurrogate(last) {
+			shipName = shipName[:len(shipName)-1]
+		}
+	}
+	return append(shipName, population...)
+}
 
-	if selectedShip >= uint32(len(s.connectedShips)) {
-		return nil, 0, fmt.Errorf("invalid ship selection: %d", selectedShip)
+// GetSelectedShipAddress returns the IPv4 address and port at which the client at
+// clientIP can reach the ship with ID shipID, looking up its address if it's a
+// hostname.
+func (s *Client) GetSelectedShipAddress(ctx context.Context, shipID uint32, clientIP string) (net.IP, int, error) {
+	s.connectedShipsMutex.RLock()
+	var ship *shipInfo
+	for i := range s.connectedShips {
+		if uint32(s.connectedShips[i].id) == shipID {
+			// Copied since the list may change once the lock is released.
+			selected := s.connectedShips[i]
+			ship = &selected
+			break
+		}
+	}
+	s.connectedShipsMutex.RUnlock()
+	if ship == nil {
+		return nil, 0, fmt.Errorf("invalid ship selection: %d", shipID)
 	}
 
-	shipIP := net.ParseIP(s.connectedShips[selectedShip].ip).To4()
-	shipPort, _ := strconv.Atoi(s.connectedShips[selectedShip].port)
+	shipIP, err := archon.ResolveIPv4(ctx, ship.address(clientIP))
+	if err != nil {
+		return nil, 0, fmt.Errorf("unable to redirect to ship: %w", err)
+	}
+	shipPort, err := strconv.Atoi(ship.port)
+	if err != nil {
+		return nil, 0, fmt.Errorf("ship at %s has an invalid port: %s", ship.ip, ship.port)
+	}
 	return shipIP, shipPort, nil
 }
 
-func (s *Client) AuthenticateAccount(ctx context.Context, username, password string) (*data.Account, error) {
+// AuthenticateAccount verifies the account's credentials and returns the account
+// along with a session token that the other servers can use to authenticate it
+// without its password (see AuthenticateSessionToken).
+func (s *Client) AuthenticateAccount(ctx context.Context, username, password string) (*data.Account, []byte, error) {
 	md := metadata.New(map[string]string{
 		"authorization": password,
 	})
@@ -116,9 +177,50 @@ func (s *Client) AuthenticateAccount(ctx context.Context, username, password str
 		&api.AccountAuthRequest{Username: username},
 	)
 	if err != nil {
-		return nil, err
+		return nil, nil, authenticationError(err)
+	}
+
+	account, err := accountFromResponse(accountpb)
+	if err != nil {
+		return nil, nil, err
+	}
+	return account, accountpb.GetSessionToken(), nil
+}
+
+// AuthenticateSessionToken verifies the session token issued to the account
+// when it logged in through the LOGIN server.
+func (s *Client) AuthenticateSessionToken(ctx context.Context, username string, token []byte) (*data.Account, error) {
+	accountpb, err := s.shipgateClient.AuthenticateSessionToken(ctx, &api.SessionTokenAuthRequest{
+		Username:     username,
+		SessionToken: token,
+	})
+	if err != nil {
+		return nil, authenticationError(err)
 	}
+	return accountFromResponse(accountpb)
+}
 
+// authenticationError maps the status returned by the shipgate for a failed
+// authentication back to the corresponding auth error.
+func authenticationError(err error) error {
+	if err == ErrUnavailable {
+		return err
+	}
+	switch status.Code(err) {
+	case codes.Unauthenticated:
+		return auth.ErrInvalidCredentials
+	case codes.PermissionDenied:
+		return auth.ErrAccountBanned
+	case codes.FailedPrecondition:
+		return auth.ErrAccountInactive
+	}
+	if st, ok := status.FromError(err); ok && st.Code() == codes.Internal {
+		return errors.New(st.Message())
+	}
+	return err
+}
+
+func accountFromResponse(accountpb *api.AccountAuthResponse) (*data.Account, error) {
 	rd, err := time.Parse(time.RFC3339, accountpb.GetRegistrationDate())
 	if err != nil {
 		return nil, err
@@ -134,7 +236,6 @@ func (s *Client) AuthenticateAccount(ctx context.Context, username, password str
 			ID: uint(accountpb.Id),
 		},
 		Username:         accountpb.GetUsername(),
-		Password:         password,
 		Email:            accountpb.GetEmail(),
 		RegistrationDate: rd,
 		Guildcard:        int(accountpb.GetGuildcard()),
@@ -146,40 +247,121 @@ func (s *Client) AuthenticateAccount(ctx context.Context, username, password str
 	}, nil
 }
 
-// Starts a loop that makes an API request to the shipgate server over an interval
-// in order to query the list of active ships. The result is parsed and stored in
-// the Server's ships field.
-func (s *Client) startShipListRefreshLoop(ctx context.Context) {
-	for {
-		select {
-		case <-ctx.Done():
-			return
-		case <-time.After(time.Second * 10):
-			if err := s.refreshShipList(); err != nil {
-				archon.Log.Errorf(err.Error())
-			}
-		}
+var (
+	// ErrAccountInUse is returned when acquiring a session for an account that is
+	// already logged in elsewhere.
+	ErrAccountInUse = errors.New("account is already logged in")
+	// ErrShipFull is returned when acquiring a session on a ship that has reached
+	// its max_players.
+	ErrShipFull = errors.New("ship is full")
+	// ErrShipGMOnly is returned when acquiring a session on a GM-only ship for an
+	// account that isn't a GM.
+	ErrShipGMOnly = errors.New("ship is only open to GMs")
+)
+
+// AcquireSession marks the account as logged in through the client connection
+// identified by sessionID. If takeover is set then any existing session for the
+// account is revoked, otherwise ErrAccountInUse is returned.
+func (s *Client) AcquireSession(ctx context.Context, account *data.Account, sessionID, block string, takeover bool) error {
+	_, err := s.shipgateClient.AcquireSession(WithShipSecret(ctx, s.shipSecret), &api.SessionRequest{
+		AccountId: uint64(account.ID),
+		SessionId: sessionID,
+		Ship:      s.shipName,
+		Block:     block,
+		Takeover:  takeover,
+		Gm:        account.GM,
+	})
+	switch status.Code(err) {
+	case codes.AlreadyExists:
+		return ErrAccountInUse
+	case codes.ResourceExhausted:
+		return ErrShipFull
+	case codes.PermissionDenied:
+		return ErrShipGMOnly
 	}
+	return err
 }
 
-func (s *Client) refreshShipList() error {
-	response, err := s.shipgateClient.GetActiveShips(context.Background(), &empty.Empty{})
+// RenewSessions extends the leases on the sessions held through block (a map of
+// session ID to the account logged in through it) and returns the IDs of any that
+// have been revoked. Sessions the shipgate has lost track of are acquired again.
+func (s *Client) RenewSessions(ctx context.Context, sessions map[string]*data.Account, block string) ([]string, error) {
+	req := &api.SessionRenewalRequest{}
+	for sessionID, account := range sessions {
+		req.Sessions = append(req.Sessions, &api.SessionRequest{
+			AccountId: uint64(account.ID),
+			SessionId: sessionID,
+			Ship:      s.shipName,
+			Block:     block,
+			Gm:        account.GM,
+		})
+	}
+
+	resp, err := s.shipgateClient.RenewSessions(WithShipSecret(ctx, s.shipSecret), req)
 	if err != nil {
-		return fmt.Errorf("failed to fetch ships from shipgate: %s", err)
+		return nil, err
 	}
+	return resp.GetRevokedSessionIds(), nil
+}
 
-	ships := make([]shipInfo, 0)
-	for _, s := range response.Ships {
-		ships = append(ships, shipInfo{
-			id:   int(s.Id),
-			name: bytes.ConvertToUtf16(s.Name),
-			ip:   s.Ip,
-			port: s.Port,
-		})
+// ReleaseSession marks the account as no longer logged in through sessionID.
+func (s *Client) ReleaseSession(ctx context.Context, accountID uint, sessionID string) error {
+	_, err := s.shipgateClient.ReleaseSession(WithShipSecret(ctx, s.shipSecret), &api.SessionRequest{
+		AccountId: uint64(accountID),
+		SessionId: sessionID,
+		Ship:      s.shipName,
+	})
+	return err
+}
+
+// receiveShipEvents applies the shipgate's ship events to the ship list until the
+// watch ends, reporting whether any were received.
+func (s *Client) receiveShipEvents(ctx context.Context) (bool, error) {
+	stream, err := s.shipgateClient.WatchShips(ctx, &empty.Empty{})
+	if err != nil {
+		return false, err
 	}
+	for received := false; ; received = true {
+		event, err := stream.Recv()
+		if err != nil {
+			return received, err
+		}
+		s.applyShipEvent(event)
+	}
+}
 
+func (s *Client) applyShipEvent(event *api.ShipEvent) {
 	s.connectedShipsMutex.Lock()
-	s.connectedShips = ships
-	s.connectedShipsMutex.Unlock()
-	return nil
+	defer s.connectedShipsMutex.Unlock()
+
+	if event.GetType() == api.ShipEvent_SNAPSHOT {
+		s.connectedShips = make([]shipInfo, 0, len(event.GetShips()))
+	}
+	for _, ship := range event.GetShips() {
+		info := shipInfo{
+			id:                int(ship.GetId()),
+			name:              ship.GetName(),
+			ip:                ship.GetIp(),
+			port:              ship.GetPort(),
+			players:           int(ship.GetPlayerCount()),
+			gmOnly:            ship.GetGmOnly(),
+			redirectAddresses: ship.GetRedirectAddresses(),
+		}
+
+		i := sort.Search(len(s.connectedShips), func(i int) bool { return s.connectedShips[i].id >= info.id })
+		exists := i < len(s.connectedShips) && s.connectedShips[i].id == info.id
+		switch {
+		case event.GetType() == api.ShipEvent_DEREGISTERED:
+			if exists {
+				s.connectedShips = append(s.connectedShips[:i], s.connectedShips[i+1:]...)
+			}
+		case exists:
+			s.connectedShips[i] = info
+		default:
+			// Keep the ships ordered by ID so that the ship select menu is stable.
+			s.connectedShips = append(s.connectedShips, shipInfo{})
+			copy(s.connectedShips[i+1:], s.connectedShips[i:])
+			s.connectedShips[i] = info
+		}
+	}
 }
@@ -0,0 +1,83 @@
+package shipgate
+
+import (
+	"context"
+	"net"
+	"testing"
+	"unicode/utf16"
+
+	"github.com/dcrodman/archon/internal/shipgate/api"
+)
+
+func TestShipListName(t *testing.T) {
+	tests := map[string]struct {
+		name    string
+		players int
+		wanted  string
+	}{
+		"short_name":      {name: "Ship01", players: 3, wanted: "Ship01 (3)"},
+		"exact_fit":       {name: "Ship of Ragol", players: 12, wanted: "Ship of Ragol (12)"},
+		"long_name":       {name: "The Very Long Ship Name", players: 150, wanted: "The Very Lon (150)"},
+		"surrogate_pair":  {name: "Ship Name 1😀😀", players: 0, wanted: "Ship Name 1😀 (0)"},
+		"many_players":    {name: "Ship01", players: 1000000, wanted: "Ship01 (1000000)"},
+		"unicode_in_name": {name: "Schiff Größe", players: 5, wanted: "Schiff Größe (5)"},
+	}
+
+	for name, tt := range tests {
+		t.Run(name, func(t *testing.T) {
+			shipName := shipListName(tt.name, tt.players)
+			if len(shipName) > shipListNameLength {
+				t.Errorf("expected at most %d characters, got %d", shipListNameLength, len(shipName))
+			}
+			if got := string(utf16.Decode(shipName)); got != tt.wanted {
+				t.Errorf("expected name = %q, got = %q", tt.wanted, got)
+			}
+		})
+	}
+}
+
+func TestClient_GetSelectedShipAddress(t *testing.T) {
+	// The ship registers its public address along with the one LAN clients use.
+	s := &shipgateServiceServer{connectedShips: make(map[string]*ship)}
+	_, _ = s.RegisterShip(context.Background(), &api.RegistrationRequest{
+		Name:    "Ship",
+		Address: "203.0.113.10",
+		Port:    "15000",
+		RedirectAddresses: []*api.RedirectAddress{
+			{Subnet: "192.168.1.0/24", Address: "192.168.1.10"},
+			{Subnet: "10.0.0.0/8", Address: "10.0.0.10"},
+		},
+	})
+	c := &Client{}
+	s.connectedShipsMutex.Lock()
+	c.applyShipEvent(&api.ShipEvent{Type: api.ShipEvent_SNAPSHOT, Ships: s.activeShips()})
+	s.connectedShipsMutex.Unlock()
+
+	tests := map[string]struct {
+		shipID   uint32
+		clientIP string
+		wantedIP string
+		wantErr  bool
+	}{
+		"lan_client":     {shipID: 1, clientIP: "192.168.1.50", wantedIP: "192.168.1.10"},
+		"other_subnet":   {shipID: 1, clientIP: "10.1.2.3", wantedIP: "10.0.0.10"},
+		"wan_client":     {shipID: 1, clientIP: "198.51.100.7", wantedIP: "203.0.113.10"},
+		"unknown_client": {shipID: 1, clientIP: "", wantedIP: "203.0.113.10"},
+		"unknown_ship":   {shipID: 2, clientIP: "192.168.1.50", wantErr: true},
+	}
+
+	for name, tt := range tests {
+		t.Run(name, func(t *testing.T) {
+			ip, port, err := c.GetSelectedShipAddress(context.Background(), tt.shipID, tt.clientIP)
+			if (err != nil) != tt.wantErr {
+				t.Fatalf("expected error = %v, got = %v", tt.wantErr, err)
+			}
+			if tt.wantErr {
+				return
+			}
+			if !ip.Equal(net.ParseIP(tt.wantedIP)) || port != 15000 {
+				t.Errorf("expected address = %s:15000, got = %s:%d", tt.wantedIP, ip, port)
+			}
+		})
+	}
+}
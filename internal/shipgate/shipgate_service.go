@@ -3,14 +3,19 @@ package shipgate
 import (
 	"context"
 	"fmt"
+	"sort"
 	"sync"
 	"time"
 
+	"google.golang.org/grpc/codes"
 	"google.golang.org/grpc/metadata"
+	"google.golang.org/grpc/status"
 	"google.golang.org/protobuf/types/known/emptypb"
 
 	"github.com/dcrodman/archon"
 	"github.com/dcrodman/archon/internal/core/auth"
+	"github.com/dcrodman/archon/internal/core/data"
+	"github.com/dcrodman/archon/internal/core/metrics"
 	"github.com/dcrodman/archon/internal/shipgate/api"
 )
 
@@ -19,9 +24,60 @@ type ship struct {
 	name string
 	ip   string
 	port string
-	// TODO: Need a way to deregister these reliably. Ships will probably need
-	// their own gRPC service so I'm deferring the heartbeating problem until then.
-	active bool
+	// Addresses that clients from particular subnets are sent to instead of ip.
+	redirectAddresses []*api.RedirectAddress
+	// Ships are active from when they register until they miss their heartbeats.
+	active        bool
+	lastHeartbeat time.Time
+	// Population reported in the ship's last heartbeat.
+	players int
+	blocks  []BlockStatus
+}
+
+// ShipStatus is a snapshot of a ship that has registered with the shipgate.
+type ShipStatus struct {
+	ID      int
+	Name    string
+	IP      string
+	Port    string
+	Active  bool
+	Players int
+	Blocks  []BlockStatus
+	// Hidden ships are left out of the public server status.
+	Hidden bool
+}
+
+// BlockStatus is the state of one of a ship's blocks as of its last heartbeat.
+type BlockStatus struct {
+	Name    string
+	Players int
+	Lobbies int
+	Games   int
+}
+
+var (
+	// The shipgate service running in this process, if any.
+	activeService      *shipgateServiceServer
+	activeServiceMutex sync.RWMutex
+)
+
+func setActiveService(s *shipgateServiceServer) {
+	activeServiceMutex.Lock()
+	activeService = s
+	activeServiceMutex.Unlock()
+}
+
+// RegisteredShips returns the ships that have registered with the shipgate running
+// in this process (ordered by ID), or nil if the shipgate isn't running.
+func RegisteredShips() []ShipStatus {
+	activeServiceMutex.RLock()
+	s := activeService
+	activeServiceMutex.RUnlock()
+
+	if s == nil {
+		return nil
+	}
+	return s.registeredShips()
 }
 
 // shipgateServiceServer implements the SHIPGATE server logic, which never directly
@@ -32,23 +88,85 @@ type shipgateServiceServer struct {
 
 	connectedShips      map[string]*ship
 	connectedShipsMutex sync.RWMutex
+
+	// Ships that are allowed to register, by name.
+	allowedShips      map[string]archon.AllowedShipConfig
+	allowedShipsMutex sync.RWMutex
+
+	// Channels on which changes to the active ships are sent to WatchShips streams,
+	// guarded by connectedShipsMutex so that watchers don't miss any changes.
+	shipWatchers       map[chan *api.ShipEvent]bool
+	shipWatchersClosed bool
+
+	// Channels on which envelopes published to the message bus are sent to the
+	// ships connected to it.
+	subscribers       map[chan *api.Envelope]bool
+	subscribersClosed bool
+	subscribersMutex  sync.Mutex
+
+	// Account sessions and session tokens by account ID, both guarded by sessionsMutex.
+	sessions      map[uint64]*accountSession
+	sessionTokens map[uint64]*sessionToken
+	sessionsMutex sync.Mutex
+}
+
+func (s *shipgateServiceServer) registeredShips() []ShipStatus {
+	s.connectedShipsMutex.Lock()
+	defer s.connectedShipsMutex.Unlock()
+
+	s.deactivateStaleShips()
+	ships := make([]ShipStatus, 0, len(s.connectedShips))
+	for _, connectedShip := range s.connectedShips {
+		allowed, _ := s.allowedShip(connectedShip.name)
+		ships = append(ships, ShipStatus{
+			ID:      connectedShip.id,
+			Name:    connectedShip.name,
+			IP:      connectedShip.ip,
+			Port:    connectedShip.port,
+			Active:  connectedShip.active,
+			Players: connectedShip.players,
+			Blocks:  connectedShip.blocks,
+			Hidden:  allowed.Hidden,
+		})
+	}
+	sort.Slice(ships, func(i, j int) bool { return ships[i].ID < ships[j].ID })
+
+	return ships
 }
 
 func (s *shipgateServiceServer) GetActiveShips(ctx context.Context, _ *emptypb.Empty) (*api.ShipList, error) {
-	s.connectedShipsMutex.RLock()
-	defer s.connectedShipsMutex.RUnlock()
+	s.connectedShipsMutex.Lock()
+	defer s.connectedShipsMutex.Unlock()
 
+	s.deactivateStaleShips()
+	return &api.ShipList{Ships: s.activeShips()}, nil
+}
+
+// activeShips returns the ships that are ready to receive players, ordered by ID.
+// Must be called with the ships lock held.
+func (s *shipgateServiceServer) activeShips() []*api.ShipList_Ship {
 	ships := make([]*api.ShipList_Ship, 0)
 	for _, connectedShip := range s.connectedShips {
-		ships = append(ships, &api.ShipList_Ship{
-			Id:   int32(connectedShip.id),
-			Name: connectedShip.name,
-			Ip:   connectedShip.ip,
-			Port: connectedShip.port,
-		})
+		if connectedShip.active {
+			ships = append(ships, s.shipListEntry(connectedShip))
+		}
 	}
+	// Keep the order of the ship select menu stable.
+	sort.Slice(ships, func(i, j int) bool { return ships[i].Id < ships[j].Id })
+	return ships
+}
 
-	return &api.ShipList{Ships: ships}, nil
+func (s *shipgateServiceServer) shipListEntry(connectedShip *ship) *api.ShipList_Ship {
+	allowed, _ := s.allowedShip(connectedShip.name)
+	return &api.ShipList_Ship{
+		Id:                int32(connectedShip.id),
+		Name:              connectedShip.name,
+		Ip:                connectedShip.ip,
+		Port:              connectedShip.port,
+		PlayerCount:       int32(connectedShip.players),
+		GmOnly:            allowed.GMOnly,
+		RedirectAddresses: connectedShip.redirectAddresses,
+	}
 }
 
 func (s *shipgateServiceServer) RegisterShip(ctx context.Context, req *api.RegistrationRequest) (*emptypb.Empty, error) {
@@ -58,20 +176,29 @@ func (s *shipgateServiceServer) RegisterShip(ctx context.Context, req *api.Regis
 	// Ships are never cleared from the map so that we can keep the IDs relatively
 	// stable and allow for brief interruptions while preserving idempotency.
 	if _, ok := s.connectedShips[req.Name]; ok {
+		eventType := api.ShipEvent_UPDATED
 		if !s.connectedShips[req.Name].active {
 			archon.Log.Infof("SHIPGATE reactivated ship %s at %s:%s", req.Name, req.Address, req.Port)
+			eventType = api.ShipEvent_REGISTERED
 		}
 		s.connectedShips[req.Name].active = true
+		s.connectedShips[req.Name].lastHeartbeat = now()
 		s.connectedShips[req.Name].ip = req.Address
 		s.connectedShips[req.Name].port = req.Port
+		s.connectedShips[req.Name].redirectAddresses = req.RedirectAddresses
+		s.publishShipEvent(eventType, s.connectedShips[req.Name])
 	} else {
 		s.connectedShips[req.Name] = &ship{
-			id:   len(s.connectedShips) + 1,
-			name: req.Name,
-			ip:   req.Address,
-			port: req.Port,
+			id:                len(s.connectedShips) + 1,
+			name:              req.Name,
+			ip:                req.Address,
+			port:              req.Port,
+			redirectAddresses: req.RedirectAddresses,
+			active:            true,
+			lastHeartbeat:     now(),
 		}
 		archon.Log.Infof("SHIPGATE registered ship %s at %s:%s", req.Name, req.Address, req.Port)
+		s.publishShipEvent(api.ShipEvent_REGISTERED, s.connectedShips[req.Name])
 	}
 	return &emptypb.Empty{}, nil
 }
@@ -88,10 +215,23 @@ func (s *shipgateServiceServer) AuthenticateAccount(ctx context.Context, req *ap
 	}
 
 	account, err := auth.VerifyAccount(req.GetUsername(), creds[0])
+	metrics.ShipgateAuthentications.WithLabelValues(authenticationResult(err)).Inc()
 	if err != nil {
-		return nil, err
+		archon.LoggerFromContext(ctx).WithField("account", req.GetUsername()).
+			Infof("SHIPGATE failed to authenticate account: %v", err)
+		return nil, authenticationStatus(err)
 	}
 
+	token, err := s.issueSessionToken(uint64(account.ID))
+	if err != nil {
+		return nil, status.Errorf(codes.Internal, "failed to issue session token: %v", err)
+	}
+	resp := accountResponse(account)
+	resp.SessionToken = token
+	return resp, nil
+}
+
+func accountResponse(account *data.Account) *api.AccountAuthResponse {
 	return &api.AccountAuthResponse{
 		Id:               uint64(account.ID),
 		Username:         account.Username,
@@ -103,5 +243,39 @@ func (s *shipgateServiceServer) AuthenticateAccount(ctx context.Context, req *ap
 		Active:           account.Active,
 		TeamId:           int64(account.TeamID),
 		PriviledgeLevel:  []byte{account.PrivilegeLevel},
-	}, nil
+	}
+}
+
+// authenticationResult maps the result of an account verification to the label
+// value recorded in the authentication metrics.
+func authenticationResult(err error) string {
+	switch err {
+	case nil:
+		return "success"
+	case auth.ErrInvalidCredentials:
+		return "invalid_credentials"
+	case auth.ErrAccountBanned:
+		return "banned"
+	case auth.ErrAccountInactive:
+		return "inactive"
+	case errInvalidSessionToken:
+		return "invalid_session_token"
+	default:
+		return "error"
+	}
+}
+
+// authenticationStatus converts the result of an account verification into a
+// status that the shipgate's clients can map back to the auth error.
+func authenticationStatus(err error) error {
+	switch err {
+	case auth.ErrInvalidCredentials, errInvalidSessionToken:
+		return status.Error(codes.Unauthenticated, err.Error())
+	case auth.ErrAccountBanned:
+		return status.Error(codes.PermissionDenied, err.Error())
+	case auth.ErrAccountInactive:
+		return status.Error(codes.FailedPrecondition, err.Error())
+	default:
+		return status.Error(codes.Internal, err.Error())
+	}
 }
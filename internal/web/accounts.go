@@ -0,0 +1,324 @@
+package web
+
+import (
+	"crypto/rand"
+	"encoding/hex"
+	"fmt"
+	"net/http"
+	"net/mail"
+	"regexp"
+	"strconv"
+	"time"
+
+	"github.com/dcrodman/archon"
+	"github.com/dcrodman/archon/internal/core/auth"
+	"github.com/dcrodman/archon/internal/core/data"
+)
+
+const (
+	// Usernames and passwords can't be longer than the fields in the login packet.
+	minUsernameLength = 3
+	maxUsernameLength = 16
+	minPasswordLength = 6
+	maxPasswordLength = 16
+	maxEmailLength    = 254
+
+	// How long a player has to follow the link in their verification email.
+	verificationTokenTTL = 48 * time.Hour
+
+	// Number of password changes that may be attempted from each IP address and
+	// for each account within passwordAttemptWindow, which keeps the endpoint from
+	// being used to guess passwords.
+	maxPasswordAttempts   = 5
+	passwordAttemptWindow = 15 * time.Minute
+)
+
+var (
+	validUsername = regexp.MustCompile(`^[A-Za-z0-9_.-]+$`)
+	// The client only allows printable ASCII characters in the password field.
+	validPassword = regexp.MustCompile(`^[\x21-\x7E]+$`)
+)
+
+type registrationRequest struct {
+	Username string `json:"username"`
+	Password string `json:"password"`
+	Email    string `json:"email"`
+}
+
+type registrationResponse struct {
+	ID                   uint   `json:"id"`
+	Username             string `json:"username"`
+	VerificationRequired bool   `json:"verification_required"`
+}
+
+type passwordChangeRequest struct {
+	Username    string `json:"username"`
+	Password    string `json:"password"`
+	NewPassword string `json:"new_password"`
+}
+
+type verificationResponse struct {
+	Username string `json:"username"`
+	Active   bool   `json:"active"`
+}
+
+// AccountHandler serves the self-service account endpoints:
+//
+//	POST /api/accounts/register  creates a new account
+//	POST /api/accounts/password  changes the password of an existing account
+//	GET  /api/accounts/verify    activates an account with an emailed token
+type AccountHandler struct {
+	mailer Mailer
+	// Whether or not new accounts must verify their email address before logging in.
+	requireVerification bool
+	// URL included in verification emails, to which the token is appended.
+	verificationURL string
+	// Password changes attempted by IP address and by account.
+	passwordAttempts *attemptLimiter
+}
+
+func NewAccountHandler(mailer Mailer, requireVerification bool, verificationURL string) *AccountHandler {
+	return &AccountHandler{
+		mailer:              mailer,
+		requireVerification: requireVerification,
+		verificationURL:     verificationURL,
+		passwordAttempts:    newAttemptLimiter(maxPasswordAttempts, passwordAttemptWindow),
+	}
+}
+
+func (h *AccountHandler) ServeHTTP(w http.ResponseWriter, r *http.Request) {
+	switch r.URL.Path {
+	case "/api/accounts/register":
+		if !allowMethod(w, r, http.MethodPost) {
+			return
+		}
+		h.register(w, r)
+	case "/api/accounts/password":
+		if !allowMethod(w, r, http.MethodPost) {
+			return
+		}
+		h.changePassword(w, r)
+	case "/api/accounts/verify":
+		if !allowMethod(w, r, http.MethodGet) {
+			return
+		}
+		h.verify(w, r)
+	default:
+		http.NotFound(w, r)
+	}
+}
+
+func (h *AccountHandler) register(w http.ResponseWriter, r *http.Request) {
+	var req registrationRequest
+	if err := decodeJSON(w, r, &req); err != nil {
+		writeError(w, http.StatusBadRequest, err.Error())
+		return
+	}
+	if err := validateRegistration(&req); err != nil {
+		writeError(w, http.StatusBadRequest, err.Error())
+		return
+	}
+
+	// Soft-deleted accounts still hold onto their username and email.
+	if existing, err := findUnscopedAccount(req.Username); err != nil {
+		archon.Log.Errorf("failed to look up account %s: %v", req.Username, err)
+		writeError(w, http.StatusInternalServerError, auth.ErrUnknown.Error())
+		return
+	} else if existing != nil {
+		writeError(w, http.StatusConflict, "username is already taken")
+		return
+	}
+	if existing, err := findUnscopedAccountByEmail(req.Email); err != nil {
+		archon.Log.Errorf("failed to look up account by email %s: %v", req.Email, err)
+		writeError(w, http.StatusInternalServerError, auth.ErrUnknown.Error())
+		return
+	} else if existing != nil {
+		writeError(w, http.StatusConflict, "email address is already registered")
+		return
+	}
+
+	// Accounts that need to be verified start out inactive so that they can't be
+	// used to log in until then.
+	account, err := createAccount(req.Username, req.Password, req.Email, !h.requireVerification)
+	if err != nil {
+		archon.Log.Errorf("failed to create account %s: %v", req.Username, err)
+		writeError(w, http.StatusInternalServerError, auth.ErrUnknown.Error())
+		return
+	}
+	archon.Log.Infof("WEB registered account %s (id: %d)", account.Username, account.ID)
+
+	if h.requireVerification {
+		if err := h.startVerification(account); err != nil {
+			archon.Log.Errorf("failed to start email verification for %s: %v", account.Username, err)
+			// Otherwise the account would hold onto the username and email without the
+			// player ever being able to activate it, and every retry would be rejected.
+			if err := permanentlyDeleteAccount(account); err != nil {
+				archon.Log.Errorf("failed to delete unverifiable account %s: %v", account.Username, err)
+			}
+			writeError(w, http.StatusInternalServerError, auth.ErrUnknown.Error())
+			return
+		}
+	}
+
+	writeJSON(w, http.StatusCreated, &registrationResponse{
+		ID:                   account.ID,
+		Username:             account.Username,
+		VerificationRequired: h.requireVerification,
+	})
+}
+
+// startVerification emails the player a link that activates the account.
+func (h *AccountHandler) startVerification(account *data.Account) error {
+	token, err := generateToken()
+	if err != nil {
+		return err
+	}
+
+	verification := &data.EmailVerification{
+		AccountID: int(account.ID),
+		Token:     token,
+		ExpiresAt: time.Now().Add(verificationTokenTTL),
+	}
+	if err := createEmailVerification(verification); err != nil {
+		return err
+	}
+
+	body := fmt.Sprintf(
+		"Welcome to Archon, %s!\n\nFollow this link to activate your account:\n%s?token=%s\n\n"+
+			"The link expires in %d hours.",
+		account.Username, h.verificationURL, token, int(verificationTokenTTL.Hours()),
+	)
+	if err := h.mailer.Send(account.Email, "Activate your account", body); err != nil {
+		if err := deleteEmailVerification(verification); err != nil {
+			archon.Log.Warnf("failed to delete verification token for %s: %v", account.Username, err)
+		}
+		return err
+	}
+	return nil
+}
+
+func (h *AccountHandler) verify(w http.ResponseWriter, r *http.Request) {
+	token := r.URL.Query().Get("token")
+	if token == "" {
+		writeError(w, http.StatusBadRequest, "token is required")
+		return
+	}
+
+	verification, err := findEmailVerification(token)
+	if err != nil {
+		archon.Log.Errorf("failed to look up verification token: %v", err)
+		writeError(w, http.StatusInternalServerError, auth.ErrUnknown.Error())
+		return
+	} else if verification == nil || verification.Account == nil || time.Now().After(verification.ExpiresAt) {
+		writeError(w, http.StatusNotFound, "verification link is invalid or has expired")
+		return
+	}
+
+	account := verification.Account
+	account.Active = true
+	if err := updateAccount(account); err != nil {
+		archon.Log.Errorf("failed to activate account %s: %v", account.Username, err)
+		writeError(w, http.StatusInternalServerError, auth.ErrUnknown.Error())
+		return
+	}
+	if err := deleteEmailVerification(verification); err != nil {
+		archon.Log.Warnf("failed to delete verification token for %s: %v", account.Username, err)
+	}
+	archon.Log.Infof("WEB activated account %s", account.Username)
+
+	writeJSON(w, http.StatusOK, &verificationResponse{Username: account.Username, Active: true})
+}
+
+func (h *AccountHandler) changePassword(w http.ResponseWriter, r *http.Request) {
+	var req passwordChangeRequest
+	if err := decodeJSON(w, r, &req); err != nil {
+		writeError(w, http.StatusBadRequest, err.Error())
+		return
+	}
+	if !h.passwordAttempts.allow("ip:"+remoteIP(r), "account:"+req.Username) {
+		archon.Log.Warnf("WEB rejected password change for %s from %s: too many attempts", req.Username, remoteIP(r))
+		w.Header().Set("Retry-After", strconv.Itoa(int(passwordAttemptWindow.Seconds())))
+		writeError(w, http.StatusTooManyRequests, "too many attempts; try again later")
+		return
+	}
+	if err := validatePassword(req.NewPassword); err != nil {
+		writeError(w, http.StatusBadRequest, err.Error())
+		return
+	}
+
+	switch err := changePassword(req.Username, req.Password, req.NewPassword); err {
+	case nil:
+		w.WriteHeader(http.StatusNoContent)
+	case auth.ErrInvalidCredentials:
+		writeError(w, http.StatusUnauthorized, err.Error())
+	case auth.ErrAccountBanned, auth.ErrAccountInactive:
+		writeError(w, http.StatusForbidden, err.Error())
+	default:
+		archon.Log.Errorf("failed to change password for %s: %v", req.Username, err)
+		writeError(w, http.StatusInternalServerError, auth.ErrUnknown.Error())
+	}
+}
+
+// validateRegistration checks that the requested credentials can be used
+// with the PSOBB client.
+func validateRegistration(req *registrationRequest) error {
+	if len(req.Username) < minUsernameLength || len(req.Username) > maxUsernameLength {
+		return fmt.Errorf("username must be between %d and %d characters", minUsernameLength, maxUsernameLength)
+	} else if !validUsername.MatchString(req.Username) {
+		return fmt.Errorf("username may only contain letters, numbers, '.', '-', and '_'")
+	}
+
+	if err := validatePassword(req.Password); err != nil {
+		return err
+	}
+
+	if len(req.Email) > maxEmailLength {
+		return fmt.Errorf("email address is too long")
+	}
+	if addr, err := mail.ParseAddress(req.Email); err != nil || addr.Address != req.Email {
+		return fmt.Errorf("email address is invalid")
+	}
+
+	return nil
+}
+
+func validatePassword(password string) error {
+	if len(password) < minPasswordLength || len(password) > maxPasswordLength {
+		return fmt.Errorf("password must be between %d and %d characters", minPasswordLength, maxPasswordLength)
+	} else if !validPassword.MatchString(password) {
+		return fmt.Errorf("password may only contain printable ASCII characters and no spaces")
+	}
+	return nil
+}
+
+// allowMethod responds with a 405 and returns false if the request wasn't made with method.
+func allowMethod(w http.ResponseWriter, r *http.Request, method string) bool {
+	if r.Method != method {
+		w.Header().Set("Allow", method)
+		writeError(w, http.StatusMethodNotAllowed, "method not allowed")
+		return false
+	}
+	return true
+}
+
+// generateToken returns a random, hex-encoded token suitable for use in a URL.
+func generateToken() (string, error) {
+	b := make([]byte, 32)
+	if _, err := rand.Read(b); err != nil {
+		return "", err
+	}
+	return hex.EncodeToString(b), nil
+}
+
+// Persistence functions, overridden in tests.
+var (
+	findUnscopedAccount        = data.FindUnscopedAccount
+	findUnscopedAccountByEmail = data.FindUnscopedAccountByEmail
+	updateAccount              = data.UpdateAccount
+	permanentlyDeleteAccount   = data.PermanentlyDeleteAccount
+	createAccount              = auth.CreateAccount
+	changePassword             = auth.ChangePassword
+	findEmailVerification      = data.FindEmailVerification
+	createEmailVerification    = data.CreateEmailVerification
+	deleteEmailVerification    = data.DeleteEmailVerification
+)
@@ -0,0 +1,192 @@
+package web
+
+import (
+	"errors"
+	"io/ioutil"
+	"net/http"
+	"net/http/httptest"
+	"os"
+	"strings"
+	"testing"
+
+	"github.com/sirupsen/logrus"
+
+	"github.com/dcrodman/archon"
+	"github.com/dcrodman/archon/internal/core/auth"
+	"github.com/dcrodman/archon/internal/core/data"
+)
+
+func TestMain(m *testing.M) {
+	archon.Log = logrus.New()
+	archon.Log.Out = ioutil.Discard
+	os.Exit(m.Run())
+}
+
+type testMailer struct {
+	to, body string
+	// Returned from Send, if set.
+	err error
+}
+
+func (m *testMailer) Send(to, subject, body string) error {
+	m.to, m.body = to, body
+	return m.err
+}
+
+func TestValidateRegistration(t *testing.T) {
+	tests := map[string]struct {
+		req       registrationRequest
+		wantedErr bool
+	}{
+		"happy_path":         {registrationRequest{"player1", "password", "a@b.com"}, false},
+		"short_username":     {registrationRequest{"ab", "password", "a@b.com"}, true},
+		"long_username":      {registrationRequest{"abcdefghijklmnopq", "password", "a@b.com"}, true},
+		"username_chars":     {registrationRequest{"bad name", "password", "a@b.com"}, true},
+		"short_password":     {registrationRequest{"player1", "pass", "a@b.com"}, true},
+		"long_password":      {registrationRequest{"player1", "passwordpassword1", "a@b.com"}, true},
+		"password_chars":     {registrationRequest{"player1", "pass word", "a@b.com"}, true},
+		"invalid_email":      {registrationRequest{"player1", "password", "not-an-email"}, true},
+		"email_display_name": {registrationRequest{"player1", "password", "Bob <a@b.com>"}, true},
+	}
+
+	for name, tt := range tests {
+		t.Run(name, func(t *testing.T) {
+			if err := validateRegistration(&tt.req); (err != nil) != tt.wantedErr {
+				t.Errorf("expected error = %v, got = %v", tt.wantedErr, err)
+			}
+		})
+	}
+}
+
+func TestAccountHandler_Register(t *testing.T) {
+	tests := map[string]struct {
+		existingUsername    *data.Account
+		existingEmail       *data.Account
+		requireVerification bool
+		mailErr             error
+		wantedStatus        int
+		wantedMail          bool
+		// Whether the account is expected to be deleted again.
+		wantedDeleted bool
+	}{
+		"duplicate_username": {
+			existingUsername: &data.Account{Username: "player1"},
+			wantedStatus:     http.StatusConflict,
+		},
+		"duplicate_email": {
+			existingEmail: &data.Account{Email: "a@b.com"},
+			wantedStatus:  http.StatusConflict,
+		},
+		"happy_path": {
+			wantedStatus: http.StatusCreated,
+		},
+		"verification_required": {
+			requireVerification: true,
+			wantedStatus:        http.StatusCreated,
+			wantedMail:          true,
+		},
+		"verification_mail_fails": {
+			requireVerification: true,
+			mailErr:             errors.New("connection refused"),
+			wantedStatus:        http.StatusInternalServerError,
+			wantedMail:          true,
+			wantedDeleted:       true,
+		},
+	}
+
+	for name, tt := range tests {
+		t.Run(name, func(t *testing.T) {
+			originalFindUnscopedAccount, originalFindUnscopedAccountByEmail := findUnscopedAccount, findUnscopedAccountByEmail
+			originalCreateAccount, originalUpdateAccount := createAccount, updateAccount
+			originalCreateEmailVerification, originalDeleteEmailVerification := createEmailVerification, deleteEmailVerification
+			originalPermanentlyDeleteAccount := permanentlyDeleteAccount
+			defer func() {
+				findUnscopedAccount, findUnscopedAccountByEmail = originalFindUnscopedAccount, originalFindUnscopedAccountByEmail
+				createAccount, updateAccount = originalCreateAccount, originalUpdateAccount
+				createEmailVerification, deleteEmailVerification = originalCreateEmailVerification, originalDeleteEmailVerification
+				permanentlyDeleteAccount = originalPermanentlyDeleteAccount
+			}()
+
+			var created *data.Account
+			findUnscopedAccount = func(string) (*data.Account, error) { return tt.existingUsername, nil }
+			findUnscopedAccountByEmail = func(string) (*data.Account, error) { return tt.existingEmail, nil }
+			createAccount = func(username, password, email string, active bool) (*data.Account, error) {
+				created = &data.Account{Username: username, Email: email, Active: active}
+				return created, nil
+			}
+			updateAccount = func(*data.Account) error { return nil }
+			var verifications int
+			createEmailVerification = func(*data.EmailVerification) error { verifications++; return nil }
+			deleteEmailVerification = func(*data.EmailVerification) error { verifications--; return nil }
+			var deleted bool
+			permanentlyDeleteAccount = func(account *data.Account) error {
+				deleted = account == created
+				return nil
+			}
+
+			mailer := &testMailer{err: tt.mailErr}
+			handler := NewAccountHandler(mailer, tt.requireVerification, "http://localhost/api/accounts/verify")
+
+			body := `{"username": "player1", "password": "password", "email": "a@b.com"}`
+			req := httptest.NewRequest(http.MethodPost, "/api/accounts/register", strings.NewReader(body))
+			rec := httptest.NewRecorder()
+			handler.ServeHTTP(rec, req)
+
+			if rec.Code != tt.wantedStatus {
+				t.Fatalf("expected status = %d, got = %d (%s)", tt.wantedStatus, rec.Code, rec.Body.String())
+			}
+			if tt.wantedMail {
+				if mailer.to != "a@b.com" || !strings.Contains(mailer.body, "?token=") {
+					t.Errorf("expected verification email to be sent, got to = %s, body = %s", mailer.to, mailer.body)
+				}
+				if created.Active {
+					t.Error("expected account to be inactive until verified")
+				}
+			} else if mailer.to != "" {
+				t.Errorf("expected no email to be sent, got one to %s", mailer.to)
+			} else if created != nil && !created.Active {
+				t.Error("expected account to be active")
+			}
+			if deleted != tt.wantedDeleted {
+				t.Errorf("expected account deleted = %v, got = %v", tt.wantedDeleted, deleted)
+			}
+			if tt.wantedDeleted && verifications != 0 {
+				t.Errorf("expected verification token to be deleted with the account")
+			}
+		})
+	}
+}
+
+func TestAccountHandler_MethodNotAllowed(t *testing.T) {
+	handler := NewAccountHandler(&testMailer{}, false, "")
+
+	for _, path := range []string{"/api/accounts/register", "/api/accounts/password"} {
+		rec := httptest.NewRecorder()
+		handler.ServeHTTP(rec, httptest.NewRequest(http.MethodGet, path, nil))
+
+		if rec.Code != http.StatusMethodNotAllowed {
+			t.Errorf("expected %s to return %d, got %d", path, http.StatusMethodNotAllowed, rec.Code)
+		}
+	}
+}
+
+func TestAccountHandler_ChangePasswordRateLimited(t *testing.T) {
+	originalChangePassword := changePassword
+	defer func() { changePassword = originalChangePassword }()
+	changePassword = func(username, password, newPassword string) error { return auth.ErrInvalidCredentials }
+
+	handler := NewAccountHandler(&testMailer{}, false, "")
+	for i := 0; i <= maxPasswordAttempts; i++ {
+		body := `{"username": "player1", "password": "guess", "new_password": "password"}`
+		rec := httptest.NewRecorder()
+		handler.ServeHTTP(rec, httptest.NewRequest(http.MethodPost, "/api/accounts/password", strings.NewReader(body)))
+
+		wantedStatus := http.StatusUnauthorized
+		if i == maxPasswordAttempts {
+			wantedStatus = http.StatusTooManyRequests
+		}
+		if rec.Code != wantedStatus {
+			t.Fatalf("attempt %d: expected status = %d, got = %d", i, wantedStatus, rec.Code)
+		}
+	}
+}
@@ -0,0 +1,329 @@
+package web
+
+import (
+	"crypto/subtle"
+	"crypto/tls"
+	"crypto/x509"
+	"fmt"
+	"io/ioutil"
+	"net/http"
+	"strings"
+
+	"github.com/dcrodman/archon"
+	"github.com/dcrodman/archon/internal"
+	"github.com/dcrodman/archon/internal/character"
+	"github.com/dcrodman/archon/internal/core/data"
+	"github.com/dcrodman/archon/internal/patch"
+	"github.com/dcrodman/archon/internal/shipgate/api"
+)
+
+type clientInfo struct {
+	ID        string `json:"id"`
+	Server    string `json:"server"`
+	IP        string `json:"ip"`
+	Account   string `json:"account,omitempty"`
+	Guildcard uint32 `json:"guildcard,omitempty"`
+	State     string `json:"state"`
+}
+
+type kickRequest struct {
+	ID string `json:"id"`
+}
+
+type messageRequest struct {
+	Message string `json:"message"`
+}
+
+type broadcastResponse struct {
+	// Number of clients the message was sent to directly by this process.
+	Recipients int `json:"recipients"`
+	// Whether the message was announced to the players in the blocks of every ship.
+	Published bool `json:"published"`
+}
+
+type teamRequest struct {
+	Username string `json:"username"`
+	TeamID   int    `json:"team_id"`
+}
+
+// envelopeHandler is implemented by the Backends whose clients receive the
+// envelopes published to the shipgate's message bus (i.e. blocks).
+type envelopeHandler interface {
+	HandleEnvelope(envelope *api.Envelope)
+}
+
+// AdminHandler serves the endpoints used to control the server at runtime:
+//
+//	GET  /admin/clients          clients connected to any Frontend
+//	POST /admin/kick             disconnects a client by ID
+//	POST /admin/broadcast        sends a message to every client that can display one,
+//	                             including the players on other ships
+//	PUT  /admin/team             changes the team of an account on every ship
+//	PUT  /admin/scroll_message   replaces the ship selection scroll message
+//	PUT  /admin/welcome_message  replaces the patch screen welcome message
+//	POST /admin/reload_config    reloads the config files, as if they had changed
+//	POST /admin/shutdown         gracefully shuts the server down
+//
+// AdminHandler performs no authentication of its own and should be wrapped with
+// RequireToken and/or served over a listener configured with NewAdminTLSConfig.
+type AdminHandler struct {
+	// All Frontends running in this process.
+	Frontends []*internal.Frontend
+	// Shutdown is invoked to gracefully stop the server.
+	Shutdown func()
+	// Publish sends an envelope to the players on every ship through the shipgate's
+	// message bus. If nil, broadcasts only reach the clients in this process.
+	Publish func(*api.Envelope) error
+	// Whether the account database is available, which is only the case in the
+	// process running the shipgate.
+	AccountsAvailable bool
+}
+
+func (h *AdminHandler) ServeHTTP(w http.ResponseWriter, r *http.Request) {
+	switch r.URL.Path {
+	case "/admin/clients":
+		if allowMethod(w, r, http.MethodGet) {
+			writeJSON(w, http.StatusOK, h.listClients())
+		}
+	case "/admin/kick":
+		if allowMethod(w, r, http.MethodPost) {
+			h.kick(w, r)
+		}
+	case "/admin/broadcast":
+		if allowMethod(w, r, http.MethodPost) {
+			h.broadcast(w, r)
+		}
+	case "/admin/team":
+		if allowMethod(w, r, http.MethodPut) {
+			h.setTeam(w, r)
+		}
+	case "/admin/scroll_message":
+		if allowMethod(w, r, http.MethodPut) {
+			h.setMessage(w, r, "scroll", character.SetScrollMessage)
+		}
+	case "/admin/welcome_message":
+		if allowMethod(w, r, http.MethodPut) {
+			h.setMessage(w, r, "welcome", patch.SetWelcomeMessage)
+		}
+	case "/admin/reload_config":
+		if allowMethod(w, r, http.MethodPost) {
+			h.reloadConfig(w, r)
+		}
+	case "/admin/shutdown":
+		if allowMethod(w, r, http.MethodPost) {
+			archon.Log.Infof("ADMIN shutdown requested by %s", r.RemoteAddr)
+			w.WriteHeader(http.StatusAccepted)
+			h.Shutdown()
+		}
+	default:
+		http.NotFound(w, r)
+	}
+}
+
+func (h *AdminHandler) listClients() []clientInfo {
+	clients := make([]clientInfo, 0)
+	for _, f := range h.Frontends {
+		for _, c := range f.Clients() {
+			identity := c.Identity()
+			clients = append(clients, clientInfo{
+				ID:        c.SessionID,
+				Server:    f.Backend.Name(),
+				IP:        c.IPAddr(),
+				Account:   identity.Username,
+				Guildcard: identity.Guildcard,
+				State:     c.State().String(),
+			})
+		}
+	}
+	return clients
+}
+
+func (h *AdminHandler) kick(w http.ResponseWriter, r *http.Request) {
+	var req kickRequest
+	if err := decodeJSON(w, r, &req); err != nil {
+		writeError(w, http.StatusBadRequest, err.Error())
+		return
+	}
+
+	c := findClient(req.ID)
+	if c == nil {
+		writeError(w, http.StatusNotFound, "client not found")
+		return
+	}
+
+	// Closing the connection will cause the Frontend to clean up the client.
+	if err := c.Close(); err != nil {
+		archon.Log.Warnf("ADMIN failed to close connection for %s: %v", req.ID, err)
+	}
+	archon.Log.Infof("ADMIN kicked %s client %s", c.ServerName, req.ID)
+	w.WriteHeader(http.StatusNoContent)
+}
+
+func (h *AdminHandler) broadcast(w http.ResponseWriter, r *http.Request) {
+	var req messageRequest
+	if err := decodeJSON(w, r, &req); err != nil {
+		writeError(w, http.StatusBadRequest, err.Error())
+		return
+	} else if req.Message == "" {
+		writeError(w, http.StatusBadRequest, "message is required")
+		return
+	}
+
+	published := false
+	if h.Publish != nil {
+		announcement := &api.Envelope{Payload: &api.Envelope_Announcement{
+			Announcement: &api.Announcement{Message: req.Message},
+		}}
+		if err := h.Publish(announcement); err != nil {
+			archon.Log.Warnf("ADMIN failed to publish broadcast, sending it to local clients only: %v", err)
+		} else {
+			published = true
+		}
+	}
+
+	recipients := 0
+	for _, f := range h.Frontends {
+		messenger, ok := f.Backend.(internal.Messenger)
+		if !ok {
+			continue
+		}
+		// Players in blocks are sent the announcement once it's delivered by the message bus.
+		if _, ok := f.Backend.(envelopeHandler); ok && published {
+			continue
+		}
+		for _, c := range f.Clients() {
+			if err := messenger.SendMessage(c, req.Message); err != nil {
+				archon.Log.Warnf("ADMIN failed to send broadcast to %s: %v", c.IPAddr(), err)
+				continue
+			}
+			recipients++
+		}
+	}
+	archon.Log.Infof("ADMIN broadcast message to %d clients (published: %v)", recipients, published)
+
+	writeJSON(w, http.StatusOK, &broadcastResponse{Recipients: recipients, Published: published})
+}
+
+func (h *AdminHandler) setTeam(w http.ResponseWriter, r *http.Request) {
+	if !h.AccountsAvailable {
+		writeError(w, http.StatusNotFound, "teams can only be changed on the server running the shipgate")
+		return
+	}
+
+	var req teamRequest
+	if err := decodeJSON(w, r, &req); err != nil {
+		writeError(w, http.StatusBadRequest, err.Error())
+		return
+	} else if req.Username == "" {
+		writeError(w, http.StatusBadRequest, "username is required")
+		return
+	} else if req.TeamID < 0 {
+		writeError(w, http.StatusBadRequest, "team_id must not be negative")
+		return
+	}
+
+	account, err := findAccount(req.Username)
+	if err != nil {
+		archon.Log.Errorf("ADMIN failed to look up account %s: %v", req.Username, err)
+		writeError(w, http.StatusInternalServerError, "failed to look up account")
+		return
+	} else if account == nil {
+		writeError(w, http.StatusNotFound, "account not found")
+		return
+	}
+
+	account.TeamID = req.TeamID
+	if err := updateAccount(account); err != nil {
+		archon.Log.Errorf("ADMIN failed to update team of account %s: %v", req.Username, err)
+		writeError(w, http.StatusInternalServerError, "failed to update account")
+		return
+	}
+	archon.Log.Infof("ADMIN moved account %s to team %d", req.Username, req.TeamID)
+
+	// Players that are logged in pick up the change without having to log in again.
+	if h.Publish != nil {
+		update := &api.Envelope{Payload: &api.Envelope_TeamUpdate{TeamUpdate: &api.TeamUpdate{
+			AccountId: uint64(account.ID),
+			TeamId:    uint64(req.TeamID),
+		}}}
+		if err := h.Publish(update); err != nil {
+			archon.Log.Warnf("ADMIN failed to publish team update for %s: %v", req.Username, err)
+		}
+	}
+
+	w.WriteHeader(http.StatusNoContent)
+}
+
+func (h *AdminHandler) setMessage(w http.ResponseWriter, r *http.Request, name string, setFn func(string)) {
+	var req messageRequest
+	if err := decodeJSON(w, r, &req); err != nil {
+		writeError(w, http.StatusBadRequest, err.Error())
+		return
+	}
+
+	setFn(req.Message)
+	archon.Log.Infof("ADMIN updated %s message", name)
+
+	w.WriteHeader(http.StatusNoContent)
+}
+
+func (h *AdminHandler) reloadConfig(w http.ResponseWriter, r *http.Request) {
+	changes, err := archon.ReloadConfig()
+	if err != nil {
+		writeError(w, http.StatusBadRequest, err.Error())
+		return
+	}
+	archon.Log.Infof("ADMIN reloaded config (applied: %v, restart required: %v)", changes.Applied, changes.RestartRequired)
+
+	writeJSON(w, http.StatusOK, changes)
+}
+
+// RequireToken wraps handler such that requests are rejected unless they include
+// token as a bearer token in the Authorization header.
+func RequireToken(token string, handler http.Handler) http.Handler {
+	return http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		provided := strings.TrimPrefix(r.Header.Get("Authorization"), "Bearer ")
+		if subtle.ConstantTimeCompare([]byte(provided), []byte(token)) != 1 {
+			archon.Log.Warnf("ADMIN rejected unauthorized request from %s", r.RemoteAddr)
+			writeError(w, http.StatusUnauthorized, "unauthorized")
+			return
+		}
+		handler.ServeHTTP(w, r)
+	})
+}
+
+// NewAdminTLSConfig returns a TLS configuration that serves certFile/keyFile and,
+// if clientCAFile is not empty, requires clients to present a certificate signed
+// by it (such as those generated by certgen).
+func NewAdminTLSConfig(certFile, keyFile, clientCAFile string) (*tls.Config, error) {
+	cert, err := tls.LoadX509KeyPair(certFile, keyFile)
+	if err != nil {
+		return nil, fmt.Errorf("unable to load X.509 certificate: %s", err)
+	}
+
+	config := &tls.Config{
+		Certificates: []tls.Certificate{cert},
+		MinVersion:   tls.VersionTLS12,
+	}
+
+	if clientCAFile != "" {
+		caBytes, err := ioutil.ReadFile(clientCAFile)
+		if err != nil {
+			return nil, fmt.Errorf("unable to load client CA file: %s", err)
+		}
+		pool := x509.NewCertPool()
+		if !pool.AppendCertsFromPEM(caBytes) {
+			return nil, fmt.Errorf("no certificates found in client CA file %s", clientCAFile)
+		}
+		config.ClientCAs = pool
+		config.ClientAuth = tls.RequireAndVerifyClientCert
+	}
+
+	return config, nil
+}
+
+// Overridden in tests.
+var (
+	findClient  = internal.FindClient
+	findAccount = data.FindAccount
+)
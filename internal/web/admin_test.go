@@ -0,0 +1,196 @@
+package web
+
+import (
+	"encoding/json"
+	"errors"
+	"net"
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"testing"
+
+	"github.com/dcrodman/archon/internal/client"
+	"github.com/dcrodman/archon/internal/core/data"
+	"github.com/dcrodman/archon/internal/shipgate/api"
+)
+
+func TestRequireToken(t *testing.T) {
+	tests := map[string]struct {
+		authorization string
+		wantedStatus  int
+	}{
+		"missing_token": {"", http.StatusUnauthorized},
+		"wrong_token":   {"Bearer nope", http.StatusUnauthorized},
+		"not_bearer":    {"Basic secret", http.StatusUnauthorized},
+		"valid_token":   {"Bearer secret", http.StatusOK},
+	}
+
+	handler := RequireToken("secret", http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		w.WriteHeader(http.StatusOK)
+	}))
+
+	for name, tt := range tests {
+		t.Run(name, func(t *testing.T) {
+			req := httptest.NewRequest(http.MethodGet, "/admin/clients", nil)
+			if tt.authorization != "" {
+				req.Header.Set("Authorization", tt.authorization)
+			}
+			rec := httptest.NewRecorder()
+			handler.ServeHTTP(rec, req)
+
+			if rec.Code != tt.wantedStatus {
+				t.Errorf("expected status = %d, got = %d", tt.wantedStatus, rec.Code)
+			}
+		})
+	}
+}
+
+func TestAdminHandler_Kick(t *testing.T) {
+	originalFindClient := findClient
+	defer func() { findClient = originalFindClient }()
+
+	server, conn := net.Pipe()
+	defer server.Close()
+
+	c := client.NewClient(conn)
+	c.SessionID = "abc123"
+	findClient = func(id string) *client.Client {
+		if id == c.SessionID {
+			return c
+		}
+		return nil
+	}
+
+	tests := map[string]struct {
+		body         string
+		wantedStatus int
+	}{
+		"unknown_client": {`{"id": "nope"}`, http.StatusNotFound},
+		"invalid_body":   {`{"client": "abc123"}`, http.StatusBadRequest},
+		"known_client":   {`{"id": "abc123"}`, http.StatusNoContent},
+	}
+
+	handler := &AdminHandler{}
+	for name, tt := range tests {
+		t.Run(name, func(t *testing.T) {
+			req := httptest.NewRequest(http.MethodPost, "/admin/kick", strings.NewReader(tt.body))
+			rec := httptest.NewRecorder()
+			handler.ServeHTTP(rec, req)
+
+			if rec.Code != tt.wantedStatus {
+				t.Errorf("expected status = %d, got = %d", tt.wantedStatus, rec.Code)
+			}
+		})
+	}
+
+	if _, err := server.Write([]byte{0}); err == nil {
+		t.Error("expected kicked client's connection to be closed")
+	}
+}
+
+func TestAdminHandler_Broadcast(t *testing.T) {
+	tests := map[string]struct {
+		publishErr    error
+		noBus         bool
+		wantPublished bool
+	}{
+		"published":   {wantPublished: true},
+		"bus_backlog": {publishErr: errors.New("unavailable")},
+		"no_bus":      {noBus: true},
+	}
+
+	for name, tt := range tests {
+		t.Run(name, func(t *testing.T) {
+			var published []*api.Envelope
+			handler := &AdminHandler{}
+			if !tt.noBus {
+				handler.Publish = func(envelope *api.Envelope) error {
+					published = append(published, envelope)
+					return tt.publishErr
+				}
+			}
+
+			req := httptest.NewRequest(http.MethodPost, "/admin/broadcast", strings.NewReader(`{"message": "Hello"}`))
+			rec := httptest.NewRecorder()
+			handler.ServeHTTP(rec, req)
+
+			if rec.Code != http.StatusOK {
+				t.Fatalf("expected status = %d, got = %d", http.StatusOK, rec.Code)
+			}
+			var resp broadcastResponse
+			if err := json.Unmarshal(rec.Body.Bytes(), &resp); err != nil {
+				t.Fatalf("failed to decode response: %v", err)
+			}
+			if resp.Published != tt.wantPublished {
+				t.Errorf("expected published = %v, got = %v", tt.wantPublished, resp.Published)
+			}
+			if !tt.noBus && (len(published) != 1 || published[0].GetAnnouncement().GetMessage() != "Hello") {
+				t.Errorf("expected announcement to be published, got %v", published)
+			}
+		})
+	}
+}
+
+func TestAdminHandler_SetTeam(t *testing.T) {
+	originalFindAccount, originalUpdateAccount := findAccount, updateAccount
+	defer func() { findAccount, updateAccount = originalFindAccount, originalUpdateAccount }()
+
+	tests := map[string]struct {
+		body              string
+		accountsAvailable bool
+		wantedStatus      int
+		wantPublished     bool
+	}{
+		"valid":              {`{"username": "player", "team_id": 3}`, true, http.StatusNoContent, true},
+		"unknown_account":    {`{"username": "nobody", "team_id": 3}`, true, http.StatusNotFound, false},
+		"missing_username":   {`{"team_id": 3}`, true, http.StatusBadRequest, false},
+		"negative_team":      {`{"username": "player", "team_id": -1}`, true, http.StatusBadRequest, false},
+		"accounts_elsewhere": {`{"username": "player", "team_id": 3}`, false, http.StatusNotFound, false},
+	}
+
+	for name, tt := range tests {
+		t.Run(name, func(t *testing.T) {
+			account := &data.Account{Username: "player"}
+			account.ID = 7
+			findAccount = func(username string) (*data.Account, error) {
+				if username == account.Username {
+					return account, nil
+				}
+				return nil, nil
+			}
+			var updated *data.Account
+			updateAccount = func(a *data.Account) error {
+				updated = a
+				return nil
+			}
+			var published []*api.Envelope
+			handler := &AdminHandler{
+				AccountsAvailable: tt.accountsAvailable,
+				Publish: func(envelope *api.Envelope) error {
+					published = append(published, envelope)
+					return nil
+				},
+			}
+
+			req := httptest.NewRequest(http.MethodPut, "/admin/team", strings.NewReader(tt.body))
+			rec := httptest.NewRecorder()
+			handler.ServeHTTP(rec, req)
+
+			if rec.Code != tt.wantedStatus {
+				t.Errorf("expected status = %d, got = %d", tt.wantedStatus, rec.Code)
+			}
+			if !tt.wantPublished {
+				if updated != nil || len(published) > 0 {
+					t.Errorf("expected account not to be updated, got %v (published %v)", updated, published)
+				}
+				return
+			}
+			if updated == nil || updated.TeamID != 3 {
+				t.Errorf("expected team ID of account to be updated to 3, got %v", updated)
+			}
+			if len(published) != 1 || published[0].GetTeamUpdate().GetAccountId() != 7 || published[0].GetTeamUpdate().GetTeamId() != 3 {
+				t.Errorf("expected team update for account 7 to be published, got %v", published)
+			}
+		})
+	}
+}
@@ -0,0 +1,46 @@
+package web
+
+import (
+	"fmt"
+	"io"
+	"os"
+	"sync"
+	"time"
+)
+
+// Mailer delivers messages to players' email addresses.
+type Mailer interface {
+	Send(to, subject, body string) error
+}
+
+// fileMailer is a Mailer that writes messages to a file instead of delivering
+// them, which is useful for development or for servers without a mail relay.
+type fileMailer struct {
+	path string
+	mu   sync.Mutex
+}
+
+// NewFileMailer returns a Mailer that appends every message to the file at
+// path, or writes it to stdout if path is empty.
+func NewFileMailer(path string) Mailer {
+	return &fileMailer{path: path}
+}
+
+func (m *fileMailer) Send(to, subject, body string) error {
+	m.mu.Lock()
+	defer m.mu.Unlock()
+
+	var w io.Writer = os.Stdout
+	if m.path != "" {
+		f, err := os.OpenFile(m.path, os.O_CREATE|os.O_WRONLY|os.O_APPEND, 0600)
+		if err != nil {
+			return fmt.Errorf("failed to open mail file %s: %v", m.path, err)
+		}
+		defer f.Close()
+		w = f
+	}
+
+	_, err := fmt.Fprintf(w, "Date: %s\nTo: %s\nSubject: %s\n\n%s\n\n",
+		time.Now().Format(time.RFC1123Z), to, subject, body)
+	return err
+}
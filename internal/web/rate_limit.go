@@ -0,0 +1,83 @@
+package web
+
+import (
+	"net"
+	"net/http"
+	"sync"
+	"time"
+)
+
+// Overridden in tests.
+var now = time.Now
+
+// attemptLimiter limits the number of times something (e.g. an IP address) may
+// attempt an action within a window of time.
+type attemptLimiter struct {
+	max    int
+	window time.Duration
+
+	mutex     sync.Mutex
+	attempts  map[string]*attemptWindow
+	lastPrune time.Time
+}
+
+type attemptWindow struct {
+	start time.Time
+	count int
+}
+
+func newAttemptLimiter(max int, window time.Duration) *attemptLimiter {
+	return &attemptLimiter{
+		max:      max,
+		window:   window,
+		attempts: make(map[string]*attemptWindow),
+	}
+}
+
+// allow records an attempt by each of keys unless any of them has run out of
+// attempts in its current window, in which case nothing is recorded and false
+// is returned.
+func (l *attemptLimiter) allow(keys ...string) bool {
+	l.mutex.Lock()
+	defer l.mutex.Unlock()
+
+	current := now()
+	l.prune(current)
+
+	for _, key := range keys {
+		if w, ok := l.attempts[key]; ok && current.Sub(w.start) < l.window && w.count >= l.max {
+			return false
+		}
+	}
+	for _, key := range keys {
+		w, ok := l.attempts[key]
+		if !ok || current.Sub(w.start) >= l.window {
+			w = &attemptWindow{start: current}
+			l.attempts[key] = w
+		}
+		w.count++
+	}
+	return true
+}
+
+// prune forgets about windows that have ended so that the limiter doesn't grow
+// without bound. Must be called with the lock held.
+func (l *attemptLimiter) prune(current time.Time) {
+	if current.Sub(l.lastPrune) < l.window {
+		return
+	}
+	for key, w := range l.attempts {
+		if current.Sub(w.start) >= l.window {
+			delete(l.attempts, key)
+		}
+	}
+	l.lastPrune = current
+}
+
+// remoteIP returns the IP address from which the request was made.
+func remoteIP(r *http.Request) string {
+	if host, _, err := net.SplitHostPort(r.RemoteAddr); err == nil {
+		return host
+	}
+	return r.RemoteAddr
+}
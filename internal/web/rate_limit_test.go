@@ -0,0 +1,60 @@
+package web
+
+import (
+	"testing"
+	"time"
+)
+
+func TestAttemptLimiter(t *testing.T) {
+	originalNow := now
+	defer func() { now = originalNow }()
+
+	type attempt struct {
+		after  time.Duration
+		keys   []string
+		wanted bool
+	}
+	tests := map[string][]attempt{
+		"within_limit": {
+			{keys: []string{"ip"}, wanted: true},
+			{keys: []string{"ip"}, wanted: true},
+		},
+		"limit_exceeded": {
+			{keys: []string{"ip"}, wanted: true},
+			{keys: []string{"ip"}, wanted: true},
+			{keys: []string{"ip"}, wanted: false},
+		},
+		"window_ended": {
+			{keys: []string{"ip"}, wanted: true},
+			{keys: []string{"ip"}, wanted: true},
+			{after: time.Minute, keys: []string{"ip"}, wanted: true},
+		},
+		"any_key_exceeded": {
+			{keys: []string{"ip", "first"}, wanted: true},
+			{keys: []string{"ip", "second"}, wanted: true},
+			{keys: []string{"ip", "third"}, wanted: false},
+		},
+		"rejected_attempts_not_recorded": {
+			{keys: []string{"ip", "first"}, wanted: true},
+			{keys: []string{"ip", "first"}, wanted: true},
+			{keys: []string{"ip", "second"}, wanted: false},
+			{keys: []string{"other", "second"}, wanted: true},
+			{keys: []string{"other", "second"}, wanted: true},
+		},
+	}
+
+	for name, attempts := range tests {
+		t.Run(name, func(t *testing.T) {
+			current := time.Now()
+			now = func() time.Time { return current }
+
+			l := newAttemptLimiter(2, time.Minute)
+			for i, a := range attempts {
+				current = current.Add(a.after)
+				if allowed := l.allow(a.keys...); allowed != a.wanted {
+					t.Errorf("attempt %d: expected allowed = %v, got = %v", i, a.wanted, allowed)
+				}
+			}
+		})
+	}
+}
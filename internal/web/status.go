@@ -0,0 +1,146 @@
+package web
+
+import (
+	"net/http"
+	"strings"
+
+	"github.com/dcrodman/archon/internal"
+	"github.com/dcrodman/archon/internal/shipgate"
+)
+
+// BlockBackend is implemented by Backends that host lobbies and games.
+type BlockBackend interface {
+	internal.Backend
+	Lobbies() int
+	Games() int
+}
+
+type shipStatus struct {
+	ID     int    `json:"id"`
+	Name   string `json:"name"`
+	IP     string `json:"ip"`
+	Port   string `json:"port"`
+	Active bool   `json:"active"`
+	// Population is only known for active ships.
+	Players *int          `json:"players,omitempty"`
+	Blocks  []blockStatus `json:"blocks,omitempty"`
+}
+
+type blockStatus struct {
+	Name    string `json:"name"`
+	Address string `json:"address,omitempty"`
+	Players int    `json:"players"`
+	Lobbies int    `json:"lobbies"`
+	Games   int    `json:"games"`
+}
+
+type serverStatus struct {
+	Online  int            `json:"online"`
+	Servers map[string]int `json:"servers"`
+	Ships   []shipStatus   `json:"ships"`
+}
+
+// StatusHandler serves the public, read-only server status endpoints:
+//
+//	GET /api/status         total players online, per-server counts, and ships
+//	GET /api/status/ships   ships registered with the shipgate
+//	GET /api/status/blocks  blocks of the ship running in this process
+type StatusHandler struct {
+	// Name of the ship running in this process (if any).
+	ShipName string
+	// Frontends of the ship and block servers running in this process (if any).
+	Ship   *internal.Frontend
+	Blocks []*internal.Frontend
+	// All Frontends running in this process.
+	Frontends []*internal.Frontend
+}
+
+func (h *StatusHandler) ServeHTTP(w http.ResponseWriter, r *http.Request) {
+	if !allowMethod(w, r, http.MethodGet) {
+		return
+	}
+	// The status endpoints are intended to be consumed by community sites.
+	w.Header().Set("Access-Control-Allow-Origin", "*")
+
+	switch strings.TrimSuffix(r.URL.Path, "/") {
+	case "/api/status":
+		writeJSON(w, http.StatusOK, h.serverStatus())
+	case "/api/status/ships":
+		writeJSON(w, http.StatusOK, h.shipStatuses())
+	case "/api/status/blocks":
+		writeJSON(w, http.StatusOK, h.blockStatuses())
+	default:
+		http.NotFound(w, r)
+	}
+}
+
+func (h *StatusHandler) serverStatus() *serverStatus {
+	status := &serverStatus{
+		Servers: make(map[string]int),
+		Ships:   h.shipStatuses(),
+	}
+	for _, f := range h.Frontends {
+		count := f.ClientCount()
+		status.Servers[f.Backend.Name()] = count
+		status.Online += count
+	}
+	return status
+}
+
+func (h *StatusHandler) shipStatuses() []shipStatus {
+	ships := make([]shipStatus, 0)
+	for _, ship := range registeredShips() {
+		if ship.Hidden {
+			continue
+		}
+		status := shipStatus{
+			ID:     ship.ID,
+			Name:   ship.Name,
+			IP:     ship.IP,
+			Port:   ship.Port,
+			Active: ship.Active,
+		}
+		if ship.Name == h.ShipName && h.Ship != nil {
+			// The ship running in this process is more up to date than its last heartbeat.
+			players := h.Ship.ClientCount()
+			for _, block := range h.Blocks {
+				players += block.ClientCount()
+			}
+			status.Players = &players
+			status.Blocks = h.blockStatuses()
+		} else if ship.Active {
+			players := ship.Players
+			status.Players = &players
+			for _, block := range ship.Blocks {
+				status.Blocks = append(status.Blocks, blockStatus{
+					Name:    block.Name,
+					Players: block.Players,
+					Lobbies: block.Lobbies,
+					Games:   block.Games,
+				})
+			}
+		}
+		ships = append(ships, status)
+	}
+	return ships
+}
+
+func (h *StatusHandler) blockStatuses() []blockStatus {
+	blocks := make([]blockStatus, 0, len(h.Blocks))
+	for _, f := range h.Blocks {
+		status := blockStatus{
+			Name:    f.Backend.Name(),
+			Address: f.Address,
+			Players: f.ClientCount(),
+		}
+		if backend, ok := f.Backend.(BlockBackend); ok {
+			status.Lobbies = backend.Lobbies()
+			status.Games = backend.Games()
+		}
+		blocks = append(blocks, status)
+	}
+	return blocks
+}
+
+// Overridden in tests.
+var registeredShips = shipgate.RegisteredShips
@@ -0,0 +1,55 @@
+package web
+
+import (
+	"encoding/json"
+	"net/http"
+	"net/http/httptest"
+	"testing"
+
+	"github.com/dcrodman/archon/internal"
+	"github.com/dcrodman/archon/internal/shipgate"
+)
+
+func TestStatusHandler_Ships(t *testing.T) {
+	originalRegisteredShips := registeredShips
+	defer func() { registeredShips = originalRegisteredShips }()
+
+	registeredShips = func() []shipgate.ShipStatus {
+		return []shipgate.ShipStatus{
+			{ID: 1, Name: "Local", IP: "127.0.0.1", Port: "15000", Active: true},
+			{ID: 2, Name: "Remote", IP: "10.0.0.1", Port: "15000", Active: true, Players: 5,
+				Blocks: []shipgate.BlockStatus{{Name: "BLOCK01", Players: 5, Lobbies: 15}}},
+			{ID: 3, Name: "Offline", IP: "10.0.0.2", Port: "15000", Active: false},
+			{ID: 4, Name: "Hidden", IP: "10.0.0.3", Port: "15000", Active: true, Hidden: true},
+		}
+	}
+
+	handler := &StatusHandler{ShipName: "Local", Ship: &internal.Frontend{}}
+	rec := httptest.NewRecorder()
+	handler.ServeHTTP(rec, httptest.NewRequest(http.MethodGet, "/api/status/ships", nil))
+
+	if rec.Code != http.StatusOK {
+		t.Fatalf("expected status = %d, got = %d", http.StatusOK, rec.Code)
+	}
+
+	var ships []shipStatus
+	if err := json.NewDecoder(rec.Body).Decode(&ships); err != nil {
+		t.Fatalf("failed to decode response: %v", err)
+	}
+	// Hidden ships are left out.
+	if len(ships) != 3 {
+		t.Fatalf("expected 3 ships, got %d", len(ships))
+	}
+	if ships[0].Players == nil || *ships[0].Players != 0 {
+		t.Errorf("expected local ship population to be reported, got %v", ships[0].Players)
+	}
+	if ships[1].Players == nil || *ships[1].Players != 5 {
+		t.Errorf("expected remote ship population from its heartbeat, got %v", ships[1].Players)
+	}
+	if len(ships[1].Blocks) != 1 || ships[1].Blocks[0].Lobbies != 15 {
+		t.Errorf("expected remote ship blocks from its heartbeat, got %+v", ships[1].Blocks)
+	}
+	if ships[2].Players != nil {
+		t.Errorf("expected inactive ship population to be omitted, got %d", *ships[2].Players)
+	}
+}
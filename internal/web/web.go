@@ -0,0 +1,115 @@
+// Package web contains the HTTP server that hosts Archon's publicly accessible
+// API endpoints (e.g. account registration). None of these endpoints are part of
+// the PSO protocol; they exist for players and tooling outside of the game client.
+package web
+
+import (
+	"context"
+	"crypto/tls"
+	"encoding/json"
+	"fmt"
+	"net"
+	"net/http"
+	"sync"
+	"time"
+
+	"github.com/dcrodman/archon"
+)
+
+// Maximum size of a request body accepted by any of the JSON endpoints.
+const maxRequestBodySize = 1 << 16
+
+// Server hosts the HTTP handlers registered with it on Address.
+type Server struct {
+	Name    string
+	Address string
+	// Optional configuration for serving requests over HTTPS.
+	TLSConfig *tls.Config
+
+	mux *http.ServeMux
+}
+
+func NewServer(name, address string) *Server {
+	return &Server{
+		Name:    name,
+		Address: address,
+		mux:     http.NewServeMux(),
+	}
+}
+
+// Handle registers the handler for the given pattern. See http.ServeMux for
+// the semantics of pattern.
+func (s *Server) Handle(pattern string, handler http.Handler) {
+	s.mux.Handle(pattern, handler)
+}
+
+// Start opens a TCP socket on the Server's Address and begins serving requests
+// in its own goroutine, which is added to the WaitGroup. Context cancellations
+// will stop the server.
+func (s *Server) Start(ctx context.Context, wg *sync.WaitGroup) error {
+	listener, err := net.Listen("tcp", s.Address)
+	if err != nil {
+		return fmt.Errorf("failed to open socket on %s: %v", s.Address, err)
+	}
+	if s.TLSConfig != nil {
+		listener = tls.NewListener(listener, s.TLSConfig)
+	}
+
+	httpServer := &http.Server{
+		Handler:      s.mux,
+		ReadTimeout:  10 * time.Second,
+		WriteTimeout: 10 * time.Second,
+	}
+
+	wg.Add(1)
+	go func() {
+		defer wg.Done()
+		archon.Log.Printf("%s waiting for requests on %s", s.Name, s.Address)
+
+		if err := httpServer.Serve(listener); err != nil && err != http.ErrServerClosed {
+			archon.Log.Errorf("%s server error: %v", s.Name, err)
+		}
+		archon.Log.Printf("%s server exited", s.Name)
+	}()
+
+	go func() {
+		<-ctx.Done()
+
+		shutdownCtx, cancel := context.WithTimeout(context.Background(), 5*time.Second)
+		defer cancel()
+		_ = httpServer.Shutdown(shutdownCtx)
+	}()
+
+	return nil
+}
+
+// errorResponse is the body returned by any endpoint when a request fails.
+type errorResponse struct {
+	Error string `json:"error"`
+}
+
+// writeJSON serializes v as the response body with the given status code.
+func writeJSON(w http.ResponseWriter, status int, v interface{}) {
+	w.Header().Set("Content-Type", "application/json")
+	w.WriteHeader(status)
+
+	if err := json.NewEncoder(w).Encode(v); err != nil {
+		archon.Log.Warnf("failed to write HTTP response: %v", err)
+	}
+}
+
+func writeError(w http.ResponseWriter, status int, message string) {
+	writeJSON(w, status, &errorResponse{Error: message})
+}
+
+// decodeJSON reads the request body into v, rejecting bodies that are too
+// large or contain unexpected fields.
+func decodeJSON(w http.ResponseWriter, r *http.Request, v interface{}) error {
+	decoder := json.NewDecoder(http.MaxBytesReader(w, r.Body, maxRequestBodySize))
+	decoder.DisallowUnknownFields()
+
+	if err := decoder.Decode(v); err != nil {
+		return fmt.Errorf("invalid request body: %v", err)
+	}
+	return nil
+}
@@ -1,23 +1,57 @@
 package archon
 
 import (
+	"context"
 	"fmt"
 	"io"
 	"os"
 
 	"github.com/sirupsen/logrus"
-	"github.com/spf13/viper"
 )
 
 // Log is the global, threadsafe logger that can be used by any server instance.
 var Log *logrus.Logger
 
+type contextKey int
+
+const (
+	loggerKey contextKey = iota
+	sessionIDKey
+)
+
+// WithLogger returns a copy of ctx carrying logger, which should be used for any
+// log messages emitted while handling a request made with the context.
+func WithLogger(ctx context.Context, logger *logrus.Entry) context.Context {
+	return context.WithValue(ctx, loggerKey, logger)
+}
+
+// LoggerFromContext returns the logger carried by ctx or, if there isn't one,
+// an entry for the global logger with no fields.
+func LoggerFromContext(ctx context.Context) *logrus.Entry {
+	if logger, ok := ctx.Value(loggerKey).(*logrus.Entry); ok {
+		return logger
+	}
+	return logrus.NewEntry(Log)
+}
+
+// WithSessionID returns a copy of ctx carrying the ID of the client session on
+// whose behalf a request is being made.
+func WithSessionID(ctx context.Context, sessionID string) context.Context {
+	return context.WithValue(ctx, sessionIDKey, sessionID)
+}
+
+// SessionIDFromContext returns the session ID carried by ctx, if any.
+func SessionIDFromContext(ctx context.Context) string {
+	sessionID, _ := ctx.Value(sessionIDKey).(string)
+	return sessionID
+}
+
 // InitLogger configures the global logger and should be called on startup.
-func InitLogger() {
+func InitLogger(cfg *Config) {
 	var w io.Writer
 	var err error
 
-	logFile := viper.GetString("log_file_path")
+	logFile := cfg.LogFilePath
 
 	if logFile == "" {
 		w = os.Stdout
@@ -29,7 +63,7 @@ func InitLogger() {
 		}
 	}
 
-	logLvl, err := logrus.ParseLevel(viper.GetString("log_level"))
+	logLvl, err := logrus.ParseLevel(cfg.LogLevel)
 	if err != nil {
 		fmt.Println("ERROR: Failed to parse Log level: " + err.Error())
 		os.Exit(1)
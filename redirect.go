@@ -0,0 +1,214 @@
+package archon
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"net"
+	"strings"
+	"sync"
+	"time"
+)
+
+// ErrNotIPv4 is returned for addresses that can't be sent to clients, since the
+// redirect packets only have room for an IPv4 address.
+var ErrNotIPv4 = errors.New("redirect packets can only contain IPv4 addresses")
+
+// Time allowed for looking up the hostnames in the redirect addresses.
+const resolveTimeout = 10 * time.Second
+
+// lookupIP is swapped out by tests to avoid depending on DNS.
+var lookupIP = net.DefaultResolver.LookupIP
+
+// A redirectRule is an address sent in redirect packets to the clients connecting
+// from subnet, or to every client if subnet is nil.
+type redirectRule struct {
+	subnet  *net.IPNet
+	address string
+
+	// The last IPv4 address to which address resolved, or the reason it hasn't.
+	ip  net.IP
+	err error
+}
+
+var (
+	redirectMutex sync.RWMutex
+	// The redirect_addresses rules in order, followed by the external_ip rule.
+	redirectRules []*redirectRule
+)
+
+// setRedirectRules replaces the redirect rules with the ones from cfg. Any hostnames
+// that were already resolved keep their addresses until they're looked up again.
+func setRedirectRules(cfg *Config) {
+	redirectMutex.Lock()
+	defer redirectMutex.Unlock()
+
+	previous := make(map[string]*redirectRule)
+	for _, rule := range redirectRules {
+		previous[rule.address] = rule
+	}
+	newRule := func(subnet *net.IPNet, address string) *redirectRule {
+		rule := &redirectRule{subnet: subnet, address: address}
+		if ip := net.ParseIP(address); ip != nil {
+			rule.ip = ip.To4()
+			if rule.ip == nil {
+				rule.err = fmt.Errorf("%s: %w", address, ErrNotIPv4)
+			}
+		} else if p, ok := previous[address]; ok {
+			rule.ip, rule.err = p.ip, p.err
+		} else {
+			rule.err = fmt.Errorf("%s has not been resolved", address)
+		}
+		return rule
+	}
+
+	redirectRules = nil
+	for _, redirect := range cfg.RedirectAddresses {
+		// Validation makes sure the subnet is valid.
+		if _, subnet, err := net.ParseCIDR(redirect.Subnet); err == nil {
+			redirectRules = append(redirectRules, newRule(subnet, redirect.Address))
+		}
+	}
+	redirectRules = append(redirectRules, newRule(nil, cfg.ExternalIP))
+}
+
+// RedirectIP returns the address to send in redirect packets to a client connecting
+// from clientIP: the address of the first redirect_addresses entry whose subnet
+// contains clientIP, otherwise external_ip.
+func RedirectIP(clientIP string) ([4]byte, error) {
+	var redirectIP [4]byte
+	ip := net.ParseIP(clientIP)
+
+	redirectMutex.RLock()
+	defer redirectMutex.RUnlock()
+	for _, rule := range redirectRules {
+		if rule.subnet != nil && (ip == nil || !rule.subnet.Contains(ip)) {
+			continue
+		}
+		if rule.ip == nil {
+			return redirectIP, fmt.Errorf("unable to redirect client %s: %w", clientIP, rule.err)
+		}
+		copy(redirectIP[:], rule.ip)
+		return redirectIP, nil
+	}
+	return redirectIP, fmt.Errorf("unable to redirect client %s: no redirect address configured", clientIP)
+}
+
+// ResolveIPv4 returns the IPv4 address of address, which is either an IP address
+// or a hostname to look up.
+func ResolveIPv4(ctx context.Context, address string) (net.IP, error) {
+	if ip := net.ParseIP(address); ip != nil {
+		if ip.To4() == nil {
+			return nil, fmt.Errorf("%s: %w", address, ErrNotIPv4)
+		}
+		return ip.To4(), nil
+	}
+
+	ips, err := lookupIP(ctx, "ip4", address)
+	if err != nil {
+		return nil, fmt.Errorf("failed to resolve %s: %w", address, err)
+	}
+	for _, ip := range ips {
+		if ip.To4() != nil {
+			return ip.To4(), nil
+		}
+	}
+	return nil, fmt.Errorf("%s does not resolve to an IPv4 address: %w", address, ErrNotIPv4)
+}
+
+// ResolveRedirectAddresses looks up the current address of every hostname in
+// external_ip and redirect_addresses. A hostname that fails to resolve keeps its
+// previous address, if it had one.
+func ResolveRedirectAddresses(ctx context.Context) error {
+	ctx, cancel := context.WithTimeout(ctx, resolveTimeout)
+	defer cancel()
+
+	redirectMutex.RLock()
+	var hostnames []*redirectRule
+	for _, rule := range redirectRules {
+		if net.ParseIP(rule.address) == nil {
+			hostnames = append(hostnames, rule)
+		}
+	}
+	redirectMutex.RUnlock()
+
+	var problems []string
+	for _, rule := range hostnames {
+		ip, err := ResolveIPv4(ctx, rule.address)
+
+		redirectMutex.Lock()
+		if err == nil {
+			rule.ip, rule.err = ip, nil
+		} else if rule.ip == nil {
+			rule.err = err
+		}
+		redirectMutex.Unlock()
+
+		if err != nil {
+			problems = append(problems, err.Error())
+		}
+	}
+
+	if len(problems) > 0 {
+		return fmt.Errorf("failed to resolve redirect addresses: %s", strings.Join(problems, "; "))
+	}
+	return nil
+}
+
+// WatchRedirectAddresses re-resolves the hostnames in the redirect addresses every
+// interval until ctx is done so that changes to their DNS records are picked up.
+func WatchRedirectAddresses(ctx context.Context, interval time.Duration) {
+	if interval <= 0 {
+		return
+	}
+	ticker := time.NewTicker(interval)
+	defer ticker.Stop()
+
+	for {
+		select {
+		case <-ctx.Done():
+			return
+		case <-ticker.C:
+			if err := ResolveRedirectAddresses(ctx); err != nil {
+				Log.Warn(err)
+			}
+		}
+	}
+}
+
+// validateRedirectAddress checks that address is either an IPv4 address or a
+// hostname, since IPv6 addresses can't be sent in redirect packets.
+func validateRedirectAddress(address string) error {
+	if ip := net.ParseIP(address); ip != nil {
+		if ip.To4() == nil {
+			return fmt.Errorf("%q is an IPv6 address, but %v", address, ErrNotIPv4)
+		}
+		return nil
+	}
+	if !isHostname(address) {
+		return fmt.Errorf("must be an IPv4 address or hostname, got %q", address)
+	}
+	return nil
+}
+
+func isHostname(name string) bool {
+	if name == "" || len(name) > 253 {
+		return false
+	}
+	labels := strings.Split(strings.TrimSuffix(name, "."), ".")
+	// A numeric top-level label is a mistyped IP address rather than a hostname.
+	if strings.Trim(labels[len(labels)-1], "0123456789") == "" {
+		return false
+	}
+	for _, label := range labels {
+		if label == "" || len(label) > 63 || label[0] == '-' || label[len(label)-1] == '-' {
+			return false
+		}
+		for _, r := range label {
+			if !(r >= 'a' && r <= 'z' || r >= 'A' && r <= 'Z' || r >= '0' && r <= '9' || r == '-') {
+				return false
+			}
+		}
+	}
+	return true
+}
@@ -0,0 +1,85 @@
+package archon
+
+import (
+	"context"
+	"errors"
+	"net"
+	"testing"
+)
+
+func TestRedirectIP(t *testing.T) {
+	cfg := &Config{
+		ExternalIP: "203.0.113.5",
+		RedirectAddresses: []RedirectAddressConfig{
+			{Subnet: "192.168.1.0/24", Address: "192.168.1.10"},
+			{Subnet: "192.168.0.0/16", Address: "192.168.0.10"},
+			{Subnet: "10.0.0.0/8", Address: "2001:db8::1"},
+		},
+	}
+	setRedirectRules(cfg)
+	t.Cleanup(func() { redirectRules = nil })
+
+	tests := map[string]struct {
+		clientIP    string
+		expected    [4]byte
+		expectedErr error
+	}{
+		"wan client":          {clientIP: "198.51.100.7", expected: [4]byte{203, 0, 113, 5}},
+		"lan client":          {clientIP: "192.168.1.20", expected: [4]byte{192, 168, 1, 10}},
+		"first matching rule": {clientIP: "192.168.2.20", expected: [4]byte{192, 168, 0, 10}},
+		"ipv6 address":        {clientIP: "10.1.2.3", expectedErr: ErrNotIPv4},
+	}
+
+	for name, tt := range tests {
+		t.Run(name, func(t *testing.T) {
+			ip, err := RedirectIP(tt.clientIP)
+			if tt.expectedErr != nil {
+				if !errors.Is(err, tt.expectedErr) {
+					t.Fatalf("expected error %v, got: %v", tt.expectedErr, err)
+				}
+				return
+			}
+			if err != nil {
+				t.Fatalf("unexpected error: %v", err)
+			}
+			if ip != tt.expected {
+				t.Errorf("expected %v, got %v", tt.expected, ip)
+			}
+		})
+	}
+}
+
+func TestResolveRedirectAddresses(t *testing.T) {
+	addresses := map[string][]net.IP{"pso.example.com": {net.ParseIP("198.51.100.1")}}
+	lookupIP = func(_ context.Context, _, host string) ([]net.IP, error) {
+		if ips, ok := addresses[host]; ok {
+			return ips, nil
+		}
+		return nil, errors.New("no such host")
+	}
+	t.Cleanup(func() {
+		lookupIP = net.DefaultResolver.LookupIP
+		redirectRules = nil
+	})
+
+	setRedirectRules(&Config{ExternalIP: "pso.example.com"})
+	if _, err := RedirectIP("198.51.100.7"); err == nil {
+		t.Error("expected an error before the hostname is resolved")
+	}
+
+	if err := ResolveRedirectAddresses(context.Background()); err != nil {
+		t.Fatalf("failed to resolve redirect addresses: %v", err)
+	}
+	if ip, err := RedirectIP("198.51.100.7"); err != nil || ip != [4]byte{198, 51, 100, 1} {
+		t.Errorf("expected 198.51.100.1, got %v (err = %v)", ip, err)
+	}
+
+	// Failing to look up the hostname again keeps the address it last resolved to.
+	delete(addresses, "pso.example.com")
+	if err := ResolveRedirectAddresses(context.Background()); err == nil {
+		t.Error("expected resolving to fail")
+	}
+	if ip, err := RedirectIP("198.51.100.7"); err != nil || ip != [4]byte{198, 51, 100, 1} {
+		t.Errorf("expected 198.51.100.1, got %v (err = %v)", ip, err)
+	}
+}
@@ -119,6 +119,23 @@ SEARCH='ssl_key_file: "key.pem"'
 REPLACE="ssl_key_file: \"$(pwd)/key.pem\""
 sed_replace "$SEARCH" "$REPLACE" 'config.yaml'
 
+# Edit CA and client certificate locations
+SEARCH='shipgate_ca_file: "ca.pem"'
+REPLACE="shipgate_ca_file: \"$(pwd)/ca.pem\""
+sed_replace "$SEARCH" "$REPLACE" 'config.yaml'
+SEARCH='shipgate_client_certificate_file: "client-certificate.pem"'
+REPLACE="shipgate_client_certificate_file: \"$(pwd)/client-certificate.pem\""
+sed_replace "$SEARCH" "$REPLACE" 'config.yaml'
+SEARCH='shipgate_client_key_file: "client-key.pem"'
+REPLACE="shipgate_client_key_file: \"$(pwd)/client-key.pem\""
+sed_replace "$SEARCH" "$REPLACE" 'config.yaml'
+
+# Replace the example ship secret (used by the ship and the shipgate) with a random one.
+SHIP_SECRET=$(head -c 24 /dev/urandom | od -An -tx1 | tr -d ' \n')
+SEARCH='secret: "changemeshipsecret"'
+REPLACE="secret: \"$SHIP_SECRET\""
+sed_replace "$SEARCH" "$REPLACE" 'config.yaml'
+
 createdb "$DB_NAME"
 psql $DB_NAME -c "CREATE USER $ARCHON_USER WITH ENCRYPTED PASSWORD '$ARCHON_PASSWORD';"
 psql $DB_NAME -c "GRANT ALL ON ALL TABLES IN SCHEMA public TO $ARCHON_USER;"